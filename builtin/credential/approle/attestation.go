@@ -0,0 +1,214 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package approle
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+
+	"github.com/hashicorp/cap/jwt"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	// secretIDAttestationJWT binds SecretIDs to the subject of a JWT issued
+	// for the workload
+	secretIDAttestationJWT = "jwt"
+
+	// secretIDAttestationCert binds SecretIDs to the identity in the client
+	// certificate of the workload, which it must log in with
+	secretIDAttestationCert = "cert"
+
+	// attestedIdentityMetadataKey is the SecretID metadata key holding the
+	// attested identity of the workload
+	attestedIdentityMetadataKey = "attested_identity"
+)
+
+var attestationSigningAlgorithms = []jwt.Alg{
+	jwt.RS256, jwt.RS384, jwt.RS512,
+	jwt.ES256, jwt.ES384, jwt.ES512,
+	jwt.PS256, jwt.PS384, jwt.PS512,
+	jwt.EdDSA,
+}
+
+// validateAttestation checks that the attestation options set on the role
+// are consistent and can be parsed.
+func (role *roleStorageEntry) validateAttestation() error {
+	switch role.SecretIDAttestation {
+	case "":
+	case secretIDAttestationJWT:
+		if len(role.AttestationJWTValidationPubKeys) == 0 {
+			return fmt.Errorf("attestation_jwt_validation_pubkeys must be set when secret_id_attestation is %q", secretIDAttestationJWT)
+		}
+		if _, err := role.attestationValidator(); err != nil {
+			return err
+		}
+	case secretIDAttestationCert:
+		if role.AttestationCACerts == "" {
+			return fmt.Errorf("attestation_ca_certs must be set when secret_id_attestation is %q", secretIDAttestationCert)
+		}
+		if _, err := role.attestationCertPool(); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("invalid secret_id_attestation %q, must be %q or %q", role.SecretIDAttestation, secretIDAttestationJWT, secretIDAttestationCert)
+	}
+
+	return nil
+}
+
+func (role *roleStorageEntry) attestationValidator() (*jwt.Validator, error) {
+	keys := make([]crypto.PublicKey, 0, len(role.AttestationJWTValidationPubKeys))
+	for _, keyPEM := range role.AttestationJWTValidationPubKeys {
+		key, err := jwt.ParsePublicKeyPEM([]byte(keyPEM))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse attestation_jwt_validation_pubkeys: %w", err)
+		}
+		keys = append(keys, key)
+	}
+
+	keySet, err := jwt.NewStaticKeySet(keys)
+	if err != nil {
+		return nil, err
+	}
+	return jwt.NewValidator(keySet)
+}
+
+func (role *roleStorageEntry) attestationCertPool() (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM([]byte(role.AttestationCACerts)) {
+		return nil, fmt.Errorf("failed to parse attestation_ca_certs")
+	}
+	return pool, nil
+}
+
+// attestedIdentity verifies the attestation of the workload a SecretID is
+// generated for and returns its identity.
+func (role *roleStorageEntry) attestedIdentity(ctx context.Context, attestation string) (string, error) {
+	if attestation == "" {
+		return "", fmt.Errorf("missing attestation")
+	}
+
+	switch role.SecretIDAttestation {
+	case secretIDAttestationJWT:
+		return role.verifyJWTAttestation(ctx, attestation)
+	case secretIDAttestationCert:
+		var chain []*x509.Certificate
+		for rest := []byte(attestation); ; {
+			var block *pem.Block
+			block, rest = pem.Decode(rest)
+			if block == nil {
+				break
+			}
+			if block.Type != "CERTIFICATE" {
+				continue
+			}
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return "", fmt.Errorf("failed to parse certificate: %w", err)
+			}
+			chain = append(chain, cert)
+		}
+		if len(chain) == 0 {
+			return "", fmt.Errorf("attestation is not a PEM encoded certificate")
+		}
+		return role.verifyCertAttestation(chain)
+	default:
+		return "", fmt.Errorf("secret_id_attestation is not set on the role")
+	}
+}
+
+// verifyLoginAttestation checks that the client logging in with a SecretID
+// proves the identity the SecretID was bound to when it was generated.
+func (role *roleStorageEntry) verifyLoginAttestation(ctx context.Context, req *logical.Request, data *framework.FieldData, entry *secretIDStorageEntry) error {
+	if entry.AttestationType != role.SecretIDAttestation {
+		return fmt.Errorf("secret ID was not generated with the secret_id_attestation of the role")
+	}
+
+	var identity string
+	var err error
+	switch role.SecretIDAttestation {
+	case "":
+		return nil
+	case secretIDAttestationJWT:
+		attestation := data.Get("attestation").(string)
+		if attestation == "" {
+			return fmt.Errorf("missing attestation")
+		}
+		identity, err = role.verifyJWTAttestation(ctx, attestation)
+	case secretIDAttestationCert:
+		if req.Connection == nil || req.Connection.ConnState == nil || len(req.Connection.ConnState.PeerCertificates) == 0 {
+			return fmt.Errorf("client certificate must be presented")
+		}
+		identity, err = role.verifyCertAttestation(req.Connection.ConnState.PeerCertificates)
+	}
+	if err != nil {
+		return err
+	}
+
+	if identity != entry.AttestedIdentity {
+		return fmt.Errorf("attested identity %q does not match the secret ID", identity)
+	}
+	return nil
+}
+
+// verifyJWTAttestation validates a JWT attestation and returns its subject.
+func (role *roleStorageEntry) verifyJWTAttestation(ctx context.Context, token string) (string, error) {
+	validator, err := role.attestationValidator()
+	if err != nil {
+		return "", err
+	}
+
+	claims, err := validator.Validate(ctx, token, jwt.Expected{
+		Issuer:            role.AttestationBoundIssuer,
+		Audiences:         role.AttestationBoundAudiences,
+		SigningAlgorithms: attestationSigningAlgorithms,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	subject, _ := claims["sub"].(string)
+	if subject == "" {
+		return "", fmt.Errorf("attestation has no subject (sub) claim")
+	}
+	return subject, nil
+}
+
+// verifyCertAttestation verifies a client certificate chain against the CA
+// certificates of the role and returns the identity of the leaf: its first
+// URI SAN, such as a SPIFFE ID, or else its common name.
+func (role *roleStorageEntry) verifyCertAttestation(chain []*x509.Certificate) (string, error) {
+	roots, err := role.attestationCertPool()
+	if err != nil {
+		return "", err
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range chain[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	leaf := chain[0]
+	if _, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}); err != nil {
+		return "", fmt.Errorf("failed to verify certificate: %w", err)
+	}
+
+	switch {
+	case len(leaf.URIs) != 0:
+		return leaf.URIs[0].String(), nil
+	case leaf.Subject.CommonName != "":
+		return leaf.Subject.CommonName, nil
+	default:
+		return "", fmt.Errorf("certificate has neither a URI SAN nor a common name")
+	}
+}
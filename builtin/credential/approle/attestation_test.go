@@ -0,0 +1,330 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package approle
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+)
+
+func testAttestationRole(t *testing.T, b *backend, s logical.Storage, data map[string]interface{}) string {
+	t.Helper()
+
+	b.requestNoErr(t, &logical.Request{
+		Path:      "role/workload",
+		Operation: logical.CreateOperation,
+		Storage:   s,
+		Data:      data,
+	})
+	resp := b.requestNoErr(t, &logical.Request{
+		Path:      "role/workload/role-id",
+		Operation: logical.ReadOperation,
+		Storage:   s,
+	})
+	return resp.Data["role_id"].(string)
+}
+
+func testAttestationSecretID(b *backend, s logical.Storage, attestation string) (*logical.Response, error) {
+	return b.HandleRequest(context.Background(), &logical.Request{
+		Path:      "role/workload/secret-id",
+		Operation: logical.UpdateOperation,
+		Storage:   s,
+		Data: map[string]interface{}{
+			"attestation": attestation,
+		},
+		WrapInfo: &logical.RequestWrapInfo{TTL: time.Minute},
+	})
+}
+
+func TestAppRole_SecretIDAttestation_JWT(t *testing.T) {
+	b, s := createBackendWithStorage(t)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	pubKey, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.ES256, Key: key}, nil)
+	require.NoError(t, err)
+
+	issue := func(subject, audience string) string {
+		t.Helper()
+		token, err := jwt.Signed(signer).Claims(jwt.Claims{
+			Issuer:    "orchestrator",
+			Subject:   subject,
+			Audience:  jwt.Audience{audience},
+			NotBefore: jwt.NewNumericDate(time.Now().Add(-time.Minute)),
+			Expiry:    jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		}).CompactSerialize()
+		require.NoError(t, err)
+		return token
+	}
+
+	roleID := testAttestationRole(t, b, s, map[string]interface{}{
+		"secret_id_attestation":              "jwt",
+		"attestation_jwt_validation_pubkeys": string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubKey})),
+		"attestation_bound_issuer":           "orchestrator",
+		"attestation_bound_audiences":        "vault",
+	})
+
+	// Response wrapping is required
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Path:      "role/workload/secret-id",
+		Operation: logical.UpdateOperation,
+		Storage:   s,
+		Data: map[string]interface{}{
+			"attestation": issue("workload-a", "vault"),
+		},
+	})
+	require.NoError(t, err)
+	require.EqualError(t, resp.Error(), "secret IDs of roles with secret_id_attestation set must be response-wrapped")
+
+	resp, err = testAttestationSecretID(b, s, "")
+	require.NoError(t, err)
+	require.EqualError(t, resp.Error(), "failed to verify attestation: missing attestation")
+
+	resp, err = testAttestationSecretID(b, s, issue("workload-a", "other"))
+	require.NoError(t, err)
+	require.ErrorContains(t, resp.Error(), "invalid audience (aud) claim")
+
+	resp, err = testAttestationSecretID(b, s, issue("workload-a", "vault"))
+	require.NoError(t, err)
+	require.False(t, resp.IsError(), resp.Error())
+	secretID := resp.Data["secret_id"].(string)
+
+	resp = b.requestNoErr(t, &logical.Request{
+		Path:      "role/workload/secret-id/lookup",
+		Operation: logical.UpdateOperation,
+		Storage:   s,
+		Data: map[string]interface{}{
+			"secret_id": secretID,
+		},
+	})
+	require.Equal(t, map[string]string{"attested_identity": "workload-a"}, resp.Data["metadata"])
+
+	login := func(attestation string) (*logical.Response, error) {
+		return b.HandleRequest(context.Background(), &logical.Request{
+			Path:      "login",
+			Operation: logical.UpdateOperation,
+			Storage:   s,
+			Data: map[string]interface{}{
+				"role_id":     roleID,
+				"secret_id":   secretID,
+				"attestation": attestation,
+			},
+			Connection: &logical.Connection{RemoteAddr: "127.0.0.1"},
+		})
+	}
+
+	resp, err = login("")
+	require.ErrorIs(t, err, logical.ErrInvalidCredentials)
+	require.EqualError(t, resp.Error(), "failed to verify attestation: missing attestation")
+
+	resp, err = login(issue("workload-b", "vault"))
+	require.ErrorIs(t, err, logical.ErrInvalidCredentials)
+	require.EqualError(t, resp.Error(), `failed to verify attestation: attested identity "workload-b" does not match the secret ID`)
+
+	resp, err = login(issue("workload-a", "vault"))
+	require.NoError(t, err)
+	require.NotNil(t, resp.Auth)
+	require.Equal(t, "workload-a", resp.Auth.Metadata["attested_identity"])
+
+	// Disabling attestation doesn't unbind existing secret IDs
+	b.requestNoErr(t, &logical.Request{
+		Path:      "role/workload",
+		Operation: logical.UpdateOperation,
+		Storage:   s,
+		Data: map[string]interface{}{
+			"secret_id_attestation": "",
+		},
+	})
+	resp, err = login(issue("workload-a", "vault"))
+	require.ErrorIs(t, err, logical.ErrInvalidCredentials)
+	require.EqualError(t, resp.Error(), "failed to verify attestation: secret ID was not generated with the secret_id_attestation of the role")
+}
+
+func TestAppRole_SecretIDAttestation_Cert(t *testing.T) {
+	b, s := createBackendWithStorage(t)
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "orchestrator CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	ca, err := x509.ParseCertificate(caDER)
+	require.NoError(t, err)
+
+	issue := func(spiffeID string) *x509.Certificate {
+		t.Helper()
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		uri, err := url.Parse(spiffeID)
+		require.NoError(t, err)
+		der, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+			SerialNumber: big.NewInt(time.Now().UnixNano()),
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+			URIs:         []*url.URL{uri},
+		}, ca, &key.PublicKey, caKey)
+		require.NoError(t, err)
+		cert, err := x509.ParseCertificate(der)
+		require.NoError(t, err)
+		return cert
+	}
+	encode := func(cert *x509.Certificate) string {
+		return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}))
+	}
+
+	roleID := testAttestationRole(t, b, s, map[string]interface{}{
+		"secret_id_attestation": "cert",
+		"attestation_ca_certs":  encode(ca),
+	})
+
+	workloadA := issue("spiffe://example.org/workload-a")
+	workloadB := issue("spiffe://example.org/workload-b")
+
+	resp, err := testAttestationSecretID(b, s, testCACertPEM(t))
+	require.NoError(t, err)
+	require.ErrorContains(t, resp.Error(), "failed to verify attestation: failed to verify certificate")
+
+	resp, err = testAttestationSecretID(b, s, encode(workloadA))
+	require.NoError(t, err)
+	require.False(t, resp.IsError(), resp.Error())
+	secretID := resp.Data["secret_id"].(string)
+
+	login := func(conn *logical.Connection) (*logical.Response, error) {
+		return b.HandleRequest(context.Background(), &logical.Request{
+			Path:      "login",
+			Operation: logical.UpdateOperation,
+			Storage:   s,
+			Data: map[string]interface{}{
+				"role_id":   roleID,
+				"secret_id": secretID,
+			},
+			Connection: conn,
+		})
+	}
+	mTLS := func(cert *x509.Certificate) *logical.Connection {
+		return &logical.Connection{
+			RemoteAddr: "127.0.0.1",
+			ConnState:  &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}},
+		}
+	}
+
+	resp, err = login(&logical.Connection{RemoteAddr: "127.0.0.1"})
+	require.ErrorIs(t, err, logical.ErrInvalidCredentials)
+	require.EqualError(t, resp.Error(), "failed to verify attestation: client certificate must be presented")
+
+	resp, err = login(mTLS(workloadB))
+	require.ErrorIs(t, err, logical.ErrInvalidCredentials)
+	require.EqualError(t, resp.Error(), `failed to verify attestation: attested identity "spiffe://example.org/workload-b" does not match the secret ID`)
+
+	resp, err = login(mTLS(workloadA))
+	require.NoError(t, err)
+	require.NotNil(t, resp.Auth)
+	require.Equal(t, "spiffe://example.org/workload-a", resp.Auth.Metadata["attested_identity"])
+}
+
+func TestAppRole_SecretIDAttestation_RoleValidation(t *testing.T) {
+	b, s := createBackendWithStorage(t)
+
+	for name, tc := range map[string]struct {
+		data map[string]interface{}
+		err  string
+	}{
+		"unknown type": {
+			data: map[string]interface{}{"secret_id_attestation": "tpm"},
+			err:  `invalid secret_id_attestation "tpm", must be "jwt" or "cert"`,
+		},
+		"jwt without keys": {
+			data: map[string]interface{}{"secret_id_attestation": "jwt"},
+			err:  `attestation_jwt_validation_pubkeys must be set when secret_id_attestation is "jwt"`,
+		},
+		"invalid key": {
+			data: map[string]interface{}{
+				"secret_id_attestation":              "jwt",
+				"attestation_jwt_validation_pubkeys": "not a key",
+			},
+			err: "failed to parse attestation_jwt_validation_pubkeys",
+		},
+		"cert without CA": {
+			data: map[string]interface{}{"secret_id_attestation": "cert"},
+			err:  `attestation_ca_certs must be set when secret_id_attestation is "cert"`,
+		},
+		"invalid CA": {
+			data: map[string]interface{}{
+				"secret_id_attestation": "cert",
+				"attestation_ca_certs":  "not a certificate",
+			},
+			err: "failed to parse attestation_ca_certs",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			resp, err := b.HandleRequest(context.Background(), &logical.Request{
+				Path:      "role/workload",
+				Operation: logical.CreateOperation,
+				Storage:   s,
+				Data:      tc.data,
+			})
+			require.NoError(t, err)
+			require.ErrorContains(t, resp.Error(), tc.err)
+		})
+	}
+
+	// Attestation binds secret IDs, so they can't be made optional
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Path:      "role/workload",
+		Operation: logical.CreateOperation,
+		Storage:   s,
+		Data: map[string]interface{}{
+			"secret_id_attestation": "cert",
+			"attestation_ca_certs":  testCACertPEM(t),
+			"bind_secret_id":        false,
+			"secret_id_bound_cidrs": "127.0.0.1/32",
+		},
+	})
+	require.Nil(t, resp)
+	require.EqualError(t, err, "bind_secret_id must be set on the role when secret_id_attestation is set")
+}
+
+func testCACertPEM(t *testing.T) string {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
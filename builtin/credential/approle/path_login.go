@@ -33,6 +33,11 @@ func pathLogin(b *backend) *framework.Path {
 				Default:     "",
 				Description: "SecretID belong to the App role",
 			},
+			"attestation": {
+				Type: framework.TypeString,
+				Description: `JWT attestation of the workload. Required if the secret_id_attestation of the
+role is "jwt". If it is "cert", the workload must instead log in with its client certificate.`,
+			},
 		},
 		Operations: map[logical.Operation]framework.OperationHandler{
 			logical.UpdateOperation: &framework.PathOperation{
@@ -220,6 +225,11 @@ func (b *backend) pathLoginUpdate(ctx context.Context, req *logical.Request, dat
 			return logical.ErrorResponse("invalid role or secret ID"), nil
 		}
 
+		// Verify the attestation before the SecretID gets used up
+		if err := role.verifyLoginAttestation(ctx, req, data, entry); err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("failed to verify attestation: %v", err)), logical.ErrInvalidCredentials
+		}
+
 		switch {
 		case entry.SecretIDNumUses == 0:
 			//
@@ -254,6 +264,29 @@ func (b *backend) pathLoginUpdate(ctx context.Context, req *logical.Request, dat
 					).Error()), nil
 				}
 			}
+
+			// Without a use limit the SecretID is only written to if the
+			// role tracks its usage, which requires switching the lock
+			// from a `read` to a `write`
+			if role.SecretIDUsageTracking {
+				secretIDLock.RUnlock()
+				secretIDLock.Lock()
+				unlockFunc = secretIDLock.Unlock
+
+				// Lock switching may change the data. Refresh the contents.
+				entry, err = b.nonLockedSecretIDStorageEntry(ctx, req.Storage, role.SecretIDPrefix, roleNameHMAC, secretIDHMAC)
+				if err != nil {
+					return nil, err
+				}
+				if entry == nil {
+					return logical.ErrorResponse("invalid role or secret ID"), nil
+				}
+
+				entry.recordUsage(time.Now(), remoteAddr(req))
+				if err := b.nonLockedSetSecretIDStorageEntry(ctx, req.Storage, role.SecretIDPrefix, roleNameHMAC, secretIDHMAC, entry); err != nil {
+					return nil, err
+				}
+			}
 		default:
 			//
 			// If the SecretIDNumUses is non-zero, it means that its use-count should be updated
@@ -291,6 +324,9 @@ func (b *backend) pathLoginUpdate(ctx context.Context, req *logical.Request, dat
 				// If the use count is greater than one, decrement it and update the last updated time.
 				entry.SecretIDNumUses -= 1
 				entry.LastUpdatedTime = time.Now()
+				if role.SecretIDUsageTracking {
+					entry.recordUsage(entry.LastUpdatedTime, remoteAddr(req))
+				}
 
 				sEntry, err := logical.StorageEntryJSON(entryIndex, &entry)
 				if err != nil {
@@ -385,6 +421,14 @@ func (b *backend) pathLoginUpdate(ctx context.Context, req *logical.Request, dat
 	}, nil
 }
 
+// remoteAddr returns the source address of the request, if known.
+func remoteAddr(req *logical.Request) string {
+	if req.Connection == nil {
+		return ""
+	}
+	return req.Connection.RemoteAddr
+}
+
 // Invoked when the token issued by this backend is attempting a renewal.
 func (b *backend) pathLoginRenew(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 	roleName := req.Auth.InternalData["role_name"].(string)
@@ -423,4 +467,9 @@ constraint (enabled by default) on the App role requires the
 
 'role_id' is fetched using the 'role/<role_name>/role_id'
 endpoint and 'secret_id' is fetched using the 'role/<role_name>/secret_id'
-endpoint.`
+endpoint.
+
+If 'secret_id_attestation' is set on the App role, the client must
+also prove the identity of the workload the 'secret_id' was generated
+for, with a JWT in the 'attestation' field or with its client
+certificate.`
@@ -80,6 +80,29 @@ type roleStorageEntry struct {
 	// SecretIDPrefix is the storage prefix for persisting secret IDs. This
 	// differs based on whether the secret IDs are cluster local or not.
 	SecretIDPrefix string `json:"secret_id_prefix" mapstructure:"secret_id_prefix"`
+
+	// SecretIDAttestation, if set, requires SecretIDs to be generated for a
+	// workload attested by a trusted orchestrator, either with a JWT or with
+	// a client certificate. The SecretID is bound to the attested identity
+	// and the workload has to prove it again during login.
+	SecretIDAttestation string `json:"secret_id_attestation" mapstructure:"secret_id_attestation"`
+
+	// SecretIDUsageTracking, if set, records the logins performed with the
+	// SecretIDs of the role. Logins with SecretIDs without a use limit then
+	// write to storage, so they are handled by the active node.
+	SecretIDUsageTracking bool `json:"secret_id_usage_tracking" mapstructure:"secret_id_usage_tracking"`
+
+	// PEM encoded public keys verifying the signature of JWT attestations
+	AttestationJWTValidationPubKeys []string `json:"attestation_jwt_validation_pubkeys" mapstructure:"attestation_jwt_validation_pubkeys"`
+
+	// Issuer JWT attestations must be issued by, if set
+	AttestationBoundIssuer string `json:"attestation_bound_issuer" mapstructure:"attestation_bound_issuer"`
+
+	// Audiences JWT attestations must be issued for, if set
+	AttestationBoundAudiences []string `json:"attestation_bound_audiences" mapstructure:"attestation_bound_audiences"`
+
+	// PEM encoded CA certificates certificate attestations must chain to
+	AttestationCACerts string `json:"attestation_ca_certs" mapstructure:"attestation_ca_certs"`
 }
 
 // roleIDStorageEntry represents the reverse mapping from RoleID to Role
@@ -185,6 +208,44 @@ to 0, meaning no expiration.`,
 				Description: `If set, the secret IDs generated using this role will be cluster local. This
 can only be set during role creation and once set, it can't be reset later.`,
 			},
+
+			"secret_id_attestation": {
+				Type: framework.TypeString,
+				Description: `If set, secret IDs can only be generated with an attestation of the workload
+they are for, and must be response-wrapped. The secret ID is bound to the
+attested identity, which the workload has to prove again during login. Either
+"jwt" or "cert". Defaults to "", meaning no attestation is required.`,
+			},
+
+			"secret_id_usage_tracking": {
+				Type: framework.TypeBool,
+				Description: `If set, the number of logins, the last login time and the source addresses
+of the secret IDs are recorded, and secret IDs can be listed by usage. Logins
+with secret IDs without a use limit then write to storage, so they are
+forwarded to the active node. Defaults to false.`,
+			},
+
+			"attestation_jwt_validation_pubkeys": {
+				Type: framework.TypeCommaStringSlice,
+				Description: `List of PEM encoded public keys verifying the signature of JWT attestations.
+Required if secret_id_attestation is "jwt".`,
+			},
+
+			"attestation_bound_issuer": {
+				Type:        framework.TypeString,
+				Description: `If set, the issuer ("iss" claim) JWT attestations must have.`,
+			},
+
+			"attestation_bound_audiences": {
+				Type:        framework.TypeCommaStringSlice,
+				Description: `If set, JWT attestations must have one of these audiences ("aud" claim).`,
+			},
+
+			"attestation_ca_certs": {
+				Type: framework.TypeString,
+				Description: `PEM encoded CA certificates client certificates of workloads must chain to.
+Required if secret_id_attestation is "cert".`,
+			},
 		},
 		ExistenceCheck: b.pathRoleExistenceCheck,
 		Operations: map[logical.Operation]framework.OperationHandler{
@@ -227,6 +288,36 @@ can only be set during role creation and once set, it can't be reset later.`,
 								Required:    true,
 								Description: "If true, the secret identifiers generated using this role will be cluster local. This can only be set during role creation and once set, it can't be reset later",
 							},
+							"secret_id_attestation": {
+								Type:        framework.TypeString,
+								Required:    true,
+								Description: "The attestation of the workload required to generate a secret ID, if any.",
+							},
+							"secret_id_usage_tracking": {
+								Type:        framework.TypeBool,
+								Required:    true,
+								Description: "If true, the logins performed with the secret IDs of the role are recorded.",
+							},
+							"attestation_jwt_validation_pubkeys": {
+								Type:        framework.TypeCommaStringSlice,
+								Required:    true,
+								Description: "List of PEM encoded public keys verifying the signature of JWT attestations.",
+							},
+							"attestation_bound_issuer": {
+								Type:        framework.TypeString,
+								Required:    true,
+								Description: "The issuer JWT attestations must have.",
+							},
+							"attestation_bound_audiences": {
+								Type:        framework.TypeCommaStringSlice,
+								Required:    true,
+								Description: "The audiences JWT attestations must have one of.",
+							},
+							"attestation_ca_certs": {
+								Type:        framework.TypeString,
+								Required:    true,
+								Description: "PEM encoded CA certificates client certificates of workloads must chain to.",
+							},
 							"token_bound_cidrs": {
 								Type:        framework.TypeCommaStringSlice,
 								Required:    true,
@@ -936,6 +1027,24 @@ Overrides secret_id_num_uses role option when supplied. May not be higher than r
 					Description: `Duration in seconds after which this SecretID expires.
 Overrides secret_id_ttl role option when supplied. May not be longer than role's secret_id_ttl.`,
 				},
+				"attestation": {
+					Type: framework.TypeString,
+					Description: `Attestation of the workload the SecretID is for. A JWT or a PEM encoded client
+certificate, depending on the secret_id_attestation role option. Required if
+secret_id_attestation is set on the role.`,
+				},
+				"filter": {
+					Type: framework.TypeString,
+					Description: `Only list the SecretIDs with this usage flag: "unused", "stale" or
+"multiple_source_ips". Only used by the list operation.`,
+					Query: true,
+				},
+				"stale_after": {
+					Type: framework.TypeDurationSecond,
+					Description: `Duration after which SecretIDs that weren't used, or created if never used,
+are flagged as stale. Required to filter stale SecretIDs. Only used by the list operation.`,
+					Query: true,
+				},
 			},
 			Operations: map[logical.Operation]framework.OperationHandler{
 				logical.UpdateOperation: &framework.PathOperation{
@@ -973,6 +1082,23 @@ Overrides secret_id_ttl role option when supplied. May not be longer than role's
 					DisplayAttrs: &framework.DisplayAttributes{
 						OperationSuffix: "secret-ids",
 					},
+					Responses: map[int][]framework.Response{
+						http.StatusOK: {{
+							Description: "OK",
+							Fields: map[string]*framework.FieldSchema{
+								"keys": {
+									Type:        framework.TypeStringSlice,
+									Required:    true,
+									Description: "Accessors of the secret IDs.",
+								},
+								"key_info": {
+									Type:        framework.TypeMap,
+									Required:    false,
+									Description: "Usage statistics and flags of the secret IDs, keyed by accessor.",
+								},
+							},
+						}},
+					},
 				},
 			},
 			HelpSynopsis:    strings.TrimSpace(roleHelp["role-secret-id"][0]),
@@ -1043,6 +1169,26 @@ Overrides secret_id_ttl role option when supplied. May not be longer than role's
 									Required:    true,
 									Description: "List of CIDR blocks. If set, specifies the blocks of IP addresses which can use the returned token. Should be a subset of the token CIDR blocks listed on the role, if any.",
 								},
+								"login_count": {
+									Type:        framework.TypeInt64,
+									Required:    false,
+									Description: "Number of logins performed with the secret ID.",
+								},
+								"last_used_time": {
+									Type:        framework.TypeTime,
+									Required:    false,
+									Description: "Time of the last login performed with the secret ID.",
+								},
+								"source_ips": {
+									Type:        framework.TypeCommaStringSlice,
+									Required:    false,
+									Description: "Most recent distinct addresses the secret ID was used from.",
+								},
+								"usage_flags": {
+									Type:        framework.TypeCommaStringSlice,
+									Required:    false,
+									Description: "Flags raised by the usage of the secret ID.",
+								},
 							},
 						}},
 					},
@@ -1152,6 +1298,26 @@ Overrides secret_id_ttl role option when supplied. May not be longer than role's
 									Required:    true,
 									Description: "List of CIDR blocks. If set, specifies the blocks of IP addresses which can use the returned token. Should be a subset of the token CIDR blocks listed on the role, if any.",
 								},
+								"login_count": {
+									Type:        framework.TypeInt64,
+									Required:    false,
+									Description: "Number of logins performed with the secret ID.",
+								},
+								"last_used_time": {
+									Type:        framework.TypeTime,
+									Required:    false,
+									Description: "Time of the last login performed with the secret ID.",
+								},
+								"source_ips": {
+									Type:        framework.TypeCommaStringSlice,
+									Required:    false,
+									Description: "Most recent distinct addresses the secret ID was used from.",
+								},
+								"usage_flags": {
+									Type:        framework.TypeCommaStringSlice,
+									Required:    false,
+									Description: "Flags raised by the usage of the secret ID.",
+								},
 							},
 						}},
 					},
@@ -1238,6 +1404,12 @@ Overrides secret_id_num_uses role option when supplied. May not be higher than r
 					Description: `Duration in seconds after which this SecretID expires.
 Overrides secret_id_ttl role option when supplied. May not be longer than role's secret_id_ttl.`,
 				},
+				"attestation": {
+					Type: framework.TypeString,
+					Description: `Attestation of the workload the SecretID is for. A JWT or a PEM encoded client
+certificate, depending on the secret_id_attestation role option. Required if
+secret_id_attestation is set on the role.`,
+				},
 			},
 			Operations: map[logical.Operation]framework.OperationHandler{
 				logical.UpdateOperation: &framework.PathOperation{
@@ -1341,7 +1513,24 @@ func (b *backend) pathRoleSecretIDList(ctx context.Context, req *logical.Request
 		return nil, err
 	}
 
+	filter := data.Get("filter").(string)
+	if filter != "" && !role.SecretIDUsageTracking {
+		return logical.ErrorResponse("secret_id_usage_tracking must be set on the role to filter secret IDs"), nil
+	}
+	switch filter {
+	case "", secretIDUsageUnused, secretIDUsageMultipleSourceIPs:
+	case secretIDUsageStale:
+		if data.Get("stale_after").(int) <= 0 {
+			return logical.ErrorResponse("stale_after must be set to filter stale secret IDs"), nil
+		}
+	default:
+		return logical.ErrorResponse(fmt.Sprintf("invalid filter %q, must be %q, %q or %q", filter, secretIDUsageUnused, secretIDUsageStale, secretIDUsageMultipleSourceIPs)), nil
+	}
+	staleAfter := time.Duration(data.Get("stale_after").(int)) * time.Second
+	now := time.Now()
+
 	var listItems []string
+	keyInfo := make(map[string]interface{})
 	for _, secretIDHMAC := range secretIDHMACs {
 		// For sanity
 		if secretIDHMAC == "" {
@@ -1372,11 +1561,24 @@ func (b *backend) pathRoleSecretIDList(ctx context.Context, req *logical.Request
 			secretIDLock.RUnlock()
 			return nil, err
 		}
-		listItems = append(listItems, result.SecretIDAccessor)
 		secretIDLock.RUnlock()
+
+		if !role.SecretIDUsageTracking {
+			listItems = append(listItems, result.SecretIDAccessor)
+			continue
+		}
+		usage := result.usageResponseData(now, staleAfter)
+		if filter != "" && !strutil.StrListContains(usage["usage_flags"].([]string), filter) {
+			continue
+		}
+		listItems = append(listItems, result.SecretIDAccessor)
+		keyInfo[result.SecretIDAccessor] = usage
 	}
 
-	return logical.ListResponse(listItems), nil
+	if !role.SecretIDUsageTracking {
+		return logical.ListResponse(listItems), nil
+	}
+	return logical.ListResponseWithInfo(listItems, keyInfo), nil
 }
 
 // validateRoleConstraints checks if the role has at least one constraint
@@ -1396,6 +1598,10 @@ func validateRoleConstraints(role *roleStorageEntry) error {
 		return fmt.Errorf("at least one constraint should be enabled on the role")
 	}
 
+	if role.SecretIDAttestation != "" && !role.BindSecretID {
+		return fmt.Errorf("bind_secret_id must be set on the role when secret_id_attestation is set")
+	}
+
 	return nil
 }
 
@@ -1648,6 +1854,28 @@ func (b *backend) pathRoleCreateUpdate(ctx context.Context, req *logical.Request
 		role.SecretIDTTL = time.Second * time.Duration(data.Get("secret_id_ttl").(int))
 	}
 
+	if attestationRaw, ok := data.GetOk("secret_id_attestation"); ok {
+		role.SecretIDAttestation = attestationRaw.(string)
+	}
+	if pubKeysRaw, ok := data.GetOk("attestation_jwt_validation_pubkeys"); ok {
+		role.AttestationJWTValidationPubKeys = pubKeysRaw.([]string)
+	}
+	if issuerRaw, ok := data.GetOk("attestation_bound_issuer"); ok {
+		role.AttestationBoundIssuer = issuerRaw.(string)
+	}
+	if audiencesRaw, ok := data.GetOk("attestation_bound_audiences"); ok {
+		role.AttestationBoundAudiences = audiencesRaw.([]string)
+	}
+	if caCertsRaw, ok := data.GetOk("attestation_ca_certs"); ok {
+		role.AttestationCACerts = caCertsRaw.(string)
+	}
+	if err := role.validateAttestation(); err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+	if trackingRaw, ok := data.GetOk("secret_id_usage_tracking"); ok {
+		role.SecretIDUsageTracking = trackingRaw.(bool)
+	}
+
 	// handle upgrade cases
 	{
 		if err := tokenutil.UpgradeValue(data, "policies", "token_policies", &role.Policies, &role.TokenPolicies); err != nil {
@@ -1702,6 +1930,13 @@ func (b *backend) pathRoleRead(ctx context.Context, req *logical.Request, data *
 		"secret_id_num_uses":    role.SecretIDNumUses,
 		"secret_id_ttl":         role.SecretIDTTL / time.Second,
 		"local_secret_ids":      false,
+
+		"secret_id_attestation":              role.SecretIDAttestation,
+		"secret_id_usage_tracking":           role.SecretIDUsageTracking,
+		"attestation_jwt_validation_pubkeys": role.AttestationJWTValidationPubKeys,
+		"attestation_bound_issuer":           role.AttestationBoundIssuer,
+		"attestation_bound_audiences":        role.AttestationBoundAudiences,
+		"attestation_ca_certs":               role.AttestationCACerts,
 	}
 	role.PopulateTokenData(respData)
 
@@ -1867,11 +2102,11 @@ func (b *backend) pathRoleSecretIDLookupUpdate(ctx context.Context, req *logical
 	}
 
 	return &logical.Response{
-		Data: secretIDEntry.ToResponseData(),
+		Data: secretIDEntry.ToResponseData(role),
 	}, nil
 }
 
-func (entry *secretIDStorageEntry) ToResponseData() map[string]interface{} {
+func (entry *secretIDStorageEntry) ToResponseData(role *roleStorageEntry) map[string]interface{} {
 	ret := map[string]interface{}{
 		"secret_id_accessor": entry.SecretIDAccessor,
 		"secret_id_num_uses": entry.SecretIDNumUses,
@@ -1886,6 +2121,27 @@ func (entry *secretIDStorageEntry) ToResponseData() map[string]interface{} {
 	if len(entry.TokenBoundCIDRs) == 0 {
 		ret["token_bound_cidrs"] = []string{}
 	}
+	// The usage is only recorded if the role tracks it
+	if role.SecretIDUsageTracking {
+		for k, v := range entry.usageResponseData(time.Now(), 0) {
+			ret[k] = v
+		}
+	}
+	return ret
+}
+
+// usageResponseData returns the usage statistics of the SecretID and the
+// flags they raise.
+func (entry *secretIDStorageEntry) usageResponseData(now time.Time, staleAfter time.Duration) map[string]interface{} {
+	ret := map[string]interface{}{
+		"login_count":    entry.LoginCount,
+		"last_used_time": entry.LastUsedTime,
+		"source_ips":     entry.SourceIPs,
+		"usage_flags":    entry.usageFlags(now, staleAfter),
+	}
+	if len(entry.SourceIPs) == 0 {
+		ret["source_ips"] = []string{}
+	}
 	return ret
 }
 
@@ -2008,7 +2264,7 @@ func (b *backend) pathRoleSecretIDAccessorLookupUpdate(ctx context.Context, req
 	}
 
 	return &logical.Response{
-		Data: secretIDEntry.ToResponseData(),
+		Data: secretIDEntry.ToResponseData(role),
 	}, nil
 }
 
@@ -3085,6 +3341,22 @@ func (b *backend) handleRoleSecretIDCommon(ctx context.Context, req *logical.Req
 		return logical.ErrorResponse(fmt.Sprintf("failed to parse metadata: %v", err)), nil
 	}
 
+	if role.SecretIDAttestation != "" {
+		// The SecretID is meant for the attested workload only, so it must not
+		// be readable by whoever relays the response
+		if req.WrapInfo == nil || req.WrapInfo.TTL == 0 {
+			return logical.ErrorResponse("secret IDs of roles with secret_id_attestation set must be response-wrapped"), nil
+		}
+
+		identity, err := role.attestedIdentity(ctx, data.Get("attestation").(string))
+		if err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("failed to verify attestation: %v", err)), nil
+		}
+		secretIDStorage.AttestationType = role.SecretIDAttestation
+		secretIDStorage.AttestedIdentity = identity
+		secretIDStorage.Metadata[attestedIdentityMetadataKey] = identity
+	}
+
 	if secretIDStorage, err = b.registerSecretIDEntry(ctx, req.Storage, role.name, secretID, role.HMACKey, role.SecretIDPrefix, secretIDStorage); err != nil {
 		return nil, fmt.Errorf("failed to store secret_id: %w", err)
 	}
@@ -3305,7 +3577,12 @@ this endpoint.`,
 just this role and none else. The properties of this SecretID will be
 based on the options set on the role. It will expire after a period
 defined by the 'ttl' field or 'secret_id_ttl' option on the role,
-and/or the backend mount's maximum TTL value.`,
+and/or the backend mount's maximum TTL value.
+
+If 'secret_id_attestation' is set on the role, an attestation of the
+workload the SecretID is for must be supplied in the 'attestation' field
+and the response must be wrapped. The SecretID is bound to the attested
+identity, which the workload has to prove again during login.`,
 	},
 	"role-custom-secret-id": {
 		"Assign a SecretID of choice against the role.",
@@ -2132,3 +2132,242 @@ func TestAppRole_RoleSecretIDAccessorCrossDelete(t *testing.T) {
 		t.Fatalf("expected error")
 	}
 }
+
+func TestAppRole_SecretIDUsage(t *testing.T) {
+	b, storage := createBackendWithStorage(t)
+
+	createRole(t, b, storage, "role1", "a,b")
+	b.requestNoErr(t, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "role/role1",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"secret_id_usage_tracking": true,
+		},
+	})
+	resp := b.requestNoErr(t, &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "role/role1/role-id",
+		Storage:   storage,
+	})
+	roleID := resp.Data["role_id"].(string)
+
+	secretIDs := map[string]string{}
+	for _, name := range []string{"unused", "single", "shared"} {
+		resp = b.requestNoErr(t, &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "role/role1/secret-id",
+			Storage:   storage,
+		})
+		secretIDs[name] = resp.Data["secret_id"].(string)
+	}
+
+	login := func(secretID, remoteAddr string) {
+		t.Helper()
+		resp := b.requestNoErr(t, &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "login",
+			Storage:   storage,
+			Data: map[string]interface{}{
+				"role_id":   roleID,
+				"secret_id": secretID,
+			},
+			Connection: &logical.Connection{RemoteAddr: remoteAddr},
+		})
+		if resp.Auth == nil {
+			t.Fatalf("expected login to succeed")
+		}
+	}
+	login(secretIDs["single"], "10.0.0.1")
+	login(secretIDs["single"], "10.0.0.1")
+	login(secretIDs["shared"], "10.0.0.1")
+	login(secretIDs["shared"], "192.168.0.1")
+
+	resp = b.requestNoErr(t, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "role/role1/secret-id/lookup",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"secret_id": secretIDs["shared"],
+		},
+	})
+	if resp.Data["login_count"] != int64(2) {
+		t.Fatalf("bad: login_count: %v", resp.Data["login_count"])
+	}
+	if !reflect.DeepEqual(resp.Data["source_ips"], []string{"10.0.0.1", "192.168.0.1"}) {
+		t.Fatalf("bad: source_ips: %v", resp.Data["source_ips"])
+	}
+	if !reflect.DeepEqual(resp.Data["usage_flags"], []string{"multiple_source_ips"}) {
+		t.Fatalf("bad: usage_flags: %v", resp.Data["usage_flags"])
+	}
+	if time.Since(resp.Data["last_used_time"].(time.Time)) > time.Minute {
+		t.Fatalf("bad: last_used_time: %v", resp.Data["last_used_time"])
+	}
+	sharedAccessor := resp.Data["secret_id_accessor"].(string)
+
+	list := func(data map[string]interface{}) *logical.Response {
+		t.Helper()
+		return b.requestNoErr(t, &logical.Request{
+			Operation: logical.ListOperation,
+			Path:      "role/role1/secret-id",
+			Storage:   storage,
+			Data:      data,
+		})
+	}
+
+	resp = list(nil)
+	if len(resp.Data["keys"].([]string)) != 3 || len(resp.Data["key_info"].(map[string]interface{})) != 3 {
+		t.Fatalf("bad: resp: %#v", resp.Data)
+	}
+
+	resp = list(map[string]interface{}{"filter": "multiple_source_ips"})
+	if !reflect.DeepEqual(resp.Data["keys"], []string{sharedAccessor}) {
+		t.Fatalf("bad: keys: %v", resp.Data["keys"])
+	}
+
+	resp = list(map[string]interface{}{"filter": "unused"})
+	keys := resp.Data["keys"].([]string)
+	if len(keys) != 1 {
+		t.Fatalf("bad: keys: %v", keys)
+	}
+	unusedAccessor := keys[0]
+
+	// Age the unused secret ID so that it's stale
+	roleEntry, err := b.roleEntry(context.Background(), storage, "role1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	roleNameHMAC, err := createHMAC(roleEntry.HMACKey, roleEntry.name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secretIDHMAC, err := createHMAC(roleEntry.HMACKey, secretIDs["unused"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry, err := b.nonLockedSecretIDStorageEntry(context.Background(), storage, roleEntry.SecretIDPrefix, roleNameHMAC, secretIDHMAC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry.CreationTime = time.Now().Add(-48 * time.Hour)
+	if err := b.nonLockedSetSecretIDStorageEntry(context.Background(), storage, roleEntry.SecretIDPrefix, roleNameHMAC, secretIDHMAC, entry); err != nil {
+		t.Fatal(err)
+	}
+
+	resp = list(map[string]interface{}{"filter": "stale", "stale_after": "24h"})
+	if !reflect.DeepEqual(resp.Data["keys"], []string{unusedAccessor}) {
+		t.Fatalf("bad: keys: %v", resp.Data["keys"])
+	}
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ListOperation,
+		Path:      "role/role1/secret-id",
+		Storage:   storage,
+		Data:      map[string]interface{}{"filter": "stale"},
+	})
+	if err != nil || !resp.IsError() {
+		t.Fatalf("expected an error filtering stale secret IDs without stale_after: err: %v, resp: %#v", err, resp)
+	}
+}
+
+// TestAppRole_SecretIDUsageUntracked ensures that logins with SecretIDs
+// without a use limit don't write to storage unless the role tracks their
+// usage.
+func TestAppRole_SecretIDUsageUntracked(t *testing.T) {
+	b, storage := createBackendWithStorage(t)
+
+	b.requestNoErr(t, &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "role/role1",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"policies":           "a",
+			"secret_id_num_uses": 0,
+		},
+	})
+	resp := b.requestNoErr(t, &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "role/role1/role-id",
+		Storage:   storage,
+	})
+	roleID := resp.Data["role_id"].(string)
+	resp = b.requestNoErr(t, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "role/role1/secret-id",
+		Storage:   storage,
+	})
+	secretID := resp.Data["secret_id"].(string)
+
+	roleEntry, err := b.roleEntry(context.Background(), storage, "role1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	roleNameHMAC, err := createHMAC(roleEntry.HMACKey, roleEntry.name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secretIDHMAC, err := createHMAC(roleEntry.HMACKey, secretID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entryIndex := fmt.Sprintf("%s%s/%s", roleEntry.SecretIDPrefix, roleNameHMAC, secretIDHMAC)
+	before, err := storage.Get(context.Background(), entryIndex)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		resp = b.requestNoErr(t, &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "login",
+			Storage:   storage,
+			Data: map[string]interface{}{
+				"role_id":   roleID,
+				"secret_id": secretID,
+			},
+			Connection: &logical.Connection{RemoteAddr: "10.0.0.1"},
+		})
+		if resp.Auth == nil {
+			t.Fatalf("expected login to succeed")
+		}
+	}
+
+	after, err := storage.Get(context.Background(), entryIndex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(before, after) {
+		t.Fatalf("expected the secret ID to be left untouched, got %s", after.Value)
+	}
+
+	resp = b.requestNoErr(t, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "role/role1/secret-id/lookup",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"secret_id": secretID,
+		},
+	})
+	if _, ok := resp.Data["login_count"]; ok {
+		t.Fatalf("expected no usage statistics, got %#v", resp.Data)
+	}
+
+	resp = b.requestNoErr(t, &logical.Request{
+		Operation: logical.ListOperation,
+		Path:      "role/role1/secret-id",
+		Storage:   storage,
+	})
+	if _, ok := resp.Data["key_info"]; ok || len(resp.Data["keys"].([]string)) != 1 {
+		t.Fatalf("bad: resp: %#v", resp.Data)
+	}
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ListOperation,
+		Path:      "role/role1/secret-id",
+		Storage:   storage,
+		Data:      map[string]interface{}{"filter": "unused"},
+	})
+	if err != nil || !resp.IsError() {
+		t.Fatalf("expected an error filtering secret IDs without usage tracking: err: %v, resp: %#v", err, resp)
+	}
+}
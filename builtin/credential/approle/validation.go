@@ -12,6 +12,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/hashicorp/go-secure-stdlib/strutil"
 	uuid "github.com/hashicorp/go-uuid"
 	"github.com/hashicorp/vault/helper/parseip"
 	"github.com/hashicorp/vault/sdk/helper/cidrutil"
@@ -60,10 +61,83 @@ type secretIDStorageEntry struct {
 	// restrictions on the usage of the token generated by this SecretID
 	TokenBoundCIDRs []string `json:"token_cidr_list" mapstructure:"token_bound_cidrs"`
 
+	// AttestationType is the secret_id_attestation of the role at the time
+	// the SecretID was generated
+	AttestationType string `json:"attestation_type" mapstructure:"attestation_type"`
+
+	// AttestedIdentity is the identity of the workload the SecretID was
+	// generated for, which must be attested again during login
+	AttestedIdentity string `json:"attested_identity" mapstructure:"attested_identity"`
+
+	// LoginCount is the number of logins performed with the SecretID
+	LoginCount int64 `json:"login_count" mapstructure:"login_count"`
+
+	// The time of the last login performed with the SecretID
+	LastUsedTime time.Time `json:"last_used_time" mapstructure:"last_used_time"`
+
+	// SourceIPs are the distinct addresses the SecretID was used from, the
+	// most recently used last
+	SourceIPs []string `json:"source_ips" mapstructure:"source_ips"`
+
 	// This is a deprecated field
 	SecretIDNumUsesDeprecated int `json:"SecretIDNumUses" mapstructure:"SecretIDNumUses"`
 }
 
+const (
+	// maxSecretIDSourceIPs bounds the number of source addresses recorded
+	// per SecretID
+	maxSecretIDSourceIPs = 10
+
+	// secretIDUsageUnused flags SecretIDs that were never used to login
+	secretIDUsageUnused = "unused"
+
+	// secretIDUsageStale flags SecretIDs that haven't been used, or created
+	// if never used, within a given duration
+	secretIDUsageStale = "stale"
+
+	// secretIDUsageMultipleSourceIPs flags SecretIDs used from more than one
+	// address, which may indicate that they are shared or leaked
+	secretIDUsageMultipleSourceIPs = "multiple_source_ips"
+)
+
+// recordUsage updates the usage statistics of the SecretID with a login
+// from the given source address.
+func (entry *secretIDStorageEntry) recordUsage(now time.Time, sourceIP string) {
+	entry.LoginCount++
+	entry.LastUsedTime = now
+
+	if sourceIP == "" {
+		return
+	}
+	entry.SourceIPs = strutil.StrListDelete(entry.SourceIPs, sourceIP)
+	entry.SourceIPs = append(entry.SourceIPs, sourceIP)
+	if len(entry.SourceIPs) > maxSecretIDSourceIPs {
+		entry.SourceIPs = entry.SourceIPs[len(entry.SourceIPs)-maxSecretIDSourceIPs:]
+	}
+}
+
+// usageFlags returns the flags raised by the usage of the SecretID. The
+// stale flag is only computed if staleAfter is set.
+func (entry *secretIDStorageEntry) usageFlags(now time.Time, staleAfter time.Duration) []string {
+	flags := []string{}
+	if entry.LoginCount == 0 {
+		flags = append(flags, secretIDUsageUnused)
+	}
+	if staleAfter > 0 {
+		lastActivity := entry.LastUsedTime
+		if lastActivity.IsZero() {
+			lastActivity = entry.CreationTime
+		}
+		if now.Sub(lastActivity) > staleAfter {
+			flags = append(flags, secretIDUsageStale)
+		}
+	}
+	if len(entry.SourceIPs) > 1 {
+		flags = append(flags, secretIDUsageMultipleSourceIPs)
+	}
+	return flags
+}
+
 // Represents the payload of the storage entry of the accessor that maps to a
 // unique SecretID. Note that SecretIDs should never be stored in plaintext
 // anywhere in the backend. SecretIDHMAC will be used as an index to fetch the
@@ -5,7 +5,10 @@ package approle
 
 import (
 	"context"
+	"fmt"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/vault/sdk/logical"
 )
@@ -58,3 +61,43 @@ func TestAppRole_SecretIDNumUsesUpgrade(t *testing.T) {
 		t.Fatal("invalid secret_id_num_uses")
 	}
 }
+
+func TestAppRole_SecretIDUsageFlags(t *testing.T) {
+	now := time.Now()
+	entry := &secretIDStorageEntry{
+		CreationTime: now.Add(-48 * time.Hour),
+	}
+
+	if flags := entry.usageFlags(now, 0); !reflect.DeepEqual(flags, []string{"unused"}) {
+		t.Fatalf("bad: flags: %v", flags)
+	}
+	if flags := entry.usageFlags(now, 24*time.Hour); !reflect.DeepEqual(flags, []string{"unused", "stale"}) {
+		t.Fatalf("bad: flags: %v", flags)
+	}
+
+	entry.recordUsage(now.Add(-time.Hour), "10.0.0.1")
+	entry.recordUsage(now.Add(-time.Hour), "10.0.0.1")
+	if entry.LoginCount != 2 || !reflect.DeepEqual(entry.SourceIPs, []string{"10.0.0.1"}) {
+		t.Fatalf("bad: entry: %#v", entry)
+	}
+	if flags := entry.usageFlags(now, 24*time.Hour); len(flags) != 0 {
+		t.Fatalf("bad: flags: %v", flags)
+	}
+
+	entry.recordUsage(now, "10.0.0.2")
+	if flags := entry.usageFlags(now, 0); !reflect.DeepEqual(flags, []string{"multiple_source_ips"}) {
+		t.Fatalf("bad: flags: %v", flags)
+	}
+
+	// The most recently used addresses are kept
+	for i := 0; i <= maxSecretIDSourceIPs; i++ {
+		entry.recordUsage(now, fmt.Sprintf("10.0.1.%d", i))
+	}
+	entry.recordUsage(now, "10.0.1.1")
+	if len(entry.SourceIPs) != maxSecretIDSourceIPs {
+		t.Fatalf("bad: source IPs: %v", entry.SourceIPs)
+	}
+	if entry.SourceIPs[0] != "10.0.1.2" || entry.SourceIPs[maxSecretIDSourceIPs-1] != "10.0.1.1" {
+		t.Fatalf("bad: source IPs: %v", entry.SourceIPs)
+	}
+}
@@ -59,12 +59,14 @@ func Backend() *backend {
 			pathCerts(&b),
 			pathListCRLs(&b),
 			pathCRLs(&b),
+			pathListSPIFFETrustDomains(&b),
+			pathSPIFFETrustDomains(&b),
 		},
 		AuthRenew:      b.loginPathWrapper(b.pathLoginRenew),
 		Invalidate:     b.invalidate,
 		BackendType:    logical.TypeCredential,
 		InitializeFunc: b.initialize,
-		PeriodicFunc:   b.updateCRLs,
+		PeriodicFunc:   b.periodicFunc,
 	}
 
 	b.crlUpdateMutex = &sync.RWMutex{}
@@ -183,6 +185,17 @@ func (b *backend) updateCRLs(ctx context.Context, req *logical.Request) error {
 	return errs.ErrorOrNil()
 }
 
+func (b *backend) periodicFunc(ctx context.Context, req *logical.Request) error {
+	var errs *multierror.Error
+	if err := b.updateCRLs(ctx, req); err != nil {
+		errs = multierror.Append(errs, err)
+	}
+	if err := b.refreshSPIFFEBundles(ctx, req.Storage); err != nil {
+		errs = multierror.Append(errs, err)
+	}
+	return errs.ErrorOrNil()
+}
+
 func (b *backend) storeConfig(ctx context.Context, storage logical.Storage, config *config) error {
 	entry, err := logical.StorageEntryJSON("config", config)
 	if err != nil {
@@ -211,4 +224,8 @@ Trusted certificates are configured using the "certs/" endpoint
 by a user with root access. A certificate authority can be trusted,
 which permits all keys signed by it. Alternatively, self-signed
 certificates can be trusted avoiding the need for a CA.
+
+The trust bundles of SPIFFE trust domains are configured using the
+"spiffe/trust-domains/" endpoint. Certificates issued to workloads of a
+trust domain, X509-SVIDs, can then be trusted by SPIFFE ID.
 `
@@ -166,6 +166,31 @@ At least one must exist in the OU field.`,
 				},
 			},
 
+			"spiffe_trust_domain": {
+				Type: framework.TypeString,
+				Description: `The SPIFFE trust domain whose workloads can authenticate.
+The X.509 authorities of its bundle, configured using the "spiffe/trust-domains/"
+endpoint, are trusted instead of 'certificate', and client certificates must be
+valid X509-SVIDs of the trust domain.`,
+				DisplayAttrs: &framework.DisplayAttributes{
+					Name:        "SPIFFE trust domain",
+					Group:       "Constraints",
+					Description: "The SPIFFE trust domain whose workloads can authenticate. The X.509 authorities of its bundle are trusted instead of 'certificate', and client certificates must be valid X509-SVIDs of the trust domain.",
+				},
+			},
+
+			"allowed_spiffe_ids": {
+				Type: framework.TypeCommaStringSlice,
+				Description: `A comma-separated list of SPIFFE IDs of the trust domain.
+The SPIFFE ID of the client certificate must match one. Globs match within a path
+segment, and a trailing "/**" matches one or more segments. Requires spiffe_trust_domain.`,
+				DisplayAttrs: &framework.DisplayAttributes{
+					Name:        "Allowed SPIFFE IDs",
+					Group:       "Constraints",
+					Description: "A list of SPIFFE IDs of the trust domain. The SPIFFE ID of the client certificate must match one. Globs match within a path segment, and a trailing '/**' matches one or more segments.",
+				},
+			},
+
 			"required_extensions": {
 				Type: framework.TypeCommaStringSlice,
 				Description: `A comma-separated string or array of extensions
@@ -187,6 +212,16 @@ separated by a dash (-) instead of a dot (.) to allow usage in ACL templates.`,
 				},
 			},
 
+			"certificate_bound_tokens": {
+				Type: framework.TypeBool,
+				Description: `If set, tokens issued for this certificate are bound to the client
+certificate used to log in, and can only be used in requests sent over mTLS
+with that same certificate.`,
+				DisplayAttrs: &framework.DisplayAttributes{
+					Description: "If set, tokens issued for this certificate are bound to the client certificate used to log in, and can only be used in requests sent over mTLS with that same certificate.",
+				},
+			},
+
 			"display_name": {
 				Type: framework.TypeString,
 				Description: `The display name to use for clients using this
@@ -312,8 +347,11 @@ func (b *backend) pathCertRead(ctx context.Context, req *logical.Request, d *fra
 		"allowed_email_sans":           cert.AllowedEmailSANs,
 		"allowed_uri_sans":             cert.AllowedURISANs,
 		"allowed_organizational_units": cert.AllowedOrganizationalUnits,
+		"spiffe_trust_domain":          cert.SPIFFETrustDomain,
+		"allowed_spiffe_ids":           cert.AllowedSPIFFEIDs,
 		"required_extensions":          cert.RequiredExtensions,
 		"allowed_metadata_extensions":  cert.AllowedMetadataExtensions,
+		"certificate_bound_tokens":     cert.CertificateBoundTokens,
 		"ocsp_ca_certificates":         cert.OcspCaCertificates,
 		"ocsp_enabled":                 cert.OcspEnabled,
 		"ocsp_servers_override":        cert.OcspServersOverride,
@@ -414,6 +452,12 @@ func (b *backend) pathCertWrite(ctx context.Context, req *logical.Request, d *fr
 	if allowedOrganizationalUnitsRaw, ok := d.GetOk("allowed_organizational_units"); ok {
 		cert.AllowedOrganizationalUnits = allowedOrganizationalUnitsRaw.([]string)
 	}
+	if spiffeTrustDomainRaw, ok := d.GetOk("spiffe_trust_domain"); ok {
+		cert.SPIFFETrustDomain = strings.ToLower(spiffeTrustDomainRaw.(string))
+	}
+	if allowedSPIFFEIDsRaw, ok := d.GetOk("allowed_spiffe_ids"); ok {
+		cert.AllowedSPIFFEIDs = allowedSPIFFEIDsRaw.([]string)
+	}
 	if requiredExtensionsRaw, ok := d.GetOk("required_extensions"); ok {
 		cert.RequiredExtensions = requiredExtensionsRaw.([]string)
 	}
@@ -421,6 +465,10 @@ func (b *backend) pathCertWrite(ctx context.Context, req *logical.Request, d *fr
 		cert.AllowedMetadataExtensions = allowedMetadataExtensionsRaw.([]string)
 	}
 
+	if certificateBoundTokensRaw, ok := d.GetOk("certificate_bound_tokens"); ok {
+		cert.CertificateBoundTokens = certificateBoundTokensRaw.(bool)
+	}
+
 	// Get tokenutil fields
 	if err := cert.ParseTokenFields(req, d); err != nil {
 		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
@@ -474,6 +522,9 @@ func (b *backend) pathCertWrite(ctx context.Context, req *logical.Request, d *fr
 	if cert.TokenMaxTTL != 0 && cert.TokenTTL > cert.TokenMaxTTL {
 		return logical.ErrorResponse("ttl should be shorter than max_ttl"), nil
 	}
+	if cert.CertificateBoundTokens && cert.TokenType == logical.TokenTypeBatch {
+		return logical.ErrorResponse("batch tokens cannot be bound to a client certificate"), nil
+	}
 	if cert.TokenPeriod > systemMaxTTL {
 		resp.AddWarning(fmt.Sprintf("Given period of %d seconds is greater than the backend's maximum TTL of %d seconds", cert.TokenPeriod/time.Second, systemMaxTTL/time.Second))
 	}
@@ -483,13 +534,37 @@ func (b *backend) pathCertWrite(ctx context.Context, req *logical.Request, d *fr
 		cert.DisplayName = name
 	}
 
+	if cert.SPIFFETrustDomain != "" {
+		if cert.Certificate != "" {
+			return logical.ErrorResponse("only one of 'certificate' or 'spiffe_trust_domain' must be provided"), nil
+		}
+		if !spiffeTrustDomainRegex.MatchString(cert.SPIFFETrustDomain) {
+			return logical.ErrorResponse("invalid spiffe_trust_domain %q", cert.SPIFFETrustDomain), nil
+		}
+		for _, pattern := range cert.AllowedSPIFFEIDs {
+			if err := validateSPIFFEIDPattern(cert.SPIFFETrustDomain, pattern); err != nil {
+				return logical.ErrorResponse(err.Error()), nil
+			}
+		}
+
+		trustDomain, err := b.SPIFFETrustDomain(ctx, req.Storage, cert.SPIFFETrustDomain)
+		if err != nil {
+			return nil, err
+		}
+		if trustDomain == nil {
+			resp.AddWarning(fmt.Sprintf("SPIFFE trust domain %q is not configured, logins will fail until its bundle is configured", cert.SPIFFETrustDomain))
+		}
+	} else if len(cert.AllowedSPIFFEIDs) != 0 {
+		return logical.ErrorResponse("allowed_spiffe_ids requires spiffe_trust_domain"), nil
+	}
+
 	parsed := parsePEM([]byte(cert.Certificate))
-	if len(parsed) == 0 {
+	if len(parsed) == 0 && cert.SPIFFETrustDomain == "" {
 		return logical.ErrorResponse("failed to parse certificate"), nil
 	}
 
 	// If the certificate is not a CA cert, then ensure that x509.ExtKeyUsageClientAuth is set
-	if !parsed[0].IsCA && parsed[0].ExtKeyUsage != nil {
+	if len(parsed) != 0 && !parsed[0].IsCA && parsed[0].ExtKeyUsage != nil {
 		var clientAuth bool
 		for _, usage := range parsed[0].ExtKeyUsage {
 			if usage == x509.ExtKeyUsageClientAuth || usage == x509.ExtKeyUsageAny {
@@ -536,6 +611,9 @@ type CertEntry struct {
 	AllowedOrganizationalUnits []string
 	RequiredExtensions         []string
 	AllowedMetadataExtensions  []string
+	SPIFFETrustDomain          string
+	AllowedSPIFFEIDs           []string
+	CertificateBoundTokens     bool
 	BoundCIDRs                 []*sockaddr.SockAddrMarshaler
 
 	OcspCaCertificates   string
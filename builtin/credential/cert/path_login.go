@@ -93,10 +93,26 @@ func (b *backend) pathLoginAliasLookahead(ctx context.Context, req *logical.Requ
 		return nil, fmt.Errorf("no client certificate found")
 	}
 
+	aliasName := clientCerts[0].Subject.CommonName
+
+	// The alias of SPIFFE roles is the SPIFFE ID, which can only be known
+	// ahead of the login when the role is given
+	if name := d.Get("name").(string); name != "" {
+		entry, err := b.Cert(ctx, req.Storage, name)
+		if err != nil {
+			return nil, err
+		}
+		if entry != nil && entry.SPIFFETrustDomain != "" {
+			if id, err := svidSPIFFEID(clientCerts[0]); err == nil {
+				aliasName = id.String()
+			}
+		}
+	}
+
 	return &logical.Response{
 		Auth: &logical.Auth{
 			Alias: &logical.Alias{
-				Name: clientCerts[0].Subject.CommonName,
+				Name: aliasName,
 			},
 		},
 	}, nil
@@ -149,6 +165,18 @@ func (b *backend) pathLogin(ctx context.Context, req *logical.Request, data *fra
 		"authority_key_id": certutil.GetHexFormatted(clientCerts[0].AuthorityKeyId, ":"),
 	}
 
+	// Workloads of SPIFFE roles are identified by their SPIFFE ID rather than
+	// the common name, which X509-SVIDs do not need to set
+	aliasName := clientCerts[0].Subject.CommonName
+	if matched.Entry.SPIFFETrustDomain != "" {
+		id, err := svidSPIFFEID(clientCerts[0])
+		if err != nil {
+			return nil, err
+		}
+		aliasName = id.String()
+		metadata["spiffe_id"] = aliasName
+	}
+
 	// Add metadata from allowed_metadata_extensions when present,
 	// with sanitized oids (dash-separated instead of dot-separated) as keys.
 	for k, v := range b.certificateExtensionsMetadata(clientCerts[0], matched) {
@@ -163,7 +191,7 @@ func (b *backend) pathLogin(ctx context.Context, req *logical.Request, data *fra
 		DisplayName: matched.Entry.DisplayName,
 		Metadata:    metadata,
 		Alias: &logical.Alias{
-			Name: clientCerts[0].Subject.CommonName,
+			Name: aliasName,
 		},
 	}
 
@@ -173,6 +201,15 @@ func (b *backend) pathLogin(ctx context.Context, req *logical.Request, data *fra
 
 	matched.Entry.PopulateTokenAuth(auth)
 
+	// Bind the token to the client certificate; batch tokens cannot carry
+	// the binding, so use a service token unless the role asks otherwise
+	if matched.Entry.CertificateBoundTokens {
+		auth.BoundCertificateThumbprint = certutil.GetCertificateThumbprint(clientCerts[0])
+		if auth.TokenType == logical.TokenTypeDefault {
+			auth.TokenType = logical.TokenTypeService
+		}
+	}
+
 	return &logical.Response{
 		Auth: auth,
 	}, nil
@@ -366,6 +403,7 @@ func (b *backend) matchesConstraints(ctx context.Context, clientCert *x509.Certi
 		b.matchesEmailSANs(clientCert, config) &&
 		b.matchesURISANs(clientCert, config) &&
 		b.matchesOrganizationalUnits(clientCert, config) &&
+		b.matchesSPIFFEID(clientCert, config) &&
 		b.matchesCertificateExtensions(clientCert, config)
 	if config.Entry.OcspEnabled {
 		ocspGood, err := b.checkForCertInOCSP(ctx, clientCert, trustedChain, conf)
@@ -499,6 +537,36 @@ func (b *backend) matchesOrganizationalUnits(clientCert *x509.Certificate, confi
 	return false
 }
 
+// matchesSPIFFEID verifies that the certificate is a valid X509-SVID of the
+// trust domain of a SPIFFE role, with a SPIFFE ID matching at least one
+// configured allowed SPIFFE ID
+func (b *backend) matchesSPIFFEID(clientCert *x509.Certificate, config *ParsedCert) bool {
+	if config.Entry.SPIFFETrustDomain == "" {
+		return true
+	}
+
+	id, err := svidSPIFFEID(clientCert)
+	if err != nil {
+		b.Logger().Debug("client certificate is not a valid X509-SVID", "name", config.Entry.Name, "error", err)
+		return false
+	}
+	if id.Host != config.Entry.SPIFFETrustDomain {
+		return false
+	}
+
+	// Default behavior (no SPIFFE IDs) is to allow all workloads of the trust domain
+	if len(config.Entry.AllowedSPIFFEIDs) == 0 {
+		return true
+	}
+	for _, allowedSPIFFEID := range config.Entry.AllowedSPIFFEIDs {
+		if matchesSPIFFEIDPattern(allowedSPIFFEID, id) {
+			return true
+		}
+	}
+
+	return false
+}
+
 // matchesCertificateExtensions verifies that the certificate matches configured
 // required extensions
 func (b *backend) matchesCertificateExtensions(clientCert *x509.Certificate, config *ParsedCert) bool {
@@ -631,14 +699,29 @@ func (b *backend) loadTrustedCerts(ctx context.Context, storage logical.Storage,
 			continue
 		}
 
-		parsed := parsePEM([]byte(entry.Certificate))
+		certificates := entry.Certificate
+		if entry.SPIFFETrustDomain != "" {
+			// SPIFFE roles trust the current bundle of their trust domain
+			trustDomain, err := b.SPIFFETrustDomain(ctx, storage, entry.SPIFFETrustDomain)
+			if err != nil {
+				b.Logger().Error("failed to load SPIFFE trust domain", "name", name, "trust_domain", entry.SPIFFETrustDomain, "error", err)
+				continue
+			}
+			if trustDomain == nil {
+				b.Logger().Error("SPIFFE trust domain is not configured", "name", name, "trust_domain", entry.SPIFFETrustDomain)
+				continue
+			}
+			certificates = trustDomain.Bundle
+		}
+
+		parsed := parsePEM([]byte(certificates))
 		if len(parsed) == 0 {
 			b.Logger().Error("failed to parse certificate", "name", name)
 			continue
 		}
 		parsed = append(parsed, parsePEM([]byte(entry.OcspCaCertificates))...)
 
-		if !parsed[0].IsCA {
+		if !parsed[0].IsCA && entry.SPIFFETrustDomain == "" {
 			trustedNonCAs = append(trustedNonCAs, &ParsedCert{
 				Entry:        entry,
 				Certificates: parsed,
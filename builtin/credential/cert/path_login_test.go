@@ -374,3 +374,40 @@ func TestCert_RoleResolveOCSP(t *testing.T) {
 func serialFromBigInt(serial *big.Int) string {
 	return strings.TrimSpace(certutil.GetHexFormatted(serial.Bytes(), ":"))
 }
+
+func TestCert_CertificateBoundTokens(t *testing.T) {
+	b, s := testSPIFFEBackend(t)
+	ca := newTestSPIFFECA(t, "example.org")
+
+	resp, err := testSPIFFERequest(t, b, s, logical.UpdateOperation, "certs/bound", map[string]interface{}{
+		"certificate":              ca.pem(),
+		"certificate_bound_tokens": true,
+		"token_type":               "batch",
+	}, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp == nil || resp.Error().Error() != "batch tokens cannot be bound to a client certificate" {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	resp, err = testSPIFFERequest(t, b, s, logical.UpdateOperation, "certs/bound", map[string]interface{}{
+		"certificate":              ca.pem(),
+		"certificate_bound_tokens": true,
+	}, nil)
+	if err != nil || resp != nil {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+
+	connState := ca.svid(t, nil, nil)
+	resp, err = testSPIFFERequest(t, b, s, logical.UpdateOperation, "login", nil, connState)
+	if err != nil || resp == nil || resp.Auth == nil {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	if expected := certutil.GetCertificateThumbprint(connState.PeerCertificates[0]); resp.Auth.BoundCertificateThumbprint != expected {
+		t.Fatalf("expected token to be bound to %q, got %q", expected, resp.Auth.BoundCertificateThumbprint)
+	}
+	if resp.Auth.TokenType != logical.TokenTypeService {
+		t.Fatalf("expected a service token, got %q", resp.Auth.TokenType)
+	}
+}
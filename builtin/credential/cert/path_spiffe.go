@@ -0,0 +1,470 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package cert
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-cleanhttp"
+	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	spiffeTrustDomainPath = "spiffe/trust-domains/"
+	spiffeScheme          = "spiffe"
+
+	defaultSPIFFEBundleRefreshInterval = 5 * time.Minute
+	spiffeBundleFetchTimeout           = 30 * time.Second
+
+	// spiffeBundleX509SVIDUse is the "use" of the JWKS keys of a SPIFFE
+	// bundle holding the X.509 authorities of the trust domain.
+	spiffeBundleX509SVIDUse = "x509-svid"
+)
+
+var spiffeTrustDomainRegex = regexp.MustCompile(`^[a-z0-9._-]+$`)
+
+func pathListSPIFFETrustDomains(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "spiffe/trust-domains/?$",
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixCert,
+			OperationSuffix: "spiffe-trust-domains",
+		},
+
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ListOperation: &framework.PathOperation{
+				Callback: b.pathSPIFFETrustDomainList,
+			},
+		},
+
+		HelpSynopsis:    pathSPIFFETrustDomainHelpSyn,
+		HelpDescription: pathSPIFFETrustDomainHelpDesc,
+	}
+}
+
+func pathSPIFFETrustDomains(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "spiffe/trust-domains/" + framework.GenericNameRegex("trust_domain"),
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixCert,
+			OperationSuffix: "spiffe-trust-domain",
+		},
+
+		Fields: map[string]*framework.FieldSchema{
+			"trust_domain": {
+				Type:        framework.TypeString,
+				Description: "The name of the SPIFFE trust domain, such as example.org.",
+			},
+			"bundle": {
+				Type: framework.TypeString,
+				Description: `The X.509 authorities of the trust domain, PEM encoded.
+Only one of 'bundle' or 'bundle_url' parameters should be specified.`,
+				DisplayAttrs: &framework.DisplayAttributes{
+					EditType: "file",
+				},
+			},
+			"bundle_url": {
+				Type: framework.TypeString,
+				Description: `The HTTPS URL of a SPIFFE bundle endpoint serving the bundle of the trust domain.
+The bundle is fetched when written and refreshed periodically to follow rotations of the authorities.`,
+			},
+			"bundle_url_ca_certificates": {
+				Type:        framework.TypeString,
+				Description: `CA certificates to verify the TLS certificate of the bundle endpoint with, PEM encoded. Defaults to the system roots.`,
+				DisplayAttrs: &framework.DisplayAttributes{
+					EditType: "file",
+				},
+			},
+			"refresh_interval": {
+				Type:        framework.TypeDurationSecond,
+				Default:     int(defaultSPIFFEBundleRefreshInterval.Seconds()),
+				Description: "How often the bundle is fetched from 'bundle_url'.",
+			},
+		},
+
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.pathSPIFFETrustDomainRead,
+			},
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathSPIFFETrustDomainWrite,
+			},
+			logical.DeleteOperation: &framework.PathOperation{
+				Callback: b.pathSPIFFETrustDomainDelete,
+			},
+		},
+
+		HelpSynopsis:    pathSPIFFETrustDomainHelpSyn,
+		HelpDescription: pathSPIFFETrustDomainHelpDesc,
+	}
+}
+
+// SPIFFETrustDomainEntry holds the trust bundle of a SPIFFE trust domain.
+type SPIFFETrustDomainEntry struct {
+	TrustDomain             string        `json:"trust_domain"`
+	Bundle                  string        `json:"bundle"`
+	BundleURL               string        `json:"bundle_url"`
+	BundleURLCACertificates string        `json:"bundle_url_ca_certificates"`
+	RefreshInterval         time.Duration `json:"refresh_interval"`
+	LastRefreshTime         time.Time     `json:"last_refresh_time"`
+}
+
+func (b *backend) SPIFFETrustDomain(ctx context.Context, s logical.Storage, trustDomain string) (*SPIFFETrustDomainEntry, error) {
+	entry, err := s.Get(ctx, spiffeTrustDomainPath+strings.ToLower(trustDomain))
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var result SPIFFETrustDomainEntry
+	if err := entry.DecodeJSON(&result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (b *backend) setSPIFFETrustDomain(ctx context.Context, s logical.Storage, trustDomain *SPIFFETrustDomainEntry) error {
+	entry, err := logical.StorageEntryJSON(spiffeTrustDomainPath+trustDomain.TrustDomain, trustDomain)
+	if err != nil {
+		return err
+	}
+	if err := s.Put(ctx, entry); err != nil {
+		return err
+	}
+	b.flushTrustedCache()
+	return nil
+}
+
+func (b *backend) pathSPIFFETrustDomainList(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	trustDomains, err := req.Storage.List(ctx, spiffeTrustDomainPath)
+	if err != nil {
+		return nil, err
+	}
+	return logical.ListResponse(trustDomains), nil
+}
+
+func (b *backend) pathSPIFFETrustDomainRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	trustDomain, err := b.SPIFFETrustDomain(ctx, req.Storage, d.Get("trust_domain").(string))
+	if err != nil {
+		return nil, err
+	}
+	if trustDomain == nil {
+		return nil, nil
+	}
+
+	data := map[string]interface{}{
+		"trust_domain":               trustDomain.TrustDomain,
+		"bundle":                     trustDomain.Bundle,
+		"bundle_url":                 trustDomain.BundleURL,
+		"bundle_url_ca_certificates": trustDomain.BundleURLCACertificates,
+		"refresh_interval":           int64(trustDomain.RefreshInterval.Seconds()),
+	}
+	if !trustDomain.LastRefreshTime.IsZero() {
+		data["last_refresh_time"] = trustDomain.LastRefreshTime.Format(time.RFC3339)
+	}
+
+	return &logical.Response{
+		Data: data,
+	}, nil
+}
+
+func (b *backend) pathSPIFFETrustDomainWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := strings.ToLower(d.Get("trust_domain").(string))
+	if !spiffeTrustDomainRegex.MatchString(name) {
+		return logical.ErrorResponse("invalid trust domain %q: only lowercase letters, digits, dots, dashes and underscores are allowed", name), nil
+	}
+
+	trustDomain, err := b.SPIFFETrustDomain(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if trustDomain == nil {
+		trustDomain = &SPIFFETrustDomainEntry{
+			TrustDomain:     name,
+			RefreshInterval: defaultSPIFFEBundleRefreshInterval,
+		}
+	}
+
+	bundleRaw, hasBundle := d.GetOk("bundle")
+	bundleURLRaw, hasBundleURL := d.GetOk("bundle_url")
+	if hasBundle && hasBundleURL {
+		return logical.ErrorResponse("only one of 'bundle' or 'bundle_url' must be provided"), nil
+	}
+	if caCertsRaw, ok := d.GetOk("bundle_url_ca_certificates"); ok {
+		trustDomain.BundleURLCACertificates = caCertsRaw.(string)
+	}
+	if refreshIntervalRaw, ok := d.GetOk("refresh_interval"); ok {
+		trustDomain.RefreshInterval = time.Duration(refreshIntervalRaw.(int)) * time.Second
+	}
+	if trustDomain.RefreshInterval <= 0 {
+		return logical.ErrorResponse("refresh_interval must be positive"), nil
+	}
+
+	switch {
+	case hasBundle:
+		trustDomain.Bundle = bundleRaw.(string)
+		trustDomain.BundleURL = ""
+		trustDomain.LastRefreshTime = time.Time{}
+	case hasBundleURL:
+		trustDomain.BundleURL = bundleURLRaw.(string)
+	case trustDomain.Bundle == "" && trustDomain.BundleURL == "":
+		return logical.ErrorResponse("one of 'bundle' or 'bundle_url' must be provided"), nil
+	}
+
+	if trustDomain.BundleURL != "" {
+		u, err := url.Parse(trustDomain.BundleURL)
+		if err != nil || u.Scheme != "https" || u.Host == "" {
+			return logical.ErrorResponse("invalid bundle_url %q: must be an https URL", trustDomain.BundleURL), nil
+		}
+		if err := b.fetchSPIFFEBundle(ctx, trustDomain); err != nil {
+			return logical.ErrorResponse("failed to fetch SPIFFE bundle: %v", err), nil
+		}
+	}
+
+	if len(parsePEM([]byte(trustDomain.Bundle))) == 0 {
+		return logical.ErrorResponse("bundle does not contain any X.509 authority"), nil
+	}
+
+	return nil, b.setSPIFFETrustDomain(ctx, req.Storage, trustDomain)
+}
+
+func (b *backend) pathSPIFFETrustDomainDelete(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	defer b.flushTrustedCache()
+	return nil, req.Storage.Delete(ctx, spiffeTrustDomainPath+strings.ToLower(d.Get("trust_domain").(string)))
+}
+
+// spiffeBundle is a SPIFFE bundle as served by a bundle endpoint, a JWK set
+// with SPIFFE specific parameters.
+type spiffeBundle struct {
+	Keys []struct {
+		Use string   `json:"use"`
+		X5c []string `json:"x5c"`
+	} `json:"keys"`
+}
+
+// fetchSPIFFEBundle fetches the bundle of the trust domain from its bundle
+// endpoint and stores its X.509 authorities in the entry.
+func (b *backend) fetchSPIFFEBundle(ctx context.Context, trustDomain *SPIFFETrustDomainEntry) error {
+	client := cleanhttp.DefaultClient()
+	client.Timeout = spiffeBundleFetchTimeout
+	if trustDomain.BundleURLCACertificates != "" {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM([]byte(trustDomain.BundleURLCACertificates)) {
+			return fmt.Errorf("failed to parse bundle_url_ca_certificates")
+		}
+		client.Transport.(*http.Transport).TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, trustDomain.BundleURL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected response code %d fetching SPIFFE bundle from %s", resp.StatusCode, trustDomain.BundleURL)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+
+	var bundle spiffeBundle
+	if err := json.Unmarshal(body, &bundle); err != nil {
+		return fmt.Errorf("failed to decode SPIFFE bundle: %w", err)
+	}
+
+	var authorities strings.Builder
+	for _, key := range bundle.Keys {
+		if key.Use != spiffeBundleX509SVIDUse {
+			continue
+		}
+		if len(key.X5c) != 1 {
+			return fmt.Errorf("x509-svid key must have exactly one certificate in x5c")
+		}
+		der, err := base64.StdEncoding.DecodeString(key.X5c[0])
+		if err != nil {
+			return fmt.Errorf("failed to decode x509-svid key: %w", err)
+		}
+		if _, err := x509.ParseCertificate(der); err != nil {
+			return fmt.Errorf("failed to parse x509-svid key: %w", err)
+		}
+		if err := pem.Encode(&authorities, &pem.Block{Type: "CERTIFICATE", Bytes: der}); err != nil {
+			return err
+		}
+	}
+	if authorities.Len() == 0 {
+		return fmt.Errorf("SPIFFE bundle does not contain any X.509 authority")
+	}
+
+	trustDomain.Bundle = authorities.String()
+	trustDomain.LastRefreshTime = time.Now()
+	return nil
+}
+
+// refreshSPIFFEBundles fetches the bundles of the trust domains configured
+// with a bundle endpoint whose refresh interval has elapsed. A trust domain
+// keeps its current bundle when its endpoint cannot be reached.
+func (b *backend) refreshSPIFFEBundles(ctx context.Context, storage logical.Storage) error {
+	names, err := storage.List(ctx, spiffeTrustDomainPath)
+	if err != nil {
+		return fmt.Errorf("failed to list SPIFFE trust domains: %w", err)
+	}
+
+	var errs *multierror.Error
+	for _, name := range names {
+		trustDomain, err := b.SPIFFETrustDomain(ctx, storage, name)
+		if err != nil {
+			errs = multierror.Append(errs, err)
+			continue
+		}
+		if trustDomain == nil || trustDomain.BundleURL == "" ||
+			time.Since(trustDomain.LastRefreshTime) < trustDomain.RefreshInterval {
+			continue
+		}
+
+		if err := b.fetchSPIFFEBundle(ctx, trustDomain); err != nil {
+			b.Logger().Warn("failed to refresh SPIFFE bundle", "trust_domain", name, "error", err)
+			errs = multierror.Append(errs, fmt.Errorf("failed to refresh SPIFFE bundle of %q: %w", name, err))
+			continue
+		}
+		if err := b.setSPIFFETrustDomain(ctx, storage, trustDomain); err != nil {
+			errs = multierror.Append(errs, err)
+		}
+	}
+	return errs.ErrorOrNil()
+}
+
+// svidSPIFFEID returns the SPIFFE ID of an X509-SVID, validating the
+// certificate against the rules of the X509-SVID specification for leaf
+// certificates.
+func svidSPIFFEID(cert *x509.Certificate) (*url.URL, error) {
+	if len(cert.URIs) != 1 {
+		return nil, fmt.Errorf("X509-SVID must have exactly one URI SAN, found %d", len(cert.URIs))
+	}
+	if cert.IsCA {
+		return nil, fmt.Errorf("X509-SVID must not be a CA certificate")
+	}
+	if cert.KeyUsage&x509.KeyUsageDigitalSignature == 0 {
+		return nil, fmt.Errorf("X509-SVID must have the digitalSignature key usage")
+	}
+	if cert.KeyUsage&(x509.KeyUsageCertSign|x509.KeyUsageCRLSign) != 0 {
+		return nil, fmt.Errorf("X509-SVID must not have the keyCertSign or cRLSign key usages")
+	}
+
+	id := cert.URIs[0]
+	if err := validateSPIFFEID(id); err != nil {
+		return nil, err
+	}
+	return id, nil
+}
+
+// validateSPIFFEID checks that a URI is a valid SPIFFE ID of a workload.
+func validateSPIFFEID(id *url.URL) error {
+	switch {
+	case id.Scheme != spiffeScheme:
+		return fmt.Errorf("%q is not a SPIFFE ID", id)
+	case !spiffeTrustDomainRegex.MatchString(id.Host):
+		return fmt.Errorf("SPIFFE ID %q has an invalid trust domain", id)
+	case id.User != nil || id.Port() != "" || id.RawQuery != "" || id.Fragment != "" || id.Opaque != "":
+		return fmt.Errorf("SPIFFE ID %q must not have a user info, port, query or fragment", id)
+	case id.Path == "" || id.Path == "/":
+		return fmt.Errorf("SPIFFE ID %q has no path", id)
+	}
+	for _, segment := range strings.Split(id.Path, "/")[1:] {
+		if segment == "" || segment == "." || segment == ".." {
+			return fmt.Errorf("SPIFFE ID %q has an invalid path", id)
+		}
+	}
+	return nil
+}
+
+// validateSPIFFEIDPattern checks that an allowed_spiffe_ids pattern is
+// well-formed and within the trust domain.
+func validateSPIFFEIDPattern(trustDomain, pattern string) error {
+	prefix := spiffeScheme + "://" + trustDomain + "/"
+	if !strings.HasPrefix(pattern, prefix) {
+		return fmt.Errorf("allowed SPIFFE ID %q is not in the trust domain %q", pattern, trustDomain)
+	}
+	segments := strings.Split(strings.TrimPrefix(pattern, prefix), "/")
+	for i, segment := range segments {
+		if segment == "**" {
+			if i != len(segments)-1 {
+				return fmt.Errorf("allowed SPIFFE ID %q may only use ** as its last segment", pattern)
+			}
+			continue
+		}
+		if segment == "" {
+			return fmt.Errorf("allowed SPIFFE ID %q has an empty path segment", pattern)
+		}
+		if _, err := path.Match(segment, ""); err != nil {
+			return fmt.Errorf("allowed SPIFFE ID %q is malformed: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// matchesSPIFFEIDPattern matches a SPIFFE ID against an allowed_spiffe_ids
+// pattern. Globs apply to a single path segment, except for a trailing **
+// which matches one or more segments.
+func matchesSPIFFEIDPattern(pattern string, id *url.URL) bool {
+	prefix := spiffeScheme + "://" + id.Host + "/"
+	if !strings.HasPrefix(pattern, prefix) {
+		return false
+	}
+	patternSegments := strings.Split(strings.TrimPrefix(pattern, prefix), "/")
+	idSegments := strings.Split(strings.TrimPrefix(id.Path, "/"), "/")
+
+	for i, patternSegment := range patternSegments {
+		if patternSegment == "**" && i == len(patternSegments)-1 {
+			return len(idSegments) > i
+		}
+		if i >= len(idSegments) {
+			return false
+		}
+		if ok, _ := path.Match(patternSegment, idSegments[i]); !ok {
+			return false
+		}
+	}
+	return len(patternSegments) == len(idSegments)
+}
+
+const pathSPIFFETrustDomainHelpSyn = `
+Manage the trust bundles of SPIFFE trust domains.
+`
+
+const pathSPIFFETrustDomainHelpDesc = `
+This endpoint allows you to list, create, read, update, and delete the trust
+bundles of SPIFFE trust domains. Certificate roles with a "spiffe_trust_domain"
+trust the X.509 authorities of the bundle of that trust domain and validate
+client certificates as X509-SVIDs.
+
+A bundle is either given directly, as PEM encoded certificates, or fetched
+from the SPIFFE bundle endpoint of the trust domain. Bundles fetched from an
+endpoint are refreshed every "refresh_interval" so that rotations of the
+authorities of the trust domain are followed without reconfiguring Vault. If
+a refresh fails, the previous bundle is kept.
+`
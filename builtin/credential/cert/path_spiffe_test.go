@@ -0,0 +1,333 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package cert
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	mathrand "math/rand"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+)
+
+type testSPIFFECA struct {
+	cert *x509.Certificate
+	key  crypto.Signer
+}
+
+func newTestSPIFFECA(t *testing.T, trustDomain string) *testSPIFFECA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(mathrand.Int63()),
+		URIs:                  []*url.URL{{Scheme: spiffeScheme, Host: trustDomain}},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testSPIFFECA{cert: cert, key: key}
+}
+
+func (ca *testSPIFFECA) pem() string {
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.cert.Raw}))
+}
+
+// svid issues a leaf certificate for the given URI SANs, which the modify
+// function can alter to break the X509-SVID rules.
+func (ca *testSPIFFECA) svid(t *testing.T, ids []string, modify func(*x509.Certificate)) *tls.ConnectionState {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(mathrand.Int63()),
+		Subject:      pkix.Name{CommonName: "workload"},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	for _, id := range ids {
+		u, err := url.Parse(id)
+		require.NoError(t, err)
+		template.URIs = append(template.URIs, u)
+	}
+	if modify != nil {
+		modify(template)
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, key.Public(), ca.key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+}
+
+func testSPIFFERequest(t *testing.T, b logical.Backend, s logical.Storage, op logical.Operation, path string, data map[string]interface{}, connState *tls.ConnectionState) (*logical.Response, error) {
+	t.Helper()
+	req := &logical.Request{
+		Operation: op,
+		Path:      path,
+		Storage:   s,
+		Data:      data,
+	}
+	if connState != nil {
+		req.Connection = &logical.Connection{ConnState: connState}
+	}
+	return b.HandleRequest(context.Background(), req)
+}
+
+func testSPIFFEBackend(t *testing.T) (*backend, logical.Storage) {
+	t.Helper()
+	storage := &logical.InmemStorage{}
+	b, err := Factory(context.Background(), &logical.BackendConfig{
+		System: &logical.StaticSystemView{
+			DefaultLeaseTTLVal: 300 * time.Second,
+			MaxLeaseTTLVal:     1800 * time.Second,
+		},
+		StorageView: storage,
+	})
+	require.NoError(t, err)
+	require.NoError(t, b.Initialize(context.Background(), &logical.InitializationRequest{Storage: storage}))
+	return b.(*backend), storage
+}
+
+func TestCert_SPIFFEIDPatterns(t *testing.T) {
+	for _, tc := range []struct {
+		pattern string
+		id      string
+		match   bool
+	}{
+		{"spiffe://example.org/ns/prod/sa/web", "spiffe://example.org/ns/prod/sa/web", true},
+		{"spiffe://example.org/ns/*/sa/web", "spiffe://example.org/ns/prod/sa/web", true},
+		{"spiffe://example.org/ns/*/sa/web", "spiffe://example.org/ns/prod/extra/sa/web", false},
+		{"spiffe://example.org/ns/prod/sa/web-*", "spiffe://example.org/ns/prod/sa/web-1", true},
+		{"spiffe://example.org/ns/prod/**", "spiffe://example.org/ns/prod/sa/web", true},
+		{"spiffe://example.org/ns/prod/**", "spiffe://example.org/ns/prod", false},
+		{"spiffe://example.org/ns/prod", "spiffe://example.org/ns/prod/sa/web", false},
+		{"spiffe://example.org/ns/prod/sa/web", "spiffe://other.org/ns/prod/sa/web", false},
+	} {
+		id, err := url.Parse(tc.id)
+		require.NoError(t, err)
+		require.Equal(t, tc.match, matchesSPIFFEIDPattern(tc.pattern, id), "%s against %s", tc.id, tc.pattern)
+	}
+
+	require.NoError(t, validateSPIFFEIDPattern("example.org", "spiffe://example.org/ns/*/**"))
+	require.Error(t, validateSPIFFEIDPattern("example.org", "spiffe://other.org/ns/prod"))
+	require.Error(t, validateSPIFFEIDPattern("example.org", "spiffe://example.org/**/web"))
+	require.Error(t, validateSPIFFEIDPattern("example.org", "spiffe://example.org/ns//web"))
+	require.Error(t, validateSPIFFEIDPattern("example.org", "spiffe://example.org/ns/[web"))
+}
+
+func TestCert_SPIFFELogin(t *testing.T) {
+	b, s := testSPIFFEBackend(t)
+	ca := newTestSPIFFECA(t, "example.org")
+	otherCA := newTestSPIFFECA(t, "other.org")
+
+	for trustDomain, ca := range map[string]*testSPIFFECA{"example.org": ca, "other.org": otherCA} {
+		resp, err := testSPIFFERequest(t, b, s, logical.UpdateOperation, "spiffe/trust-domains/"+trustDomain, map[string]interface{}{
+			"bundle": ca.pem(),
+		}, nil)
+		require.NoError(t, err)
+		require.Nil(t, resp)
+	}
+
+	resp, err := testSPIFFERequest(t, b, s, logical.UpdateOperation, "certs/web", map[string]interface{}{
+		"spiffe_trust_domain": "example.org",
+		"allowed_spiffe_ids":  "spiffe://example.org/ns/*/sa/web",
+		"policies":            "web",
+	}, nil)
+	require.NoError(t, err)
+	require.Nil(t, resp)
+
+	resp, err = testSPIFFERequest(t, b, s, logical.ReadOperation, "certs/web", nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, "example.org", resp.Data["spiffe_trust_domain"])
+	require.Equal(t, []string{"spiffe://example.org/ns/*/sa/web"}, resp.Data["allowed_spiffe_ids"])
+
+	t.Run("invalid roles", func(t *testing.T) {
+		resp, err := testSPIFFERequest(t, b, s, logical.UpdateOperation, "certs/invalid", map[string]interface{}{
+			"spiffe_trust_domain": "example.org",
+			"allowed_spiffe_ids":  "spiffe://other.org/ns/*/sa/web",
+		}, nil)
+		require.NoError(t, err)
+		require.Contains(t, resp.Error().Error(), "is not in the trust domain")
+
+		resp, err = testSPIFFERequest(t, b, s, logical.UpdateOperation, "certs/invalid", map[string]interface{}{
+			"allowed_spiffe_ids": "spiffe://example.org/ns/*/sa/web",
+			"certificate":        ca.pem(),
+		}, nil)
+		require.NoError(t, err)
+		require.EqualError(t, resp.Error(), "allowed_spiffe_ids requires spiffe_trust_domain")
+
+		resp, err = testSPIFFERequest(t, b, s, logical.UpdateOperation, "certs/invalid", map[string]interface{}{
+			"spiffe_trust_domain": "unknown.org",
+		}, nil)
+		require.NoError(t, err)
+		require.Len(t, resp.Warnings, 1)
+		_, err = testSPIFFERequest(t, b, s, logical.DeleteOperation, "certs/invalid", nil, nil)
+		require.NoError(t, err)
+	})
+
+	t.Run("valid SVID", func(t *testing.T) {
+		connState := ca.svid(t, []string{"spiffe://example.org/ns/prod/sa/web"}, nil)
+		resp, err := testSPIFFERequest(t, b, s, logical.UpdateOperation, "login", nil, connState)
+		require.NoError(t, err)
+		require.NotNil(t, resp.Auth)
+		require.Equal(t, "spiffe://example.org/ns/prod/sa/web", resp.Auth.Alias.Name)
+		require.Equal(t, "spiffe://example.org/ns/prod/sa/web", resp.Auth.Metadata["spiffe_id"])
+		require.Equal(t, []string{"web"}, resp.Auth.Policies)
+
+		resp, err = testSPIFFERequest(t, b, s, logical.AliasLookaheadOperation, "login", map[string]interface{}{
+			"name": "web",
+		}, connState)
+		require.NoError(t, err)
+		require.Equal(t, "spiffe://example.org/ns/prod/sa/web", resp.Auth.Alias.Name)
+	})
+
+	for name, connState := range map[string]*tls.ConnectionState{
+		"unmatched SPIFFE ID": ca.svid(t, []string{"spiffe://example.org/ns/prod/sa/db"}, nil),
+		"other trust domain":  otherCA.svid(t, []string{"spiffe://other.org/ns/prod/sa/web"}, nil),
+		"foreign SPIFFE ID":   ca.svid(t, []string{"spiffe://other.org/ns/prod/sa/web"}, nil),
+		"multiple URI SANs": ca.svid(t, []string{
+			"spiffe://example.org/ns/prod/sa/web",
+			"spiffe://example.org/ns/prod/sa/db",
+		}, nil),
+		"no URI SAN": ca.svid(t, nil, nil),
+		"no digitalSignature": ca.svid(t, []string{"spiffe://example.org/ns/prod/sa/web"}, func(c *x509.Certificate) {
+			c.KeyUsage = x509.KeyUsageKeyEncipherment
+		}),
+		"CA certificate": ca.svid(t, []string{"spiffe://example.org/ns/prod/sa/web"}, func(c *x509.Certificate) {
+			c.BasicConstraintsValid = true
+			c.IsCA = true
+			c.KeyUsage |= x509.KeyUsageCertSign
+		}),
+	} {
+		t.Run(name, func(t *testing.T) {
+			resp, err := testSPIFFERequest(t, b, s, logical.UpdateOperation, "login", nil, connState)
+			require.NoError(t, err)
+			require.True(t, resp.IsError(), "expected login to fail")
+		})
+	}
+
+	t.Run("deleted trust domain", func(t *testing.T) {
+		_, err := testSPIFFERequest(t, b, s, logical.DeleteOperation, "spiffe/trust-domains/example.org", nil, nil)
+		require.NoError(t, err)
+
+		connState := ca.svid(t, []string{"spiffe://example.org/ns/prod/sa/web"}, nil)
+		resp, err := testSPIFFERequest(t, b, s, logical.UpdateOperation, "login", nil, connState)
+		require.NoError(t, err)
+		require.True(t, resp.IsError(), "expected login to fail")
+	})
+}
+
+func TestCert_SPIFFEBundleEndpoint(t *testing.T) {
+	b, s := testSPIFFEBackend(t)
+	oldCA := newTestSPIFFECA(t, "example.org")
+	newCA := newTestSPIFFECA(t, "example.org")
+
+	var served atomic.Pointer[testSPIFFECA]
+	served.Store(oldCA)
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"spiffe_sequence": 1,
+			"keys": []map[string]interface{}{
+				{
+					"use": spiffeBundleX509SVIDUse,
+					"kty": "EC",
+					"x5c": []string{base64.StdEncoding.EncodeToString(served.Load().cert.Raw)},
+				},
+			},
+		})
+	}))
+	defer srv.Close()
+	srvCAPEM := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}))
+
+	resp, err := testSPIFFERequest(t, b, s, logical.UpdateOperation, "spiffe/trust-domains/example.org", map[string]interface{}{
+		"bundle_url": srv.URL,
+	}, nil)
+	require.NoError(t, err)
+	require.Contains(t, resp.Error().Error(), "failed to fetch SPIFFE bundle")
+
+	resp, err = testSPIFFERequest(t, b, s, logical.UpdateOperation, "spiffe/trust-domains/example.org", map[string]interface{}{
+		"bundle_url":                 srv.URL,
+		"bundle_url_ca_certificates": srvCAPEM,
+		"refresh_interval":           "1h",
+	}, nil)
+	require.NoError(t, err)
+	require.Nil(t, resp)
+
+	resp, err = testSPIFFERequest(t, b, s, logical.ReadOperation, "spiffe/trust-domains/example.org", nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, oldCA.pem(), resp.Data["bundle"])
+	require.Equal(t, int64(3600), resp.Data["refresh_interval"])
+	require.NotEmpty(t, resp.Data["last_refresh_time"])
+
+	resp, err = testSPIFFERequest(t, b, s, logical.ListOperation, "spiffe/trust-domains/", nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"example.org"}, resp.Data["keys"])
+
+	resp, err = testSPIFFERequest(t, b, s, logical.UpdateOperation, "certs/mesh", map[string]interface{}{
+		"spiffe_trust_domain": "example.org",
+	}, nil)
+	require.NoError(t, err)
+	require.Nil(t, resp)
+
+	login := func(ca *testSPIFFECA) *logical.Response {
+		t.Helper()
+		resp, err := testSPIFFERequest(t, b, s, logical.UpdateOperation, "login", nil,
+			ca.svid(t, []string{"spiffe://example.org/workload"}, nil))
+		require.NoError(t, err)
+		return resp
+	}
+	require.NotNil(t, login(oldCA).Auth)
+	require.True(t, login(newCA).IsError())
+
+	// Rotate the authority: the bundle is only refreshed once the refresh
+	// interval has elapsed.
+	served.Store(newCA)
+	require.NoError(t, b.PeriodicFunc(context.Background(), &logical.Request{Storage: s}))
+	require.True(t, login(newCA).IsError())
+
+	trustDomain, err := b.SPIFFETrustDomain(context.Background(), s, "example.org")
+	require.NoError(t, err)
+	trustDomain.LastRefreshTime = time.Now().Add(-2 * time.Hour)
+	require.NoError(t, b.setSPIFFETrustDomain(context.Background(), s, trustDomain))
+
+	require.NoError(t, b.PeriodicFunc(context.Background(), &logical.Request{Storage: s}))
+	require.NotNil(t, login(newCA).Auth)
+	require.True(t, login(oldCA).IsError())
+
+	// A failing refresh keeps the current bundle
+	srv.Close()
+	trustDomain, err = b.SPIFFETrustDomain(context.Background(), s, "example.org")
+	require.NoError(t, err)
+	trustDomain.LastRefreshTime = time.Now().Add(-2 * time.Hour)
+	require.NoError(t, b.setSPIFFETrustDomain(context.Background(), s, trustDomain))
+
+	require.Error(t, b.PeriodicFunc(context.Background(), &logical.Request{Storage: s}))
+	require.NotNil(t, login(newCA).Auth)
+}
@@ -6,6 +6,9 @@ package github
 import (
 	"context"
 	"net/url"
+	"sync"
+
+	"github.com/hashicorp/cap/jwt"
 
 	"github.com/google/go-github/github"
 	"github.com/hashicorp/go-cleanhttp"
@@ -26,6 +29,7 @@ func Factory(ctx context.Context, conf *logical.BackendConfig) (logical.Backend,
 
 func Backend() *backend {
 	var b backend
+	b.actionsCtx, b.actionsCancel = context.WithCancel(context.Background())
 	b.TeamMap = &framework.PolicyMap{
 		PathMap: framework.PathMap{
 			Name: "teams",
@@ -102,8 +106,15 @@ func Backend() *backend {
 			},
 		},
 
-		Paths:       append([]*framework.Path{pathConfig(&b), pathLogin(&b)}, allPaths...),
+		Paths: append([]*framework.Path{
+			pathConfig(&b),
+			pathLogin(&b),
+			pathListRoles(&b),
+			pathRoles(&b),
+		}, allPaths...),
 		AuthRenew:   b.pathLoginRenew,
+		Invalidate:  b.invalidate,
+		Clean:       b.cleanup,
 		BackendType: logical.TypeCredential,
 	}
 
@@ -116,6 +127,24 @@ type backend struct {
 	TeamMap *framework.PolicyMap
 
 	UserMap *framework.PolicyMap
+
+	// actionsValidator validates GitHub Actions OIDC tokens, and is created
+	// on first use with actionsCtx, which lives as long as the backend
+	actionsLock      sync.Mutex
+	actionsValidator *jwt.Validator
+	actionsCtx       context.Context
+	actionsCancel    context.CancelFunc
+}
+
+func (b *backend) invalidate(_ context.Context, key string) {
+	switch key {
+	case "config":
+		b.resetActionsTokenValidator()
+	}
+}
+
+func (b *backend) cleanup(_ context.Context) {
+	b.actionsCancel()
 }
 
 // Client returns the GitHub client to communicate to GitHub via the
@@ -154,6 +183,10 @@ provider verifies they're part of the correct organization and then
 maps the user to a set of Vault policies according to the teams they're
 part of.
 
+GitHub Actions workflows and GitHub Apps of the organization can log in
+with a role instead, using the OIDC token of the workflow job or a JWT
+signed by the app. Use the "roles" route to manage them.
+
 After enabling the credential provider, use the "config" route to
 configure it.
 `
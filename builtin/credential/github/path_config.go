@@ -45,6 +45,24 @@ API-compatible authentication server.`,
 					Group: "GitHub Options",
 				},
 			},
+			"actions_issuer": {
+				Type: framework.TypeString,
+				Description: `The issuer of the OIDC tokens of GitHub Actions, used to discover
+their signing keys. Defaults to "` + defaultActionsIssuer + `".
+Set it when running GitHub Enterprise Server.`,
+				DisplayAttrs: &framework.DisplayAttributes{
+					Name:  "GitHub Actions issuer",
+					Group: "GitHub Options",
+				},
+			},
+			"actions_issuer_ca_pem": {
+				Type:        framework.TypeString,
+				Description: "CA certificates, PEM encoded, to verify the TLS certificate of the GitHub Actions issuer with. Defaults to the system roots.",
+				DisplayAttrs: &framework.DisplayAttributes{
+					Name:  "GitHub Actions issuer CA PEM",
+					Group: "GitHub Options",
+				},
+			},
 			"ttl": {
 				Type:        framework.TypeDurationSecond,
 				Description: tokenutil.DeprecationText("token_ttl"),
@@ -113,6 +131,16 @@ func (b *backend) pathConfigWrite(ctx context.Context, req *logical.Request, dat
 		c.BaseURL = baseURL
 	}
 
+	if actionsIssuerRaw, ok := data.GetOk("actions_issuer"); ok {
+		c.ActionsIssuer = actionsIssuerRaw.(string)
+		if _, err := url.Parse(c.ActionsIssuer); err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("error parsing given actions_issuer: %s", err)), nil
+		}
+	}
+	if actionsIssuerCAPEMRaw, ok := data.GetOk("actions_issuer_ca_pem"); ok {
+		c.ActionsIssuerCAPEM = actionsIssuerCAPEMRaw.(string)
+	}
+
 	if c.OrganizationID == 0 {
 		githubToken := os.Getenv("VAULT_AUTH_CONFIG_GITHUB_TOKEN")
 		client, err := b.Client(githubToken)
@@ -157,6 +185,7 @@ func (b *backend) pathConfigWrite(ctx context.Context, req *logical.Request, dat
 	if err := req.Storage.Put(ctx, entry); err != nil {
 		return nil, err
 	}
+	b.resetActionsTokenValidator()
 
 	if len(resp.Warnings) == 0 {
 		return nil, nil
@@ -175,9 +204,11 @@ func (b *backend) pathConfigRead(ctx context.Context, req *logical.Request, data
 	}
 
 	d := map[string]interface{}{
-		"organization_id": config.OrganizationID,
-		"organization":    config.Organization,
-		"base_url":        config.BaseURL,
+		"organization_id":       config.OrganizationID,
+		"organization":          config.Organization,
+		"base_url":              config.BaseURL,
+		"actions_issuer":        config.actionsIssuer(),
+		"actions_issuer_ca_pem": config.ActionsIssuerCAPEM,
 	}
 	config.PopulateTokenData(d)
 
@@ -223,11 +254,22 @@ func (b *backend) Config(ctx context.Context, s logical.Storage) (*config, error
 type config struct {
 	tokenutil.TokenParams
 
-	OrganizationID int64         `json:"organization_id" structs:"organization_id" mapstructure:"organization_id"`
-	Organization   string        `json:"organization" structs:"organization" mapstructure:"organization"`
-	BaseURL        string        `json:"base_url" structs:"base_url" mapstructure:"base_url"`
-	TTL            time.Duration `json:"ttl" structs:"ttl" mapstructure:"ttl"`
-	MaxTTL         time.Duration `json:"max_ttl" structs:"max_ttl" mapstructure:"max_ttl"`
+	OrganizationID     int64         `json:"organization_id" structs:"organization_id" mapstructure:"organization_id"`
+	Organization       string        `json:"organization" structs:"organization" mapstructure:"organization"`
+	BaseURL            string        `json:"base_url" structs:"base_url" mapstructure:"base_url"`
+	ActionsIssuer      string        `json:"actions_issuer" structs:"actions_issuer" mapstructure:"actions_issuer"`
+	ActionsIssuerCAPEM string        `json:"actions_issuer_ca_pem" structs:"actions_issuer_ca_pem" mapstructure:"actions_issuer_ca_pem"`
+	TTL                time.Duration `json:"ttl" structs:"ttl" mapstructure:"ttl"`
+	MaxTTL             time.Duration `json:"max_ttl" structs:"max_ttl" mapstructure:"max_ttl"`
+}
+
+// actionsIssuer returns the issuer of GitHub Actions OIDC tokens, defaulting
+// to the one of github.com.
+func (c *config) actionsIssuer() string {
+	if c.ActionsIssuer == "" {
+		return defaultActionsIssuer
+	}
+	return c.ActionsIssuer
 }
 
 func (c *config) setOrganizationID(ctx context.Context, client *github.Client) error {
@@ -30,6 +30,18 @@ func pathLogin(b *backend) *framework.Path {
 				Type:        framework.TypeString,
 				Description: "GitHub personal API token",
 			},
+			"role": {
+				Type:        framework.TypeString,
+				Description: "Name of the role to log in with. Required to log in with jwt or app_jwt.",
+			},
+			"jwt": {
+				Type:        framework.TypeString,
+				Description: `OIDC token of a GitHub Actions workflow job, for roles of type "actions".`,
+			},
+			"app_jwt": {
+				Type:        framework.TypeString,
+				Description: `JWT signed by the private key of a GitHub App, for roles of type "app".`,
+			},
 		},
 
 		Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -40,6 +52,10 @@ func pathLogin(b *backend) *framework.Path {
 }
 
 func (b *backend) pathLoginAliasLookahead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	if roleName := data.Get("role").(string); roleName != "" {
+		return b.pathLoginRoleAliasLookahead(ctx, req, data, roleName)
+	}
+
 	token := data.Get("token").(string)
 
 	verifyResp, err := b.verifyCredentials(ctx, req, token)
@@ -58,6 +74,10 @@ func (b *backend) pathLoginAliasLookahead(ctx context.Context, req *logical.Requ
 }
 
 func (b *backend) pathLogin(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	if roleName := data.Get("role").(string); roleName != "" {
+		return b.pathLoginRole(ctx, req, data, roleName)
+	}
+
 	token := data.Get("token").(string)
 
 	verifyResp, err := b.verifyCredentials(ctx, req, token)
@@ -107,6 +127,10 @@ func (b *backend) pathLoginRenew(ctx context.Context, req *logical.Request, d *f
 		return nil, fmt.Errorf("request auth was nil")
 	}
 
+	if roleNameRaw, ok := req.Auth.InternalData["role"]; ok {
+		return b.pathLoginRoleRenew(ctx, req, roleNameRaw.(string))
+	}
+
 	tokenRaw, ok := req.Auth.InternalData["token"]
 	if !ok {
 		return nil, fmt.Errorf("token created in previous version of Vault cannot be validated properly at renewal time")
@@ -0,0 +1,317 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/hashicorp/cap/jwt"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/cidrutil"
+	"github.com/hashicorp/vault/sdk/helper/policyutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// defaultActionsIssuer is the issuer of the OIDC tokens GitHub Actions
+// issues on github.com.
+const defaultActionsIssuer = "https://token.actions.githubusercontent.com"
+
+// actionsClaimsMetadata are the claims of GitHub Actions OIDC tokens that
+// are added to the metadata of the tokens issued for them.
+var actionsClaimsMetadata = []string{
+	"repository",
+	"repository_owner",
+	"environment",
+	"ref",
+	"job_workflow_ref",
+	"workflow",
+	"actor",
+	"event_name",
+}
+
+// roleLoginResp is the identity a workload proved at login with a role.
+type roleLoginResp struct {
+	Alias    string
+	Metadata map[string]string
+}
+
+// pathLoginRole logs in a GitHub Actions workflow or GitHub App with the
+// given role.
+func (b *backend) pathLoginRole(ctx context.Context, req *logical.Request, data *framework.FieldData, roleName string) (*logical.Response, error) {
+	role, loginResp, err := b.verifyRoleCredentials(ctx, req, data, roleName)
+	if err != nil {
+		return nil, err
+	}
+
+	auth := &logical.Auth{
+		InternalData: map[string]interface{}{
+			"role": roleName,
+		},
+		Metadata:    loginResp.Metadata,
+		DisplayName: loginResp.Alias,
+		Alias: &logical.Alias{
+			Name:     loginResp.Alias,
+			Metadata: loginResp.Metadata,
+		},
+	}
+	role.PopulateTokenAuth(auth)
+
+	return &logical.Response{
+		Auth: auth,
+	}, nil
+}
+
+func (b *backend) pathLoginRoleAliasLookahead(ctx context.Context, req *logical.Request, data *framework.FieldData, roleName string) (*logical.Response, error) {
+	_, loginResp, err := b.verifyRoleCredentials(ctx, req, data, roleName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Auth: &logical.Auth{
+			Alias: &logical.Alias{
+				Name: loginResp.Alias,
+			},
+		},
+	}, nil
+}
+
+// pathLoginRoleRenew renews tokens issued with a role. The credentials used
+// to log in are short-lived, so renewal only checks that the role still
+// grants the policies of the token.
+func (b *backend) pathLoginRoleRenew(ctx context.Context, req *logical.Request, roleName string) (*logical.Response, error) {
+	role, err := b.role(ctx, req.Storage, roleName)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, fmt.Errorf("role %q no longer exists", roleName)
+	}
+
+	if !policyutil.EquivalentPolicies(role.TokenPolicies, req.Auth.TokenPolicies) {
+		return nil, fmt.Errorf("policies do not match")
+	}
+
+	resp := &logical.Response{Auth: req.Auth}
+	resp.Auth.Period = role.TokenPeriod
+	resp.Auth.TTL = role.TokenTTL
+	resp.Auth.MaxTTL = role.TokenMaxTTL
+	return resp, nil
+}
+
+func (b *backend) verifyRoleCredentials(ctx context.Context, req *logical.Request, data *framework.FieldData, roleName string) (*roleEntry, *roleLoginResp, error) {
+	config, err := b.Config(ctx, req.Storage)
+	if err != nil {
+		return nil, nil, err
+	}
+	if config == nil {
+		return nil, nil, errors.New("configuration has not been set")
+	}
+	if config.OrganizationID == 0 {
+		return nil, nil, errors.New("organization_id has not been set, update the configuration to set it")
+	}
+
+	role, err := b.role(ctx, req.Storage, roleName)
+	if err != nil {
+		return nil, nil, err
+	}
+	if role == nil {
+		return nil, nil, logical.ErrPermissionDenied
+	}
+
+	// Check for a CIDR match.
+	if len(role.TokenBoundCIDRs) > 0 {
+		if req.Connection == nil {
+			b.Logger().Error("token bound CIDRs found but no connection information available for validation")
+			return nil, nil, logical.ErrPermissionDenied
+		}
+		if !cidrutil.RemoteAddrIsOk(req.Connection.RemoteAddr, role.TokenBoundCIDRs) {
+			return nil, nil, logical.ErrPermissionDenied
+		}
+	}
+
+	var loginResp *roleLoginResp
+	switch role.RoleType {
+	case roleTypeActions:
+		token := data.Get("jwt").(string)
+		if token == "" {
+			return nil, nil, logical.CodedError(400, "missing jwt")
+		}
+		loginResp, err = b.verifyActionsToken(ctx, config, role, token)
+	case roleTypeApp:
+		token := data.Get("app_jwt").(string)
+		if token == "" {
+			return nil, nil, logical.CodedError(400, "missing app_jwt")
+		}
+		loginResp, err = b.verifyApp(ctx, config, role, token)
+	default:
+		return nil, nil, fmt.Errorf("invalid role_type %q", role.RoleType)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	loginResp.Metadata["role"] = roleName
+	loginResp.Metadata["org"] = config.Organization
+	return role, loginResp, nil
+}
+
+// verifyActionsToken validates the OIDC token of a GitHub Actions workflow
+// job and checks its claims against the constraints of the role.
+func (b *backend) verifyActionsToken(ctx context.Context, config *config, role *roleEntry, token string) (*roleLoginResp, error) {
+	validator, err := b.actionsTokenValidator(config)
+	if err != nil {
+		return nil, err
+	}
+
+	audiences := role.BoundAudiences
+	if len(audiences) == 0 {
+		audiences = []string{"https://github.com/" + config.Organization}
+	}
+
+	claims, err := validator.Validate(ctx, token, jwt.Expected{
+		Issuer:            config.actionsIssuer(),
+		Audiences:         audiences,
+		SigningAlgorithms: []jwt.Alg{jwt.RS256},
+	})
+	if err != nil {
+		return nil, logical.CodedError(403, fmt.Sprintf("error validating token: %s", err))
+	}
+
+	// The repository must belong to the organization. IDs are compared as
+	// names can be reused after an organization is renamed.
+	if ownerID := claimString(claims, "repository_owner_id"); ownerID != strconv.FormatInt(config.OrganizationID, 10) {
+		return nil, logical.CodedError(403, "repository is not part of required org")
+	}
+
+	sub := claimString(claims, "sub")
+	if sub == "" {
+		return nil, logical.CodedError(403, "token has no sub claim")
+	}
+
+	switch {
+	case !matchesAny(role.BoundRepositories, claimString(claims, "repository")):
+		return nil, logical.CodedError(403, "repository is not allowed by the role")
+	case !matchesAny(role.BoundEnvironments, claimString(claims, "environment")):
+		return nil, logical.CodedError(403, "environment is not allowed by the role")
+	case !matchesAny(role.BoundWorkflowRefs, claimString(claims, "job_workflow_ref")):
+		return nil, logical.CodedError(403, "workflow is not allowed by the role")
+	case !matchesAny(role.BoundRefs, claimString(claims, "ref")):
+		return nil, logical.CodedError(403, "ref is not allowed by the role")
+	}
+
+	metadata := make(map[string]string)
+	for _, claim := range actionsClaimsMetadata {
+		if v := claimString(claims, claim); v != "" {
+			metadata[claim] = v
+		}
+	}
+
+	return &roleLoginResp{
+		Alias:    sub,
+		Metadata: metadata,
+	}, nil
+}
+
+// verifyApp checks with GitHub that the JWT is signed by a GitHub App that
+// is installed in the organization and allowed by the role.
+func (b *backend) verifyApp(ctx context.Context, config *config, role *roleEntry, token string) (*roleLoginResp, error) {
+	client, err := b.Client(token)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.BaseURL != "" {
+		parsedURL, err := url.Parse(config.BaseURL)
+		if err != nil {
+			return nil, fmt.Errorf("successfully parsed base_url when set but failing to parse now: %w", err)
+		}
+		client.BaseURL = parsedURL
+	}
+
+	// Get the app the JWT is signed by
+	app, _, err := client.Apps.Get(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	var allowed bool
+	for _, id := range role.BoundAppIDs {
+		if id == app.GetID() {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return nil, logical.CodedError(403, "app is not allowed by the role")
+	}
+
+	// Verify that the app is installed in the organization
+	installation, _, err := client.Apps.FindOrganizationInstallation(ctx, config.Organization)
+	if err != nil {
+		return nil, err
+	}
+	if installation.GetAppID() != app.GetID() || installation.GetAccount().GetID() != config.OrganizationID {
+		return nil, logical.CodedError(403, "app is not installed in required org")
+	}
+
+	appID := strconv.FormatInt(app.GetID(), 10)
+	return &roleLoginResp{
+		Alias: appID,
+		Metadata: map[string]string{
+			"app_id":          appID,
+			"app_name":        app.GetName(),
+			"installation_id": strconv.FormatInt(installation.GetID(), 10),
+		},
+	}, nil
+}
+
+// actionsTokenValidator returns the validator of GitHub Actions OIDC tokens,
+// creating it on first use. It is reset whenever the configuration changes.
+func (b *backend) actionsTokenValidator(config *config) (*jwt.Validator, error) {
+	b.actionsLock.Lock()
+	defer b.actionsLock.Unlock()
+
+	if b.actionsValidator != nil {
+		return b.actionsValidator, nil
+	}
+
+	// The key set fetches keys for as long as it is used, so it gets the
+	// context of the backend rather than the one of the request.
+	keySet, err := jwt.NewOIDCDiscoveryKeySet(b.actionsCtx, config.actionsIssuer(), config.ActionsIssuerCAPEM)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching the signing keys of %q: %w", config.actionsIssuer(), err)
+	}
+	validator, err := jwt.NewValidator(keySet)
+	if err != nil {
+		return nil, err
+	}
+
+	b.actionsValidator = validator
+	return validator, nil
+}
+
+func (b *backend) resetActionsTokenValidator() {
+	b.actionsLock.Lock()
+	defer b.actionsLock.Unlock()
+
+	b.actionsValidator = nil
+}
+
+// claimString returns the claim as a string. GitHub encodes IDs as strings,
+// but numbers are accepted too.
+func claimString(claims map[string]interface{}, name string) string {
+	switch v := claims[name].(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,368 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package github
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testAppJWT is the JWT the mock GitHub API accepts for the app with ID 42.
+const testAppJWT = "app-42-jwt"
+
+// setupRoleTestServer configures an httptest server that serves both the
+// GitHub API, with an app installed in foo-org, and the OIDC discovery
+// document and keys of GitHub Actions.
+func setupRoleTestServer(t *testing.T, key *rsa.PrivateKey) *httptest.Server {
+	t.Helper()
+
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var resp interface{}
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			resp = map[string]string{
+				"issuer":   ts.URL,
+				"jwks_uri": ts.URL + "/jwks",
+			}
+		case "/jwks":
+			resp = jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+				{Key: key.Public(), KeyID: "actions", Algorithm: string(jose.RS256), Use: "sig"},
+			}}
+		case "/app", "/orgs/foo-org/installation":
+			if r.Header.Get("Authorization") != "Bearer "+testAppJWT {
+				w.WriteHeader(http.StatusUnauthorized)
+				fmt.Fprintln(w, `{"message": "A JSON web token could not be decoded"}`)
+				return
+			}
+			if r.URL.Path == "/app" {
+				resp = map[string]interface{}{"id": 42, "name": "deployer"}
+			} else {
+				resp = map[string]interface{}{"id": 7, "app_id": 42, "account": map[string]interface{}{"login": "foo-org", "id": 12345}}
+			}
+		case "/orgs/foo-org":
+			w.Header().Add("Content-Type", "application/json")
+			fmt.Fprintln(w, getOrgResponse)
+			return
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}))
+	return ts
+}
+
+// testActionsToken returns an OIDC token for a workflow job of
+// foo-org/service, as GitHub Actions issues them, with the given changes to
+// the claims.
+func testActionsToken(t *testing.T, key *rsa.PrivateKey, issuer string, modify func(map[string]interface{})) string {
+	t.Helper()
+
+	now := time.Now()
+	claims := map[string]interface{}{
+		"iss":                 issuer,
+		"aud":                 "https://github.com/foo-org",
+		"sub":                 "repo:foo-org/service:environment:production",
+		"iat":                 now.Unix(),
+		"nbf":                 now.Unix(),
+		"exp":                 now.Add(5 * time.Minute).Unix(),
+		"repository":          "foo-org/service",
+		"repository_owner":    "foo-org",
+		"repository_owner_id": "12345",
+		"environment":         "production",
+		"ref":                 "refs/heads/main",
+		"job_workflow_ref":    "foo-org/service/.github/workflows/deploy.yml@refs/heads/main",
+		"workflow":            "deploy",
+		"actor":               "user-foo",
+		"event_name":          "push",
+	}
+	if modify != nil {
+		modify(claims)
+	}
+
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: key}, (&jose.SignerOptions{}).WithHeader("kid", "actions"))
+	require.NoError(t, err)
+	token, err := jwt.Signed(signer).Claims(claims).CompactSerialize()
+	require.NoError(t, err)
+	return token
+}
+
+func testRoleBackend(t *testing.T) (*backend, logical.Storage, *httptest.Server, *rsa.PrivateKey) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	ts := setupRoleTestServer(t, key)
+	t.Cleanup(ts.Close)
+
+	b, s := createBackendWithStorage(t)
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Path:      "config",
+		Operation: logical.UpdateOperation,
+		Data: map[string]interface{}{
+			"organization":   "foo-org",
+			"base_url":       ts.URL,
+			"actions_issuer": ts.URL,
+		},
+		Storage: s,
+	})
+	require.NoError(t, err)
+	require.NoError(t, resp.Error())
+
+	return b, s, ts, key
+}
+
+func testRoleWrite(t *testing.T, b *backend, s logical.Storage, name string, data map[string]interface{}) *logical.Response {
+	t.Helper()
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Path:      "roles/" + name,
+		Operation: logical.CreateOperation,
+		Data:      data,
+		Storage:   s,
+	})
+	require.NoError(t, err)
+	return resp
+}
+
+func testRoleLogin(b *backend, s logical.Storage, data map[string]interface{}) (*logical.Response, error) {
+	return b.HandleRequest(context.Background(), &logical.Request{
+		Path:       "login",
+		Operation:  logical.UpdateOperation,
+		Data:       data,
+		Storage:    s,
+		Connection: &logical.Connection{RemoteAddr: "127.0.0.1"},
+	})
+}
+
+// TestGitHub_Roles tests that roles are validated, and can be read, listed
+// and deleted
+func TestGitHub_Roles(t *testing.T) {
+	b, s, _, _ := testRoleBackend(t)
+
+	resp := testRoleWrite(t, b, s, "deploy", map[string]interface{}{})
+	assert.EqualError(t, resp.Error(), `bound_repositories must be set for "actions" roles`)
+
+	resp = testRoleWrite(t, b, s, "deploy", map[string]interface{}{
+		"role_type":     "app",
+		"bound_app_ids": "42",
+		"bound_refs":    "refs/heads/main",
+	})
+	assert.EqualError(t, resp.Error(), `only bound_app_ids can be set for "app" roles`)
+
+	resp = testRoleWrite(t, b, s, "deploy", map[string]interface{}{
+		"role_type": "user",
+	})
+	assert.EqualError(t, resp.Error(), `invalid role_type "user", must be "actions" or "app"`)
+
+	resp = testRoleWrite(t, b, s, "deploy", map[string]interface{}{
+		"bound_repositories": "foo-org/service",
+		"bound_environments": "production",
+		"token_policies":     "deploy",
+	})
+	assert.Nil(t, resp)
+	resp = testRoleWrite(t, b, s, "app", map[string]interface{}{
+		"role_type":     "app",
+		"bound_app_ids": "42",
+	})
+	assert.Nil(t, resp)
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Path:      "roles/deploy",
+		Operation: logical.ReadOperation,
+		Storage:   s,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "actions", resp.Data["role_type"])
+	assert.Equal(t, []string{"foo-org/service"}, resp.Data["bound_repositories"])
+	assert.Equal(t, []string{"production"}, resp.Data["bound_environments"])
+	assert.Equal(t, []string{"deploy"}, resp.Data["token_policies"])
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Path:      "roles/",
+		Operation: logical.ListOperation,
+		Storage:   s,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"app", "deploy"}, resp.Data["keys"])
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Path:      "roles/deploy",
+		Operation: logical.DeleteOperation,
+		Storage:   s,
+	})
+	require.NoError(t, err)
+	assert.Nil(t, resp)
+
+	role, err := b.role(context.Background(), s, "deploy")
+	require.NoError(t, err)
+	assert.Nil(t, role)
+}
+
+// TestGitHub_Login_Actions tests that GitHub Actions workflow jobs can log in
+// with their OIDC token, as long as its claims satisfy the role
+func TestGitHub_Login_Actions(t *testing.T) {
+	b, s, ts, key := testRoleBackend(t)
+
+	resp := testRoleWrite(t, b, s, "deploy", map[string]interface{}{
+		"bound_repositories":  "foo-org/*",
+		"bound_environments":  "production",
+		"bound_workflow_refs": "foo-org/service/.github/workflows/deploy.yml@*",
+		"token_policies":      "deploy",
+	})
+	require.Nil(t, resp)
+
+	resp, err := testRoleLogin(b, s, map[string]interface{}{
+		"role": "deploy",
+		"jwt":  testActionsToken(t, key, ts.URL, nil),
+	})
+	require.NoError(t, err)
+	require.NoError(t, resp.Error())
+	assert.Equal(t, "repo:foo-org/service:environment:production", resp.Auth.Alias.Name)
+	assert.Equal(t, []string{"deploy"}, resp.Auth.Policies)
+	assert.Equal(t, map[string]string{
+		"role":             "deploy",
+		"org":              "foo-org",
+		"repository":       "foo-org/service",
+		"repository_owner": "foo-org",
+		"environment":      "production",
+		"ref":              "refs/heads/main",
+		"job_workflow_ref": "foo-org/service/.github/workflows/deploy.yml@refs/heads/main",
+		"workflow":         "deploy",
+		"actor":            "user-foo",
+		"event_name":       "push",
+	}, resp.Auth.Metadata)
+
+	// Tokens are renewed as long as the role grants the same policies
+	auth := resp.Auth
+	auth.TokenPolicies = auth.Policies
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Path:      "login",
+		Operation: logical.RenewOperation,
+		Auth:      auth,
+		Storage:   s,
+	})
+	require.NoError(t, err)
+	require.NoError(t, resp.Error())
+
+	for name, modify := range map[string]func(map[string]interface{}){
+		"other org": func(c map[string]interface{}) {
+			c["repository"] = "bar-org/service"
+			c["repository_owner_id"] = "999"
+		},
+		"other repository owner id": func(c map[string]interface{}) { c["repository_owner_id"] = "999" },
+		"other repository":          func(c map[string]interface{}) { c["repository"] = "bar-org/service" },
+		"other environment":         func(c map[string]interface{}) { c["environment"] = "staging" },
+		"no environment":            func(c map[string]interface{}) { delete(c, "environment") },
+		"other workflow": func(c map[string]interface{}) {
+			c["job_workflow_ref"] = "foo-org/service/.github/workflows/test.yml@refs/heads/main"
+		},
+		"other audience": func(c map[string]interface{}) { c["aud"] = "https://github.com/bar-org" },
+		"other issuer":   func(c map[string]interface{}) { c["iss"] = "https://token.actions.githubusercontent.com" },
+		"expired":        func(c map[string]interface{}) { c["exp"] = time.Now().Add(-time.Hour).Unix() },
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := testRoleLogin(b, s, map[string]interface{}{
+				"role": "deploy",
+				"jwt":  testActionsToken(t, key, ts.URL, modify),
+			})
+			assert.Error(t, err)
+		})
+	}
+
+	// Tokens signed by other keys are rejected
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	_, err = testRoleLogin(b, s, map[string]interface{}{
+		"role": "deploy",
+		"jwt":  testActionsToken(t, otherKey, ts.URL, nil),
+	})
+	assert.Error(t, err)
+
+	// Tokens are no longer renewed once the role is deleted
+	_, err = b.HandleRequest(context.Background(), &logical.Request{
+		Path:      "roles/deploy",
+		Operation: logical.DeleteOperation,
+		Storage:   s,
+	})
+	require.NoError(t, err)
+	_, err = b.HandleRequest(context.Background(), &logical.Request{
+		Path:      "login",
+		Operation: logical.RenewOperation,
+		Auth:      auth,
+		Storage:   s,
+	})
+	assert.EqualError(t, err, `role "deploy" no longer exists`)
+}
+
+// TestGitHub_Login_App tests that GitHub Apps installed in the organization
+// can log in with a JWT they signed
+func TestGitHub_Login_App(t *testing.T) {
+	b, s, _, _ := testRoleBackend(t)
+
+	resp := testRoleWrite(t, b, s, "app", map[string]interface{}{
+		"role_type":      "app",
+		"bound_app_ids":  "42",
+		"token_policies": "app",
+	})
+	require.Nil(t, resp)
+	resp = testRoleWrite(t, b, s, "other-app", map[string]interface{}{
+		"role_type":     "app",
+		"bound_app_ids": "43",
+	})
+	require.Nil(t, resp)
+
+	resp, err := testRoleLogin(b, s, map[string]interface{}{
+		"role":    "app",
+		"app_jwt": testAppJWT,
+	})
+	require.NoError(t, err)
+	require.NoError(t, resp.Error())
+	assert.Equal(t, "42", resp.Auth.Alias.Name)
+	assert.Equal(t, []string{"app"}, resp.Auth.Policies)
+	assert.Equal(t, map[string]string{
+		"role":            "app",
+		"org":             "foo-org",
+		"app_id":          "42",
+		"app_name":        "deployer",
+		"installation_id": "7",
+	}, resp.Auth.Metadata)
+
+	_, err = testRoleLogin(b, s, map[string]interface{}{
+		"role":    "other-app",
+		"app_jwt": testAppJWT,
+	})
+	assert.EqualError(t, err, "app is not allowed by the role")
+
+	_, err = testRoleLogin(b, s, map[string]interface{}{
+		"role":    "app",
+		"app_jwt": "forged",
+	})
+	assert.Error(t, err)
+
+	// Actions tokens can't be used with app roles
+	_, err = testRoleLogin(b, s, map[string]interface{}{
+		"role": "app",
+		"jwt":  testAppJWT,
+	})
+	assert.True(t, strings.Contains(err.Error(), "missing app_jwt"))
+}
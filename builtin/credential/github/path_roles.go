@@ -0,0 +1,312 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package github
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/tokenutil"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/ryanuber/go-glob"
+)
+
+const (
+	rolePrefix = "roles/"
+
+	// roleTypeActions roles authenticate GitHub Actions workflow runs with
+	// their OIDC token
+	roleTypeActions = "actions"
+
+	// roleTypeApp roles authenticate GitHub Apps installed in the
+	// organization with a JWT signed by the app
+	roleTypeApp = "app"
+)
+
+func pathListRoles(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "roles/?$",
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixGithub,
+			OperationSuffix: "roles",
+		},
+
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ListOperation: &framework.PathOperation{
+				Callback: b.pathRoleList,
+			},
+		},
+
+		HelpSynopsis:    pathRoleHelpSyn,
+		HelpDescription: pathRoleHelpDesc,
+	}
+}
+
+func pathRoles(b *backend) *framework.Path {
+	p := &framework.Path{
+		Pattern: "roles/" + framework.GenericNameRegex("name"),
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixGithub,
+			OperationSuffix: "role",
+		},
+
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: "Name of the role.",
+			},
+			"role_type": {
+				Type:        framework.TypeString,
+				Default:     roleTypeActions,
+				Description: `Type of the role: "actions" for GitHub Actions OIDC tokens, or "app" for GitHub Apps.`,
+			},
+			"bound_audiences": {
+				Type: framework.TypeCommaStringSlice,
+				Description: `Comma-separated list of audiences, one of which the OIDC token must be issued for.
+Defaults to the default audience of GitHub Actions, the URL of the organization.`,
+			},
+			"bound_repositories": {
+				Type: framework.TypeCommaStringSlice,
+				Description: `Comma-separated list of repositories of the organization, such as "org/repo",
+that can log in with the role. Supports globbing. Required for "actions" roles.`,
+			},
+			"bound_environments": {
+				Type:        framework.TypeCommaStringSlice,
+				Description: "Comma-separated list of deployment environments the workflow job must run in. Supports globbing.",
+			},
+			"bound_workflow_refs": {
+				Type: framework.TypeCommaStringSlice,
+				Description: `Comma-separated list of workflows the job must be defined in, matched against the
+job_workflow_ref claim, such as "org/repo/.github/workflows/deploy.yml@refs/heads/main".
+Supports globbing.`,
+			},
+			"bound_refs": {
+				Type:        framework.TypeCommaStringSlice,
+				Description: `Comma-separated list of git refs the workflow must run for, such as "refs/heads/main". Supports globbing.`,
+			},
+			"bound_app_ids": {
+				Type:        framework.TypeCommaIntSlice,
+				Description: `Comma-separated list of IDs of the GitHub Apps that can log in with the role. Required for "app" roles.`,
+			},
+		},
+
+		ExistenceCheck: b.pathRoleExistenceCheck,
+
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.CreateOperation: &framework.PathOperation{
+				Callback: b.pathRoleWrite,
+			},
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathRoleWrite,
+			},
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.pathRoleRead,
+			},
+			logical.DeleteOperation: &framework.PathOperation{
+				Callback: b.pathRoleDelete,
+			},
+		},
+
+		HelpSynopsis:    pathRoleHelpSyn,
+		HelpDescription: pathRoleHelpDesc,
+	}
+
+	tokenutil.AddTokenFields(p.Fields)
+	return p
+}
+
+type roleEntry struct {
+	tokenutil.TokenParams
+
+	RoleType string `json:"role_type"`
+
+	// Constraints on the claims of GitHub Actions OIDC tokens
+	BoundAudiences    []string `json:"bound_audiences"`
+	BoundRepositories []string `json:"bound_repositories"`
+	BoundEnvironments []string `json:"bound_environments"`
+	BoundWorkflowRefs []string `json:"bound_workflow_refs"`
+	BoundRefs         []string `json:"bound_refs"`
+
+	// Constraints on GitHub Apps
+	BoundAppIDs []int64 `json:"bound_app_ids"`
+}
+
+// role returns the role with the given name, or nil if it doesn't exist.
+func (b *backend) role(ctx context.Context, s logical.Storage, name string) (*roleEntry, error) {
+	entry, err := s.Get(ctx, rolePrefix+strings.ToLower(name))
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var role roleEntry
+	if err := entry.DecodeJSON(&role); err != nil {
+		return nil, fmt.Errorf("error reading role: %w", err)
+	}
+	return &role, nil
+}
+
+func (b *backend) pathRoleExistenceCheck(ctx context.Context, req *logical.Request, data *framework.FieldData) (bool, error) {
+	role, err := b.role(ctx, req.Storage, data.Get("name").(string))
+	if err != nil {
+		return false, err
+	}
+	return role != nil, nil
+}
+
+func (b *backend) pathRoleList(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	roles, err := req.Storage.List(ctx, rolePrefix)
+	if err != nil {
+		return nil, err
+	}
+	return logical.ListResponse(roles), nil
+}
+
+func (b *backend) pathRoleRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	role, err := b.role(ctx, req.Storage, data.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, nil
+	}
+
+	d := map[string]interface{}{
+		"role_type":           role.RoleType,
+		"bound_audiences":     role.BoundAudiences,
+		"bound_repositories":  role.BoundRepositories,
+		"bound_environments":  role.BoundEnvironments,
+		"bound_workflow_refs": role.BoundWorkflowRefs,
+		"bound_refs":          role.BoundRefs,
+		"bound_app_ids":       role.BoundAppIDs,
+	}
+	role.PopulateTokenData(d)
+
+	return &logical.Response{
+		Data: d,
+	}, nil
+}
+
+func (b *backend) pathRoleWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := strings.ToLower(data.Get("name").(string))
+	role, err := b.role(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		role = &roleEntry{}
+	}
+
+	if roleTypeRaw, ok := data.GetOk("role_type"); ok {
+		role.RoleType = roleTypeRaw.(string)
+	} else if req.Operation == logical.CreateOperation {
+		role.RoleType = data.Get("role_type").(string)
+	}
+	if boundAudiencesRaw, ok := data.GetOk("bound_audiences"); ok {
+		role.BoundAudiences = boundAudiencesRaw.([]string)
+	}
+	if boundRepositoriesRaw, ok := data.GetOk("bound_repositories"); ok {
+		role.BoundRepositories = boundRepositoriesRaw.([]string)
+	}
+	if boundEnvironmentsRaw, ok := data.GetOk("bound_environments"); ok {
+		role.BoundEnvironments = boundEnvironmentsRaw.([]string)
+	}
+	if boundWorkflowRefsRaw, ok := data.GetOk("bound_workflow_refs"); ok {
+		role.BoundWorkflowRefs = boundWorkflowRefsRaw.([]string)
+	}
+	if boundRefsRaw, ok := data.GetOk("bound_refs"); ok {
+		role.BoundRefs = boundRefsRaw.([]string)
+	}
+	if boundAppIDsRaw, ok := data.GetOk("bound_app_ids"); ok {
+		role.BoundAppIDs = nil
+		for _, id := range boundAppIDsRaw.([]int) {
+			role.BoundAppIDs = append(role.BoundAppIDs, int64(id))
+		}
+	}
+
+	switch role.RoleType {
+	case roleTypeActions:
+		if len(role.BoundRepositories) == 0 {
+			return logical.ErrorResponse("bound_repositories must be set for %q roles", roleTypeActions), nil
+		}
+		if len(role.BoundAppIDs) != 0 {
+			return logical.ErrorResponse("bound_app_ids can only be set for %q roles", roleTypeApp), nil
+		}
+	case roleTypeApp:
+		if len(role.BoundAppIDs) == 0 {
+			return logical.ErrorResponse("bound_app_ids must be set for %q roles", roleTypeApp), nil
+		}
+		if len(role.BoundAudiences)+len(role.BoundRepositories)+len(role.BoundEnvironments)+
+			len(role.BoundWorkflowRefs)+len(role.BoundRefs) != 0 {
+			return logical.ErrorResponse("only bound_app_ids can be set for %q roles", roleTypeApp), nil
+		}
+	default:
+		return logical.ErrorResponse("invalid role_type %q, must be %q or %q", role.RoleType, roleTypeActions, roleTypeApp), nil
+	}
+
+	if err := role.ParseTokenFields(req, data); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
+	var resp logical.Response
+	if role.TokenMaxTTL != 0 && role.TokenTTL > role.TokenMaxTTL {
+		return logical.ErrorResponse("token_ttl should not be greater than token_max_ttl"), nil
+	}
+	if systemMaxTTL := b.System().MaxLeaseTTL(); role.TokenMaxTTL > systemMaxTTL {
+		resp.AddWarning(fmt.Sprintf("Given token_max_ttl of %d seconds is greater than current mount/system default of %d seconds", role.TokenMaxTTL/time.Second, systemMaxTTL/time.Second))
+	}
+
+	entry, err := logical.StorageEntryJSON(rolePrefix+name, role)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	if len(resp.Warnings) == 0 {
+		return nil, nil
+	}
+	return &resp, nil
+}
+
+func (b *backend) pathRoleDelete(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	return nil, req.Storage.Delete(ctx, rolePrefix+strings.ToLower(data.Get("name").(string)))
+}
+
+// matchesAny reports whether no patterns are given or the value matches at
+// least one of them.
+func matchesAny(patterns []string, value string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if glob.Glob(pattern, value) {
+			return true
+		}
+	}
+	return false
+}
+
+const pathRoleHelpSyn = `
+Manage the roles GitHub Actions workflows and GitHub Apps log in with.
+`
+
+const pathRoleHelpDesc = `
+Roles authenticate workloads of the organization rather than users. A role of
+type "actions" accepts the OIDC tokens GitHub Actions issues to workflow jobs,
+and restricts which repositories, environments, workflows and git refs can log
+in with it. A role of type "app" accepts a JWT signed by one of the given
+GitHub Apps, which must be installed in the organization.
+
+Tokens issued with a role get the token settings of the role, such as its
+policies, rather than the ones of the configuration.
+`
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/hashicorp/cap/ldap"
 	"github.com/hashicorp/go-secure-stdlib/strutil"
@@ -32,6 +33,7 @@ func Factory(ctx context.Context, conf *logical.BackendConfig) (logical.Backend,
 
 func Backend() *backend {
 	var b backend
+	b.groupCache = ldaputil.NewGroupCache()
 	b.Backend = &framework.Backend{
 		Help: backendHelp,
 
@@ -56,6 +58,7 @@ func Backend() *backend {
 		},
 
 		AuthRenew:   b.pathLoginRenew,
+		Invalidate:  b.invalidate,
 		BackendType: logical.TypeCredential,
 	}
 
@@ -66,6 +69,17 @@ type backend struct {
 	*framework.Backend
 
 	mu sync.RWMutex
+
+	// groupCache holds the LDAP groups of users who logged in recently, when
+	// group_cache_ttl is set
+	groupCache *ldaputil.GroupCache
+}
+
+func (b *backend) invalidate(_ context.Context, key string) {
+	switch key {
+	case "config":
+		b.groupCache.Purge()
+	}
 }
 
 func (b *backend) Login(ctx context.Context, req *logical.Request, username string, password string, usernameAsAlias bool) (string, []string, *logical.Response, []string, error) {
@@ -81,7 +95,18 @@ func (b *backend) Login(ctx context.Context, req *logical.Request, username stri
 		return "", nil, logical.ErrorResponse("password cannot be of zero length when passwordless binds are being denied"), nil, nil
 	}
 
-	ldapClient, err := ldap.NewClient(ctx, ldaputil.ConvertConfig(cfg.ConfigEntry))
+	// Nested groups and the group cache are handled by ldaputil, which then
+	// searches for the groups instead of cap/ldap.
+	resolveGroups := cfg.ResolvesNestedGroups() || cfg.GroupCacheTTL > 0
+	clientConfig := ldaputil.ConvertConfig(cfg.ConfigEntry)
+	opts := []ldap.Option{ldap.WithUserAttributes()}
+	if resolveGroups {
+		clientConfig.IncludeUserGroups = false
+	} else {
+		opts = append(opts, ldap.WithGroups())
+	}
+
+	ldapClient, err := ldap.NewClient(ctx, clientConfig)
 	if err != nil {
 		return "", nil, logical.ErrorResponse(err.Error()), nil, nil
 	}
@@ -89,7 +114,7 @@ func (b *backend) Login(ctx context.Context, req *logical.Request, username stri
 	// Clean connection
 	defer ldapClient.Close(ctx)
 
-	c, err := ldapClient.Authenticate(ctx, username, password, ldap.WithGroups(), ldap.WithUserAttributes())
+	c, err := ldapClient.Authenticate(ctx, username, password, opts...)
 	if err != nil {
 		if strings.Contains(err.Error(), "discovery of user bind DN failed") ||
 			strings.Contains(err.Error(), "unable to bind user") {
@@ -100,6 +125,13 @@ func (b *backend) Login(ctx context.Context, req *logical.Request, username stri
 	}
 
 	ldapGroups := c.Groups
+	if resolveGroups {
+		ldapGroups, err = b.getLdapGroups(cfg, c.UserDN, username, password)
+		if err != nil {
+			return "", nil, logical.ErrorResponse(err.Error()), nil, nil
+		}
+	}
+
 	ldapResponse := &logical.Response{
 		Data: map[string]interface{}{},
 	}
@@ -167,6 +199,63 @@ func (b *backend) Login(ctx context.Context, req *logical.Request, username stri
 	return entityAliasAttribute, policies, ldapResponse, allGroups, nil
 }
 
+// getLdapGroups returns the LDAP groups of the authenticated user, including
+// nested groups if configured, from the group cache if possible.
+func (b *backend) getLdapGroups(cfg *ldapConfigEntry, userDN, username, password string) ([]string, error) {
+	if cfg.GroupCacheTTL > 0 {
+		if groups, ok := b.groupCache.Get(userDN, username); ok {
+			return groups, nil
+		}
+	}
+
+	ldapClient := ldaputil.Client{
+		Logger: b.Logger(),
+		LDAP:   ldaputil.NewLDAP(),
+	}
+
+	conn, err := ldapClient.DialLDAP(cfg.ConfigEntry)
+	if err != nil {
+		return nil, err
+	}
+	if conn == nil {
+		return nil, fmt.Errorf("invalid connection returned from LDAP dial")
+	}
+	defer conn.Close()
+
+	// Search as the same identity cap/ldap does: the BindDN if it is
+	// defined, the user otherwise, unless searches are anonymous.
+	switch {
+	case cfg.AnonymousGroupSearch:
+		err = conn.UnauthenticatedBind("")
+	case cfg.BindDN != "" && cfg.BindPassword != "":
+		err = conn.Bind(cfg.BindDN, cfg.BindPassword)
+	default:
+		var userBindDN string
+		userBindDN, err = ldapClient.GetUserBindDN(cfg.ConfigEntry, conn, username)
+		if err != nil {
+			return nil, err
+		}
+		if len(password) > 0 {
+			err = conn.Bind(userBindDN, password)
+		} else {
+			err = conn.UnauthenticatedBind(userBindDN)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("LDAP bind for group search failed: %w", err)
+	}
+
+	groups, err := ldapClient.GetLdapGroups(cfg.ConfigEntry, conn, userDN, username)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.GroupCacheTTL > 0 {
+		b.groupCache.Set(userDN, username, groups, time.Duration(cfg.GroupCacheTTL)*time.Second)
+	}
+	return groups, nil
+}
+
 const backendHelp = `
 The "ldap" credential provider allows authentication querying
 a LDAP server, checking username and password, and associating groups
@@ -1313,6 +1313,9 @@ func TestLdapAuthBackend_ConfigUpgrade(t *testing.T) {
 			UsernameAsAlias:          false,
 			DerefAliases:             "never",
 			MaximumPageSize:          1000,
+			NestedGroups:             defParams.NestedGroups,
+			NestedGroupsMaxDepth:     defParams.NestedGroupsMaxDepth,
+			GroupMemberAttr:          defParams.GroupMemberAttr,
 		},
 	}
 
@@ -218,6 +218,7 @@ func (b *backend) pathConfigWrite(ctx context.Context, req *logical.Request, d *
 	if err := req.Storage.Put(ctx, entry); err != nil {
 		return nil, err
 	}
+	b.groupCache.Purge()
 
 	if warnings := b.checkConfigUserFilter(cfg); len(warnings) > 0 {
 		return &logical.Response{
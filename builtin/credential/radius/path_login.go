@@ -5,6 +5,7 @@ package radius
 
 import (
 	"context"
+	"encoding/base64"
 	"fmt"
 	"net"
 	"strconv"
@@ -42,7 +43,12 @@ func pathLogin(b *backend) *framework.Path {
 
 			"password": {
 				Type:        framework.TypeString,
-				Description: "Password for this user.",
+				Description: "Password for this user, or the response to the challenge when state is set.",
+			},
+
+			"state": {
+				Type:        framework.TypeString,
+				Description: "State returned by a previous login attempt the RADIUS server answered with a challenge.",
 			},
 		},
 
@@ -105,32 +111,55 @@ func (b *backend) pathLogin(ctx context.Context, req *logical.Request, d *framew
 		return logical.ErrorResponse("password cannot be empty"), nil
 	}
 
-	policies, resp, err := b.RadiusLogin(ctx, req, username, password)
+	var state []byte
+	if stateRaw := d.Get("state").(string); stateRaw != "" {
+		state, err = base64.StdEncoding.DecodeString(stateRaw)
+		if err != nil {
+			return logical.ErrorResponse("invalid state: %s", err), nil
+		}
+	}
+
+	policies, resp, err := b.RadiusLogin(ctx, req, username, password, state)
 	// Handle an internal error
 	if err != nil {
 		return nil, err
 	}
 	if resp != nil {
-		// Handle a logical error
-		if resp.IsError() {
+		// Handle a logical error, or a challenge the client must respond to
+		// before logging in
+		if resp.IsError() || isChallenge(resp) {
 			return resp, nil
 		}
 	}
 
+	// The response to a challenge is usually a one-time code, which can't be
+	// used to check the credentials again at renewal time, so tokens issued
+	// after a challenge are not renewable.
+	internalData := map[string]interface{}{
+		"password": password,
+	}
+	if state != nil {
+		internalData = map[string]interface{}{
+			"challenge": true,
+		}
+	}
+
 	auth := &logical.Auth{
 		Metadata: map[string]string{
 			"username": username,
 			"policies": strings.Join(policies, ","),
 		},
-		InternalData: map[string]interface{}{
-			"password": password,
-		},
-		DisplayName: username,
+		InternalData: internalData,
+		DisplayName:  username,
 		Alias: &logical.Alias{
 			Name: username,
 		},
 	}
 	cfg.PopulateTokenAuth(auth)
+	if state != nil {
+		auth.Renewable = false
+		auth.Period = 0
+	}
 
 	resp.Auth = auth
 	if policies != nil {
@@ -150,15 +179,23 @@ func (b *backend) pathLoginRenew(ctx context.Context, req *logical.Request, d *f
 	}
 
 	username := req.Auth.Metadata["username"]
-	password := req.Auth.InternalData["password"].(string)
 
 	var resp *logical.Response
 	var loginPolicies []string
 
-	loginPolicies, resp, err = b.RadiusLogin(ctx, req, username, password)
+	if _, ok := req.Auth.InternalData["challenge"]; ok {
+		return nil, fmt.Errorf("tokens issued after a challenge cannot be renewed")
+	}
+
+	password := req.Auth.InternalData["password"].(string)
+
+	loginPolicies, resp, err = b.RadiusLogin(ctx, req, username, password, nil)
 	if err != nil || (resp != nil && resp.IsError()) {
 		return resp, err
 	}
+	if isChallenge(resp) {
+		return nil, fmt.Errorf("the authentication server requires a challenge to be answered, not renewing")
+	}
 	finalPolicies := cfg.TokenPolicies
 	if loginPolicies != nil {
 		finalPolicies = append(finalPolicies, loginPolicies...)
@@ -174,7 +211,11 @@ func (b *backend) pathLoginRenew(ctx context.Context, req *logical.Request, d *f
 	return &logical.Response{Auth: req.Auth}, nil
 }
 
-func (b *backend) RadiusLogin(ctx context.Context, req *logical.Request, username string, password string) ([]string, *logical.Response, error) {
+// RadiusLogin authenticates the user with the RADIUS server. The state is
+// the one of the challenge the password responds to, if any. If the server
+// answers with a challenge in turn, the returned response holds the state and
+// message of the challenge, and no policies are returned.
+func (b *backend) RadiusLogin(ctx context.Context, req *logical.Request, username string, password string, state []byte) ([]string, *logical.Response, error) {
 	cfg, err := b.Config(ctx, req)
 	if err != nil {
 		return nil, nil, err
@@ -192,6 +233,11 @@ func (b *backend) RadiusLogin(ctx context.Context, req *logical.Request, usernam
 		NASIdentifier_AddString(packet, cfg.NasIdentifier)
 	}
 	packet.Add(5, radius.NewInteger(uint32(cfg.NasPort)))
+	if state != nil {
+		if err := State_Set(packet, state); err != nil {
+			return nil, logical.ErrorResponse("invalid state: %s", err), nil
+		}
+	}
 
 	client := radius.Client{
 		Dialer: net.Dialer{
@@ -204,22 +250,61 @@ func (b *backend) RadiusLogin(ctx context.Context, req *logical.Request, usernam
 	if err != nil {
 		return nil, logical.ErrorResponse(err.Error()), nil
 	}
-	if received.Code != radius.CodeAccessAccept {
+	switch received.Code {
+	case radius.CodeAccessAccept:
+	case radius.CodeAccessChallenge:
+		challengeState := State_Get(received)
+		if len(challengeState) == 0 {
+			return nil, logical.ErrorResponse("challenge from the authentication server has no state"), nil
+		}
+		return nil, &logical.Response{
+			Data: map[string]interface{}{
+				"state":         base64.StdEncoding.EncodeToString(challengeState),
+				"reply_message": replyMessage(received),
+			},
+		}, nil
+	default:
 		return nil, logical.ErrorResponse("access denied by the authentication server"), nil
 	}
 
-	policies := cfg.UnregisteredUserPolicies
+	policies, err := b.userPolicies(ctx, req, cfg, username)
+	if err != nil {
+		return nil, logical.ErrorResponse("could not retrieve user entry from storage"), err
+	}
+
+	return policies, &logical.Response{}, nil
+}
 
-	// Retrieve user entry from storage
+// userPolicies returns the policies of the user, or the ones of unregistered
+// users if it has no entry in storage.
+func (b *backend) userPolicies(ctx context.Context, req *logical.Request, cfg *ConfigEntry, username string) ([]string, error) {
 	user, err := b.user(ctx, req.Storage, username)
 	if err != nil {
-		return nil, logical.ErrorResponse("could not retrieve user entry from storage"), err
+		return nil, err
 	}
 	if user != nil {
-		policies = user.Policies
+		return user.Policies, nil
 	}
+	return cfg.UnregisteredUserPolicies, nil
+}
 
-	return policies, &logical.Response{}, nil
+// isChallenge reports whether the response of RadiusLogin is a challenge.
+func isChallenge(resp *logical.Response) bool {
+	if resp == nil {
+		return false
+	}
+	_, ok := resp.Data["state"]
+	return ok
+}
+
+// replyMessage returns the Reply-Message attributes of the packet, which are
+// meant to be displayed to the user, joined by newlines.
+func replyMessage(p *radius.Packet) string {
+	messages, err := ReplyMessage_GetStrings(p)
+	if err != nil {
+		return ""
+	}
+	return strings.Join(messages, "\n")
 }
 
 const pathLoginSyn = `
@@ -229,4 +314,9 @@ Log in with a username and password.
 const pathLoginDesc = `
 This endpoint authenticates using a username and password. Please be sure to
 read the note on escaping from the path-help for the 'config' endpoint.
+
+If the RADIUS server answers with a challenge, such as for a token code, no
+token is issued. Instead, the response holds a "state" and the "reply_message"
+of the server. Log in again with the same username, the response to the
+challenge as password, and the state.
 `
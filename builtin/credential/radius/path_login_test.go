@@ -0,0 +1,180 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package radius
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"layeh.com/radius"
+	. "layeh.com/radius/rfc2865"
+)
+
+const testRadiusSecret = "testing123"
+
+// startTestRadiusServer starts an in-process RADIUS server. bob logs in with
+// his password alone, while alice is challenged for a token code after her
+// password.
+func startTestRadiusServer(t *testing.T) int {
+	t.Helper()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	server := &radius.PacketServer{
+		SecretSource: radius.StaticSecretSource([]byte(testRadiusSecret)),
+		Handler: radius.HandlerFunc(func(w radius.ResponseWriter, r *radius.Request) {
+			username := UserName_GetString(r.Packet)
+			password := UserPassword_GetString(r.Packet)
+			state := State_GetString(r.Packet)
+
+			code := radius.CodeAccessReject
+			var resp *radius.Packet
+			switch {
+			case username == "bob" && password == "pass" && state == "":
+				code = radius.CodeAccessAccept
+			case username == "alice" && password == "pass" && state == "":
+				resp = r.Response(radius.CodeAccessChallenge)
+				State_SetString(resp, "alice-challenge")
+				ReplyMessage_AddString(resp, "Enter your token code")
+			case username == "alice" && password == "123456" && state == "alice-challenge":
+				code = radius.CodeAccessAccept
+			}
+			if resp == nil {
+				resp = r.Response(code)
+			}
+			w.Write(resp)
+		}),
+	}
+	go server.Serve(conn)
+	t.Cleanup(func() { server.Shutdown(context.Background()) })
+
+	return conn.LocalAddr().(*net.UDPAddr).Port
+}
+
+func testRadiusBackend(t *testing.T) (*backend, logical.Storage) {
+	t.Helper()
+
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+	b := Backend()
+	require.NoError(t, b.Setup(context.Background(), config))
+
+	port := startTestRadiusServer(t)
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Path:      "config",
+		Operation: logical.CreateOperation,
+		Storage:   config.StorageView,
+		Data: map[string]interface{}{
+			"host":                       "127.0.0.1",
+			"port":                       strconv.Itoa(port),
+			"secret":                     testRadiusSecret,
+			"unregistered_user_policies": "otp",
+		},
+	})
+	require.NoError(t, err)
+	require.False(t, resp != nil && resp.IsError(), "bad: %#v", resp)
+
+	return b, config.StorageView
+}
+
+func testRadiusLogin(t *testing.T, b *backend, s logical.Storage, data map[string]interface{}) *logical.Response {
+	t.Helper()
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Path:       "login",
+		Operation:  logical.UpdateOperation,
+		Storage:    s,
+		Data:       data,
+		Connection: &logical.Connection{RemoteAddr: "127.0.0.1"},
+	})
+	require.NoError(t, err)
+	require.NotNil(t, resp)
+	return resp
+}
+
+func testRadiusRenew(b *backend, s logical.Storage, auth *logical.Auth) (*logical.Response, error) {
+	auth.TokenPolicies = auth.Policies
+	return b.HandleRequest(context.Background(), &logical.Request{
+		Path:      "login",
+		Operation: logical.RenewOperation,
+		Storage:   s,
+		Auth:      auth,
+	})
+}
+
+// TestRadius_Login_Challenge tests that logins the RADIUS server challenges
+// return the challenge to the client, and succeed once it responds to it
+func TestRadius_Login_Challenge(t *testing.T) {
+	b, s := testRadiusBackend(t)
+
+	resp := testRadiusLogin(t, b, s, map[string]interface{}{
+		"username": "alice",
+		"password": "pass",
+	})
+	require.NoError(t, resp.Error())
+	assert.Nil(t, resp.Auth)
+	assert.Equal(t, "Enter your token code", resp.Data["reply_message"])
+	state := resp.Data["state"].(string)
+	assert.NotEmpty(t, state)
+
+	// A wrong response to the challenge is rejected
+	resp = testRadiusLogin(t, b, s, map[string]interface{}{
+		"username": "alice",
+		"password": "654321",
+		"state":    state,
+	})
+	assert.EqualError(t, resp.Error(), "access denied by the authentication server")
+
+	resp = testRadiusLogin(t, b, s, map[string]interface{}{
+		"username": "alice",
+		"password": "123456",
+		"state":    state,
+	})
+	require.NoError(t, resp.Error())
+	require.NotNil(t, resp.Auth)
+	assert.Equal(t, "alice", resp.Auth.Alias.Name)
+	assert.Equal(t, []string{"otp"}, resp.Auth.Policies)
+	assert.NotContains(t, resp.Auth.InternalData, "password")
+
+	// The token code can't be used again, so the token is not renewable
+	assert.False(t, resp.Auth.Renewable)
+	_, err := testRadiusRenew(b, s, resp.Auth)
+	assert.EqualError(t, err, "tokens issued after a challenge cannot be renewed")
+
+	resp = testRadiusLogin(t, b, s, map[string]interface{}{
+		"username": "alice",
+		"password": "123456",
+		"state":    "not base64",
+	})
+	assert.ErrorContains(t, resp.Error(), "invalid state")
+}
+
+// TestRadius_Login_NoChallenge tests that logins without a challenge still
+// issue a token right away
+func TestRadius_Login_NoChallenge(t *testing.T) {
+	b, s := testRadiusBackend(t)
+
+	resp := testRadiusLogin(t, b, s, map[string]interface{}{
+		"username": "bob",
+		"password": "pass",
+	})
+	require.NoError(t, resp.Error())
+	require.NotNil(t, resp.Auth)
+	assert.Equal(t, "pass", resp.Auth.InternalData["password"])
+
+	_, err := testRadiusRenew(b, s, resp.Auth)
+	require.NoError(t, err)
+
+	resp = testRadiusLogin(t, b, s, map[string]interface{}{
+		"username": "bob",
+		"password": "wrong",
+	})
+	assert.EqualError(t, resp.Error(), "access denied by the authentication server")
+}
@@ -28,14 +28,17 @@ func Backend() *backend {
 		PathsSpecial: &logical.Paths{
 			Unauthenticated: []string{
 				"login/*",
+				"change-password/*",
 			},
 		},
 
 		Paths: []*framework.Path{
+			pathConfig(&b),
 			pathUsers(&b),
 			pathUsersList(&b),
 			pathUserPolicies(&b),
 			pathUserPassword(&b),
+			pathChangePassword(&b),
 			pathLogin(&b),
 		},
 
@@ -57,5 +60,6 @@ are supported.
 
 The username/password combination is configured using the "users/"
 endpoints by a user with root access. Authentication is then done
-by supplying the two fields for "login".
+by supplying the two fields for "login". Users can change their own
+password with "change-password" by supplying the current one.
 `
@@ -0,0 +1,117 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package userpass
+
+import (
+	"context"
+	"strings"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	pathChangePasswordHelpSyn = `
+Change the password of a user by presenting the current one.
+`
+	pathChangePasswordHelpDesc = `
+This endpoint allows users to change their own password. It does not require
+a token: the request is authenticated with the current password, like a
+login, so users with an expired password can change it as well. The new
+password must satisfy the password requirements of the auth method.
+`
+
+	// The name of the current password parameter supplied via the API.
+	paramCurrentPassword = "current_password"
+
+	// The name of the new password parameter supplied via the API.
+	paramNewPassword = "new_password"
+)
+
+func pathChangePassword(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "change-password/" + framework.GenericNameRegex(paramUsername),
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixUserpass,
+			OperationVerb:   "change",
+			OperationSuffix: "password",
+		},
+
+		Fields: map[string]*framework.FieldSchema{
+			paramUsername: {
+				Type:        framework.TypeString,
+				Description: "Username of the user.",
+			},
+
+			paramCurrentPassword: {
+				Type:        framework.TypeString,
+				Description: "Current password of the user.",
+				DisplayAttrs: &framework.DisplayAttributes{
+					Sensitive: true,
+				},
+			},
+
+			paramNewPassword: {
+				Type:        framework.TypeString,
+				Description: "New password for the user.",
+				DisplayAttrs: &framework.DisplayAttributes{
+					Sensitive: true,
+				},
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation:         b.pathChangePassword,
+			logical.AliasLookaheadOperation: b.pathLoginAliasLookahead,
+		},
+
+		HelpSynopsis:    pathChangePasswordHelpSyn,
+		HelpDescription: pathChangePasswordHelpDesc,
+	}
+}
+
+func (b *backend) pathChangePassword(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	username := strings.ToLower(d.Get(paramUsername).(string))
+
+	currentPassword := d.Get(paramCurrentPassword).(string)
+	if currentPassword == "" {
+		return logical.ErrorResponse("missing %s", paramCurrentPassword), logical.ErrInvalidRequest
+	}
+	newPassword := d.Get(paramNewPassword).(string)
+	if newPassword == "" {
+		return logical.ErrorResponse("missing %s", paramNewPassword), logical.ErrInvalidRequest
+	}
+
+	user, resp, err := b.authenticateUser(ctx, req, username, currentPassword)
+	if resp != nil || err != nil {
+		return resp, err
+	}
+
+	// Changing to the same password would only reset its age
+	if newPassword == currentPassword {
+		return logical.ErrorResponse("new password must differ from the current password"), logical.ErrInvalidRequest
+	}
+
+	config, err := b.config(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	userErr, intErr := b.checkPassword(ctx, config, user, newPassword)
+	if intErr != nil {
+		return nil, intErr
+	}
+	if userErr != nil {
+		return logical.ErrorResponse(userErr.Error()), logical.ErrInvalidRequest
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	user.setPasswordHash(hash, config.PasswordHistory)
+
+	return nil, b.setUser(ctx, req.Storage, username, user)
+}
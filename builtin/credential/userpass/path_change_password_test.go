@@ -0,0 +1,115 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package userpass
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+)
+
+// TestUserPass_ChangePassword ensures that users can change their own
+// password by presenting the current one.
+func TestUserPass_ChangePassword(t *testing.T) {
+	b, s := createBackendWithPasswordPolicies(t)
+
+	resp, err := testHandleRequest(b, s, logical.CreateOperation, "users/alice", map[string]interface{}{
+		"password":          "current password 1",
+		"token_bound_cidrs": "127.0.0.1",
+	})
+	require.NoError(t, err)
+	require.Nil(t, resp)
+
+	login := func(password string) *logical.Response {
+		t.Helper()
+		resp, _ := testHandleRequest(b, s, logical.UpdateOperation, "login/alice", map[string]interface{}{
+			"password": password,
+		})
+		return resp
+	}
+	changePassword := func(current, new string) (*logical.Response, error) {
+		return testHandleRequest(b, s, logical.UpdateOperation, "change-password/Alice", map[string]interface{}{
+			paramCurrentPassword: current,
+			paramNewPassword:     new,
+		})
+	}
+
+	t.Run("wrong current password", func(t *testing.T) {
+		resp, err := changePassword("wrong", "new password 2")
+		require.ErrorIs(t, err, logical.ErrInvalidCredentials)
+		require.EqualError(t, resp.Error(), "invalid username or password")
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		resp, err := testHandleRequest(b, s, logical.UpdateOperation, "change-password/bob", map[string]interface{}{
+			paramCurrentPassword: "current password 1",
+			paramNewPassword:     "new password 2",
+		})
+		require.NoError(t, err)
+		require.EqualError(t, resp.Error(), "invalid username or password")
+	})
+
+	t.Run("same password", func(t *testing.T) {
+		resp, err := changePassword("current password 1", "current password 1")
+		require.ErrorIs(t, err, logical.ErrInvalidRequest)
+		require.EqualError(t, resp.Error(), "new password must differ from the current password")
+	})
+
+	t.Run("policy", func(t *testing.T) {
+		resp, err := testHandleRequest(b, s, logical.UpdateOperation, "config", map[string]interface{}{
+			"password_policy": "strong",
+		})
+		require.NoError(t, err)
+		require.Nil(t, resp)
+
+		resp, err = changePassword("current password 1", "weak")
+		require.ErrorIs(t, err, logical.ErrInvalidRequest)
+		require.Contains(t, resp.Error().Error(), `password does not satisfy the password policy "strong"`)
+	})
+
+	t.Run("bound CIDRs", func(t *testing.T) {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "change-password/alice",
+			Storage:   s,
+			Data: map[string]interface{}{
+				paramCurrentPassword: "current password 1",
+				paramNewPassword:     "new password 2",
+			},
+			Connection: &logical.Connection{RemoteAddr: "10.0.0.1"},
+		})
+		require.ErrorIs(t, err, logical.ErrPermissionDenied)
+		require.Nil(t, resp)
+	})
+
+	t.Run("expired password", func(t *testing.T) {
+		resp, err := testHandleRequest(b, s, logical.UpdateOperation, "config", map[string]interface{}{
+			"password_max_age": "1h",
+		})
+		require.NoError(t, err)
+		require.Nil(t, resp)
+
+		user, err := b.user(context.Background(), s, "alice")
+		require.NoError(t, err)
+		user.PasswordLastUpdated = time.Now().Add(-2 * time.Hour)
+		require.NoError(t, b.setUser(context.Background(), s, "alice", user))
+		require.EqualError(t, login("current password 1").Error(), "password has expired and must be changed")
+
+		resp, err = changePassword("current password 1", "new password 2")
+		require.NoError(t, err)
+		require.Nil(t, resp)
+
+		require.NotNil(t, login("new password 2").Auth)
+		require.Nil(t, login("current password 1").Auth)
+	})
+
+	t.Run("alias lookahead", func(t *testing.T) {
+		resp, err := testHandleRequest(b, s, logical.AliasLookaheadOperation, "change-password/alice", nil)
+		require.NoError(t, err)
+		require.Equal(t, "alice", resp.Auth.Alias.Name)
+	})
+}
@@ -0,0 +1,156 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package userpass
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	configPath = "config"
+
+	// maxPasswordHistory bounds the number of previous passwords kept, as
+	// every one of them is compared with bcrypt when a password is set.
+	maxPasswordHistory = 24
+)
+
+func pathConfig(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config$",
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixUserpass,
+			Action:          "Configure",
+		},
+
+		Fields: map[string]*framework.FieldSchema{
+			"password_policy": {
+				Type:        framework.TypeString,
+				Description: "Name of the password policy passwords set for users must satisfy.",
+			},
+			"password_history": {
+				Type: framework.TypeInt,
+				Description: fmt.Sprintf("Number of most recent passwords of a user, including the current one, "+
+					"that cannot be reused. Defaults to 0, allowing any reuse. Up to %d.", maxPasswordHistory),
+			},
+			"password_max_age": {
+				Type: framework.TypeDurationSecond,
+				Description: "Duration after which a password expires and must be changed before the user " +
+					"can log in again. Defaults to 0, meaning passwords do not expire.",
+			},
+		},
+
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.pathConfigRead,
+				DisplayAttrs: &framework.DisplayAttributes{
+					OperationSuffix: "configuration",
+				},
+			},
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathConfigWrite,
+				DisplayAttrs: &framework.DisplayAttributes{
+					OperationVerb: "configure",
+				},
+			},
+		},
+
+		HelpSynopsis:    pathConfigHelpSyn,
+		HelpDescription: pathConfigHelpDesc,
+	}
+}
+
+// config returns the configuration of the mount, or the defaults if it
+// hasn't been configured.
+func (b *backend) config(ctx context.Context, s logical.Storage) (*userpassConfig, error) {
+	entry, err := s.Get(ctx, configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &userpassConfig{}
+	if entry == nil {
+		return config, nil
+	}
+	if err := entry.DecodeJSON(config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func (b *backend) pathConfigRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config, err := b.config(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"password_policy":  config.PasswordPolicy,
+			"password_history": config.PasswordHistory,
+			"password_max_age": int64(config.PasswordMaxAge.Seconds()),
+		},
+	}, nil
+}
+
+func (b *backend) pathConfigWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config, err := b.config(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	if policy, ok := d.GetOk("password_policy"); ok {
+		config.PasswordPolicy = policy.(string)
+	}
+	if config.PasswordPolicy != "" {
+		if _, ok := b.System().(logical.PasswordPolicyValidator); !ok {
+			return logical.ErrorResponse("password policies are not supported when running as an external plugin"), nil
+		}
+	}
+
+	if history, ok := d.GetOk("password_history"); ok {
+		config.PasswordHistory = history.(int)
+	}
+	if config.PasswordHistory < 0 || config.PasswordHistory > maxPasswordHistory {
+		return logical.ErrorResponse("password_history must be between 0 and %d", maxPasswordHistory), nil
+	}
+
+	if maxAge, ok := d.GetOk("password_max_age"); ok {
+		config.PasswordMaxAge = time.Duration(maxAge.(int)) * time.Second
+	}
+	if config.PasswordMaxAge < 0 {
+		return logical.ErrorResponse("password_max_age cannot be negative"), nil
+	}
+
+	entry, err := logical.StorageEntryJSON(configPath, config)
+	if err != nil {
+		return nil, err
+	}
+	return nil, req.Storage.Put(ctx, entry)
+}
+
+type userpassConfig struct {
+	PasswordPolicy  string        `json:"password_policy"`
+	PasswordHistory int           `json:"password_history"`
+	PasswordMaxAge  time.Duration `json:"password_max_age"`
+}
+
+const pathConfigHelpSyn = `
+Configure the password requirements of the auth method.
+`
+
+const pathConfigHelpDesc = `
+This endpoint configures the requirements passwords of users must satisfy:
+a password policy new passwords are validated against, the number of
+previous passwords that cannot be reused, and the maximum age of passwords,
+after which users must change them before logging in again.
+
+Pre-hashed passwords cannot be validated against a password policy, so
+"password_hash" is rejected while a password policy is configured.
+`
@@ -70,6 +70,39 @@ func (b *backend) pathLogin(ctx context.Context, req *logical.Request, d *framew
 		return nil, fmt.Errorf("missing password")
 	}
 
+	user, resp, err := b.authenticateUser(ctx, req, username, password)
+	if resp != nil || err != nil {
+		return resp, err
+	}
+
+	config, err := b.config(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if user.passwordExpired(config.PasswordMaxAge) {
+		return logical.ErrorResponse("password has expired and must be changed"), nil
+	}
+
+	auth := &logical.Auth{
+		Metadata: map[string]string{
+			"username": username,
+		},
+		DisplayName: username,
+		Alias: &logical.Alias{
+			Name: username,
+		},
+	}
+	user.PopulateTokenAuth(auth)
+
+	return &logical.Response{
+		Auth: auth,
+	}, nil
+}
+
+// authenticateUser looks up the user and checks the password and the bound
+// CIDRs of its tokens. If authentication fails, the response and error to
+// return are set instead of the user.
+func (b *backend) authenticateUser(ctx context.Context, req *logical.Request, username, password string) (*UserEntry, *logical.Response, error) {
 	// Get the user and validate auth
 	user, userError := b.user(ctx, req.Storage, username)
 
@@ -101,54 +134,41 @@ func (b *backend) pathLogin(ctx context.Context, req *logical.Request, d *framew
 			// The failed login info of existing users alone are tracked as only
 			// existing user's failed login information is stored in storage for optimization
 			if user == nil || userError != nil {
-				return logical.ErrorResponse("invalid username or password"), nil
+				return nil, logical.ErrorResponse("invalid username or password"), nil
 			}
-			return logical.ErrorResponse("invalid username or password"), logical.ErrInvalidCredentials
+			return nil, logical.ErrorResponse("invalid username or password"), logical.ErrInvalidCredentials
 		}
 	default:
 		if subtle.ConstantTimeCompare(userPassword, passwordBytes) != 1 {
 			// The failed login info of existing users alone are tracked as only
 			// existing user's failed login information is stored in storage for optimization
 			if user == nil || userError != nil {
-				return logical.ErrorResponse("invalid username or password"), nil
+				return nil, logical.ErrorResponse("invalid username or password"), nil
 			}
-			return logical.ErrorResponse("invalid username or password"), logical.ErrInvalidCredentials
+			return nil, logical.ErrorResponse("invalid username or password"), logical.ErrInvalidCredentials
 		}
 
 	}
 
 	if userError != nil {
-		return nil, userError
+		return nil, nil, userError
 	}
 	if user == nil {
-		return logical.ErrorResponse("invalid username or password"), nil
+		return nil, logical.ErrorResponse("invalid username or password"), nil
 	}
 
 	// Check for a CIDR match.
 	if len(user.TokenBoundCIDRs) > 0 {
 		if req.Connection == nil {
 			b.Logger().Warn("token bound CIDRs found but no connection information available for validation")
-			return nil, logical.ErrPermissionDenied
+			return nil, nil, logical.ErrPermissionDenied
 		}
 		if !cidrutil.RemoteAddrIsOk(req.Connection.RemoteAddr, user.TokenBoundCIDRs) {
-			return nil, logical.ErrPermissionDenied
+			return nil, nil, logical.ErrPermissionDenied
 		}
 	}
 
-	auth := &logical.Auth{
-		Metadata: map[string]string{
-			"username": username,
-		},
-		DisplayName: username,
-		Alias: &logical.Alias{
-			Name: username,
-		},
-	}
-	user.PopulateTokenAuth(auth)
-
-	return &logical.Response{
-		Auth: auth,
-	}, nil
+	return user, nil, nil
 }
 
 func (b *backend) pathLoginRenew(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
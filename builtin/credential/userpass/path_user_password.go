@@ -81,9 +81,9 @@ func (b *backend) pathUserPasswordUpdate(ctx context.Context, req *logical.Reque
 		return nil, fmt.Errorf("username does not exist")
 	}
 
-	userErr, intErr := b.updateUserPassword(req, d, userEntry)
+	userErr, intErr := b.updateUserPassword(ctx, req, d, userEntry)
 	if intErr != nil {
-		return nil, err
+		return nil, intErr
 	}
 	if userErr != nil {
 		return logical.ErrorResponse(userErr.Error()), logical.ErrInvalidRequest
@@ -92,12 +92,16 @@ func (b *backend) pathUserPasswordUpdate(ctx context.Context, req *logical.Reque
 	return nil, b.setUser(ctx, req.Storage, username, userEntry)
 }
 
-func (b *backend) updateUserPassword(_ *logical.Request, d *framework.FieldData, userEntry *UserEntry) (error, error) {
+func (b *backend) updateUserPassword(ctx context.Context, req *logical.Request, d *framework.FieldData, userEntry *UserEntry) (error, error) {
 	password := d.Get(paramPassword).(string)
 	passwordHash := d.Get(paramPasswordHash).(string)
 
+	config, err := b.config(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
 	var hash []byte
-	var err error
 
 	switch {
 	case password != "" && passwordHash != "":
@@ -105,8 +109,15 @@ func (b *backend) updateUserPassword(_ *logical.Request, d *framework.FieldData,
 	case password == "" && passwordHash == "":
 		return fmt.Errorf("%q or %q must be supplied", paramPassword, paramPasswordHash), nil
 	case password != "":
+		if userErr, intErr := b.checkPassword(ctx, config, userEntry, password); userErr != nil || intErr != nil {
+			return userErr, intErr
+		}
 		hash, err = bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	case passwordHash != "":
+		// A pre-hashed password can't be checked against the policy
+		if config.PasswordPolicy != "" {
+			return fmt.Errorf("%q cannot be supplied while a password policy is configured", paramPasswordHash), nil
+		}
 		hash, err = parsePasswordHash(passwordHash)
 	}
 
@@ -114,7 +125,27 @@ func (b *backend) updateUserPassword(_ *logical.Request, d *framework.FieldData,
 		return nil, err
 	}
 
-	userEntry.PasswordHash = hash
+	userEntry.setPasswordHash(hash, config.PasswordHistory)
+
+	return nil, nil
+}
+
+// checkPassword checks a new password against the password policy and the
+// password history of the user.
+func (b *backend) checkPassword(ctx context.Context, config *userpassConfig, userEntry *UserEntry, password string) (error, error) {
+	if config.PasswordPolicy != "" {
+		validator, ok := b.System().(logical.PasswordPolicyValidator)
+		if !ok {
+			return nil, fmt.Errorf("password policies are not supported when running as an external plugin")
+		}
+		if err := validator.ValidatePasswordWithPolicy(ctx, config.PasswordPolicy, password); err != nil {
+			return fmt.Errorf("password does not satisfy the password policy %q: %w", config.PasswordPolicy, err), nil
+		}
+	}
+
+	if userEntry.passwordReused(password, config.PasswordHistory) {
+		return fmt.Errorf("password was used recently and cannot be reused"), nil
+	}
 
 	return nil, nil
 }
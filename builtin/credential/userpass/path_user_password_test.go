@@ -4,8 +4,13 @@
 package userpass
 
 import (
+	"context"
+	"fmt"
 	"testing"
+	"time"
 
+	"github.com/hashicorp/vault/helper/random"
+	"github.com/hashicorp/vault/sdk/logical"
 	"github.com/stretchr/testify/require"
 	"golang.org/x/crypto/bcrypt"
 )
@@ -90,3 +95,202 @@ func TestUserPass_BcryptHashLength(t *testing.T) {
 		require.Len(t, hash, bcryptHashLength)
 	}
 }
+
+// passwordPolicySystemView checks passwords against in-memory password
+// policies, like the system view of a builtin mount does.
+type passwordPolicySystemView struct {
+	*logical.StaticSystemView
+	policies map[string]string
+}
+
+func (s *passwordPolicySystemView) ValidatePasswordWithPolicy(_ context.Context, policyName, password string) error {
+	raw, ok := s.policies[policyName]
+	if !ok {
+		return fmt.Errorf("no password policy found")
+	}
+	policy, err := random.ParsePolicy(raw)
+	if err != nil {
+		return err
+	}
+	return policy.Validate(password)
+}
+
+func createBackendWithPasswordPolicies(t *testing.T) (*backend, logical.Storage) {
+	t.Helper()
+
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+	config.System = &passwordPolicySystemView{
+		StaticSystemView: config.System.(*logical.StaticSystemView),
+		policies: map[string]string{
+			"strong": `
+length = 12
+rule "charset" {
+  charset = "0123456789"
+  min-chars = 1
+}`,
+		},
+	}
+
+	b, err := Factory(context.Background(), config)
+	require.NoError(t, err)
+	return b.(*backend), config.StorageView
+}
+
+func testHandleRequest(b *backend, s logical.Storage, op logical.Operation, path string, data map[string]interface{}) (*logical.Response, error) {
+	return b.HandleRequest(context.Background(), &logical.Request{
+		Operation:  op,
+		Path:       path,
+		Storage:    s,
+		Data:       data,
+		Connection: &logical.Connection{RemoteAddr: "127.0.0.1"},
+	})
+}
+
+// TestUserPass_PasswordPolicy ensures that passwords set for users must
+// satisfy the configured password policy.
+func TestUserPass_PasswordPolicy(t *testing.T) {
+	b, s := createBackendWithPasswordPolicies(t)
+
+	resp, err := testHandleRequest(b, s, logical.UpdateOperation, "config", map[string]interface{}{
+		"password_policy": "strong",
+	})
+	require.NoError(t, err)
+	require.Nil(t, resp)
+
+	resp, err = testHandleRequest(b, s, logical.CreateOperation, "users/alice", map[string]interface{}{
+		"password": "short1",
+	})
+	require.ErrorIs(t, err, logical.ErrInvalidRequest)
+	require.EqualError(t, resp.Error(), `password does not satisfy the password policy "strong": must be at least 12 characters long`)
+
+	resp, err = testHandleRequest(b, s, logical.CreateOperation, "users/alice", map[string]interface{}{
+		"password": "long enough password 1",
+	})
+	require.NoError(t, err)
+	require.Nil(t, resp)
+
+	resp, err = testHandleRequest(b, s, logical.UpdateOperation, "users/alice/password", map[string]interface{}{
+		"password": "long enough but no digits",
+	})
+	require.ErrorIs(t, err, logical.ErrInvalidRequest)
+	require.Contains(t, resp.Error().Error(), `must contain at least 1 of the characters "0123456789"`)
+
+	// Pre-hashed passwords can't be checked against the policy
+	hash, err := bcrypt.GenerateFromPassword([]byte("weak"), bcrypt.MinCost)
+	require.NoError(t, err)
+	resp, err = testHandleRequest(b, s, logical.UpdateOperation, "users/alice/password", map[string]interface{}{
+		"password_hash": string(hash),
+	})
+	require.ErrorIs(t, err, logical.ErrInvalidRequest)
+	require.EqualError(t, resp.Error(), `"password_hash" cannot be supplied while a password policy is configured`)
+
+	resp, err = testHandleRequest(b, s, logical.UpdateOperation, "config", map[string]interface{}{
+		"password_policy": "missing",
+	})
+	require.NoError(t, err)
+	resp, err = testHandleRequest(b, s, logical.UpdateOperation, "users/alice/password", map[string]interface{}{
+		"password": "long enough password 2",
+	})
+	require.ErrorIs(t, err, logical.ErrInvalidRequest)
+	require.Contains(t, resp.Error().Error(), "no password policy found")
+}
+
+// TestUserPass_PasswordHistory ensures that the most recent passwords of a
+// user cannot be reused.
+func TestUserPass_PasswordHistory(t *testing.T) {
+	b, s := createBackendWithPasswordPolicies(t)
+
+	resp, err := testHandleRequest(b, s, logical.UpdateOperation, "config", map[string]interface{}{
+		"password_history": 3,
+	})
+	require.NoError(t, err)
+	require.Nil(t, resp)
+
+	setPassword := func(password string) *logical.Response {
+		t.Helper()
+		resp, _ := testHandleRequest(b, s, logical.UpdateOperation, "users/alice/password", map[string]interface{}{
+			"password": password,
+		})
+		return resp
+	}
+
+	resp, err = testHandleRequest(b, s, logical.CreateOperation, "users/alice", map[string]interface{}{
+		"password": "first",
+	})
+	require.NoError(t, err)
+	require.Nil(t, resp)
+
+	require.Nil(t, setPassword("second"))
+	require.Nil(t, setPassword("third"))
+	for _, reused := range []string{"first", "second", "third"} {
+		resp := setPassword(reused)
+		require.NotNil(t, resp)
+		require.EqualError(t, resp.Error(), "password was used recently and cannot be reused")
+	}
+
+	// "first" drops out of the history once a fourth password is set
+	require.Nil(t, setPassword("fourth"))
+	require.Nil(t, setPassword("first"))
+
+	user, err := b.user(context.Background(), s, "alice")
+	require.NoError(t, err)
+	require.Len(t, user.PasswordHistory, 2)
+
+	// Login keeps working with the current password
+	resp, err = testHandleRequest(b, s, logical.UpdateOperation, "login/alice", map[string]interface{}{
+		"password": "first",
+	})
+	require.NoError(t, err)
+	require.NotNil(t, resp.Auth)
+}
+
+// TestUserPass_PasswordMaxAge ensures that users can't log in with expired
+// passwords until they are changed.
+func TestUserPass_PasswordMaxAge(t *testing.T) {
+	b, s := createBackendWithPasswordPolicies(t)
+	ctx := context.Background()
+
+	resp, err := testHandleRequest(b, s, logical.UpdateOperation, "config", map[string]interface{}{
+		"password_max_age": "24h",
+	})
+	require.NoError(t, err)
+	require.Nil(t, resp)
+
+	resp, err = testHandleRequest(b, s, logical.CreateOperation, "users/alice", map[string]interface{}{
+		"password": "secret",
+	})
+	require.NoError(t, err)
+	require.Nil(t, resp)
+
+	login := func() *logical.Response {
+		t.Helper()
+		resp, err := testHandleRequest(b, s, logical.UpdateOperation, "login/alice", map[string]interface{}{
+			"password": "secret",
+		})
+		require.NoError(t, err)
+		return resp
+	}
+	require.NotNil(t, login().Auth)
+
+	user, err := b.user(ctx, s, "alice")
+	require.NoError(t, err)
+	user.PasswordLastUpdated = time.Now().Add(-25 * time.Hour)
+	require.NoError(t, b.setUser(ctx, s, "alice", user))
+
+	resp = login()
+	require.Nil(t, resp.Auth)
+	require.EqualError(t, resp.Error(), "password has expired and must be changed")
+
+	// Resetting the password restarts the clock
+	resp, err = testHandleRequest(b, s, logical.UpdateOperation, "users/alice/password", map[string]interface{}{
+		"password": "secret",
+	})
+	require.NoError(t, err)
+	require.Nil(t, resp)
+	require.NotNil(t, login().Auth)
+
+	resp, err = testHandleRequest(b, s, logical.ReadOperation, "users/alice", nil)
+	require.NoError(t, err)
+	require.NotEmpty(t, resp.Data["password_last_updated"])
+}
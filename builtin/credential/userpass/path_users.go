@@ -5,6 +5,7 @@ package userpass
 
 import (
 	"context"
+	"crypto/subtle"
 	"fmt"
 	"strings"
 	"time"
@@ -13,6 +14,7 @@ import (
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/helper/tokenutil"
 	"github.com/hashicorp/vault/sdk/logical"
+	"golang.org/x/crypto/bcrypt"
 )
 
 func pathUsersList(b *backend) *framework.Path {
@@ -204,6 +206,9 @@ func (b *backend) pathUserRead(ctx context.Context, req *logical.Request, d *fra
 	if len(user.BoundCIDRs) > 0 {
 		data["bound_cidrs"] = user.BoundCIDRs
 	}
+	if !user.PasswordLastUpdated.IsZero() {
+		data["password_last_updated"] = user.PasswordLastUpdated.Format(time.RFC3339)
+	}
 
 	return &logical.Response{
 		Data: data,
@@ -226,7 +231,7 @@ func (b *backend) userCreateUpdate(ctx context.Context, req *logical.Request, d
 	}
 
 	if d.Get(paramPassword).(string) != "" || d.Get(paramPasswordHash).(string) != "" {
-		userErr, intErr := b.updateUserPassword(req, d, userEntry)
+		userErr, intErr := b.updateUserPassword(ctx, req, d, userEntry)
 		if intErr != nil {
 			return nil, intErr
 		}
@@ -283,6 +288,14 @@ type UserEntry struct {
 	// used instead of the actual password in Vault 0.2+.
 	PasswordHash []byte
 
+	// PasswordHistory holds the bcrypt hashes of previous passwords,
+	// most recent first, which cannot be reused.
+	PasswordHistory [][]byte
+
+	// PasswordLastUpdated is when the password was last set. It is
+	// zero for passwords set before it was tracked, which don't expire.
+	PasswordLastUpdated time.Time
+
 	Policies []string
 
 	// Duration after which the user will be revoked unless renewed
@@ -294,6 +307,53 @@ type UserEntry struct {
 	BoundCIDRs []*sockaddr.SockAddrMarshaler
 }
 
+// setPasswordHash replaces the password of the user, remembering the
+// current one if previous passwords are kept.
+func (u *UserEntry) setPasswordHash(hash []byte, history int) {
+	if len(u.PasswordHash) > 0 {
+		u.PasswordHistory = append([][]byte{u.PasswordHash}, u.PasswordHistory...)
+	}
+	// The history includes the current password
+	keep := history - 1
+	if keep < 0 {
+		keep = 0
+	}
+	if len(u.PasswordHistory) > keep {
+		u.PasswordHistory = u.PasswordHistory[:keep]
+	}
+	if len(u.PasswordHistory) == 0 {
+		u.PasswordHistory = nil
+	}
+
+	u.PasswordHash = hash
+	u.PasswordLastUpdated = time.Now()
+}
+
+// passwordReused reports whether the password is one of the given number
+// of most recent passwords of the user.
+func (u *UserEntry) passwordReused(password string, history int) bool {
+	if history <= 0 {
+		return false
+	}
+
+	hashes := append([][]byte{u.PasswordHash}, u.PasswordHistory...)
+	if len(hashes) > history {
+		hashes = hashes[:history]
+	}
+	for _, hash := range hashes {
+		if len(hash) > 0 && bcrypt.CompareHashAndPassword(hash, []byte(password)) == nil {
+			return true
+		}
+	}
+	return len(u.PasswordHash) == 0 && u.Password != "" && subtle.ConstantTimeCompare([]byte(u.Password), []byte(password)) == 1
+}
+
+// passwordExpired reports whether the password is older than the maximum
+// age.
+func (u *UserEntry) passwordExpired(maxAge time.Duration) bool {
+	return maxAge > 0 && !u.PasswordLastUpdated.IsZero() && time.Since(u.PasswordLastUpdated) > maxAge
+}
+
 const pathUserHelpSyn = `
 Manage users allowed to authenticate.
 `
@@ -0,0 +1,79 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package webauthn
+
+import (
+	"context"
+	"sync"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const operationPrefixWebauthn = "webauthn"
+
+func Factory(ctx context.Context, conf *logical.BackendConfig) (logical.Backend, error) {
+	b := Backend()
+	if err := b.Setup(ctx, conf); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func Backend() *backend {
+	b := backend{
+		userLocks: locksutil.CreateLocks(),
+	}
+	b.Backend = &framework.Backend{
+		Help: backendHelp,
+
+		PathsSpecial: &logical.Paths{
+			Unauthenticated: []string{
+				"login",
+				"login/challenge",
+			},
+		},
+
+		Paths: []*framework.Path{
+			pathConfig(&b),
+			pathUsersList(&b),
+			pathUsers(&b),
+			pathCredentialsList(&b),
+			pathCredentials(&b),
+			pathRegisterChallenge(&b),
+			pathRegister(&b),
+			pathLoginChallenge(&b),
+			pathLogin(&b),
+		},
+
+		PeriodicFunc: b.tidyChallenges,
+		AuthRenew:    b.pathLoginRenew,
+		BackendType:  logical.TypeCredential,
+	}
+
+	return &b
+}
+
+type backend struct {
+	*framework.Backend
+
+	// userLocks serialize updates to a user, such as registering a
+	// credential or recording the signature counter after a login.
+	userLocks []*locksutil.LockEntry
+
+	// challengeLock serializes recording the challenges consumed by a
+	// ceremony, which ensures a challenge is only ever consumed once.
+	challengeLock sync.Mutex
+}
+
+const backendHelp = `
+The "webauthn" credential provider allows passwordless authentication with
+FIDO2 passkeys using the WebAuthn protocol.
+
+Users are created using the "users/" endpoints, and register one or more
+credentials with their authenticators through the "users/<name>/register"
+endpoints. Authentication is then done by requesting a challenge from
+"login/challenge" and sending the authenticator's assertion to "login".
+`
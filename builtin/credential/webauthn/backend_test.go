@@ -0,0 +1,617 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package webauthn
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	testRPID   = "vault.example.com"
+	testOrigin = "https://vault.example.com"
+)
+
+var testAAGUID = []byte("vault-test-authn")
+
+// testAuthenticator is a software stand-in for a FIDO2 authenticator
+// holding a single ES256 credential. Its attestation statements are signed
+// with the attestation key if it has an attestation certificate, and are
+// self attestations otherwise.
+type testAuthenticator struct {
+	t               *testing.T
+	key             *ecdsa.PrivateKey
+	id              []byte
+	signCount       uint32
+	flags           byte
+	origin          string
+	attestationKey  *ecdsa.PrivateKey
+	attestationCert []byte
+}
+
+func newTestAuthenticator(t *testing.T) *testAuthenticator {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		t.Fatal(err)
+	}
+	return &testAuthenticator{
+		t:      t,
+		key:    key,
+		id:     id,
+		flags:  flagUserPresent | flagUserVerified,
+		origin: testOrigin,
+	}
+}
+
+func (a *testAuthenticator) coseKey() []byte {
+	a.t.Helper()
+	x := make([]byte, 32)
+	y := make([]byte, 32)
+	a.key.X.FillBytes(x)
+	a.key.Y.FillBytes(y)
+	encoded, err := cbor.Marshal(map[int]interface{}{
+		coseKeyType:      coseKeyTypeEC2,
+		coseKeyAlgorithm: coseAlgES256,
+		coseKeyCurve:     coseCurveP256,
+		coseKeyX:         x,
+		coseKeyY:         y,
+	})
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	return encoded
+}
+
+func (a *testAuthenticator) authData(attested bool) []byte {
+	rpIDHash := sha256.Sum256([]byte(testRPID))
+	data := append([]byte{}, rpIDHash[:]...)
+	flags := a.flags
+	if attested {
+		flags |= flagAttestedCredential
+	}
+	data = append(data, flags)
+	data = binary.BigEndian.AppendUint32(data, a.signCount)
+	if attested {
+		data = append(data, testAAGUID...)
+		data = binary.BigEndian.AppendUint16(data, uint16(len(a.id)))
+		data = append(data, a.id...)
+		data = append(data, a.coseKey()...)
+	}
+	return data
+}
+
+func (a *testAuthenticator) clientData(typ, challenge string) []byte {
+	a.t.Helper()
+	encoded, err := json.Marshal(map[string]interface{}{
+		"type":      typ,
+		"challenge": challenge,
+		"origin":    a.origin,
+	})
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	return encoded
+}
+
+func (a *testAuthenticator) sign(authData, clientDataJSON []byte) []byte {
+	a.t.Helper()
+	return a.signWith(a.key, authData, clientDataJSON)
+}
+
+func (a *testAuthenticator) signWith(key *ecdsa.PrivateKey, authData, clientDataJSON []byte) []byte {
+	a.t.Helper()
+	digest := sha256.Sum256(signedData(authData, clientDataJSON))
+	sig, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	return sig
+}
+
+// testAttestationCA creates a CA and issues an attestation certificate for
+// the authenticator's model, returning the PEM encoded CA certificate.
+func testAttestationCA(t *testing.T, a *testAuthenticator) string {
+	t.Helper()
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test Attestation CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a.attestationKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	aaguid, err := asn1.Marshal(testAAGUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.attestationCert, err = x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "Test Authenticator"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtraExtensions: []pkix.Extension{
+			{Id: oidFIDOGenCEAAGUID, Value: aaguid},
+		},
+	}, ca, &a.attestationKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}))
+}
+
+// create returns the registration data for the challenge, with an
+// attestation statement of the given format.
+func (a *testAuthenticator) create(challenge, format string) map[string]interface{} {
+	a.t.Helper()
+	clientDataJSON := a.clientData(clientDataTypeCreate, challenge)
+	authData := a.authData(true)
+
+	attStmt := map[string]interface{}{}
+	if format == attestationFormatPacked && a.attestationCert != nil {
+		attStmt = map[string]interface{}{
+			"alg": coseAlgES256,
+			"sig": a.signWith(a.attestationKey, authData, clientDataJSON),
+			"x5c": [][]byte{a.attestationCert},
+		}
+	} else if format == attestationFormatPacked {
+		attStmt = map[string]interface{}{
+			"alg": coseAlgES256,
+			"sig": a.sign(authData, clientDataJSON),
+		}
+	}
+	obj, err := cbor.Marshal(map[string]interface{}{
+		"fmt":      format,
+		"attStmt":  attStmt,
+		"authData": authData,
+	})
+	if err != nil {
+		a.t.Fatal(err)
+	}
+
+	return map[string]interface{}{
+		"client_data_json":   encodeBase64(clientDataJSON),
+		"attestation_object": encodeBase64(obj),
+	}
+}
+
+// get returns the login data for the challenge.
+func (a *testAuthenticator) get(username, challenge string) map[string]interface{} {
+	a.t.Helper()
+	a.signCount++
+	clientDataJSON := a.clientData(clientDataTypeGet, challenge)
+	authData := a.authData(false)
+	return map[string]interface{}{
+		"username":           username,
+		"credential_id":      encodeBase64(a.id),
+		"client_data_json":   encodeBase64(clientDataJSON),
+		"authenticator_data": encodeBase64(authData),
+		"signature":          encodeBase64(a.sign(authData, clientDataJSON)),
+	}
+}
+
+func createBackendWithStorage(t *testing.T) (*backend, logical.Storage) {
+	t.Helper()
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+
+	b := Backend()
+	if err := b.Setup(context.Background(), config); err != nil {
+		t.Fatal(err)
+	}
+	return b, config.StorageView
+}
+
+func testRequest(t *testing.T, b *backend, s logical.Storage, op logical.Operation, path string, data map[string]interface{}) *logical.Response {
+	t.Helper()
+	resp, err := testRequestErr(b, s, op, path, data)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("%s %s: resp: %#v, err: %v", op, path, resp, err)
+	}
+	return resp
+}
+
+func testRequestErr(b *backend, s logical.Storage, op logical.Operation, path string, data map[string]interface{}) (*logical.Response, error) {
+	return b.HandleRequest(context.Background(), &logical.Request{
+		Operation:  op,
+		Path:       path,
+		Storage:    s,
+		Data:       data,
+		Connection: &logical.Connection{RemoteAddr: "127.0.0.1"},
+	})
+}
+
+func testConfigure(t *testing.T, b *backend, s logical.Storage, data map[string]interface{}) {
+	t.Helper()
+	config := map[string]interface{}{
+		"rp_id":           testRPID,
+		"allowed_origins": testOrigin,
+	}
+	for k, v := range data {
+		config[k] = v
+	}
+	testRequest(t, b, s, logical.UpdateOperation, "config", config)
+}
+
+// testRegister registers the authenticator's credential for the user.
+func testRegister(t *testing.T, b *backend, s logical.Storage, a *testAuthenticator, username, format string) (*logical.Response, error) {
+	t.Helper()
+	resp := testRequest(t, b, s, logical.UpdateOperation, "users/"+username+"/register/challenge", nil)
+	challenge := resp.Data["challenge"].(string)
+	data := a.create(challenge, format)
+	data["name"] = "test key"
+	return testRequestErr(b, s, logical.UpdateOperation, "users/"+username+"/register", data)
+}
+
+// testLogin logs in with the authenticator's credential.
+func testLogin(t *testing.T, b *backend, s logical.Storage, a *testAuthenticator, username string) (*logical.Response, error) {
+	t.Helper()
+	resp := testRequest(t, b, s, logical.UpdateOperation, "login/challenge", map[string]interface{}{
+		"username": username,
+	})
+	return testRequestErr(b, s, logical.UpdateOperation, "login", a.get(username, resp.Data["challenge"].(string)))
+}
+
+func TestBackend_RegisterAndLogin(t *testing.T) {
+	b, s := createBackendWithStorage(t)
+	testConfigure(t, b, s, nil)
+	testRequest(t, b, s, logical.CreateOperation, "users/alice", map[string]interface{}{
+		"token_policies": "dev",
+		"token_ttl":      "1h",
+	})
+
+	resp := testRequest(t, b, s, logical.UpdateOperation, "users/alice/register/challenge", nil)
+	publicKey := resp.Data["public_key"].(map[string]interface{})
+	if publicKey["challenge"] != resp.Data["challenge"] {
+		t.Fatalf("expected the creation options to carry the challenge, got %#v", publicKey)
+	}
+	if rp := publicKey["rp"].(map[string]interface{}); rp["id"] != testRPID || rp["name"] != "Vault" {
+		t.Fatalf("unexpected relying party %#v", rp)
+	}
+
+	a := newTestAuthenticator(t)
+	data := a.create(resp.Data["challenge"].(string), attestationFormatNone)
+	data["name"] = "laptop"
+	resp = testRequest(t, b, s, logical.UpdateOperation, "users/alice/register", data)
+	credentialID := resp.Data["credential_id"].(string)
+	if credentialID != encodeBase64(a.id) {
+		t.Fatalf("expected credential ID %q, got %q", encodeBase64(a.id), credentialID)
+	}
+
+	// The challenge can only be used once
+	if resp, err := testRequestErr(b, s, logical.UpdateOperation, "users/alice/register", data); err != nil || !resp.IsError() {
+		t.Fatalf("expected a reused challenge to be rejected, got resp: %#v, err: %v", resp, err)
+	}
+
+	resp = testRequest(t, b, s, logical.ReadOperation, "users/alice/credentials/"+credentialID, nil)
+	if resp.Data["name"] != "laptop" || resp.Data["aaguid"] != "7661756c742d746573742d617574686e" || resp.Data["algorithm"] != int64(coseAlgES256) {
+		t.Fatalf("unexpected credential %#v", resp.Data)
+	}
+
+	// The challenge doesn't tell whether the user exists
+	unknown := testRequest(t, b, s, logical.UpdateOperation, "login/challenge", map[string]interface{}{
+		"username": "carol",
+	}).Data["public_key"].(map[string]interface{})
+	resp = testRequest(t, b, s, logical.UpdateOperation, "login/challenge", map[string]interface{}{
+		"username": "Alice",
+	})
+	known := resp.Data["public_key"].(map[string]interface{})
+	delete(unknown, "challenge")
+	delete(known, "challenge")
+	if !reflect.DeepEqual(known, unknown) {
+		t.Fatalf("expected the same options for unknown users, got %#v and %#v", known, unknown)
+	}
+	loginData := a.get("alice", resp.Data["challenge"].(string))
+	resp = testRequest(t, b, s, logical.UpdateOperation, "login", loginData)
+	if resp.Auth == nil {
+		t.Fatal("expected auth")
+	}
+	if resp.Auth.Metadata["credential_id"] != credentialID || resp.Auth.Metadata["credential_name"] != "laptop" {
+		t.Fatalf("unexpected metadata %#v", resp.Auth.Metadata)
+	}
+	if resp.Auth.Alias.Name != "alice" || resp.Auth.TTL != time.Hour || len(resp.Auth.Policies) != 1 || resp.Auth.Policies[0] != "dev" {
+		t.Fatalf("unexpected auth %#v", resp.Auth)
+	}
+
+	// Replaying the assertion fails, its challenge has been used
+	if resp, err := testRequestErr(b, s, logical.UpdateOperation, "login", loginData); err != nil || !resp.IsError() {
+		t.Fatalf("expected a replayed assertion to be rejected, got resp: %#v, err: %v", resp, err)
+	}
+
+	resp = testRequest(t, b, s, logical.ReadOperation, "users/alice/credentials/"+credentialID, nil)
+	if resp.Data["sign_count"] != uint32(1) || resp.Data["last_used_time"] == nil {
+		t.Fatalf("expected the login to be recorded, got %#v", resp.Data)
+	}
+}
+
+func TestBackend_LoginRejected(t *testing.T) {
+	b, s := createBackendWithStorage(t)
+	testConfigure(t, b, s, nil)
+	testRequest(t, b, s, logical.CreateOperation, "users/alice", nil)
+	testRequest(t, b, s, logical.CreateOperation, "users/bob", nil)
+
+	a := newTestAuthenticator(t)
+	testRequest(t, b, s, logical.UpdateOperation, "users/alice/register", func() map[string]interface{} {
+		resp := testRequest(t, b, s, logical.UpdateOperation, "users/alice/register/challenge", nil)
+		return a.create(resp.Data["challenge"].(string), attestationFormatNone)
+	}())
+
+	t.Run("other user", func(t *testing.T) {
+		if resp, err := testLogin(t, b, s, a, "bob"); err != logical.ErrInvalidCredentials || resp.Auth != nil {
+			t.Fatalf("expected invalid credentials, got resp: %#v, err: %v", resp, err)
+		}
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		if resp, err := testLogin(t, b, s, a, "carol"); err != logical.ErrInvalidCredentials || resp.Auth != nil {
+			t.Fatalf("expected invalid credentials, got resp: %#v, err: %v", resp, err)
+		}
+	})
+
+	t.Run("challenge for another user", func(t *testing.T) {
+		resp := testRequest(t, b, s, logical.UpdateOperation, "login/challenge", map[string]interface{}{
+			"username": "bob",
+		})
+		resp, err := testRequestErr(b, s, logical.UpdateOperation, "login", a.get("alice", resp.Data["challenge"].(string)))
+		if err != nil || !resp.IsError() || !strings.Contains(resp.Error().Error(), "invalid or expired challenge") {
+			t.Fatalf("expected an invalid challenge, got resp: %#v, err: %v", resp, err)
+		}
+	})
+
+	t.Run("wrong origin", func(t *testing.T) {
+		a.origin = "https://evil.example.com"
+		defer func() { a.origin = testOrigin }()
+		resp, err := testLogin(t, b, s, a, "alice")
+		if err != nil || !resp.IsError() || !strings.Contains(resp.Error().Error(), "is not allowed") {
+			t.Fatalf("expected the origin to be rejected, got resp: %#v, err: %v", resp, err)
+		}
+	})
+
+	t.Run("bad signature", func(t *testing.T) {
+		resp := testRequest(t, b, s, logical.UpdateOperation, "login/challenge", map[string]interface{}{
+			"username": "alice",
+		})
+		data := a.get("alice", resp.Data["challenge"].(string))
+		data["signature"] = encodeBase64([]byte("not a signature"))
+		if resp, err := testRequestErr(b, s, logical.UpdateOperation, "login", data); err != logical.ErrInvalidCredentials || resp.Auth != nil {
+			t.Fatalf("expected invalid credentials, got resp: %#v, err: %v", resp, err)
+		}
+	})
+
+	t.Run("sign count regression", func(t *testing.T) {
+		if _, err := testLogin(t, b, s, a, "alice"); err != nil {
+			t.Fatal(err)
+		}
+		// A clone of the authenticator lags behind the original's counter
+		a.signCount -= 2
+		if resp, err := testLogin(t, b, s, a, "alice"); err != logical.ErrInvalidCredentials || resp.Auth != nil {
+			t.Fatalf("expected invalid credentials, got resp: %#v, err: %v", resp, err)
+		}
+	})
+
+	t.Run("user verification required", func(t *testing.T) {
+		testConfigure(t, b, s, map[string]interface{}{"user_verification": userVerificationRequired})
+		defer testConfigure(t, b, s, map[string]interface{}{"user_verification": userVerificationPreferred})
+
+		a.flags = flagUserPresent
+		defer func() { a.flags = flagUserPresent | flagUserVerified }()
+		a.signCount += 10
+		if resp, err := testLogin(t, b, s, a, "alice"); err != logical.ErrInvalidCredentials || resp.Auth != nil {
+			t.Fatalf("expected invalid credentials, got resp: %#v, err: %v", resp, err)
+		}
+	})
+}
+
+func TestBackend_AttestationPolicy(t *testing.T) {
+	b, s := createBackendWithStorage(t)
+	testConfigure(t, b, s, nil)
+	testRequest(t, b, s, logical.CreateOperation, "users/alice", nil)
+
+	a := newTestAuthenticator(t)
+	caPEM := testAttestationCA(t, a)
+
+	// The attestation can only be trusted with CAs to verify it against
+	for _, data := range []map[string]interface{}{
+		{"attestation": attestationDirect},
+		{"allowed_aaguids": "7661756c-742d-7465-7374-2d617574686e"},
+	} {
+		data["rp_id"], data["allowed_origins"] = testRPID, testOrigin
+		if resp, err := testRequestErr(b, s, logical.UpdateOperation, "config", data); err != nil || !resp.IsError() {
+			t.Fatalf("expected the config %v to be rejected, got resp: %#v, err: %v", data, resp, err)
+		}
+	}
+
+	testConfigure(t, b, s, map[string]interface{}{
+		"attestation":          attestationDirect,
+		"attestation_ca_certs": caPEM,
+	})
+
+	resp, err := testRegister(t, b, s, a, "alice", attestationFormatNone)
+	if err != nil || !resp.IsError() || !strings.Contains(resp.Error().Error(), "unsupported attestation format") {
+		t.Fatalf("expected the attestation to be rejected, got resp: %#v, err: %v", resp, err)
+	}
+
+	self := newTestAuthenticator(t)
+	resp, err = testRegister(t, b, s, self, "alice", attestationFormatPacked)
+	if err != nil || !resp.IsError() || !strings.Contains(resp.Error().Error(), "self attestation is not allowed") {
+		t.Fatalf("expected the self attestation to be rejected, got resp: %#v, err: %v", resp, err)
+	}
+
+	untrusted := newTestAuthenticator(t)
+	testAttestationCA(t, untrusted)
+	resp, err = testRegister(t, b, s, untrusted, "alice", attestationFormatPacked)
+	if err != nil || !resp.IsError() || !strings.Contains(resp.Error().Error(), "is not trusted") {
+		t.Fatalf("expected the attestation to be untrusted, got resp: %#v, err: %v", resp, err)
+	}
+
+	resp, err = testRegister(t, b, s, a, "alice", attestationFormatPacked)
+	if err != nil || resp.IsError() {
+		t.Fatalf("expected the attestation to be accepted, got resp: %#v, err: %v", resp, err)
+	}
+	resp = testRequest(t, b, s, logical.ReadOperation, "users/alice/credentials/"+encodeBase64(a.id), nil)
+	if resp.Data["attestation_format"] != attestationFormatPacked {
+		t.Fatalf("unexpected attestation format %#v", resp.Data["attestation_format"])
+	}
+
+	// Only the allowed models can register credentials
+	testConfigure(t, b, s, map[string]interface{}{"allowed_aaguids": "00000000-0000-0000-0000-000000000000"})
+	other := newTestAuthenticator(t)
+	other.attestationKey, other.attestationCert = a.attestationKey, a.attestationCert
+	resp, err = testRegister(t, b, s, other, "alice", attestationFormatPacked)
+	if err != nil || !resp.IsError() || !strings.Contains(resp.Error().Error(), "is not allowed") {
+		t.Fatalf("expected the model to be rejected, got resp: %#v, err: %v", resp, err)
+	}
+}
+
+func TestBackend_MultipleCredentials(t *testing.T) {
+	b, s := createBackendWithStorage(t)
+	testConfigure(t, b, s, nil)
+	testRequest(t, b, s, logical.CreateOperation, "users/alice", map[string]interface{}{
+		"token_policies": "dev",
+	})
+
+	laptop, phone := newTestAuthenticator(t), newTestAuthenticator(t)
+	for _, a := range []*testAuthenticator{laptop, phone} {
+		if resp, err := testRegister(t, b, s, a, "alice", attestationFormatNone); err != nil || resp.IsError() {
+			t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+		}
+	}
+	if resp, err := testRegister(t, b, s, phone, "alice", attestationFormatNone); err != nil || !resp.IsError() {
+		t.Fatalf("expected a duplicate credential to be rejected, got resp: %#v, err: %v", resp, err)
+	}
+
+	resp := testRequest(t, b, s, logical.ListOperation, "users/alice/credentials", nil)
+	if keys := resp.Data["keys"].([]string); len(keys) != 2 {
+		t.Fatalf("expected two credentials, got %v", keys)
+	}
+
+	var auth *logical.Auth
+	for _, a := range []*testAuthenticator{laptop, phone} {
+		resp, err := testLogin(t, b, s, a, "alice")
+		if err != nil || resp.Auth == nil {
+			t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+		}
+		auth = resp.Auth
+	}
+	// Core sets the token's policies when it creates the token
+	auth.TokenPolicies = auth.Policies
+
+	renew := func() (*logical.Response, error) {
+		return b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.RenewOperation,
+			Path:      "login",
+			Storage:   s,
+			Auth:      auth,
+		})
+	}
+	if resp, err := renew(); err != nil || resp.Auth == nil {
+		t.Fatalf("expected the token to be renewed, got resp: %#v, err: %v", resp, err)
+	}
+
+	// Deleting the phone's credential only locks the phone out
+	testRequest(t, b, s, logical.DeleteOperation, "users/alice/credentials/"+encodeBase64(phone.id), nil)
+	if resp, err := testLogin(t, b, s, phone, "alice"); err != logical.ErrInvalidCredentials || resp.Auth != nil {
+		t.Fatalf("expected invalid credentials, got resp: %#v, err: %v", resp, err)
+	}
+	if resp, err := testLogin(t, b, s, laptop, "alice"); err != nil || resp.Auth == nil {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	if _, err := renew(); err == nil {
+		t.Fatal("expected the renewal to fail after the credential was deleted")
+	}
+}
+
+func TestBackend_Challenges(t *testing.T) {
+	b, s := createBackendWithStorage(t)
+	testConfigure(t, b, s, map[string]interface{}{"challenge_ttl": 1})
+	testRequest(t, b, s, logical.CreateOperation, "users/alice", nil)
+	keys, err := s.List(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Issuing a challenge doesn't write to storage
+	challenge := testRequest(t, b, s, logical.UpdateOperation, "login/challenge", map[string]interface{}{
+		"username": "alice",
+	}).Data["challenge"].(string)
+	if after, _ := s.List(context.Background(), ""); !reflect.DeepEqual(keys, after) {
+		t.Fatalf("expected no new storage entries, got %v", after)
+	}
+
+	config, err := b.config(context.Background(), s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.consumeChallenge(context.Background(), s, config, challenge, ceremonyRegistration, "alice"); err != errInvalidChallenge {
+		t.Fatalf("expected the challenge to be invalid for another ceremony, got %v", err)
+	}
+	raw, _ := decodeBase64("challenge", challenge)
+	raw[0] ^= 1
+	if err := b.consumeChallenge(context.Background(), s, config, encodeBase64(raw), ceremonyLogin, "alice"); err != errInvalidChallenge {
+		t.Fatalf("expected a tampered challenge to be invalid, got %v", err)
+	}
+	if err := b.consumeChallenge(context.Background(), s, config, challenge, ceremonyLogin, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.consumeChallenge(context.Background(), s, config, challenge, ceremonyLogin, "alice"); err != errInvalidChallenge {
+		t.Fatalf("expected a used challenge to be invalid, got %v", err)
+	}
+
+	// A new backend on the same storage, as after a leader change, still
+	// rejects the used challenge
+	if err := Backend().consumeChallenge(context.Background(), s, config, challenge, ceremonyLogin, "alice"); err != errInvalidChallenge {
+		t.Fatalf("expected a used challenge to be invalid after a restart, got %v", err)
+	}
+
+	time.Sleep(1100 * time.Millisecond)
+	if err := b.tidyChallenges(context.Background(), &logical.Request{Storage: s}); err != nil {
+		t.Fatal(err)
+	}
+	if used, _ := s.List(context.Background(), usedChallengePrefix); len(used) != 0 {
+		t.Fatalf("expected the expired challenge to be deleted, got %v", used)
+	}
+	if err := b.consumeChallenge(context.Background(), s, config, challenge, ceremonyLogin, "alice"); err != errInvalidChallenge {
+		t.Fatalf("expected an expired challenge to be invalid, got %v", err)
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package webauthn
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/helper/consts"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	usedChallengePrefix = "used_challenge/"
+	challengeKeySize    = 32
+	challengeNonceSize  = 16
+
+	ceremonyRegistration = "registration"
+	ceremonyLogin        = "login"
+)
+
+// errInvalidChallenge is returned for challenges that are unknown, expired,
+// already used, or issued for another ceremony or user.
+var errInvalidChallenge = errors.New("invalid or expired challenge")
+
+// usedChallengeEntry records a challenge consumed by a ceremony until it
+// expires, after which it is rejected based on its expiration alone.
+type usedChallengeEntry struct {
+	Expiration time.Time `json:"expiration"`
+}
+
+// newChallengeKey generates the key challenges are signed with.
+func newChallengeKey() ([]byte, error) {
+	key := make([]byte, challengeKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate challenge key: %w", err)
+	}
+	return key, nil
+}
+
+// newChallenge issues a challenge for a ceremony of the user. Challenges
+// are not stored: they carry a random nonce and their expiration, signed
+// with the challenge key of the configuration, so issuing one doesn't
+// write to storage and works on any node.
+func (b *backend) newChallenge(config *webauthnConfig, ceremony, username string) (string, error) {
+	if len(config.ChallengeKey) == 0 {
+		return "", errors.New("missing challenge key, the config must be written again")
+	}
+
+	raw := make([]byte, challengeNonceSize, challengeNonceSize+8+sha256.Size)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate challenge: %w", err)
+	}
+	raw = binary.BigEndian.AppendUint64(raw, uint64(time.Now().Add(config.ChallengeTTL).UnixNano()))
+	raw = append(raw, challengeMAC(config.ChallengeKey, ceremony, username, raw)...)
+
+	return encodeBase64(raw), nil
+}
+
+// consumeChallenge checks that the challenge was issued for the ceremony
+// of the user and hasn't expired, and records it as used so it can only be
+// used once. Used challenges are stored under the digest of the challenge
+// so they are still rejected after a leader change.
+func (b *backend) consumeChallenge(ctx context.Context, s logical.Storage, config *webauthnConfig, challenge, ceremony, username string) error {
+	raw, err := decodeBase64("challenge", challenge)
+	if err != nil || len(raw) != challengeNonceSize+8+sha256.Size || len(config.ChallengeKey) == 0 {
+		return errInvalidChallenge
+	}
+	signed, mac := raw[:challengeNonceSize+8], raw[challengeNonceSize+8:]
+	if !hmac.Equal(mac, challengeMAC(config.ChallengeKey, ceremony, username, signed)) {
+		return errInvalidChallenge
+	}
+	expiration := time.Unix(0, int64(binary.BigEndian.Uint64(signed[challengeNonceSize:])))
+	if time.Now().After(expiration) {
+		return errInvalidChallenge
+	}
+
+	sum := sha256.Sum256(raw)
+	key := usedChallengePrefix + hex.EncodeToString(sum[:])
+
+	b.challengeLock.Lock()
+	defer b.challengeLock.Unlock()
+
+	used, err := s.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	if used != nil {
+		return errInvalidChallenge
+	}
+	entry, err := logical.StorageEntryJSON(key, &usedChallengeEntry{
+		Expiration: expiration,
+	})
+	if err != nil {
+		return err
+	}
+	return s.Put(ctx, entry)
+}
+
+// challengeMAC signs the nonce and expiration of a challenge for a
+// ceremony of the user.
+func challengeMAC(key []byte, ceremony, username string, signed []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(ceremony))
+	mac.Write([]byte{0})
+	mac.Write([]byte(username))
+	mac.Write([]byte{0})
+	mac.Write(signed)
+	return mac.Sum(nil)
+}
+
+// tidyChallenges deletes the used challenges that have expired, they are
+// rejected based on their expiration alone.
+func (b *backend) tidyChallenges(ctx context.Context, req *logical.Request) error {
+	// Used challenges are only written on the active node
+	if !b.System().LocalMount() && b.System().ReplicationState().HasState(consts.ReplicationPerformanceSecondary|consts.ReplicationPerformanceStandby) {
+		return nil
+	}
+
+	challenges, err := req.Storage.List(ctx, usedChallengePrefix)
+	if err != nil {
+		return err
+	}
+
+	b.challengeLock.Lock()
+	defer b.challengeLock.Unlock()
+
+	now := time.Now()
+	for _, challenge := range challenges {
+		raw, err := req.Storage.Get(ctx, usedChallengePrefix+challenge)
+		if err != nil {
+			return err
+		}
+		if raw == nil {
+			continue
+		}
+		var entry usedChallengeEntry
+		if err := raw.DecodeJSON(&entry); err != nil {
+			return err
+		}
+		if now.After(entry.Expiration) {
+			if err := req.Storage.Delete(ctx, usedChallengePrefix+challenge); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,331 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package webauthn
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/certutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	configPath = "config"
+
+	attestationNone   = "none"
+	attestationDirect = "direct"
+
+	userVerificationRequired    = "required"
+	userVerificationPreferred   = "preferred"
+	userVerificationDiscouraged = "discouraged"
+
+	defaultChallengeTTL = 5 * time.Minute
+)
+
+func pathConfig(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config",
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixWebauthn,
+			Action:          "Configure",
+		},
+
+		Fields: map[string]*framework.FieldSchema{
+			"rp_id": {
+				Type:        framework.TypeString,
+				Description: "Relying party ID, the domain passkeys are scoped to, e.g. vault.example.com.",
+				DisplayAttrs: &framework.DisplayAttributes{
+					Name: "Relying party ID",
+				},
+			},
+			"rp_name": {
+				Type:        framework.TypeString,
+				Default:     "Vault",
+				Description: "Relying party name shown by authenticators during registration.",
+				DisplayAttrs: &framework.DisplayAttributes{
+					Name:  "Relying party name",
+					Value: "Vault",
+				},
+			},
+			"allowed_origins": {
+				Type:        framework.TypeCommaStringSlice,
+				Description: "Origins WebAuthn ceremonies are allowed from, e.g. https://vault.example.com.",
+			},
+			"attestation": {
+				Type:          framework.TypeString,
+				Default:       attestationNone,
+				AllowedValues: []interface{}{attestationNone, attestationDirect},
+				Description: `Attestation policy for registered credentials. With "none", attestation ` +
+					`statements are not verified. With "direct", credentials must come with a valid ` +
+					`"packed" attestation signed by a certificate chaining to attestation_ca_certs.`,
+			},
+			"attestation_ca_certs": {
+				Type: framework.TypeString,
+				Description: `PEM encoded CA certificates attestation certificates must chain to. ` +
+					`Required by the "direct" attestation policy.`,
+				DisplayAttrs: &framework.DisplayAttributes{
+					Name:     "Attestation CA certificates",
+					EditType: "file",
+				},
+			},
+			"allowed_aaguids": {
+				Type:        framework.TypeCommaStringSlice,
+				Description: `AAGUIDs of the authenticator models allowed to register credentials. Requires the "direct" attestation policy. If empty, all models are allowed.`,
+				DisplayAttrs: &framework.DisplayAttributes{
+					Name: "Allowed AAGUIDs",
+				},
+			},
+			"user_verification": {
+				Type:          framework.TypeString,
+				Default:       userVerificationPreferred,
+				AllowedValues: []interface{}{userVerificationRequired, userVerificationPreferred, userVerificationDiscouraged},
+				Description:   `Whether authenticators must verify the user, e.g. with a PIN or biometrics. If "required", ceremonies without user verification are rejected.`,
+			},
+			"challenge_ttl": {
+				Type:        framework.TypeDurationSecond,
+				Default:     int(defaultChallengeTTL.Seconds()),
+				Description: "Duration a registration or login challenge is valid for.",
+				DisplayAttrs: &framework.DisplayAttributes{
+					Name:  "Challenge TTL",
+					Value: int(defaultChallengeTTL.Seconds()),
+				},
+			},
+		},
+
+		ExistenceCheck: b.pathConfigExistenceCheck,
+
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.pathConfigRead,
+				DisplayAttrs: &framework.DisplayAttributes{
+					OperationSuffix: "configuration",
+				},
+			},
+			logical.CreateOperation: &framework.PathOperation{
+				Callback: b.pathConfigWrite,
+				DisplayAttrs: &framework.DisplayAttributes{
+					OperationVerb: "configure",
+				},
+			},
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathConfigWrite,
+				DisplayAttrs: &framework.DisplayAttributes{
+					OperationVerb: "configure",
+				},
+			},
+		},
+
+		HelpSynopsis:    pathConfigHelpSyn,
+		HelpDescription: pathConfigHelpDesc,
+	}
+}
+
+func (b *backend) pathConfigExistenceCheck(ctx context.Context, req *logical.Request, d *framework.FieldData) (bool, error) {
+	config, err := b.config(ctx, req.Storage)
+	if err != nil {
+		return false, err
+	}
+	return config != nil, nil
+}
+
+func (b *backend) config(ctx context.Context, s logical.Storage) (*webauthnConfig, error) {
+	entry, err := s.Get(ctx, configPath)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var config webauthnConfig
+	if err := entry.DecodeJSON(&config); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+func (b *backend) pathConfigRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config, err := b.config(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"rp_id":                config.RPID,
+			"rp_name":              config.RPName,
+			"allowed_origins":      config.AllowedOrigins,
+			"attestation":          config.Attestation,
+			"attestation_ca_certs": config.AttestationCACerts,
+			"allowed_aaguids":      config.AllowedAAGUIDs,
+			"user_verification":    config.UserVerification,
+			"challenge_ttl":        int64(config.ChallengeTTL.Seconds()),
+		},
+	}, nil
+}
+
+func (b *backend) pathConfigWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config, err := b.config(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	isNew := config == nil
+	if isNew {
+		config = &webauthnConfig{}
+	}
+
+	if rpID, ok := d.GetOk("rp_id"); ok {
+		config.RPID = strings.ToLower(rpID.(string))
+	}
+	if config.RPID == "" {
+		return logical.ErrorResponse("missing rp_id"), nil
+	}
+
+	if rpName, ok := d.GetOk("rp_name"); ok {
+		config.RPName = rpName.(string)
+	} else if isNew {
+		config.RPName = d.Get("rp_name").(string)
+	}
+
+	if origins, ok := d.GetOk("allowed_origins"); ok {
+		config.AllowedOrigins = origins.([]string)
+	}
+	if len(config.AllowedOrigins) == 0 {
+		return logical.ErrorResponse("missing allowed_origins"), nil
+	}
+	for _, origin := range config.AllowedOrigins {
+		u, err := url.Parse(origin)
+		if err != nil || u.Scheme == "" || u.Host == "" || u.Path != "" {
+			return logical.ErrorResponse("invalid origin %q: must be a scheme and host, e.g. https://vault.example.com", origin), nil
+		}
+	}
+
+	if attestation, ok := d.GetOk("attestation"); ok {
+		config.Attestation = attestation.(string)
+	} else if isNew {
+		config.Attestation = d.Get("attestation").(string)
+	}
+
+	if caCerts, ok := d.GetOk("attestation_ca_certs"); ok {
+		config.AttestationCACerts = caCerts.(string)
+	}
+	if config.AttestationCACerts != "" {
+		if config.Attestation != attestationDirect {
+			return logical.ErrorResponse(`attestation_ca_certs requires the "direct" attestation policy`), nil
+		}
+		if _, err := config.attestationRoots(); err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+	}
+
+	if aaguids, ok := d.GetOk("allowed_aaguids"); ok {
+		config.AllowedAAGUIDs = nil
+		for _, aaguid := range aaguids.([]string) {
+			normalized := strings.ToLower(strings.ReplaceAll(aaguid, "-", ""))
+			if decoded, err := hex.DecodeString(normalized); err != nil || len(decoded) != 16 {
+				return logical.ErrorResponse("invalid AAGUID %q", aaguid), nil
+			}
+			config.AllowedAAGUIDs = append(config.AllowedAAGUIDs, normalized)
+		}
+	}
+	if len(config.AllowedAAGUIDs) > 0 && config.Attestation != attestationDirect {
+		return logical.ErrorResponse(`allowed_aaguids requires the "direct" attestation policy`), nil
+	}
+
+	// The authenticator model and the attestation are only trustworthy
+	// when the attestation certificate chains to a trusted CA
+	if config.Attestation == attestationDirect && config.AttestationCACerts == "" {
+		return logical.ErrorResponse(`the "direct" attestation policy requires attestation_ca_certs`), nil
+	}
+
+	if uv, ok := d.GetOk("user_verification"); ok {
+		config.UserVerification = uv.(string)
+	} else if isNew {
+		config.UserVerification = d.Get("user_verification").(string)
+	}
+
+	if ttl, ok := d.GetOk("challenge_ttl"); ok {
+		config.ChallengeTTL = time.Duration(ttl.(int)) * time.Second
+	} else if isNew {
+		config.ChallengeTTL = time.Duration(d.Get("challenge_ttl").(int)) * time.Second
+	}
+	if config.ChallengeTTL <= 0 {
+		return logical.ErrorResponse("challenge_ttl must be greater than zero"), nil
+	}
+
+	if len(config.ChallengeKey) == 0 {
+		config.ChallengeKey, err = newChallengeKey()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	entry, err := logical.StorageEntryJSON(configPath, config)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+type webauthnConfig struct {
+	RPID               string        `json:"rp_id"`
+	RPName             string        `json:"rp_name"`
+	AllowedOrigins     []string      `json:"allowed_origins"`
+	Attestation        string        `json:"attestation"`
+	AttestationCACerts string        `json:"attestation_ca_certs"`
+	AllowedAAGUIDs     []string      `json:"allowed_aaguids"`
+	UserVerification   string        `json:"user_verification"`
+	ChallengeTTL       time.Duration `json:"challenge_ttl"`
+
+	// ChallengeKey signs the challenges issued by the backend. It is
+	// never returned.
+	ChallengeKey []byte `json:"challenge_key"`
+}
+
+// attestationRoots returns the pool of attestation CA certificates, or nil
+// if none are configured.
+func (c *webauthnConfig) attestationRoots() (*x509.CertPool, error) {
+	if c.AttestationCACerts == "" {
+		return nil, nil
+	}
+	certs, err := certutil.ParseCertsPEM([]byte(c.AttestationCACerts))
+	if err != nil {
+		return nil, fmt.Errorf("invalid attestation_ca_certs: %w", err)
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("invalid attestation_ca_certs: no certificates found")
+	}
+	pool := x509.NewCertPool()
+	for _, cert := range certs {
+		pool.AddCert(cert)
+	}
+	return pool, nil
+}
+
+const pathConfigHelpSyn = `
+Configure the relying party and the policies for WebAuthn credentials.
+`
+
+const pathConfigHelpDesc = `
+This endpoint configures the relying party the passkeys registered with this
+auth method are scoped to, the origins ceremonies are accepted from, and the
+attestation and user verification policies applied to registrations and
+logins.
+`
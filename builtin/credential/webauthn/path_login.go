@@ -0,0 +1,300 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package webauthn
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/cidrutil"
+	"github.com/hashicorp/vault/sdk/helper/policyutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// errInvalidCredential is returned for every login failure caused by the
+// assertion, so the response doesn't tell which check failed.
+var errInvalidCredential = errors.New("invalid username or credential")
+
+func pathLoginChallenge(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "login/challenge",
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixWebauthn,
+			OperationVerb:   "generate",
+			OperationSuffix: "login-challenge",
+		},
+
+		Fields: map[string]*framework.FieldSchema{
+			"username": {
+				Type:        framework.TypeString,
+				Description: "Username of the user logging in.",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathLoginChallenge,
+		},
+
+		HelpSynopsis:    pathLoginHelpSyn,
+		HelpDescription: pathLoginHelpDesc,
+	}
+}
+
+func pathLogin(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "login",
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixWebauthn,
+			OperationVerb:   "login",
+		},
+
+		Fields: map[string]*framework.FieldSchema{
+			"username": {
+				Type:        framework.TypeString,
+				Description: "Username of the user logging in.",
+			},
+			"credential_id": {
+				Type:        framework.TypeString,
+				Description: "Base64url encoded ID of the credential used.",
+			},
+			"client_data_json": {
+				Type:        framework.TypeString,
+				Description: "Base64url encoded clientDataJSON of the assertion.",
+			},
+			"authenticator_data": {
+				Type:        framework.TypeString,
+				Description: "Base64url encoded authenticatorData of the assertion.",
+			},
+			"signature": {
+				Type:        framework.TypeString,
+				Description: "Base64url encoded signature of the assertion.",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation:         b.pathLogin,
+			logical.AliasLookaheadOperation: b.pathLoginAliasLookahead,
+		},
+
+		HelpSynopsis:    pathLoginHelpSyn,
+		HelpDescription: pathLoginHelpDesc,
+	}
+}
+
+func (b *backend) pathLoginAliasLookahead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	username := strings.ToLower(d.Get("username").(string))
+	if username == "" {
+		return nil, fmt.Errorf("missing username")
+	}
+
+	return &logical.Response{
+		Auth: &logical.Auth{
+			Alias: &logical.Alias{
+				Name: username,
+			},
+		},
+	}, nil
+}
+
+func (b *backend) pathLoginChallenge(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config, err := b.config(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return logical.ErrorResponse("webauthn is not configured"), nil
+	}
+
+	username := strings.ToLower(d.Get("username").(string))
+	if username == "" {
+		return logical.ErrorResponse("missing username"), nil
+	}
+	// The user isn't looked up, the response is the same for unknown users
+	// and the login fails later on
+	challenge, err := b.newChallenge(config, ceremonyLogin, username)
+	if err != nil {
+		return nil, err
+	}
+
+	// The options follow PublicKeyCredentialRequestOptions, with binary
+	// values base64url encoded, so clients can pass them on to the
+	// authenticator. Credentials are registered as discoverable, so
+	// allowCredentials is left out rather than listing the user's.
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"challenge": challenge,
+			"public_key": map[string]interface{}{
+				"challenge":        challenge,
+				"rpId":             config.RPID,
+				"timeout":          config.ChallengeTTL.Milliseconds(),
+				"userVerification": config.UserVerification,
+			},
+		},
+	}, nil
+}
+
+func (b *backend) pathLogin(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config, err := b.config(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return logical.ErrorResponse("webauthn is not configured"), nil
+	}
+
+	username := strings.ToLower(d.Get("username").(string))
+	if username == "" {
+		return logical.ErrorResponse("missing username"), nil
+	}
+	credentialID, err := decodeBase64("credential_id", d.Get("credential_id").(string))
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+	clientDataJSON, err := decodeBase64("client_data_json", d.Get("client_data_json").(string))
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+	rawAuthData, err := decodeBase64("authenticator_data", d.Get("authenticator_data").(string))
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+	signature, err := decodeBase64("signature", d.Get("signature").(string))
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	cd, err := parseClientData(clientDataJSON, clientDataTypeGet, config)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+	if err := b.consumeChallenge(ctx, req.Storage, config, cd.Challenge, ceremonyLogin, username); err != nil {
+		if errors.Is(err, errInvalidChallenge) {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+		return nil, err
+	}
+
+	lock := b.lockUser(username)
+	lock.Lock()
+	defer lock.Unlock()
+
+	user, err := b.user(ctx, req.Storage, username)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return logical.ErrorResponse(errInvalidCredential.Error()), logical.ErrInvalidCredentials
+	}
+	cred := user.credential(credentialID)
+	if cred == nil {
+		return logical.ErrorResponse(errInvalidCredential.Error()), logical.ErrInvalidCredentials
+	}
+
+	ad, err := parseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+	if err := ad.verify(config); err != nil {
+		b.Logger().Debug("rejected assertion", "username", username, "error", err)
+		return logical.ErrorResponse(errInvalidCredential.Error()), logical.ErrInvalidCredentials
+	}
+	key, err := parseCOSEKey(cred.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse stored credential key: %w", err)
+	}
+	if err := verifySignature(key.PublicKey, key.Algorithm, signedData(rawAuthData, clientDataJSON), signature); err != nil {
+		b.Logger().Debug("rejected assertion", "username", username, "error", err)
+		return logical.ErrorResponse(errInvalidCredential.Error()), logical.ErrInvalidCredentials
+	}
+
+	// Authenticators that keep a signature counter increase it with every
+	// assertion. A counter that didn't increase points to a cloned
+	// authenticator.
+	if (ad.SignCount != 0 || cred.SignCount != 0) && ad.SignCount <= cred.SignCount {
+		b.Logger().Warn("signature counter did not increase, the authenticator may have been cloned",
+			"username", username, "credential_id", encodeBase64(cred.ID))
+		return logical.ErrorResponse(errInvalidCredential.Error()), logical.ErrInvalidCredentials
+	}
+
+	// Check for a CIDR match.
+	if len(user.TokenBoundCIDRs) > 0 {
+		if req.Connection == nil {
+			b.Logger().Warn("token bound CIDRs found but no connection information available for validation")
+			return nil, logical.ErrPermissionDenied
+		}
+		if !cidrutil.RemoteAddrIsOk(req.Connection.RemoteAddr, user.TokenBoundCIDRs) {
+			return nil, logical.ErrPermissionDenied
+		}
+	}
+
+	cred.SignCount = ad.SignCount
+	cred.LastUsedTime = time.Now()
+	if err := b.setUser(ctx, req.Storage, username, user); err != nil {
+		return nil, err
+	}
+
+	auth := &logical.Auth{
+		Metadata: map[string]string{
+			"username":        username,
+			"credential_id":   encodeBase64(cred.ID),
+			"credential_name": cred.Name,
+		},
+		DisplayName: username,
+		Alias: &logical.Alias{
+			Name: username,
+		},
+	}
+	user.PopulateTokenAuth(auth)
+
+	return &logical.Response{
+		Auth: auth,
+	}, nil
+}
+
+func (b *backend) pathLoginRenew(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	user, err := b.user(ctx, req.Storage, req.Auth.Metadata["username"])
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		// User no longer exists, do not renew
+		return nil, nil
+	}
+
+	credentialID, err := decodeBase64("credential_id", req.Auth.Metadata["credential_id"])
+	if err != nil {
+		return nil, err
+	}
+	if user.credential(credentialID) == nil {
+		return nil, fmt.Errorf("credential has been removed, not renewing")
+	}
+
+	if !policyutil.EquivalentPolicies(user.TokenPolicies, req.Auth.TokenPolicies) {
+		return nil, fmt.Errorf("policies have changed, not renewing")
+	}
+
+	resp := &logical.Response{Auth: req.Auth}
+	resp.Auth.Period = user.TokenPeriod
+	resp.Auth.TTL = user.TokenTTL
+	resp.Auth.MaxTTL = user.TokenMaxTTL
+	return resp, nil
+}
+
+const pathLoginHelpSyn = `
+Log in with a passkey.
+`
+
+const pathLoginHelpDesc = `
+Login is a two step ceremony. The "login/challenge" endpoint returns the
+options to request an assertion with, including a single-use challenge. The
+client data, authenticator data, and signature returned by the authenticator
+are then sent to the "login" endpoint, which verifies them and returns a
+token.
+`
@@ -0,0 +1,272 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package webauthn
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const userHandleSize = 32
+
+func pathRegisterChallenge(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "users/" + framework.GenericNameRegex("username") + "/register/challenge",
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixWebauthn,
+			OperationVerb:   "generate",
+			OperationSuffix: "registration-challenge",
+		},
+
+		Fields: map[string]*framework.FieldSchema{
+			"username": {
+				Type:        framework.TypeString,
+				Description: "Username of the user registering a credential.",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathRegisterChallenge,
+		},
+
+		HelpSynopsis:    pathRegisterHelpSyn,
+		HelpDescription: pathRegisterHelpDesc,
+	}
+}
+
+func pathRegister(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "users/" + framework.GenericNameRegex("username") + "/register",
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixWebauthn,
+			OperationVerb:   "register",
+			OperationSuffix: "credential",
+		},
+
+		Fields: map[string]*framework.FieldSchema{
+			"username": {
+				Type:        framework.TypeString,
+				Description: "Username of the user registering a credential.",
+			},
+			"client_data_json": {
+				Type:        framework.TypeString,
+				Description: "Base64url encoded clientDataJSON of the registration.",
+			},
+			"attestation_object": {
+				Type:        framework.TypeString,
+				Description: "Base64url encoded attestationObject of the registration.",
+			},
+			"name": {
+				Type:        framework.TypeString,
+				Description: "Name of the credential, to tell a user's credentials apart.",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathRegister,
+		},
+
+		HelpSynopsis:    pathRegisterHelpSyn,
+		HelpDescription: pathRegisterHelpDesc,
+	}
+}
+
+func (b *backend) pathRegisterChallenge(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config, err := b.config(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return logical.ErrorResponse("webauthn is not configured"), nil
+	}
+
+	username := strings.ToLower(d.Get("username").(string))
+	lock := b.lockUser(username)
+	lock.Lock()
+	defer lock.Unlock()
+
+	user, err := b.user(ctx, req.Storage, username)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return logical.ErrorResponse("user %q not found", username), nil
+	}
+	if len(user.UserHandle) == 0 {
+		user.UserHandle = make([]byte, userHandleSize)
+		if _, err := rand.Read(user.UserHandle); err != nil {
+			return nil, fmt.Errorf("failed to generate user handle: %w", err)
+		}
+		if err := b.setUser(ctx, req.Storage, username, user); err != nil {
+			return nil, err
+		}
+	}
+
+	challenge, err := b.newChallenge(config, ceremonyRegistration, username)
+	if err != nil {
+		return nil, err
+	}
+
+	pubKeyCredParams := make([]map[string]interface{}, 0, len(supportedAlgorithms))
+	for _, alg := range supportedAlgorithms {
+		pubKeyCredParams = append(pubKeyCredParams, map[string]interface{}{
+			"type": "public-key",
+			"alg":  alg,
+		})
+	}
+	excludeCredentials := make([]map[string]interface{}, 0, len(user.Credentials))
+	for _, cred := range user.Credentials {
+		excludeCredentials = append(excludeCredentials, map[string]interface{}{
+			"type": "public-key",
+			"id":   encodeBase64(cred.ID),
+		})
+	}
+
+	// The options follow PublicKeyCredentialCreationOptions, with binary
+	// values base64url encoded, so clients can pass them on to the
+	// authenticator.
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"challenge": challenge,
+			"public_key": map[string]interface{}{
+				"challenge": challenge,
+				"rp": map[string]interface{}{
+					"id":   config.RPID,
+					"name": config.RPName,
+				},
+				"user": map[string]interface{}{
+					"id":          encodeBase64(user.UserHandle),
+					"name":        username,
+					"displayName": username,
+				},
+				"pubKeyCredParams":   pubKeyCredParams,
+				"timeout":            config.ChallengeTTL.Milliseconds(),
+				"attestation":        config.Attestation,
+				"excludeCredentials": excludeCredentials,
+				"authenticatorSelection": map[string]interface{}{
+					"residentKey":        "required",
+					"requireResidentKey": true,
+					"userVerification":   config.UserVerification,
+				},
+			},
+		},
+	}, nil
+}
+
+func (b *backend) pathRegister(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config, err := b.config(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return logical.ErrorResponse("webauthn is not configured"), nil
+	}
+
+	username := strings.ToLower(d.Get("username").(string))
+	clientDataJSON, err := decodeBase64("client_data_json", d.Get("client_data_json").(string))
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+	rawAttestation, err := decodeBase64("attestation_object", d.Get("attestation_object").(string))
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	cd, err := parseClientData(clientDataJSON, clientDataTypeCreate, config)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+	if err := b.consumeChallenge(ctx, req.Storage, config, cd.Challenge, ceremonyRegistration, username); err != nil {
+		if errors.Is(err, errInvalidChallenge) {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+		return nil, err
+	}
+
+	var obj attestationObject
+	if err := cbor.Unmarshal(rawAttestation, &obj); err != nil {
+		return logical.ErrorResponse("invalid attestation object: %s", err), nil
+	}
+	ad, err := parseAuthenticatorData(obj.AuthData)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+	if err := ad.verify(config); err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+	if len(ad.CredentialID) == 0 {
+		return logical.ErrorResponse("attestation object contains no credential"), nil
+	}
+	key, err := parseCOSEKey(ad.CredentialKey)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+	if err := verifyAttestation(config, &obj, ad, clientDataJSON); err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	lock := b.lockUser(username)
+	lock.Lock()
+	defer lock.Unlock()
+
+	user, err := b.user(ctx, req.Storage, username)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return logical.ErrorResponse("user %q not found", username), nil
+	}
+	if user.credential(ad.CredentialID) != nil {
+		return logical.ErrorResponse("credential is already registered"), nil
+	}
+
+	format := obj.Format
+	if config.Attestation == attestationNone {
+		format = attestationFormatNone
+	}
+	cred := &credential{
+		ID:                ad.CredentialID,
+		Name:              d.Get("name").(string),
+		PublicKey:         ad.CredentialKey,
+		Algorithm:         key.Algorithm,
+		SignCount:         ad.SignCount,
+		AAGUID:            ad.aaguid(),
+		AttestationFormat: format,
+		CreationTime:      time.Now(),
+	}
+	user.Credentials = append(user.Credentials, cred)
+	if err := b.setUser(ctx, req.Storage, username, user); err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"credential_id": encodeBase64(cred.ID),
+			"name":          cred.Name,
+			"aaguid":        cred.AAGUID,
+		},
+	}, nil
+}
+
+const pathRegisterHelpSyn = `
+Register a passkey for a user.
+`
+
+const pathRegisterHelpDesc = `
+Registration is a two step ceremony. The "register/challenge" endpoint returns
+the options to create a credential with, including a single-use challenge.
+The client data and attestation object returned by the authenticator are then
+sent to the "register" endpoint, which verifies them against the attestation
+policy and stores the credential.
+`
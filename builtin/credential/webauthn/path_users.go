@@ -0,0 +1,381 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package webauthn
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
+	"github.com/hashicorp/vault/sdk/helper/tokenutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const userPrefix = "user/"
+
+// credentialIDRegex matches a base64url encoded credential ID, which may
+// start or end with characters framework.GenericNameRegex doesn't allow.
+func credentialIDRegex(name string) string {
+	return fmt.Sprintf("(?P<%s>[A-Za-z0-9_-]+)", name)
+}
+
+func pathUsersList(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "users/?",
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixWebauthn,
+			OperationSuffix: "users",
+			Navigation:      true,
+			ItemType:        "User",
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ListOperation: b.pathUserList,
+		},
+
+		HelpSynopsis:    pathUserHelpSyn,
+		HelpDescription: pathUserHelpDesc,
+	}
+}
+
+func pathUsers(b *backend) *framework.Path {
+	p := &framework.Path{
+		Pattern: "users/" + framework.GenericNameRegex("username"),
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixWebauthn,
+			OperationSuffix: "user",
+			Action:          "Create",
+			ItemType:        "User",
+		},
+
+		Fields: map[string]*framework.FieldSchema{
+			"username": {
+				Type:        framework.TypeString,
+				Description: "Username for this user.",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.DeleteOperation: b.pathUserDelete,
+			logical.ReadOperation:   b.pathUserRead,
+			logical.UpdateOperation: b.pathUserWrite,
+			logical.CreateOperation: b.pathUserWrite,
+		},
+
+		ExistenceCheck: b.userExistenceCheck,
+
+		HelpSynopsis:    pathUserHelpSyn,
+		HelpDescription: pathUserHelpDesc,
+	}
+
+	tokenutil.AddTokenFields(p.Fields)
+	return p
+}
+
+func pathCredentialsList(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "users/" + framework.GenericNameRegex("username") + "/credentials/?",
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixWebauthn,
+			OperationSuffix: "credentials",
+		},
+
+		Fields: map[string]*framework.FieldSchema{
+			"username": {
+				Type:        framework.TypeString,
+				Description: "Username of the user.",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ListOperation: b.pathCredentialList,
+		},
+
+		HelpSynopsis:    pathCredentialHelpSyn,
+		HelpDescription: pathCredentialHelpDesc,
+	}
+}
+
+func pathCredentials(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "users/" + framework.GenericNameRegex("username") + "/credentials/" + credentialIDRegex("credential_id"),
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixWebauthn,
+			OperationSuffix: "credential",
+		},
+
+		Fields: map[string]*framework.FieldSchema{
+			"username": {
+				Type:        framework.TypeString,
+				Description: "Username of the user.",
+			},
+			"credential_id": {
+				Type:        framework.TypeString,
+				Description: "Base64url encoded ID of the credential.",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathCredentialRead,
+			logical.DeleteOperation: b.pathCredentialDelete,
+		},
+
+		HelpSynopsis:    pathCredentialHelpSyn,
+		HelpDescription: pathCredentialHelpDesc,
+	}
+}
+
+func (b *backend) userExistenceCheck(ctx context.Context, req *logical.Request, d *framework.FieldData) (bool, error) {
+	user, err := b.user(ctx, req.Storage, d.Get("username").(string))
+	if err != nil {
+		return false, err
+	}
+	return user != nil, nil
+}
+
+func (b *backend) user(ctx context.Context, s logical.Storage, username string) (*userEntry, error) {
+	if username == "" {
+		return nil, fmt.Errorf("missing username")
+	}
+
+	entry, err := s.Get(ctx, userPrefix+strings.ToLower(username))
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var result userEntry
+	if err := entry.DecodeJSON(&result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (b *backend) setUser(ctx context.Context, s logical.Storage, username string, user *userEntry) error {
+	entry, err := logical.StorageEntryJSON(userPrefix+strings.ToLower(username), user)
+	if err != nil {
+		return err
+	}
+	return s.Put(ctx, entry)
+}
+
+func (b *backend) lockUser(username string) *locksutil.LockEntry {
+	return locksutil.LockForKey(b.userLocks, strings.ToLower(username))
+}
+
+func (b *backend) pathUserList(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	users, err := req.Storage.List(ctx, userPrefix)
+	if err != nil {
+		return nil, err
+	}
+	return logical.ListResponse(users), nil
+}
+
+func (b *backend) pathUserDelete(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	username := d.Get("username").(string)
+	lock := b.lockUser(username)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if err := req.Storage.Delete(ctx, userPrefix+strings.ToLower(username)); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+func (b *backend) pathUserRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	user, err := b.user(ctx, req.Storage, d.Get("username").(string))
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, nil
+	}
+
+	credentialIDs := make([]string, 0, len(user.Credentials))
+	for _, cred := range user.Credentials {
+		credentialIDs = append(credentialIDs, encodeBase64(cred.ID))
+	}
+
+	data := map[string]interface{}{
+		"credential_ids": credentialIDs,
+	}
+	user.PopulateTokenData(data)
+
+	return &logical.Response{
+		Data: data,
+	}, nil
+}
+
+func (b *backend) pathUserWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	username := strings.ToLower(d.Get("username").(string))
+	lock := b.lockUser(username)
+	lock.Lock()
+	defer lock.Unlock()
+
+	user, err := b.user(ctx, req.Storage, username)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		user = &userEntry{}
+	}
+
+	if err := user.ParseTokenFields(req, d); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
+	return nil, b.setUser(ctx, req.Storage, username, user)
+}
+
+func (b *backend) pathCredentialList(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	user, err := b.user(ctx, req.Storage, d.Get("username").(string))
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, nil
+	}
+
+	keys := make([]string, 0, len(user.Credentials))
+	keyInfo := make(map[string]interface{}, len(user.Credentials))
+	for _, cred := range user.Credentials {
+		id := encodeBase64(cred.ID)
+		keys = append(keys, id)
+		keyInfo[id] = map[string]interface{}{
+			"name":   cred.Name,
+			"aaguid": cred.AAGUID,
+		}
+	}
+	return logical.ListResponseWithInfo(keys, keyInfo), nil
+}
+
+func (b *backend) pathCredentialRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	user, err := b.user(ctx, req.Storage, d.Get("username").(string))
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, nil
+	}
+	id, err := decodeBase64("credential_id", d.Get("credential_id").(string))
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+	cred := user.credential(id)
+	if cred == nil {
+		return nil, nil
+	}
+
+	data := map[string]interface{}{
+		"credential_id":      encodeBase64(cred.ID),
+		"name":               cred.Name,
+		"aaguid":             cred.AAGUID,
+		"algorithm":          cred.Algorithm,
+		"attestation_format": cred.AttestationFormat,
+		"sign_count":         cred.SignCount,
+		"creation_time":      cred.CreationTime.Format(time.RFC3339),
+	}
+	if !cred.LastUsedTime.IsZero() {
+		data["last_used_time"] = cred.LastUsedTime.Format(time.RFC3339)
+	}
+	return &logical.Response{
+		Data: data,
+	}, nil
+}
+
+func (b *backend) pathCredentialDelete(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	username := d.Get("username").(string)
+	id, err := decodeBase64("credential_id", d.Get("credential_id").(string))
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	lock := b.lockUser(username)
+	lock.Lock()
+	defer lock.Unlock()
+
+	user, err := b.user(ctx, req.Storage, username)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, nil
+	}
+
+	credentials := user.Credentials[:0]
+	for _, cred := range user.Credentials {
+		if !bytes.Equal(cred.ID, id) {
+			credentials = append(credentials, cred)
+		}
+	}
+	user.Credentials = credentials
+
+	return nil, b.setUser(ctx, req.Storage, username, user)
+}
+
+type userEntry struct {
+	tokenutil.TokenParams
+
+	// UserHandle is the random user ID passkeys are registered for. It
+	// is generated on the first registration.
+	UserHandle []byte `json:"user_handle"`
+
+	Credentials []*credential `json:"credentials"`
+}
+
+// credential returns the registered credential with the given ID, or nil.
+func (u *userEntry) credential(id []byte) *credential {
+	for _, cred := range u.Credentials {
+		if bytes.Equal(cred.ID, id) {
+			return cred
+		}
+	}
+	return nil
+}
+
+// credential is a passkey registered for a user.
+type credential struct {
+	ID                []byte    `json:"id"`
+	Name              string    `json:"name"`
+	PublicKey         []byte    `json:"public_key"`
+	Algorithm         int64     `json:"algorithm"`
+	SignCount         uint32    `json:"sign_count"`
+	AAGUID            string    `json:"aaguid"`
+	AttestationFormat string    `json:"attestation_format"`
+	CreationTime      time.Time `json:"creation_time"`
+	LastUsedTime      time.Time `json:"last_used_time"`
+}
+
+const pathUserHelpSyn = `
+Manage users allowed to authenticate.
+`
+
+const pathUserHelpDesc = `
+This endpoint allows you to create, read, update, and delete users that are
+allowed to authenticate, and the token parameters of their logins.
+
+Deleting a user will not revoke auth for prior authenticated users with that
+name, but the tokens will no longer be renewable.
+`
+
+const pathCredentialHelpSyn = `
+Manage the passkeys registered for a user.
+`
+
+const pathCredentialHelpDesc = `
+This endpoint allows you to list, read, and delete the passkeys registered
+for a user. Deleting a credential prevents further logins with it, and the
+tokens issued to it will no longer be renewable.
+`
@@ -0,0 +1,379 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/hashicorp/go-secure-stdlib/strutil"
+)
+
+const (
+	clientDataTypeCreate = "webauthn.create"
+	clientDataTypeGet    = "webauthn.get"
+
+	attestationFormatNone   = "none"
+	attestationFormatPacked = "packed"
+
+	// Authenticator data flags, see
+	// https://www.w3.org/TR/webauthn-2/#sctn-authenticator-data
+	flagUserPresent        = 0x01
+	flagUserVerified       = 0x04
+	flagAttestedCredential = 0x40
+
+	// COSE key parameters and algorithms, see RFC 8152 and RFC 8230.
+	coseKeyType      = 1
+	coseKeyAlgorithm = 3
+	coseKeyCurve     = -1
+	coseKeyX         = -2
+	coseKeyY         = -3
+	coseKeyRSAN      = -1
+	coseKeyRSAE      = -2
+
+	coseKeyTypeOKP = 1
+	coseKeyTypeEC2 = 2
+	coseKeyTypeRSA = 3
+
+	coseCurveP256    = 1
+	coseCurveEd25519 = 6
+
+	coseAlgES256 = -7
+	coseAlgEdDSA = -8
+	coseAlgRS256 = -257
+)
+
+// supportedAlgorithms are the COSE algorithms of the credentials that can
+// be registered, in order of preference.
+var supportedAlgorithms = []int64{coseAlgES256, coseAlgEdDSA, coseAlgRS256}
+
+// decodeBase64 decodes a value encoded as base64url, which WebAuthn uses
+// throughout, or as standard base64 for clients that re-encode binary
+// values.
+func decodeBase64(field, value string) ([]byte, error) {
+	if value == "" {
+		return nil, fmt.Errorf("missing %s", field)
+	}
+	value = strings.TrimRight(value, "=")
+	if decoded, err := base64.RawURLEncoding.DecodeString(value); err == nil {
+		return decoded, nil
+	}
+	decoded, err := base64.RawStdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: not base64 encoded", field)
+	}
+	return decoded, nil
+}
+
+func encodeBase64(value []byte) string {
+	return base64.RawURLEncoding.EncodeToString(value)
+}
+
+// clientData is the client data collected by the browser or platform
+// during a ceremony.
+type clientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin"`
+}
+
+// parseClientData decodes clientDataJSON and checks its type and origin.
+// The challenge is returned for the caller to look up.
+func parseClientData(raw []byte, expectedType string, config *webauthnConfig) (*clientData, error) {
+	var cd clientData
+	if err := json.Unmarshal(raw, &cd); err != nil {
+		return nil, fmt.Errorf("invalid client data: %w", err)
+	}
+	if cd.Type != expectedType {
+		return nil, fmt.Errorf("invalid client data type %q", cd.Type)
+	}
+	if cd.Challenge == "" {
+		return nil, errors.New("missing challenge in client data")
+	}
+	if !strutil.StrListContains(config.AllowedOrigins, cd.Origin) {
+		return nil, fmt.Errorf("origin %q is not allowed", cd.Origin)
+	}
+	if cd.CrossOrigin {
+		return nil, errors.New("cross-origin ceremonies are not allowed")
+	}
+	return &cd, nil
+}
+
+// authenticatorData is the parsed authenticator data of a ceremony.
+type authenticatorData struct {
+	RPIDHash  []byte
+	Flags     byte
+	SignCount uint32
+
+	// Attested credential data, only present on registration.
+	AAGUID        []byte
+	CredentialID  []byte
+	CredentialKey []byte
+}
+
+func parseAuthenticatorData(raw []byte) (*authenticatorData, error) {
+	if len(raw) < 37 {
+		return nil, errors.New("authenticator data is too short")
+	}
+	ad := &authenticatorData{
+		RPIDHash:  raw[:32],
+		Flags:     raw[32],
+		SignCount: binary.BigEndian.Uint32(raw[33:37]),
+	}
+	if ad.Flags&flagAttestedCredential == 0 {
+		return ad, nil
+	}
+
+	rest := raw[37:]
+	if len(rest) < 18 {
+		return nil, errors.New("attested credential data is too short")
+	}
+	ad.AAGUID = rest[:16]
+	idLen := int(binary.BigEndian.Uint16(rest[16:18]))
+	rest = rest[18:]
+	if len(rest) < idLen {
+		return nil, errors.New("attested credential data is too short")
+	}
+	ad.CredentialID = rest[:idLen]
+
+	var key cbor.RawMessage
+	if _, err := cbor.UnmarshalFirst(rest[idLen:], &key); err != nil {
+		return nil, fmt.Errorf("invalid credential public key: %w", err)
+	}
+	ad.CredentialKey = key
+
+	return ad, nil
+}
+
+// verify checks the relying party and the user presence and verification
+// flags of the authenticator data.
+func (ad *authenticatorData) verify(config *webauthnConfig) error {
+	rpIDHash := sha256.Sum256([]byte(config.RPID))
+	if subtle.ConstantTimeCompare(ad.RPIDHash, rpIDHash[:]) != 1 {
+		return errors.New("authenticator data is for a different relying party")
+	}
+	if ad.Flags&flagUserPresent == 0 {
+		return errors.New("user presence was not asserted")
+	}
+	if config.UserVerification == userVerificationRequired && ad.Flags&flagUserVerified == 0 {
+		return errors.New("user verification is required but was not performed")
+	}
+	return nil
+}
+
+func (ad *authenticatorData) aaguid() string {
+	return hex.EncodeToString(ad.AAGUID)
+}
+
+// coseKey is a parsed COSE public key.
+type coseKey struct {
+	Algorithm int64
+	PublicKey crypto.PublicKey
+}
+
+func parseCOSEKey(raw []byte) (*coseKey, error) {
+	var params map[int64]interface{}
+	if err := cbor.Unmarshal(raw, &params); err != nil {
+		return nil, fmt.Errorf("invalid COSE key: %w", err)
+	}
+
+	kty, _ := params[coseKeyType].(uint64)
+	alg, ok := params[coseKeyAlgorithm].(int64)
+	if !ok {
+		return nil, errors.New("COSE key has no algorithm")
+	}
+	bytesParam := func(label int64) []byte {
+		b, _ := params[label].([]byte)
+		return b
+	}
+
+	key := &coseKey{Algorithm: alg}
+	switch {
+	case kty == coseKeyTypeEC2 && alg == coseAlgES256:
+		if crv, _ := params[coseKeyCurve].(uint64); crv != coseCurveP256 {
+			return nil, fmt.Errorf("unsupported EC2 curve %v", params[coseKeyCurve])
+		}
+		x, y := bytesParam(coseKeyX), bytesParam(coseKeyY)
+		if len(x) != 32 || len(y) != 32 {
+			return nil, errors.New("invalid EC2 key coordinates")
+		}
+		pub := &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}
+		if !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+			return nil, errors.New("EC2 key is not on the curve")
+		}
+		key.PublicKey = pub
+
+	case kty == coseKeyTypeOKP && alg == coseAlgEdDSA:
+		if crv, _ := params[coseKeyCurve].(uint64); crv != coseCurveEd25519 {
+			return nil, fmt.Errorf("unsupported OKP curve %v", params[coseKeyCurve])
+		}
+		x := bytesParam(coseKeyX)
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key")
+		}
+		key.PublicKey = ed25519.PublicKey(x)
+
+	case kty == coseKeyTypeRSA && alg == coseAlgRS256:
+		n, e := bytesParam(coseKeyRSAN), bytesParam(coseKeyRSAE)
+		if len(n) == 0 || len(e) == 0 || len(e) > 4 {
+			return nil, errors.New("invalid RSA key")
+		}
+		pub := &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+		if pub.N.BitLen() < 2048 {
+			return nil, errors.New("RSA keys must be at least 2048 bits")
+		}
+		key.PublicKey = pub
+
+	default:
+		return nil, fmt.Errorf("unsupported key type %d with algorithm %d", kty, alg)
+	}
+
+	return key, nil
+}
+
+// verifySignature verifies a signature made with the algorithm over the
+// message.
+func verifySignature(pub crypto.PublicKey, alg int64, message, sig []byte) error {
+	digest := sha256.Sum256(message)
+	switch alg {
+	case coseAlgES256:
+		pub, ok := pub.(*ecdsa.PublicKey)
+		if !ok || !ecdsa.VerifyASN1(pub, digest[:], sig) {
+			return errors.New("invalid signature")
+		}
+	case coseAlgEdDSA:
+		pub, ok := pub.(ed25519.PublicKey)
+		if !ok || !ed25519.Verify(pub, message, sig) {
+			return errors.New("invalid signature")
+		}
+	case coseAlgRS256:
+		pub, ok := pub.(*rsa.PublicKey)
+		if !ok || rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], sig) != nil {
+			return errors.New("invalid signature")
+		}
+	default:
+		return fmt.Errorf("unsupported signature algorithm %d", alg)
+	}
+	return nil
+}
+
+// signedData returns the data an authenticator signs in a ceremony: the
+// authenticator data followed by the hash of the client data.
+func signedData(authData, clientDataJSON []byte) []byte {
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	return append(append([]byte{}, authData...), clientDataHash[:]...)
+}
+
+// attestationObject is the CBOR encoded result of a registration ceremony.
+type attestationObject struct {
+	Format   string          `cbor:"fmt"`
+	AttStmt  cbor.RawMessage `cbor:"attStmt"`
+	AuthData []byte          `cbor:"authData"`
+}
+
+// oidFIDOGenCEAAGUID is the extension of attestation certificates holding
+// the AAGUID of the authenticator model.
+var oidFIDOGenCEAAGUID = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 45724, 1, 1, 4}
+
+type packedAttestationStatement struct {
+	Algorithm int64    `cbor:"alg"`
+	Signature []byte   `cbor:"sig"`
+	X5C       [][]byte `cbor:"x5c"`
+}
+
+// verifyAttestation enforces the attestation policy of the configuration
+// on a registration. With the "none" policy the attestation statement is
+// ignored. With the "direct" policy it must be a valid "packed"
+// attestation signed by a certificate chaining to one of the configured
+// CAs.
+func verifyAttestation(config *webauthnConfig, obj *attestationObject, ad *authenticatorData, clientDataJSON []byte) error {
+	if config.Attestation == attestationNone {
+		return nil
+	}
+
+	if obj.Format != attestationFormatPacked {
+		return fmt.Errorf("unsupported attestation format %q", obj.Format)
+	}
+	var stmt packedAttestationStatement
+	if err := cbor.Unmarshal(obj.AttStmt, &stmt); err != nil {
+		return fmt.Errorf("invalid attestation statement: %w", err)
+	}
+	// A self attestation is made with the credential key itself, and proves
+	// nothing about the authenticator
+	if len(stmt.X5C) == 0 {
+		return errors.New("self attestation is not allowed")
+	}
+
+	certs := make([]*x509.Certificate, 0, len(stmt.X5C))
+	for _, der := range stmt.X5C {
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return fmt.Errorf("invalid attestation certificate: %w", err)
+		}
+		certs = append(certs, cert)
+	}
+	if err := verifySignature(certs[0].PublicKey, stmt.Algorithm, signedData(obj.AuthData, clientDataJSON), stmt.Signature); err != nil {
+		return fmt.Errorf("invalid attestation signature: %w", err)
+	}
+
+	roots, err := config.attestationRoots()
+	if err != nil {
+		return err
+	}
+	if roots == nil {
+		return errors.New("no attestation CA certificates are configured")
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	if _, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}); err != nil {
+		return fmt.Errorf("attestation certificate is not trusted: %w", err)
+	}
+
+	// The authenticator model is only known once the attestation is
+	// trusted. If the certificate names the model, it must be the one of
+	// the authenticator data.
+	for _, ext := range certs[0].Extensions {
+		if !ext.Id.Equal(oidFIDOGenCEAAGUID) {
+			continue
+		}
+		var aaguid []byte
+		if rest, err := asn1.Unmarshal(ext.Value, &aaguid); err != nil || len(rest) != 0 || subtle.ConstantTimeCompare(aaguid, ad.AAGUID) != 1 {
+			return errors.New("attestation certificate AAGUID does not match the authenticator")
+		}
+	}
+	if len(config.AllowedAAGUIDs) > 0 && !strutil.StrListContains(config.AllowedAAGUIDs, ad.aaguid()) {
+		return fmt.Errorf("authenticator model %q is not allowed", ad.aaguid())
+	}
+	return nil
+}
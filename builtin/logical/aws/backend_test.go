@@ -900,6 +900,7 @@ func testAccStepReadPolicy(t *testing.T, name string, value string) logicaltest.
 			expected := map[string]interface{}{
 				"policy_arns":              []string(nil),
 				"role_arns":                []string(nil),
+				"role_chain":               []string(nil),
 				"policy_document":          value,
 				"credential_type":          strings.Join([]string{iamUserCred, federationTokenCred}, ","),
 				"default_sts_ttl":          int64(0),
@@ -909,6 +910,8 @@ func testAccStepReadPolicy(t *testing.T, name string, value string) logicaltest.
 				"iam_groups":               []string(nil),
 				"iam_tags":                 map[string]string(nil),
 				"mfa_serial_number":        "",
+				"session_tags":             map[string]string(nil),
+				"source_identity":          "",
 			}
 			if !reflect.DeepEqual(resp.Data, expected) {
 				return fmt.Errorf("bad: got: %#v\nexpected: %#v", resp.Data, expected)
@@ -1026,6 +1029,7 @@ func TestAcceptanceBackend_iamUserManagedInlinePoliciesGroups(t *testing.T) {
 		"policy_arns":              []string{ec2PolicyArn, iamPolicyArn},
 		"credential_type":          iamUserCred,
 		"role_arns":                []string(nil),
+		"role_chain":               []string(nil),
 		"default_sts_ttl":          int64(0),
 		"max_sts_ttl":              int64(0),
 		"user_path":                "/path/",
@@ -1033,6 +1037,8 @@ func TestAcceptanceBackend_iamUserManagedInlinePoliciesGroups(t *testing.T) {
 		"iam_groups":               []string{groupName},
 		"iam_tags":                 map[string]string(nil),
 		"mfa_serial_number":        "",
+		"session_tags":             map[string]string(nil),
+		"source_identity":          "",
 	}
 
 	logicaltest.Test(t, logicaltest.TestCase{
@@ -1071,6 +1077,7 @@ func TestAcceptanceBackend_iamUserGroups(t *testing.T) {
 		"policy_arns":              []string(nil),
 		"credential_type":          iamUserCred,
 		"role_arns":                []string(nil),
+		"role_chain":               []string(nil),
 		"default_sts_ttl":          int64(0),
 		"max_sts_ttl":              int64(0),
 		"user_path":                "/path/",
@@ -1078,6 +1085,8 @@ func TestAcceptanceBackend_iamUserGroups(t *testing.T) {
 		"iam_groups":               []string{group1Name, group2Name},
 		"iam_tags":                 map[string]string(nil),
 		"mfa_serial_number":        "",
+		"session_tags":             map[string]string(nil),
+		"source_identity":          "",
 	}
 
 	logicaltest.Test(t, logicaltest.TestCase{
@@ -1474,6 +1483,7 @@ func testAccStepReadArnPolicy(t *testing.T, name string, value string) logicalte
 			expected := map[string]interface{}{
 				"policy_arns":              []string{value},
 				"role_arns":                []string(nil),
+				"role_chain":               []string(nil),
 				"policy_document":          "",
 				"credential_type":          iamUserCred,
 				"default_sts_ttl":          int64(0),
@@ -1483,6 +1493,8 @@ func testAccStepReadArnPolicy(t *testing.T, name string, value string) logicalte
 				"iam_groups":               []string(nil),
 				"iam_tags":                 map[string]string(nil),
 				"mfa_serial_number":        "",
+				"session_tags":             map[string]string(nil),
+				"source_identity":          "",
 			}
 			if !reflect.DeepEqual(resp.Data, expected) {
 				return fmt.Errorf("bad: got: %#v\nexpected: %#v", resp.Data, expected)
@@ -1545,6 +1557,7 @@ func testAccStepReadIamGroups(t *testing.T, name string, groups []string) logica
 			expected := map[string]interface{}{
 				"policy_arns":              []string(nil),
 				"role_arns":                []string(nil),
+				"role_chain":               []string(nil),
 				"policy_document":          "",
 				"credential_type":          iamUserCred,
 				"default_sts_ttl":          int64(0),
@@ -1554,6 +1567,8 @@ func testAccStepReadIamGroups(t *testing.T, name string, groups []string) logica
 				"iam_groups":               groups,
 				"iam_tags":                 map[string]string(nil),
 				"mfa_serial_number":        "",
+				"session_tags":             map[string]string(nil),
+				"source_identity":          "",
 			}
 			if !reflect.DeepEqual(resp.Data, expected) {
 				return fmt.Errorf("bad: got: %#v\nexpected: %#v", resp.Data, expected)
@@ -1605,6 +1620,7 @@ func testAccStepReadIamTags(t *testing.T, name string, tags map[string]string) l
 			expected := map[string]interface{}{
 				"policy_arns":              []string(nil),
 				"role_arns":                []string(nil),
+				"role_chain":               []string(nil),
 				"policy_document":          "",
 				"credential_type":          iamUserCred,
 				"default_sts_ttl":          int64(0),
@@ -1614,6 +1630,8 @@ func testAccStepReadIamTags(t *testing.T, name string, tags map[string]string) l
 				"iam_groups":               []string(nil),
 				"iam_tags":                 tags,
 				"mfa_serial_number":        "",
+				"session_tags":             map[string]string(nil),
+				"source_identity":          "",
 			}
 			if !reflect.DeepEqual(resp.Data, expected) {
 				return fmt.Errorf("bad: got: %#v\nexpected: %#v", resp.Data, expected)
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/iam"
@@ -26,8 +27,9 @@ import (
 // NOTE: The caller is required to ensure that b.clientMutex is at least read locked
 func (b *backend) getRootConfig(ctx context.Context, s logical.Storage, clientType string, logger hclog.Logger) (*aws.Config, error) {
 	credsConfig := &awsutil.CredentialsConfig{}
-	var endpoint string
+	var endpoint, stsEndpoint string
 	var maxRetries int = aws.UseServiceDefaultRetries
+	var tokenFetcher stscreds.TokenFetcher
 
 	entry, err := s.Get(ctx, "config/root")
 	if err != nil {
@@ -43,6 +45,7 @@ func (b *backend) getRootConfig(ctx context.Context, s logical.Storage, clientTy
 		credsConfig.SecretKey = config.SecretKey
 		credsConfig.Region = config.Region
 		maxRetries = config.MaxRetries
+		stsEndpoint = config.STSEndpoint
 		switch {
 		case clientType == "iam" && config.IAMEndpoint != "":
 			endpoint = *aws.String(config.IAMEndpoint)
@@ -66,8 +69,8 @@ func (b *backend) getRootConfig(ctx context.Context, s logical.Storage, clientTy
 
 			sessionSuffix := strconv.FormatInt(time.Now().UnixNano(), 10)
 			credsConfig.RoleSessionName = fmt.Sprintf("vault-aws-secrets-%s", sessionSuffix)
-			credsConfig.WebIdentityTokenFetcher = fetcher
 			credsConfig.RoleARN = config.RoleARN
+			tokenFetcher = fetcher
 		}
 	}
 
@@ -85,7 +88,12 @@ func (b *backend) getRootConfig(ctx context.Context, s logical.Storage, clientTy
 
 	credsConfig.Logger = logger
 
-	creds, err := credsConfig.GenerateCredentialChain()
+	var creds *credentials.Credentials
+	if tokenFetcher != nil {
+		creds, err = webIdentityCredentials(credsConfig, stsEndpoint, maxRetries, tokenFetcher)
+	} else {
+		creds, err = credsConfig.GenerateCredentialChain()
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -99,6 +107,27 @@ func (b *backend) getRootConfig(ctx context.Context, s logical.Storage, clientTy
 	}, nil
 }
 
+// webIdentityCredentials returns credentials that exchange plugin identity
+// tokens for credentials of the configured role via
+// sts:AssumeRoleWithWebIdentity. Unlike the awsutil credential chain, the
+// exchange uses the configured STS endpoint and region and never falls back to
+// credentials found in the environment, so no long-lived keys are involved.
+func webIdentityCredentials(credsConfig *awsutil.CredentialsConfig, stsEndpoint string, maxRetries int, fetcher stscreds.TokenFetcher) (*credentials.Credentials, error) {
+	sess, err := session.NewSession(&aws.Config{
+		Credentials: credentials.AnonymousCredentials,
+		Region:      aws.String(credsConfig.Region),
+		Endpoint:    aws.String(stsEndpoint),
+		HTTPClient:  credsConfig.HTTPClient,
+		MaxRetries:  aws.Int(maxRetries),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error creating session for web identity credentials: %w", err)
+	}
+
+	provider := stscreds.NewWebIdentityRoleProviderWithToken(sts.New(sess), credsConfig.RoleARN, credsConfig.RoleSessionName, fetcher)
+	return credentials.NewCredentials(provider), nil
+}
+
 func (b *backend) nonCachedClientIAM(ctx context.Context, s logical.Storage, logger hclog.Logger) (*iam.IAM, error) {
 	awsConfig, err := b.getRootConfig(ctx, s, "iam", logger)
 	if err != nil {
@@ -131,6 +160,29 @@ func (b *backend) nonCachedClientSTS(ctx context.Context, s logical.Storage, log
 	return client, nil
 }
 
+// nonCachedClientSTSWithCredentials returns an STS client for the configured
+// endpoint and region that authenticates with the given session credentials
+// instead of the root credentials.
+func (b *backend) nonCachedClientSTSWithCredentials(ctx context.Context, s logical.Storage, creds *sts.Credentials) (*sts.STS, error) {
+	b.clientMutex.RLock()
+	awsConfig, err := b.getRootConfig(ctx, s, "sts", b.Logger())
+	b.clientMutex.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	awsConfig.Credentials = credentials.NewStaticCredentials(
+		aws.StringValue(creds.AccessKeyId),
+		aws.StringValue(creds.SecretAccessKey),
+		aws.StringValue(creds.SessionToken),
+	)
+	sess, err := session.NewSession(awsConfig)
+	if err != nil {
+		return nil, err
+	}
+	return sts.New(sess), nil
+}
+
 // PluginIdentityTokenFetcher fetches plugin identity tokens from Vault. It is provided
 // to the AWS SDK client to keep assumed role credentials refreshed through expiration.
 // When the client's STS credentials expire, it will use this interface to fetch a new
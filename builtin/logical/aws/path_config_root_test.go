@@ -5,9 +5,17 @@ package aws
 
 import (
 	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"reflect"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/hashicorp/vault/helper/namespace"
 	"github.com/hashicorp/vault/sdk/helper/pluginidentityutil"
 	"github.com/hashicorp/vault/sdk/helper/pluginutil"
 	"github.com/hashicorp/vault/sdk/logical"
@@ -103,3 +111,113 @@ type testSystemView struct {
 func (d testSystemView) GenerateIdentityToken(_ context.Context, _ *pluginutil.IdentityTokenRequest) (*pluginutil.IdentityTokenResponse, error) {
 	return nil, pluginidentityutil.ErrPluginWorkloadIdentityUnsupported
 }
+
+// TestBackend_PathConfigRoot_WebIdentity tests that plugin identity tokens are
+// exchanged for root credentials at the configured STS endpoint.
+func TestBackend_PathConfigRoot_WebIdentity(t *testing.T) {
+	sts := newTestSTSServer(t)
+
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+	config.System = &webIdentitySystemView{}
+
+	b := Backend(config)
+	require.NoError(t, b.Setup(context.Background(), config))
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Storage:   config.StorageView,
+		Path:      "config/root",
+		Data: map[string]interface{}{
+			"identity_token_audience": "sts.amazonaws.com",
+			"role_arn":                "arn:aws:iam::123456789012:role/vault-root",
+			"sts_endpoint":            sts.URL,
+			"region":                  "us-west-2",
+		},
+	})
+	require.NoError(t, err)
+	require.Nil(t, resp)
+
+	awsConfig, err := b.getRootConfig(namespace.RootContext(nil), config.StorageView, "iam", b.Logger())
+	require.NoError(t, err)
+	creds, err := awsConfig.Credentials.Get()
+	require.NoError(t, err)
+	require.Equal(t, testWebIdentityAccessKey, creds.AccessKeyID)
+
+	require.Len(t, sts.requests, 1)
+	require.Equal(t, "AssumeRoleWithWebIdentity", sts.requests[0].Get("Action"))
+	require.Equal(t, testWebIdentityToken, sts.requests[0].Get("WebIdentityToken"))
+	require.Equal(t, "arn:aws:iam::123456789012:role/vault-root", sts.requests[0].Get("RoleArn"))
+}
+
+const (
+	testWebIdentityToken     = "test-plugin-identity-token"
+	testWebIdentityAccessKey = "ASIAWEBIDENTITY"
+)
+
+// testAssumedRoleAccessKey returns the access key the STS stand-in issues for
+// the given role, so that tests can tell which credentials signed a request.
+func testAssumedRoleAccessKey(roleARN string) string {
+	return "ASIA" + strings.ToUpper(roleARN[strings.LastIndex(roleARN, "/")+1:])
+}
+
+type webIdentitySystemView struct {
+	logical.StaticSystemView
+}
+
+func (d webIdentitySystemView) GenerateIdentityToken(_ context.Context, req *pluginutil.IdentityTokenRequest) (*pluginutil.IdentityTokenResponse, error) {
+	return &pluginutil.IdentityTokenResponse{
+		Token: pluginutil.IdentityToken(testWebIdentityToken),
+		TTL:   req.TTL,
+	}, nil
+}
+
+// testSTSServer is a local stand-in for the STS query API that answers
+// AssumeRoleWithWebIdentity and AssumeRole requests and records them.
+type testSTSServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests []url.Values
+	// authorizations holds the Authorization header of each request.
+	authorizations []string
+}
+
+func newTestSTSServer(t *testing.T) *testSTSServer {
+	t.Helper()
+	s := &testSTSServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.mu.Lock()
+		s.requests = append(s.requests, r.PostForm)
+		s.authorizations = append(s.authorizations, r.Header.Get("Authorization"))
+		s.mu.Unlock()
+
+		action := r.PostForm.Get("Action")
+		accessKey := testAssumedRoleAccessKey(r.PostForm.Get("RoleArn"))
+		if action == "AssumeRoleWithWebIdentity" {
+			accessKey = testWebIdentityAccessKey
+		}
+		w.Header().Set("Content-Type", "text/xml")
+		fmt.Fprintf(w, `<%[1]sResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+  <%[1]sResult>
+    <Credentials>
+      <AccessKeyId>%[2]s</AccessKeyId>
+      <SecretAccessKey>secret</SecretAccessKey>
+      <SessionToken>session-token</SessionToken>
+      <Expiration>%[3]s</Expiration>
+    </Credentials>
+    <AssumedRoleUser>
+      <Arn>%[4]s/session</Arn>
+      <AssumedRoleId>AROAEXAMPLE:session</AssumedRoleId>
+    </AssumedRoleUser>
+  </%[1]sResult>
+  <ResponseMetadata><RequestId>test</RequestId></ResponseMetadata>
+</%[1]sResponse>`, action, accessKey, time.Now().Add(time.Hour).UTC().Format(time.RFC3339), r.PostForm.Get("RoleArn"))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
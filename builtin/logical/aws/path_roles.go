@@ -72,6 +72,17 @@ func pathRoles(b *backend) *framework.Path {
 				},
 			},
 
+			"role_chain": {
+				Type: framework.TypeCommaStringSlice,
+				Description: `ARNs of AWS roles to assume in order before assuming the role from role_arns,
+for example a hub account role that is trusted by the spoke account roles. Each
+role is assumed with the credentials of the previous one. Only valid when
+credential_type is ` + assumedRoleCred,
+				DisplayAttrs: &framework.DisplayAttributes{
+					Name: "Role Chain",
+				},
+			},
+
 			"policy_arns": {
 				Type: framework.TypeCommaStringSlice,
 				Description: fmt.Sprintf(`ARNs of AWS policies. Behavior varies by credential_type. When credential_type is
@@ -116,6 +127,24 @@ delimited key pairs.`,
 				},
 			},
 
+			"session_tags": {
+				Type: framework.TypeKVPairs,
+				Description: fmt.Sprintf(`Session tags to set on the credentials when credential_type is %s or %s.
+These must be presented as Key-Value pairs. Values may contain identity templates,
+such as {{identity.entity.name}}, which are populated from the requesting entity.`, assumedRoleCred, federationTokenCred),
+				DisplayAttrs: &framework.DisplayAttributes{
+					Name:  "Session Tags",
+					Value: "[key1=value1, key2=value2]",
+				},
+			},
+
+			"source_identity": {
+				Type: framework.TypeString,
+				Description: `Source identity to set when assuming the role; only valid when credential_type is ` + assumedRoleCred + `.
+May contain identity templates, such as {{identity.entity.name}}, which are
+populated from the requesting entity.`,
+			},
+
 			"default_sts_ttl": {
 				Type:        framework.TypeDurationSecond,
 				Description: fmt.Sprintf("Default TTL for %s, %s, and %s credential types when no TTL is explicitly requested with the credentials", assumedRoleCred, federationTokenCred, sessionTokenCred),
@@ -279,6 +308,13 @@ func (b *backend) pathRolesWrite(ctx context.Context, req *logical.Request, d *f
 		roleEntry.RoleArns = roleArnsRaw.([]string)
 	}
 
+	if roleChainRaw, ok := d.GetOk("role_chain"); ok {
+		if legacyRole != "" {
+			return logical.ErrorResponse("cannot supply deprecated role or policy parameters with role_chain"), nil
+		}
+		roleEntry.RoleChain = roleChainRaw.([]string)
+	}
+
 	if policyArnsRaw, ok := d.GetOk("policy_arns"); ok {
 		if legacyRole != "" {
 			return logical.ErrorResponse("cannot supply deprecated role or policy parameters with policy_arns"), nil
@@ -341,6 +377,14 @@ func (b *backend) pathRolesWrite(ctx context.Context, req *logical.Request, d *f
 		roleEntry.SerialNumber = serialNumber.(string)
 	}
 
+	if sessionTags, ok := d.GetOk("session_tags"); ok {
+		roleEntry.SessionTags = sessionTags.(map[string]string)
+	}
+
+	if sourceIdentity, ok := d.GetOk("source_identity"); ok {
+		roleEntry.SourceIdentity = sourceIdentity.(string)
+	}
+
 	if legacyRole != "" {
 		roleEntry = upgradeLegacyPolicyEntry(legacyRole)
 		if roleEntry.InvalidData != "" {
@@ -524,6 +568,7 @@ type awsRoleEntry struct {
 	CredentialTypes          []string          `json:"credential_types"`                      // Entries must all be in the set of ("iam_user", "assumed_role", "federation_token")
 	PolicyArns               []string          `json:"policy_arns"`                           // ARNs of managed policies to attach to an IAM user
 	RoleArns                 []string          `json:"role_arns"`                             // ARNs of roles to assume for AssumedRole credentials
+	RoleChain                []string          `json:"role_chain"`                            // ARNs of roles to assume in order before assuming one of RoleArns
 	PolicyDocument           string            `json:"policy_document"`                       // JSON-serialized inline policy to attach to IAM users and/or to specify as the Policy parameter in AssumeRole calls
 	IAMGroups                []string          `json:"iam_groups"`                            // Names of IAM groups that generated IAM users will be added to
 	IAMTags                  map[string]string `json:"iam_tags"`                              // IAM tags that will be added to the generated IAM users
@@ -535,6 +580,8 @@ type awsRoleEntry struct {
 	UserPath                 string            `json:"user_path"`                             // The path for the IAM user when using "iam_user" credential type
 	PermissionsBoundaryARN   string            `json:"permissions_boundary_arn"`              // ARN of an IAM policy to attach as a permissions boundary
 	SerialNumber             string            `json:"mfa_serial_number"`                     // Serial number or ARN of the MFA device
+	SessionTags              map[string]string `json:"session_tags"`                          // Session tags, possibly templated, set on STS credentials
+	SourceIdentity           string            `json:"source_identity"`                       // Source identity, possibly templated, set on assumed role credentials
 }
 
 func (r *awsRoleEntry) toResponseData() map[string]interface{} {
@@ -542,6 +589,7 @@ func (r *awsRoleEntry) toResponseData() map[string]interface{} {
 		"credential_type":          strings.Join(r.CredentialTypes, ","),
 		"policy_arns":              r.PolicyArns,
 		"role_arns":                r.RoleArns,
+		"role_chain":               r.RoleChain,
 		"policy_document":          r.PolicyDocument,
 		"iam_groups":               r.IAMGroups,
 		"iam_tags":                 r.IAMTags,
@@ -550,6 +598,8 @@ func (r *awsRoleEntry) toResponseData() map[string]interface{} {
 		"user_path":                r.UserPath,
 		"permissions_boundary_arn": r.PermissionsBoundaryARN,
 		"mfa_serial_number":        r.SerialNumber,
+		"session_tags":             r.SessionTags,
+		"source_identity":          r.SourceIdentity,
 	}
 
 	if r.InvalidData != "" {
@@ -612,6 +662,35 @@ func (r *awsRoleEntry) validate() error {
 		errors = multierror.Append(errors, fmt.Errorf("cannot supply role_arns when credential_type isn't %s", assumedRoleCred))
 	}
 
+	if len(r.RoleChain) > 0 {
+		if !strutil.StrListContains(r.CredentialTypes, assumedRoleCred) {
+			errors = multierror.Append(errors, fmt.Errorf("cannot supply role_chain when credential_type isn't %s", assumedRoleCred))
+		}
+		for _, roleARN := range r.RoleChain {
+			if _, err := arn.Parse(roleARN); err != nil {
+				errors = multierror.Append(errors, fmt.Errorf("invalid role_chain ARN %q: %w", roleARN, err))
+			}
+		}
+	}
+
+	if len(r.SessionTags) > 0 && !strutil.StrListContains(r.CredentialTypes, assumedRoleCred) && !strutil.StrListContains(r.CredentialTypes, federationTokenCred) {
+		errors = multierror.Append(errors, fmt.Errorf("session_tags parameter only valid for %s and %s credential types", assumedRoleCred, federationTokenCred))
+	}
+	for key, value := range r.SessionTags {
+		if _, err := framework.ValidateIdentityTemplate(value); err != nil {
+			errors = multierror.Append(errors, fmt.Errorf("invalid template for session tag %q: %w", key, err))
+		}
+	}
+
+	if r.SourceIdentity != "" {
+		if !strutil.StrListContains(r.CredentialTypes, assumedRoleCred) {
+			errors = multierror.Append(errors, fmt.Errorf("cannot supply source_identity when credential_type isn't %s", assumedRoleCred))
+		}
+		if _, err := framework.ValidateIdentityTemplate(r.SourceIdentity); err != nil {
+			errors = multierror.Append(errors, fmt.Errorf("invalid template for source_identity: %w", err))
+		}
+	}
+
 	return errors.ErrorOrNil()
 }
 
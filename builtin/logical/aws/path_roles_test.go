@@ -449,3 +449,67 @@ func TestRoleEntryValidationFederationTokenCred(t *testing.T) {
 		t.Errorf("bad: invalid roleEntry with unrecognized PermissionsBoundary %#v passed validation", roleEntry)
 	}
 }
+
+func TestRoleEntryValidationSessionIdentity(t *testing.T) {
+	role := awsRoleEntry{
+		CredentialTypes: []string{assumedRoleCred},
+		RoleArns:        []string{"arn:aws:iam::123456789012:role/VaultRole"},
+		SessionTags:     map[string]string{"team": "{{identity.entity.metadata.team}}"},
+		SourceIdentity:  "{{identity.entity.name}}",
+	}
+	if err := role.validate(); err != nil {
+		t.Fatalf("bad: valid role %#v failed validation: %v", role, err)
+	}
+
+	role.SourceIdentity = "{{identity.entity.name"
+	if err := role.validate(); err == nil {
+		t.Fatalf("bad: expected invalid source_identity template to fail validation")
+	}
+
+	role = awsRoleEntry{
+		CredentialTypes: []string{federationTokenCred},
+		PolicyDocument:  "{}",
+		SessionTags:     map[string]string{"team": "payments"},
+	}
+	if err := role.validate(); err != nil {
+		t.Fatalf("bad: valid role %#v failed validation: %v", role, err)
+	}
+
+	role.SourceIdentity = "vault"
+	if err := role.validate(); err == nil {
+		t.Fatalf("bad: expected source_identity to be rejected for %s", federationTokenCred)
+	}
+
+	role = awsRoleEntry{
+		CredentialTypes: []string{iamUserCred},
+		SessionTags:     map[string]string{"team": "payments"},
+	}
+	if err := role.validate(); err == nil {
+		t.Fatalf("bad: expected session_tags to be rejected for %s", iamUserCred)
+	}
+}
+
+func TestRoleEntryValidationRoleChain(t *testing.T) {
+	role := awsRoleEntry{
+		CredentialTypes: []string{assumedRoleCred},
+		RoleArns:        []string{"arn:aws:iam::333333333333:role/spoke"},
+		RoleChain:       []string{"arn:aws:iam::222222222222:role/hub"},
+	}
+	if err := role.validate(); err != nil {
+		t.Fatalf("bad: valid role %#v failed validation: %v", role, err)
+	}
+
+	role.RoleChain = []string{"hub"}
+	if err := role.validate(); err == nil {
+		t.Fatalf("bad: expected invalid role_chain ARN to fail validation")
+	}
+
+	role = awsRoleEntry{
+		CredentialTypes: []string{federationTokenCred},
+		PolicyDocument:  "{}",
+		RoleChain:       []string{"arn:aws:iam::222222222222:role/hub"},
+	}
+	if err := role.validate(); err == nil {
+		t.Fatalf("bad: expected role_chain to be rejected for %s", federationTokenCred)
+	}
+}
@@ -5,6 +5,7 @@ package aws
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -144,6 +145,11 @@ func (b *backend) pathCredsRead(ctx context.Context, req *logical.Request, d *fr
 		}
 	}
 
+	sessionTags, sourceIdentity, err := b.populateSessionIdentity(role, req.EntityID)
+	if err != nil {
+		return logical.ErrorResponse("unable to populate session identity: %s", err), nil
+	}
+
 	switch credentialType {
 	case iamUserCred:
 		return b.secretAccessKeysCreate(ctx, req.Storage, req.DisplayName, roleName, role)
@@ -157,9 +163,9 @@ func (b *backend) pathCredsRead(ctx context.Context, req *logical.Request, d *fr
 		case !strutil.StrListContains(role.RoleArns, roleArn):
 			return logical.ErrorResponse(fmt.Sprintf("role_arn %q not in allowed role arns for Vault role %q", roleArn, roleName)), nil
 		}
-		return b.assumeRole(ctx, req.Storage, req.DisplayName, roleName, roleArn, role.PolicyDocument, role.PolicyArns, role.IAMGroups, ttl, roleSessionName)
+		return b.assumeRole(ctx, req.Storage, req.DisplayName, roleName, roleArn, role.PolicyDocument, role.PolicyArns, role.IAMGroups, ttl, roleSessionName, sessionTags, sourceIdentity, role.RoleChain)
 	case federationTokenCred:
-		return b.getFederationToken(ctx, req.Storage, req.DisplayName, roleName, role.PolicyDocument, role.PolicyArns, role.IAMGroups, ttl)
+		return b.getFederationToken(ctx, req.Storage, req.DisplayName, roleName, role.PolicyDocument, role.PolicyArns, role.IAMGroups, ttl, sessionTags)
 	case sessionTokenCred:
 		return b.getSessionToken(ctx, req.Storage, role.SerialNumber, mfaCode, ttl)
 	default:
@@ -167,6 +173,43 @@ func (b *backend) pathCredsRead(ctx context.Context, req *logical.Request, d *fr
 	}
 }
 
+// populateSessionIdentity renders the session tags and source identity of the
+// role, populating any identity templates from the requesting entity.
+func (b *backend) populateSessionIdentity(role *awsRoleEntry, entityID string) (map[string]string, string, error) {
+	populate := func(tpl string) (string, error) {
+		hasTemplating, err := framework.ValidateIdentityTemplate(tpl)
+		if err != nil {
+			return "", err
+		}
+		if !hasTemplating {
+			return tpl, nil
+		}
+		if entityID == "" {
+			return "", errors.New("templated values require a request from an identity entity")
+		}
+		return framework.PopulateIdentityTemplate(tpl, entityID, b.System())
+	}
+
+	var sessionTags map[string]string
+	if len(role.SessionTags) > 0 {
+		sessionTags = make(map[string]string, len(role.SessionTags))
+		for key, value := range role.SessionTags {
+			populated, err := populate(value)
+			if err != nil {
+				return nil, "", fmt.Errorf("session tag %q: %w", key, err)
+			}
+			sessionTags[key] = populated
+		}
+	}
+
+	sourceIdentity, err := populate(role.SourceIdentity)
+	if err != nil {
+		return nil, "", fmt.Errorf("source_identity: %w", err)
+	}
+
+	return sessionTags, sourceIdentity, nil
+}
+
 func (b *backend) pathUserRollback(ctx context.Context, req *logical.Request, _kind string, data interface{}) error {
 	var entry walUser
 	if err := mapstructure.Decode(data, &entry); err != nil {
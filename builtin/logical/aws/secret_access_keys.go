@@ -7,6 +7,7 @@ import (
 	"context"
 	"fmt"
 	"regexp"
+	"sort"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -22,6 +23,14 @@ import (
 const (
 	secretAccessKeyType = "access_keys"
 	storageKey          = "config/root"
+
+	// roleChainSessionSeconds is the session duration of the intermediate roles
+	// of a role_chain, which are only used to assume the next role.
+	roleChainSessionSeconds = 900
+
+	// maxRoleChainSessionSeconds is the maximum session duration AWS allows
+	// for roles assumed through role chaining.
+	maxRoleChainSessionSeconds = 3600
 )
 
 func secretAccessKeys(b *backend) *framework.Secret {
@@ -99,7 +108,7 @@ func genUsername(displayName, policyName, userType, usernameTemplate string) (re
 
 func (b *backend) getFederationToken(ctx context.Context, s logical.Storage,
 	displayName, policyName, policy string, policyARNs []string,
-	iamGroups []string, lifeTimeInSeconds int64) (*logical.Response, error,
+	iamGroups []string, lifeTimeInSeconds int64, sessionTags map[string]string) (*logical.Response, error,
 ) {
 	groupPolicies, groupPolicyARNs, err := b.getGroupPolicies(ctx, s, iamGroups)
 	if err != nil {
@@ -148,6 +157,9 @@ func (b *backend) getFederationToken(ctx context.Context, s logical.Storage,
 	if len(policyARNs) > 0 {
 		getTokenInput.PolicyArns = convertPolicyARNs(policyARNs)
 	}
+	if len(sessionTags) > 0 {
+		getTokenInput.Tags = convertSessionTags(sessionTags)
+	}
 
 	// If neither a policy document nor policy ARNs are specified, then GetFederationToken will
 	// return credentials equivalent to that of the Vault server itself. We probably don't want
@@ -238,7 +250,8 @@ func (b *backend) getSessionToken(ctx context.Context, s logical.Storage, serial
 
 func (b *backend) assumeRole(ctx context.Context, s logical.Storage,
 	displayName, roleName, roleArn, policy string, policyARNs []string,
-	iamGroups []string, lifeTimeInSeconds int64, roleSessionName string) (*logical.Response, error,
+	iamGroups []string, lifeTimeInSeconds int64, roleSessionName string,
+	sessionTags map[string]string, sourceIdentity string, roleChain []string) (*logical.Response, error,
 ) {
 	// grab any IAM group policies associated with the vault role, both inline
 	// and managed
@@ -284,6 +297,35 @@ func (b *backend) assumeRole(ctx context.Context, s logical.Storage,
 		roleSessionName = normalizeDisplayName(roleSessionName)
 	}
 
+	// Assume the roles of the chain in order, each with the credentials of the
+	// previous one, so that the requested role is assumed from the last of them.
+	for _, chainRoleArn := range roleChain {
+		chainInput := &sts.AssumeRoleInput{
+			RoleSessionName: aws.String(roleSessionName),
+			RoleArn:         aws.String(chainRoleArn),
+			DurationSeconds: aws.Int64(roleChainSessionSeconds),
+		}
+		if len(sessionTags) > 0 {
+			chainInput.SetTags(convertSessionTags(sessionTags))
+		}
+		if sourceIdentity != "" {
+			chainInput.SetSourceIdentity(sourceIdentity)
+		}
+		chainResp, err := stsClient.AssumeRoleWithContext(ctx, chainInput)
+		if err != nil {
+			return logical.ErrorResponse("Error assuming role %q of role_chain: %s", chainRoleArn, err), awsutil.CheckAWSError(err)
+		}
+		stsClient, err = b.nonCachedClientSTSWithCredentials(ctx, s, chainResp.Credentials)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// AWS limits the session duration of chained roles to one hour.
+	if len(roleChain) > 0 && lifeTimeInSeconds > maxRoleChainSessionSeconds {
+		lifeTimeInSeconds = maxRoleChainSessionSeconds
+	}
+
 	assumeRoleInput := &sts.AssumeRoleInput{
 		RoleSessionName: aws.String(roleSessionName),
 		RoleArn:         aws.String(roleArn),
@@ -295,6 +337,12 @@ func (b *backend) assumeRole(ctx context.Context, s logical.Storage,
 	if len(policyARNs) > 0 {
 		assumeRoleInput.SetPolicyArns(convertPolicyARNs(policyARNs))
 	}
+	if len(sessionTags) > 0 {
+		assumeRoleInput.SetTags(convertSessionTags(sessionTags))
+	}
+	if sourceIdentity != "" {
+		assumeRoleInput.SetSourceIdentity(sourceIdentity)
+	}
 	tokenResp, err := stsClient.AssumeRoleWithContext(ctx, assumeRoleInput)
 	if err != nil {
 		return logical.ErrorResponse("Error assuming role: %s", err), awsutil.CheckAWSError(err)
@@ -304,17 +352,25 @@ func (b *backend) assumeRole(ctx context.Context, s logical.Storage,
 	// relying on a non-zero `lease_duration` in order to manage their lease lifecycles manually.
 	//
 	ttl := time.Until(*tokenResp.Credentials.Expiration)
-	resp := b.Secret(secretAccessKeyType).Response(map[string]interface{}{
+	chain := append(append([]string{}, roleChain...), roleArn)
+	data := map[string]interface{}{
 		"access_key":     *tokenResp.Credentials.AccessKeyId,
 		"secret_key":     *tokenResp.Credentials.SecretAccessKey,
 		"security_token": *tokenResp.Credentials.SessionToken,
 		"session_token":  *tokenResp.Credentials.SessionToken,
 		"arn":            *tokenResp.AssumedRoleUser.Arn,
 		"ttl":            uint64(ttl.Seconds()),
-	}, map[string]interface{}{
-		"username": roleSessionName,
-		"policy":   roleArn,
-		"is_sts":   true,
+	}
+	// The ARN of the assumed role doesn't show how it was reached, so
+	// return the roles assumed on the way as well.
+	if len(roleChain) > 0 {
+		data["role_chain"] = chain
+	}
+	resp := b.Secret(secretAccessKeyType).Response(data, map[string]interface{}{
+		"username":   roleSessionName,
+		"policy":     roleArn,
+		"is_sts":     true,
+		"role_chain": chain,
 	})
 
 	// Set the secret TTL to appropriately match the expiration of the token
@@ -573,6 +629,25 @@ func convertPolicyARNs(policyARNs []string) []*sts.PolicyDescriptorType {
 	return retval
 }
 
+// convertSessionTags converts the given tags into STS session tags, sorted by
+// key so that requests are deterministic.
+func convertSessionTags(tags map[string]string) []*sts.Tag {
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	retval := make([]*sts.Tag, 0, len(keys))
+	for _, key := range keys {
+		retval = append(retval, &sts.Tag{
+			Key:   aws.String(key),
+			Value: aws.String(tags[key]),
+		})
+	}
+	return retval
+}
+
 type UsernameMetadata struct {
 	Type        string
 	DisplayName string
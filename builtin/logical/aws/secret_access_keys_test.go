@@ -7,7 +7,9 @@ import (
 	"context"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/hashicorp/vault/helper/namespace"
 	"github.com/hashicorp/vault/sdk/logical"
 	"github.com/stretchr/testify/require"
 )
@@ -203,3 +205,164 @@ func TestReadConfig_CustomTemplate(t *testing.T) {
 		)
 	}
 }
+
+// TestAssumeRole_SessionIdentity tests that session tags and the source
+// identity are populated from the requesting entity and sent with
+// sts:AssumeRole, using credentials obtained through plugin identity token
+// federation.
+func TestAssumeRole_SessionIdentity(t *testing.T) {
+	sts := newTestSTSServer(t)
+
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+	config.System = &webIdentitySystemView{
+		StaticSystemView: logical.StaticSystemView{
+			EntityVal: &logical.Entity{
+				ID:       "entity-id",
+				Name:     "alice",
+				Metadata: map[string]string{"team": "payments"},
+			},
+			MaxLeaseTTLVal: time.Hour,
+		},
+	}
+
+	b := Backend(config)
+	require.NoError(t, b.Setup(context.Background(), config))
+	ctx := namespace.RootContext(nil)
+
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Storage:   config.StorageView,
+		Path:      "config/root",
+		Data: map[string]interface{}{
+			"identity_token_audience": "sts.amazonaws.com",
+			"role_arn":                "arn:aws:iam::123456789012:role/vault-root",
+			"sts_endpoint":            sts.URL,
+		},
+	})
+	require.NoError(t, err)
+	require.Nil(t, resp)
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Storage:   config.StorageView,
+		Path:      "roles/deploy",
+		Data: map[string]interface{}{
+			"credential_type": assumedRoleCred,
+			"role_arns":       []string{"arn:aws:iam::123456789012:role/deploy"},
+			"session_tags":    map[string]interface{}{"team": "{{identity.entity.metadata.team}}", "app": "ci"},
+			"source_identity": "{{identity.entity.name}}",
+		},
+	})
+	require.NoError(t, err)
+	require.Nil(t, resp)
+
+	// Templated values require an entity.
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.ReadOperation,
+		Storage:   config.StorageView,
+		Path:      "sts/deploy",
+	})
+	require.NoError(t, err)
+	require.True(t, resp.IsError())
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.ReadOperation,
+		Storage:   config.StorageView,
+		Path:      "sts/deploy",
+		EntityID:  "entity-id",
+	})
+	require.NoError(t, err)
+	require.False(t, resp.IsError(), "unexpected error: %v", resp.Error())
+	require.Equal(t, testAssumedRoleAccessKey("arn:aws:iam::123456789012:role/deploy"), resp.Data["access_key"])
+
+	require.Len(t, sts.requests, 2)
+	require.Equal(t, "AssumeRoleWithWebIdentity", sts.requests[0].Get("Action"))
+	assumeRole := sts.requests[1]
+	require.Equal(t, "AssumeRole", assumeRole.Get("Action"))
+	require.Contains(t, sts.authorizations[1], "Credential="+testWebIdentityAccessKey+"/")
+	require.Equal(t, "alice", assumeRole.Get("SourceIdentity"))
+	require.Equal(t, "app", assumeRole.Get("Tags.member.1.Key"))
+	require.Equal(t, "ci", assumeRole.Get("Tags.member.1.Value"))
+	require.Equal(t, "team", assumeRole.Get("Tags.member.2.Key"))
+	require.Equal(t, "payments", assumeRole.Get("Tags.member.2.Value"))
+}
+
+// TestAssumeRole_RoleChain tests that the roles of a role_chain are assumed in
+// order, each with the credentials of the previous one, before the requested
+// role.
+func TestAssumeRole_RoleChain(t *testing.T) {
+	sts := newTestSTSServer(t)
+
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+	config.System = &webIdentitySystemView{
+		StaticSystemView: logical.StaticSystemView{
+			MaxLeaseTTLVal: 12 * time.Hour,
+		},
+	}
+
+	b := Backend(config)
+	require.NoError(t, b.Setup(context.Background(), config))
+	ctx := namespace.RootContext(nil)
+
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Storage:   config.StorageView,
+		Path:      "config/root",
+		Data: map[string]interface{}{
+			"identity_token_audience": "sts.amazonaws.com",
+			"role_arn":                "arn:aws:iam::111111111111:role/vault-root",
+			"sts_endpoint":            sts.URL,
+		},
+	})
+	require.NoError(t, err)
+	require.Nil(t, resp)
+
+	const (
+		hubRole   = "arn:aws:iam::222222222222:role/hub"
+		spokeRole = "arn:aws:iam::333333333333:role/spoke"
+	)
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Storage:   config.StorageView,
+		Path:      "roles/spoke",
+		Data: map[string]interface{}{
+			"credential_type": assumedRoleCred,
+			"role_arns":       []string{spokeRole},
+			"role_chain":      []string{hubRole},
+			"policy_arns":     []string{"arn:aws:iam::aws:policy/ReadOnlyAccess"},
+		},
+	})
+	require.NoError(t, err)
+	require.Nil(t, resp)
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Storage:   config.StorageView,
+		Path:      "sts/spoke",
+		Data: map[string]interface{}{
+			"ttl": "4h",
+		},
+	})
+	require.NoError(t, err)
+	require.False(t, resp.IsError(), "unexpected error: %v", resp.Error())
+	require.Equal(t, testAssumedRoleAccessKey(spokeRole), resp.Data["access_key"])
+	require.Equal(t, []string{hubRole, spokeRole}, resp.Secret.InternalData["role_chain"])
+	require.Equal(t, []string{hubRole, spokeRole}, resp.Data["role_chain"])
+
+	require.Len(t, sts.requests, 3)
+	require.Equal(t, "AssumeRoleWithWebIdentity", sts.requests[0].Get("Action"))
+
+	hub := sts.requests[1]
+	require.Equal(t, hubRole, hub.Get("RoleArn"))
+	require.Equal(t, "900", hub.Get("DurationSeconds"))
+	require.Empty(t, hub.Get("PolicyArns.member.1.arn"))
+	require.Contains(t, sts.authorizations[1], "Credential="+testWebIdentityAccessKey+"/")
+
+	spoke := sts.requests[2]
+	require.Equal(t, spokeRole, spoke.Get("RoleArn"))
+	require.Equal(t, "3600", spoke.Get("DurationSeconds"))
+	require.Equal(t, "arn:aws:iam::aws:policy/ReadOnlyAccess", spoke.Get("PolicyArns.member.1.arn"))
+	require.Contains(t, sts.authorizations[2], "Credential="+testAssumedRoleAccessKey(hubRole)+"/")
+}
@@ -71,7 +71,8 @@ required. Defaults to 'client'.`,
 				Type: framework.TypeCommaStringSlice,
 				Description: `List of policies to attach to the token. Either "consul_policies"
 or "consul_roles" are required for Consul 1.5 and above, or just "consul_policies" if
-using Consul 1.4.`,
+using Consul 1.4. Policy names may contain identity templates, such as
+{{identity.entity.metadata.service}}, populated from the requesting entity.`,
 			},
 
 			"consul_roles": {
@@ -117,13 +118,16 @@ will be created within. Defaults to 'default'. Available in Consul 1.11 and abov
 			"service_identities": {
 				Type: framework.TypeStringSlice,
 				Description: `List of Service Identities to attach to the
-token, separated by semicolons. Available in Consul 1.5 or above.`,
+token, separated by semicolons. Service names and datacenters may contain
+identity templates populated from the requesting entity. Available in Consul
+1.5 or above.`,
 			},
 
 			"node_identities": {
 				Type: framework.TypeStringSlice,
 				Description: `List of Node Identities to attach to the
-token. Available in Consul 1.8.1 or above.`,
+token. Node names and datacenters may contain identity templates populated
+from the requesting entity. Available in Consul 1.8.1 or above.`,
 			},
 		},
 
@@ -220,6 +224,18 @@ func (b *backend) pathRolesWrite(ctx context.Context, req *logical.Request, d *f
 		consulPolicies = policies
 	}
 
+	for field, values := range map[string][]string{
+		"consul_policies":    consulPolicies,
+		"service_identities": serviceIdentities,
+		"node_identities":    nodeIdentities,
+	} {
+		for _, value := range values {
+			if _, err := framework.ValidateIdentityTemplate(value); err != nil {
+				return logical.ErrorResponse("invalid identity template in %s: %q: %s", field, value, err), nil
+			}
+		}
+	}
+
 	policyRaw, err := base64.StdEncoding.DecodeString(policy)
 	if err != nil {
 		return logical.ErrorResponse(fmt.Sprintf(
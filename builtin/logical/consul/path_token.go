@@ -100,8 +100,13 @@ func (b *backend) pathTokenRead(ctx context.Context, req *logical.Request, d *fr
 	}
 
 	// Create an ACLToken for Consul 1.4 and above
+	policies, aclServiceIdentities, aclNodeIdentities, err := populateTokenIdentities(roleConfigData, framework.IdentityTemplatePopulator(req.EntityID, b.System()))
+	if err != nil {
+		return logical.ErrorResponse("unable to populate identity templates: %s", err), nil
+	}
+
 	policyLinks := []*api.ACLTokenPolicyLink{}
-	for _, policyName := range roleConfigData.Policies {
+	for _, policyName := range policies {
 		policyLinks = append(policyLinks, &api.ACLTokenPolicyLink{
 			Name: policyName,
 		})
@@ -114,9 +119,6 @@ func (b *backend) pathTokenRead(ctx context.Context, req *logical.Request, d *fr
 		})
 	}
 
-	aclServiceIdentities := parseServiceIdentities(roleConfigData.ServiceIdentities)
-	aclNodeIdentities := parseNodeIdentities(roleConfigData.NodeIdentities)
-
 	token, _, err := c.ACL().TokenCreate(&api.ACLToken{
 		Description:       tokenName,
 		Policies:          policyLinks,
@@ -149,6 +151,47 @@ func (b *backend) pathTokenRead(ctx context.Context, req *logical.Request, d *fr
 	return s, nil
 }
 
+// populateTokenIdentities returns the Consul policies, service identities and
+// node identities of the role with their identity templates populated.
+// Service and node identities are split into their name and datacenters
+// before population, so populated values cannot add datacenters.
+func populateTokenIdentities(role roleConfig, populate func(string) (string, error)) ([]string, []*api.ACLServiceIdentity, []*api.ACLNodeIdentity, error) {
+	policies := make([]string, 0, len(role.Policies))
+	for _, policy := range role.Policies {
+		populated, err := populate(policy)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		policies = append(policies, populated)
+	}
+
+	serviceIdentities := parseServiceIdentities(role.ServiceIdentities)
+	for _, serviceIdentity := range serviceIdentities {
+		var err error
+		if serviceIdentity.ServiceName, err = populate(serviceIdentity.ServiceName); err != nil {
+			return nil, nil, nil, err
+		}
+		for i, datacenter := range serviceIdentity.Datacenters {
+			if serviceIdentity.Datacenters[i], err = populate(datacenter); err != nil {
+				return nil, nil, nil, err
+			}
+		}
+	}
+
+	nodeIdentities := parseNodeIdentities(role.NodeIdentities)
+	for _, nodeIdentity := range nodeIdentities {
+		var err error
+		if nodeIdentity.NodeName, err = populate(nodeIdentity.NodeName); err != nil {
+			return nil, nil, nil, err
+		}
+		if nodeIdentity.Datacenter, err = populate(nodeIdentity.Datacenter); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	return policies, serviceIdentities, nodeIdentities, nil
+}
+
 func parseServiceIdentities(data []string) []*api.ACLServiceIdentity {
 	aclServiceIdentities := []*api.ACLServiceIdentity{}
 
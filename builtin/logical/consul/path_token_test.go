@@ -4,10 +4,13 @@
 package consul
 
 import (
+	"context"
 	"reflect"
 	"testing"
 
 	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
 )
 
 func TestToken_parseServiceIdentities(t *testing.T) {
@@ -105,3 +108,92 @@ func TestToken_parseNodeIdentities(t *testing.T) {
 		})
 	}
 }
+
+func TestToken_populateTokenIdentities(t *testing.T) {
+	b := Backend()
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+	config.System = &logical.StaticSystemView{
+		EntityVal: &logical.Entity{
+			ID:   "entity-id",
+			Name: "entity-name",
+			Metadata: map[string]string{
+				"service": "web",
+				"dc":      "dc1",
+			},
+			Aliases: []*logical.Alias{
+				{
+					MountAccessor: "auth_userpass_1234",
+					Name:          "node-1",
+				},
+			},
+		},
+	}
+	if err := b.Setup(context.Background(), config); err != nil {
+		t.Fatal(err)
+	}
+
+	role := roleConfig{
+		Policies:          []string{"static", "{{identity.entity.metadata.service}}-policy"},
+		ServiceIdentities: []string{"{{identity.entity.metadata.service}}:{{identity.entity.metadata.dc}},dc2"},
+		NodeIdentities:    []string{"{{identity.entity.aliases.auth_userpass_1234.name}}:{{identity.entity.metadata.dc}}"},
+	}
+
+	t.Run("populated from entity", func(t *testing.T) {
+		policies, serviceIdentities, nodeIdentities, err := populateTokenIdentities(role, framework.IdentityTemplatePopulator("entity-id", b.System()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := []string{"static", "web-policy"}; !reflect.DeepEqual(policies, want) {
+			t.Fatalf("policies = %v, want %v", policies, want)
+		}
+		wantServices := []*api.ACLServiceIdentity{{ServiceName: "web", Datacenters: []string{"dc1", "dc2"}}}
+		if !reflect.DeepEqual(serviceIdentities, wantServices) {
+			t.Fatalf("service identities = %+v, want %+v", serviceIdentities[0], wantServices[0])
+		}
+		wantNodes := []*api.ACLNodeIdentity{{NodeName: "node-1", Datacenter: "dc1"}}
+		if !reflect.DeepEqual(nodeIdentities, wantNodes) {
+			t.Fatalf("node identities = %+v, want %+v", nodeIdentities[0], wantNodes[0])
+		}
+	})
+
+	t.Run("populated values cannot add datacenters", func(t *testing.T) {
+		config.System.(*logical.StaticSystemView).EntityVal.Metadata["service"] = "web:dc3"
+		defer func() {
+			config.System.(*logical.StaticSystemView).EntityVal.Metadata["service"] = "web"
+		}()
+
+		_, serviceIdentities, _, err := populateTokenIdentities(role, framework.IdentityTemplatePopulator("entity-id", b.System()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		wantServices := []*api.ACLServiceIdentity{{ServiceName: "web:dc3", Datacenters: []string{"dc1", "dc2"}}}
+		if !reflect.DeepEqual(serviceIdentities, wantServices) {
+			t.Fatalf("service identities = %+v, want %+v", serviceIdentities[0], wantServices[0])
+		}
+	})
+
+	t.Run("templated role requires entity", func(t *testing.T) {
+		if _, _, _, err := populateTokenIdentities(role, framework.IdentityTemplatePopulator("", b.System())); err == nil {
+			t.Fatal("expected error without an entity")
+		}
+	})
+
+	t.Run("static role without entity", func(t *testing.T) {
+		static := roleConfig{Policies: []string{"static"}, ServiceIdentities: []string{"api:dc1"}}
+		policies, serviceIdentities, _, err := populateTokenIdentities(static, framework.IdentityTemplatePopulator("", b.System()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(policies, []string{"static"}) || serviceIdentities[0].ServiceName != "api" {
+			t.Fatalf("unexpected result: %v %+v", policies, serviceIdentities[0])
+		}
+	})
+
+	t.Run("missing metadata key", func(t *testing.T) {
+		missing := roleConfig{Policies: []string{"{{identity.entity.metadata.missing}}"}}
+		if _, _, _, err := populateTokenIdentities(missing, framework.IdentityTemplatePopulator("entity-id", b.System())); err == nil {
+			t.Fatal("expected error for missing metadata key")
+		}
+	})
+}
@@ -103,6 +103,7 @@ func Backend(conf *logical.BackendConfig) *databaseBackend {
 			SealWrapStorage: []string{
 				"config/*",
 				"static-role/*",
+				"library-account/*",
 			},
 		},
 		Paths: framework.PathAppend(
@@ -116,10 +117,14 @@ func Backend(conf *logical.BackendConfig) *databaseBackend {
 			pathRoles(&b),
 			pathCredsCreate(&b),
 			pathRotateRootCredentials(&b),
+			pathListLibrarySets(&b),
+			pathLibrarySets(&b),
+			pathLibraryCheckOut(&b),
 		),
 
 		Secrets: []*framework.Secret{
 			secretCreds(&b),
+			secretLibraryCreds(&b),
 		},
 		Clean:             b.clean,
 		Invalidate:        b.invalidate,
@@ -132,6 +137,7 @@ func Backend(conf *logical.BackendConfig) *databaseBackend {
 	b.connections = syncmap.NewSyncMap[string, *dbPluginInstance]()
 	b.queueCtx, b.cancelQueueCtx = context.WithCancel(context.Background())
 	b.roleLocks = locksutil.CreateLocks()
+	b.connectionLocks = locksutil.CreateLocks()
 	b.schedule = &schedule.DefaultSchedule{}
 
 	return &b
@@ -181,6 +187,11 @@ type databaseBackend struct {
 	// issues with the priority queue.
 	roleLocks []*locksutil.LockEntry
 
+	// connectionLocks is used to lock modifications to connection
+	// configurations, so that config writes and manual and scheduled root
+	// credential rotations don't overwrite each other's changes.
+	connectionLocks []*locksutil.LockEntry
+
 	// the running gauge collection process
 	gaugeCollectionProcess     *metricsutil.GaugeCollectionProcess
 	gaugeCollectionProcessStop sync.Once
@@ -209,6 +209,9 @@ func TestBackend_config_connection(t *testing.T) {
 			"root_credentials_rotate_statements": []string{},
 			"password_policy":                    "",
 			"plugin_version":                     "",
+			"root_rotation_period":               int64(0),
+			"root_rotation_schedule":             "",
+			"disable_root_rotation_events":       false,
 		}
 		configReq.Operation = logical.ReadOperation
 		resp, err = b.HandleRequest(namespace.RootContext(nil), configReq)
@@ -263,6 +266,9 @@ func TestBackend_config_connection(t *testing.T) {
 			"root_credentials_rotate_statements": []string{},
 			"password_policy":                    "",
 			"plugin_version":                     "",
+			"root_rotation_period":               int64(0),
+			"root_rotation_schedule":             "",
+			"disable_root_rotation_events":       false,
 		}
 		configReq.Operation = logical.ReadOperation
 		resp, err = b.HandleRequest(namespace.RootContext(nil), configReq)
@@ -306,6 +312,9 @@ func TestBackend_config_connection(t *testing.T) {
 			"root_credentials_rotate_statements": []string{},
 			"password_policy":                    "",
 			"plugin_version":                     "",
+			"root_rotation_period":               int64(0),
+			"root_rotation_schedule":             "",
+			"disable_root_rotation_events":       false,
 		}
 		configReq.Operation = logical.ReadOperation
 		resp, err = b.HandleRequest(namespace.RootContext(nil), configReq)
@@ -765,6 +774,9 @@ func TestBackend_connectionCrud(t *testing.T) {
 		"root_credentials_rotate_statements": []any{},
 		"password_policy":                    "",
 		"plugin_version":                     "",
+		"root_rotation_period":               json.Number("0"),
+		"root_rotation_schedule":             "",
+		"disable_root_rotation_events":       false,
 	}
 	resp, err = client.Read("database/config/plugin-test")
 	if err != nil {
@@ -10,6 +10,7 @@ import (
 	"net/url"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/fatih/structs"
 	"github.com/hashicorp/go-uuid"
@@ -18,6 +19,7 @@ import (
 	v5 "github.com/hashicorp/vault/sdk/database/dbplugin/v5"
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/helper/consts"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
 	"github.com/hashicorp/vault/sdk/helper/pluginutil"
 	"github.com/hashicorp/vault/sdk/logical"
 )
@@ -41,6 +43,27 @@ type DatabaseConfig struct {
 	RootCredentialsRotateStatements []string `json:"root_credentials_rotate_statements" structs:"root_credentials_rotate_statements" mapstructure:"root_credentials_rotate_statements"`
 
 	PasswordPolicy string `json:"password_policy" structs:"password_policy" mapstructure:"password_policy"`
+
+	// RootRotationPeriod and RootRotationSchedule configure the automatic
+	// rotation of the root credential. At most one of them is set.
+	RootRotationPeriod   time.Duration `json:"root_rotation_period" structs:"-" mapstructure:"root_rotation_period"`
+	RootRotationSchedule string        `json:"root_rotation_schedule" structs:"root_rotation_schedule" mapstructure:"root_rotation_schedule"`
+
+	// DisableRootRotationEvents stops events from being sent when the root
+	// credential is rotated.
+	DisableRootRotationEvents bool `json:"disable_root_rotation_events" structs:"disable_root_rotation_events" mapstructure:"disable_root_rotation_events"`
+
+	// LastRootRotation is the time of the last successful root credential
+	// rotation, and NextRootRotation the time the next automatic rotation is
+	// due. NextRootRotation is zero when automatic rotation is disabled.
+	LastRootRotation time.Time `json:"last_root_rotation" structs:"-" mapstructure:"last_root_rotation"`
+	NextRootRotation time.Time `json:"next_root_rotation" structs:"-" mapstructure:"next_root_rotation"`
+}
+
+// rootRotationEnabled returns true if the root credential of the connection is
+// rotated automatically.
+func (c *DatabaseConfig) rootRotationEnabled() bool {
+	return c.RootRotationPeriod > 0 || c.RootRotationSchedule != ""
 }
 
 func (c *DatabaseConfig) SupportsCredentialType(credentialType v5.CredentialType) bool {
@@ -254,6 +277,23 @@ func pathConfigurePluginConnection(b *databaseBackend) *framework.Path {
 				Type:        framework.TypeString,
 				Description: `Password policy to use when generating passwords.`,
 			},
+			"root_rotation_period": {
+				Type: framework.TypeDurationSecond,
+				Description: `Period for automatically rotating the root
+				credential. Mutually exclusive with "root_rotation_schedule".
+				A zero value disables periodic rotation.`,
+			},
+			"root_rotation_schedule": {
+				Type: framework.TypeString,
+				Description: `Cron-style schedule for automatically rotating the
+				root credential. Mutually exclusive with "root_rotation_period".
+				An empty value disables scheduled rotation.`,
+			},
+			"disable_root_rotation_events": {
+				Type: framework.TypeBool,
+				Description: `If true, no events are sent when the root
+				credential is rotated. Defaults to false.`,
+			},
 		},
 
 		ExistenceCheck: b.connectionExistenceCheck(),
@@ -392,6 +432,13 @@ func (b *databaseBackend) connectionReadHandler() framework.OperationFunc {
 		}
 
 		resp.Data = structs.New(config).Map()
+		resp.Data["root_rotation_period"] = int64(config.RootRotationPeriod.Seconds())
+		if !config.LastRootRotation.IsZero() {
+			resp.Data["last_root_rotation"] = config.LastRootRotation
+		}
+		if !config.NextRootRotation.IsZero() {
+			resp.Data["next_root_rotation"] = config.NextRootRotation
+		}
 		return resp, nil
 	}
 }
@@ -404,11 +451,19 @@ func (b *databaseBackend) connectionDeleteHandler() framework.OperationFunc {
 			return logical.ErrorResponse(respErrEmptyName), nil
 		}
 
+		lock := locksutil.LockForKey(b.connectionLocks, name)
+		lock.Lock()
+		defer lock.Unlock()
+
 		err := req.Storage.Delete(ctx, fmt.Sprintf("config/%s", name))
 		if err != nil {
 			return nil, fmt.Errorf("failed to delete connection configuration: %w", err)
 		}
 
+		if err := req.Storage.Delete(ctx, rootRotationHistoryPath+name); err != nil {
+			return nil, fmt.Errorf("failed to delete root rotation history: %w", err)
+		}
+
 		if err := b.ClearConnection(name); err != nil {
 			return nil, err
 		}
@@ -429,6 +484,10 @@ func (b *databaseBackend) connectionWriteHandler() framework.OperationFunc {
 			return logical.ErrorResponse(respErrEmptyName), nil
 		}
 
+		lock := locksutil.LockForKey(b.connectionLocks, name)
+		lock.Lock()
+		defer lock.Unlock()
+
 		// Baseline
 		config := &DatabaseConfig{}
 
@@ -472,6 +531,44 @@ func (b *databaseBackend) connectionWriteHandler() framework.OperationFunc {
 			config.PasswordPolicy = passwordPolicyRaw.(string)
 		}
 
+		if disableEventsRaw, ok := data.GetOk("disable_root_rotation_events"); ok {
+			config.DisableRootRotationEvents = disableEventsRaw.(bool)
+		}
+
+		rotationPeriodRaw, rotationPeriodOk := data.GetOk("root_rotation_period")
+		rotationScheduleRaw, rotationScheduleOk := data.GetOk("root_rotation_schedule")
+		if rotationPeriodOk && rotationScheduleOk &&
+			rotationPeriodRaw.(int) != 0 && rotationScheduleRaw.(string) != "" {
+			return logical.ErrorResponse("mutually exclusive fields root_rotation_period and root_rotation_schedule were both specified; only one of them can be provided"), nil
+		}
+		if rotationPeriodOk {
+			rotationPeriod := time.Duration(rotationPeriodRaw.(int)) * time.Second
+			if rotationPeriod != 0 && rotationPeriod < minRootRotationPeriod {
+				return logical.ErrorResponse("root_rotation_period must be %d seconds or more", int(minRootRotationPeriod.Seconds())), nil
+			}
+			config.RootRotationPeriod = rotationPeriod
+			if rotationPeriod != 0 {
+				config.RootRotationSchedule = ""
+			}
+		}
+		if rotationScheduleOk {
+			rotationSchedule := rotationScheduleRaw.(string)
+			if rotationSchedule != "" {
+				if _, err := b.schedule.Parse(rotationSchedule); err != nil {
+					return logical.ErrorResponse("could not parse root_rotation_schedule: %s", err), nil
+				}
+				config.RootRotationPeriod = 0
+			}
+			config.RootRotationSchedule = rotationSchedule
+		}
+		if rotationPeriodOk || rotationScheduleOk || !config.rootRotationEnabled() {
+			next, err := b.nextRootRotationTime(config, time.Now())
+			if err != nil {
+				return nil, err
+			}
+			config.NextRootRotation = next
+		}
+
 		// Remove these entries from the data before we store it keyed under
 		// ConnectionDetails.
 		delete(data.Raw, "name")
@@ -481,6 +578,9 @@ func (b *databaseBackend) connectionWriteHandler() framework.OperationFunc {
 		delete(data.Raw, "verify_connection")
 		delete(data.Raw, "root_rotation_statements")
 		delete(data.Raw, "password_policy")
+		delete(data.Raw, "root_rotation_period")
+		delete(data.Raw, "root_rotation_schedule")
+		delete(data.Raw, "disable_root_rotation_events")
 
 		id, err := uuid.GenerateUUID()
 		if err != nil {
@@ -687,6 +787,10 @@ accepts:
 	* "verify_connection" (default: true) - A boolean value denoting if the plugin should verify
 	   it is able to connect to the database using the provided connection
        details.
+
+	* "root_rotation_period" or "root_rotation_schedule" - Rotate the root
+	   credential automatically, either periodically or on a cron-style
+	   schedule.
 `
 
 const pathResetConnectionHelpSyn = `
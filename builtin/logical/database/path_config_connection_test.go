@@ -7,6 +7,7 @@ import (
 	"context"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/vault/helper/namespace"
 	"github.com/hashicorp/vault/helper/versions"
@@ -167,3 +168,117 @@ func TestWriteConfig_HelpfulErrorMessageWhenBuiltinOverridden(t *testing.T) {
 		t.Fatalf("expected overridden error but got: %s", resp.Error())
 	}
 }
+
+func TestWriteConfig_RootRotation(t *testing.T) {
+	cluster, sys := getCluster(t)
+	t.Cleanup(cluster.Cleanup)
+
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+	config.System = sys
+
+	b, err := Factory(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Cleanup(context.Background())
+
+	writeConfig := func(data map[string]interface{}) *logical.Response {
+		t.Helper()
+		data["connection_url"] = "test"
+		data["plugin_name"] = "hana-database-plugin"
+		data["verify_connection"] = false
+		resp, err := b.HandleRequest(namespace.RootContext(nil), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "config/plugin-test",
+			Storage:   config.StorageView,
+			Data:      data,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+	readConfig := func() map[string]interface{} {
+		t.Helper()
+		resp, err := b.HandleRequest(namespace.RootContext(nil), &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "config/plugin-test",
+			Storage:   config.StorageView,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("err:%s resp:%#v\n", err, resp)
+		}
+		return resp.Data
+	}
+
+	resp := writeConfig(map[string]interface{}{
+		"root_rotation_period":   "1h",
+		"root_rotation_schedule": "0 * * * * *",
+	})
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected error for mutually exclusive fields, got %#v", resp)
+	}
+
+	resp = writeConfig(map[string]interface{}{
+		"root_rotation_period": "10s",
+	})
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected error for short root_rotation_period, got %#v", resp)
+	}
+
+	resp = writeConfig(map[string]interface{}{
+		"root_rotation_schedule": "not a schedule",
+	})
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected error for invalid root_rotation_schedule, got %#v", resp)
+	}
+
+	if resp := writeConfig(map[string]interface{}{
+		"root_rotation_period":         "1h",
+		"disable_root_rotation_events": true,
+	}); resp != nil && resp.IsError() {
+		t.Fatalf("unexpected error: %#v", resp)
+	}
+	data := readConfig()
+	if data["root_rotation_period"] != int64(3600) {
+		t.Fatalf("expected root_rotation_period 3600, got %v", data["root_rotation_period"])
+	}
+	if data["disable_root_rotation_events"] != true {
+		t.Fatalf("expected disable_root_rotation_events, got %v", data["disable_root_rotation_events"])
+	}
+	next, ok := data["next_root_rotation"].(time.Time)
+	if !ok || next.Before(time.Now().Add(59*time.Minute)) {
+		t.Fatalf("unexpected next_root_rotation %v", data["next_root_rotation"])
+	}
+	if _, ok := data["root_rotation_period"]; !ok {
+		t.Fatal("expected root_rotation_period in response")
+	}
+	for key := range data["connection_details"].(map[string]interface{}) {
+		if strings.HasPrefix(key, "root_rotation") || key == "disable_root_rotation_events" {
+			t.Fatalf("unexpected connection detail %q", key)
+		}
+	}
+
+	// Switching to a schedule clears the period.
+	if resp := writeConfig(map[string]interface{}{
+		"root_rotation_schedule": "0 * * * *",
+	}); resp != nil && resp.IsError() {
+		t.Fatalf("unexpected error: %#v", resp)
+	}
+	data = readConfig()
+	if data["root_rotation_period"] != int64(0) || data["root_rotation_schedule"] != "0 * * * *" {
+		t.Fatalf("unexpected rotation settings: %v, %v", data["root_rotation_period"], data["root_rotation_schedule"])
+	}
+
+	// Clearing the schedule disables automatic rotation.
+	if resp := writeConfig(map[string]interface{}{
+		"root_rotation_schedule": "",
+	}); resp != nil && resp.IsError() {
+		t.Fatalf("unexpected error: %#v", resp)
+	}
+	data = readConfig()
+	if _, ok := data["next_root_rotation"]; ok {
+		t.Fatalf("expected no next_root_rotation, got %v", data["next_root_rotation"])
+	}
+}
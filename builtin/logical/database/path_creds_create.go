@@ -256,7 +256,7 @@ func (b *databaseBackend) pathStaticCredsRead() framework.OperationFunc {
 		}
 
 		respData := map[string]interface{}{
-			"username":            role.StaticAccount.Username,
+			"username":            role.StaticAccount.ActiveUsername(),
 			"ttl":                 role.StaticAccount.CredentialTTL().Seconds(),
 			"last_vault_rotation": role.StaticAccount.LastVaultRotation,
 		}
@@ -272,9 +272,9 @@ func (b *databaseBackend) pathStaticCredsRead() framework.OperationFunc {
 
 		switch role.CredentialType {
 		case v5.CredentialTypePassword:
-			respData["password"] = role.StaticAccount.Password
+			respData["password"] = role.StaticAccount.ActivePassword()
 		case v5.CredentialTypeRSAPrivateKey:
-			respData["rsa_private_key"] = string(role.StaticAccount.PrivateKey)
+			respData["rsa_private_key"] = string(role.StaticAccount.ActivePrivateKey())
 		}
 
 		return &logical.Response{
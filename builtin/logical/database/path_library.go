@@ -0,0 +1,685 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/go-secure-stdlib/strutil"
+	v5 "github.com/hashicorp/vault/sdk/database/dbplugin/v5"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	databaseLibraryPath        = "library/"
+	databaseLibraryAccountPath = "library-account/"
+
+	// WAL storage key used for library account password rotations
+	libraryWALKey = "libraryRotationKey"
+)
+
+func pathListLibrarySets(b *databaseBackend) []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: "library/?$",
+
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: operationPrefixDatabase,
+				OperationVerb:   "list",
+				OperationSuffix: "library-sets",
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ListOperation: b.pathLibrarySetList,
+			},
+
+			HelpSynopsis:    pathLibrarySetHelpSyn,
+			HelpDescription: pathLibrarySetHelpDesc,
+		},
+	}
+}
+
+func pathLibrarySets(b *databaseBackend) []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: "library/" + framework.GenericNameRegex("name"),
+
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: operationPrefixDatabase,
+				OperationSuffix: "library-set",
+			},
+
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeString,
+					Description: "Name of the library set.",
+				},
+				"db_name": {
+					Type:        framework.TypeString,
+					Description: "Name of the database connection the accounts belong to.",
+				},
+				"service_account_names": {
+					Type:        framework.TypeCommaStringSlice,
+					Description: "Pre-existing database accounts that can be checked out from this set.",
+				},
+				"ttl": {
+					Type:        framework.TypeDurationSecond,
+					Description: "Default lease duration of a check-out. Defaults to the mount's default TTL.",
+				},
+				"max_ttl": {
+					Type:        framework.TypeDurationSecond,
+					Description: "Maximum time an account can stay checked out before it is returned automatically. Defaults to the mount's max TTL.",
+				},
+				"disable_check_in_enforcement": {
+					Type:        framework.TypeBool,
+					Default:     false,
+					Description: "If set, any caller with access to the check-in path may check in accounts borrowed by someone else.",
+				},
+				"rotation_statements": {
+					Type: framework.TypeStringSlice,
+					Description: `Specifies the database statements to be executed to
+				rotate the accounts credentials. Not every plugin type will support
+				this functionality. See the plugin's API page for more information on
+				support and formatting for this parameter.`,
+				},
+				"password_policy": {
+					Type:        framework.TypeString,
+					Description: "Password policy used to generate passwords on rotation. Defaults to the connection's password policy.",
+				},
+			},
+
+			ExistenceCheck: b.pathLibrarySetExistenceCheck,
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.CreateOperation: &framework.PathOperation{
+					Callback: b.pathLibrarySetCreateUpdate,
+					DisplayAttrs: &framework.DisplayAttributes{
+						OperationVerb: "write",
+					},
+				},
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.pathLibrarySetCreateUpdate,
+					DisplayAttrs: &framework.DisplayAttributes{
+						OperationVerb: "write",
+					},
+				},
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.pathLibrarySetRead,
+					DisplayAttrs: &framework.DisplayAttributes{
+						OperationVerb: "read",
+					},
+				},
+				logical.DeleteOperation: &framework.PathOperation{
+					Callback: b.pathLibrarySetDelete,
+					DisplayAttrs: &framework.DisplayAttributes{
+						OperationVerb: "delete",
+					},
+				},
+			},
+
+			HelpSynopsis:    pathLibrarySetHelpSyn,
+			HelpDescription: pathLibrarySetHelpDesc,
+		},
+		{
+			Pattern: "library/" + framework.GenericNameRegex("name") + "/status$",
+
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: operationPrefixDatabase,
+				OperationVerb:   "read",
+				OperationSuffix: "library-set-status",
+			},
+
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeString,
+					Description: "Name of the library set.",
+				},
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation: b.pathLibrarySetStatus,
+			},
+
+			HelpSynopsis:    pathLibrarySetStatusHelpSyn,
+			HelpDescription: pathLibrarySetStatusHelpDesc,
+		},
+	}
+}
+
+// librarySet is a named group of pre-created database accounts that can be
+// checked out exclusively.
+type librarySet struct {
+	DBName                    string        `json:"db_name"`
+	ServiceAccountNames       []string      `json:"service_account_names"`
+	TTL                       time.Duration `json:"ttl"`
+	MaxTTL                    time.Duration `json:"max_ttl"`
+	DisableCheckInEnforcement bool          `json:"disable_check_in_enforcement"`
+	RotationStatements        []string      `json:"rotation_statements"`
+	PasswordPolicy            string        `json:"password_policy"`
+}
+
+// libraryAccount holds the current credential and check-out state of a single
+// account in a library set.
+type libraryAccount struct {
+	Password          string    `json:"password"`
+	LastVaultRotation time.Time `json:"last_vault_rotation"`
+
+	// Available is false while the account is checked out. CheckoutID
+	// identifies the lease of the current check-out so that a stale lease
+	// cannot return an account that has since been checked out again.
+	Available                   bool      `json:"available"`
+	CheckoutID                  string    `json:"checkout_id,omitempty"`
+	BorrowerEntityID            string    `json:"borrower_entity_id,omitempty"`
+	BorrowerClientTokenAccessor string    `json:"borrower_client_token_accessor,omitempty"`
+	CheckedOutAt                time.Time `json:"checked_out_at,omitempty"`
+}
+
+// libraryRotationWAL is used to roll a library account's password forward in
+// the event that storing a rotated password fails.
+type libraryRotationWAL struct {
+	SetName     string `json:"set_name" mapstructure:"set_name"`
+	Username    string `json:"username" mapstructure:"username"`
+	NewPassword string `json:"new_password" mapstructure:"new_password"`
+}
+
+func libraryLockKey(setName string) string {
+	return databaseLibraryPath + setName
+}
+
+func (b *databaseBackend) librarySet(ctx context.Context, s logical.Storage, name string) (*librarySet, error) {
+	entry, err := s.Get(ctx, databaseLibraryPath+name)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var result librarySet
+	if err := entry.DecodeJSON(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (b *databaseBackend) libraryAccount(ctx context.Context, s logical.Storage, setName, username string) (*libraryAccount, error) {
+	entry, err := s.Get(ctx, databaseLibraryAccountPath+setName+"/"+username)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var result libraryAccount
+	if err := entry.DecodeJSON(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func storeLibraryAccount(ctx context.Context, s logical.Storage, setName, username string, account *libraryAccount) error {
+	entry, err := logical.StorageEntryJSON(databaseLibraryAccountPath+setName+"/"+username, account)
+	if err != nil {
+		return err
+	}
+
+	return s.Put(ctx, entry)
+}
+
+// librarySetForAccount returns the name of the library set, other than
+// skipSet, that manages the account of the database, if any.
+func (b *databaseBackend) librarySetForAccount(ctx context.Context, s logical.Storage, dbName, account, skipSet string) (string, error) {
+	setNames, err := s.List(ctx, databaseLibraryPath)
+	if err != nil {
+		return "", err
+	}
+	for _, setName := range setNames {
+		if setName == skipSet {
+			continue
+		}
+		set, err := b.librarySet(ctx, s, setName)
+		if err != nil {
+			return "", err
+		}
+		if set != nil && set.DBName == dbName && strutil.StrListContains(set.ServiceAccountNames, account) {
+			return setName, nil
+		}
+	}
+	return "", nil
+}
+
+// staticRoleForAccount returns the name of the static role that manages
+// the account of the database, if any.
+func (b *databaseBackend) staticRoleForAccount(ctx context.Context, s logical.Storage, dbName, account string) (string, error) {
+	roleNames, err := s.List(ctx, databaseStaticRolePath)
+	if err != nil {
+		return "", err
+	}
+	for _, roleName := range roleNames {
+		role, err := b.StaticRole(ctx, s, roleName)
+		if err != nil {
+			return "", err
+		}
+		if role == nil || role.StaticAccount == nil || role.DBName != dbName {
+			continue
+		}
+		if role.StaticAccount.Username == account || role.StaticAccount.SecondaryUsername == account {
+			return roleName, nil
+		}
+	}
+	return "", nil
+}
+
+func (b *databaseBackend) pathLibrarySetExistenceCheck(ctx context.Context, req *logical.Request, data *framework.FieldData) (bool, error) {
+	set, err := b.librarySet(ctx, req.Storage, data.Get("name").(string))
+	if err != nil {
+		return false, err
+	}
+	return set != nil, nil
+}
+
+func (b *databaseBackend) pathLibrarySetList(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	entries, err := req.Storage.List(ctx, databaseLibraryPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return logical.ListResponse(entries), nil
+}
+
+func (b *databaseBackend) pathLibrarySetRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	set, err := b.librarySet(ctx, req.Storage, data.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if set == nil {
+		return nil, nil
+	}
+
+	rotationStatements := set.RotationStatements
+	if rotationStatements == nil {
+		rotationStatements = []string{}
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"db_name":                      set.DBName,
+			"service_account_names":        set.ServiceAccountNames,
+			"ttl":                          set.TTL.Seconds(),
+			"max_ttl":                      set.MaxTTL.Seconds(),
+			"disable_check_in_enforcement": set.DisableCheckInEnforcement,
+			"rotation_statements":          rotationStatements,
+			"password_policy":              set.PasswordPolicy,
+		},
+	}, nil
+}
+
+func (b *databaseBackend) pathLibrarySetCreateUpdate(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+	if name == "" {
+		return logical.ErrorResponse(respErrEmptyName), nil
+	}
+
+	lock := locksutil.LockForKey(b.roleLocks, libraryLockKey(name))
+	lock.Lock()
+	defer lock.Unlock()
+
+	set, err := b.librarySet(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+
+	createOperation := set == nil
+	if set == nil {
+		set = &librarySet{}
+	}
+	previousAccounts := set.ServiceAccountNames
+
+	if dbNameRaw, ok := data.GetOk("db_name"); ok {
+		dbName := dbNameRaw.(string)
+		if !createOperation && dbName != set.DBName {
+			return logical.ErrorResponse("cannot update the database of a library set"), nil
+		}
+		set.DBName = dbName
+	}
+	if set.DBName == "" {
+		return logical.ErrorResponse("database name is a required field"), nil
+	}
+
+	if accountsRaw, ok := data.GetOk("service_account_names"); ok {
+		set.ServiceAccountNames = strutil.RemoveDuplicates(accountsRaw.([]string), false)
+	}
+	if len(set.ServiceAccountNames) == 0 {
+		return logical.ErrorResponse("at least one service account name is required"), nil
+	}
+
+	if ttlRaw, ok := data.GetOk("ttl"); ok {
+		set.TTL = time.Duration(ttlRaw.(int)) * time.Second
+	}
+	if maxTTLRaw, ok := data.GetOk("max_ttl"); ok {
+		set.MaxTTL = time.Duration(maxTTLRaw.(int)) * time.Second
+	}
+	if set.MaxTTL != 0 && set.TTL > set.MaxTTL {
+		return logical.ErrorResponse("ttl cannot be greater than max_ttl"), nil
+	}
+
+	if raw, ok := data.GetOk("disable_check_in_enforcement"); ok {
+		set.DisableCheckInEnforcement = raw.(bool)
+	}
+	if raw, ok := data.GetOk("rotation_statements"); ok {
+		set.RotationStatements = raw.([]string)
+	}
+	if raw, ok := data.GetOk("password_policy"); ok {
+		set.PasswordPolicy = raw.(string)
+	}
+
+	dbConfig, err := b.DatabaseConfig(ctx, req.Storage, set.DBName)
+	if err != nil {
+		return nil, err
+	}
+	if !strutil.StrListContains(dbConfig.AllowedRoles, "*") && !strutil.StrListContainsGlob(dbConfig.AllowedRoles, name) {
+		return logical.ErrorResponse("%q is not an allowed role", name), nil
+	}
+	if !dbConfig.SupportsCredentialType(v5.CredentialTypePassword) {
+		return logical.ErrorResponse("library sets require a database that supports password credentials"), nil
+	}
+
+	// An account can only be lent out by a single set, otherwise two
+	// borrowers could hold it at the same time. It cannot be a static
+	// role's account either, otherwise its rotations would change the
+	// password of a borrower mid-lease.
+	for _, account := range set.ServiceAccountNames {
+		otherName, err := b.librarySetForAccount(ctx, req.Storage, set.DBName, account, name)
+		if err != nil {
+			return nil, err
+		}
+		if otherName != "" {
+			return logical.ErrorResponse("service account %q is already managed by library set %q", account, otherName), nil
+		}
+		roleName, err := b.staticRoleForAccount(ctx, req.Storage, set.DBName, account)
+		if err != nil {
+			return nil, err
+		}
+		if roleName != "" {
+			return logical.ErrorResponse("service account %q is already managed by static role %q", account, roleName), nil
+		}
+	}
+
+	// Accounts can only leave the set while they are not lent out
+	var removed []string
+	for _, account := range previousAccounts {
+		if strutil.StrListContains(set.ServiceAccountNames, account) {
+			continue
+		}
+		entry, err := b.libraryAccount(ctx, req.Storage, name, account)
+		if err != nil {
+			return nil, err
+		}
+		if entry != nil && !entry.Available {
+			return logical.ErrorResponse("cannot remove service account %q while it is checked out", account), nil
+		}
+		removed = append(removed, account)
+	}
+
+	// Take over management of new accounts by rotating their passwords
+	for _, account := range set.ServiceAccountNames {
+		if strutil.StrListContains(previousAccounts, account) {
+			continue
+		}
+		entry := &libraryAccount{Available: true}
+		if err := b.rotateLibraryAccount(ctx, req.Storage, name, set, account, entry); err != nil {
+			return nil, fmt.Errorf("unable to rotate password for service account %q: %w", account, err)
+		}
+	}
+
+	for _, account := range removed {
+		if err := req.Storage.Delete(ctx, databaseLibraryAccountPath+name+"/"+account); err != nil {
+			return nil, err
+		}
+	}
+
+	entry, err := logical.StorageEntryJSON(databaseLibraryPath+name, set)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	b.dbEvent(ctx, fmt.Sprintf("library-set-%s", req.Operation), req.Path, name, true)
+	return nil, nil
+}
+
+func (b *databaseBackend) pathLibrarySetDelete(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+	if name == "" {
+		return logical.ErrorResponse(respErrEmptyName), nil
+	}
+
+	lock := locksutil.LockForKey(b.roleLocks, libraryLockKey(name))
+	lock.Lock()
+	defer lock.Unlock()
+
+	set, err := b.librarySet(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if set == nil {
+		return nil, nil
+	}
+
+	for _, account := range set.ServiceAccountNames {
+		entry, err := b.libraryAccount(ctx, req.Storage, name, account)
+		if err != nil {
+			return nil, err
+		}
+		if entry != nil && !entry.Available {
+			return logical.ErrorResponse("cannot delete library set while service account %q is checked out", account), nil
+		}
+	}
+
+	for _, account := range set.ServiceAccountNames {
+		if err := req.Storage.Delete(ctx, databaseLibraryAccountPath+name+"/"+account); err != nil {
+			return nil, err
+		}
+	}
+	if err := req.Storage.Delete(ctx, databaseLibraryPath+name); err != nil {
+		return nil, err
+	}
+
+	b.dbEvent(ctx, "library-set-delete", req.Path, name, true)
+	return nil, nil
+}
+
+func (b *databaseBackend) pathLibrarySetStatus(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+
+	lock := locksutil.LockForKey(b.roleLocks, libraryLockKey(name))
+	lock.RLock()
+	defer lock.RUnlock()
+
+	set, err := b.librarySet(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if set == nil {
+		return logical.ErrorResponse("unknown library set: %s", name), nil
+	}
+
+	status := make(map[string]interface{}, len(set.ServiceAccountNames))
+	for _, account := range set.ServiceAccountNames {
+		entry, err := b.libraryAccount(ctx, req.Storage, name, account)
+		if err != nil {
+			return nil, err
+		}
+		if entry == nil {
+			continue
+		}
+
+		accountStatus := map[string]interface{}{
+			"available": entry.Available,
+		}
+		if !entry.Available {
+			accountStatus["borrower_entity_id"] = entry.BorrowerEntityID
+			accountStatus["borrower_client_token_accessor"] = entry.BorrowerClientTokenAccessor
+			accountStatus["checked_out_at"] = entry.CheckedOutAt
+		}
+		status[account] = accountStatus
+	}
+
+	return &logical.Response{
+		Data: status,
+	}, nil
+}
+
+// rotateLibraryAccount sets a new password on a library account and stores
+// it. A WAL entry is kept until the new password has been persisted so that a
+// partial failure can be rolled forward by walRollback.
+func (b *databaseBackend) rotateLibraryAccount(ctx context.Context, s logical.Storage, setName string, set *librarySet, username string, account *libraryAccount) error {
+	dbConfig, err := b.DatabaseConfig(ctx, s, set.DBName)
+	if err != nil {
+		return err
+	}
+
+	dbi, err := b.GetConnection(ctx, s, set.DBName)
+	if err != nil {
+		return err
+	}
+
+	dbi.RLock()
+	defer dbi.RUnlock()
+
+	generator := passwordGenerator{PasswordPolicy: set.PasswordPolicy}
+	if generator.PasswordPolicy == "" {
+		generator.PasswordPolicy = dbConfig.PasswordPolicy
+	}
+	newPassword, err := generator.generate(ctx, b, dbi.database)
+	if err != nil {
+		b.CloseIfShutdown(dbi, err)
+		return fmt.Errorf("failed to generate password: %w", err)
+	}
+
+	walID, err := framework.PutWAL(ctx, s, libraryWALKey, &libraryRotationWAL{
+		SetName:     setName,
+		Username:    username,
+		NewPassword: newPassword,
+	})
+	if err != nil {
+		return fmt.Errorf("error writing WAL entry: %w", err)
+	}
+
+	updateReq := v5.UpdateUserRequest{
+		Username:       username,
+		CredentialType: v5.CredentialTypePassword,
+		Password: &v5.ChangePassword{
+			NewPassword: newPassword,
+			Statements: v5.Statements{
+				Commands: set.RotationStatements,
+			},
+		},
+	}
+	if _, err := dbi.database.UpdateUser(ctx, updateReq, false); err != nil {
+		b.CloseIfShutdown(dbi, err)
+		return fmt.Errorf("error setting credentials: %w", err)
+	}
+
+	account.Password = newPassword
+	account.LastVaultRotation = time.Now()
+	if err := storeLibraryAccount(ctx, s, setName, username, account); err != nil {
+		return err
+	}
+
+	if err := framework.DeleteWAL(ctx, s, walID); err != nil {
+		b.Logger().Warn("unable to delete WAL", "error", err, "WAL ID", walID)
+	}
+	return nil
+}
+
+// rollbackLibraryRotation rolls a library account's password forward to the
+// password recorded in the WAL entry if storage does not already hold it.
+func (b *databaseBackend) rollbackLibraryRotation(ctx context.Context, s logical.Storage, wal libraryRotationWAL) error {
+	lock := locksutil.LockForKey(b.roleLocks, libraryLockKey(wal.SetName))
+	lock.Lock()
+	defer lock.Unlock()
+
+	set, err := b.librarySet(ctx, s, wal.SetName)
+	if err != nil {
+		return err
+	}
+	if set == nil || !strutil.StrListContains(set.ServiceAccountNames, wal.Username) {
+		// The account is no longer managed, nothing to reconcile
+		return nil
+	}
+
+	account, err := b.libraryAccount(ctx, s, wal.SetName, wal.Username)
+	if err != nil {
+		return err
+	}
+	if account == nil {
+		// The set was being created and never stored the account; it will
+		// be rotated again when the set is written.
+		return nil
+	}
+	if account.Password == wal.NewPassword {
+		return nil
+	}
+
+	dbi, err := b.GetConnection(ctx, s, set.DBName)
+	if err != nil {
+		return err
+	}
+
+	dbi.RLock()
+	defer dbi.RUnlock()
+
+	updateReq := v5.UpdateUserRequest{
+		Username:       wal.Username,
+		CredentialType: v5.CredentialTypePassword,
+		Password: &v5.ChangePassword{
+			NewPassword: wal.NewPassword,
+			Statements: v5.Statements{
+				Commands: set.RotationStatements,
+			},
+		},
+	}
+	if _, err := dbi.database.UpdateUser(ctx, updateReq, false); err != nil {
+		b.CloseIfShutdown(dbi, err)
+		return err
+	}
+
+	account.Password = wal.NewPassword
+	account.LastVaultRotation = time.Now()
+	return storeLibraryAccount(ctx, s, wal.SetName, wal.Username, account)
+}
+
+var errLibraryAccountNotCheckedOut = errors.New("service account is not checked out")
+
+const pathLibrarySetHelpSyn = `
+Manage library sets of database accounts that can be checked out.
+`
+
+const pathLibrarySetHelpDesc = `
+This path lets you manage library sets. A library set is a group of
+pre-existing database accounts that Vault lends out exclusively: an account
+that is checked out cannot be checked out again until it is checked in, at
+which point Vault rotates its password. Accounts that are not checked in
+before their lease reaches max_ttl are returned automatically.
+`
+
+const pathLibrarySetStatusHelpSyn = `
+Read the check-out status of the accounts in a library set.
+`
+
+const pathLibrarySetStatusHelpDesc = `
+This path returns, for every account in the library set, whether it is
+available and, if it is checked out, who borrowed it.
+`
@@ -0,0 +1,331 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/go-secure-stdlib/strutil"
+	"github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func pathLibraryCheckOut(b *databaseBackend) []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: "library/" + framework.GenericNameRegex("name") + "/check-out$",
+
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: operationPrefixDatabase,
+				OperationVerb:   "check-out",
+				OperationSuffix: "library-account",
+			},
+
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeString,
+					Description: "Name of the library set.",
+				},
+				"ttl": {
+					Type:        framework.TypeDurationSecond,
+					Description: "Requested lease duration of the check-out. Cannot exceed the set's max_ttl.",
+				},
+			},
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.pathLibraryCheckOut,
+				},
+			},
+
+			HelpSynopsis:    pathLibraryCheckOutHelpSyn,
+			HelpDescription: pathLibraryCheckOutHelpDesc,
+		},
+		{
+			Pattern: "library/" + framework.GenericNameRegex("name") + "/check-in$",
+
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: operationPrefixDatabase,
+				OperationVerb:   "check-in",
+				OperationSuffix: "library-accounts",
+			},
+
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeString,
+					Description: "Name of the library set.",
+				},
+				"service_account_names": {
+					Type:        framework.TypeCommaStringSlice,
+					Description: "Accounts to check in. Defaults to every account of the set checked out by the caller.",
+				},
+			},
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.pathLibraryCheckIn(false),
+				},
+			},
+
+			HelpSynopsis:    pathLibraryCheckInHelpSyn,
+			HelpDescription: pathLibraryCheckInHelpDesc,
+		},
+		{
+			Pattern: "library/manage/" + framework.GenericNameRegex("name") + "/check-in$",
+
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: operationPrefixDatabase,
+				OperationVerb:   "force-check-in",
+				OperationSuffix: "library-accounts",
+			},
+
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeString,
+					Description: "Name of the library set.",
+				},
+				"service_account_names": {
+					Type:        framework.TypeCommaStringSlice,
+					Description: "Accounts to check in. Defaults to every checked out account of the set.",
+				},
+			},
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.pathLibraryCheckIn(true),
+				},
+			},
+
+			HelpSynopsis:    pathLibraryManageCheckInHelpSyn,
+			HelpDescription: pathLibraryManageCheckInHelpDesc,
+		},
+	}
+}
+
+func (b *databaseBackend) pathLibraryCheckOut(ctx context.Context, req *logical.Request, data *framework.FieldData) (resp *logical.Response, err error) {
+	name := data.Get("name").(string)
+	modified := false
+	defer func() {
+		if err == nil && (resp == nil || !resp.IsError()) {
+			b.dbEvent(ctx, "library-check-out", req.Path, name, modified)
+		} else {
+			b.dbEvent(ctx, "library-check-out-fail", req.Path, name, modified)
+		}
+	}()
+
+	lock := locksutil.LockForKey(b.roleLocks, libraryLockKey(name))
+	lock.Lock()
+	defer lock.Unlock()
+
+	set, err := b.librarySet(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if set == nil {
+		return logical.ErrorResponse("unknown library set: %s", name), nil
+	}
+
+	dbConfig, err := b.DatabaseConfig(ctx, req.Storage, set.DBName)
+	if err != nil {
+		return nil, err
+	}
+	if !strutil.StrListContains(dbConfig.AllowedRoles, "*") && !strutil.StrListContainsGlob(dbConfig.AllowedRoles, name) {
+		return nil, fmt.Errorf("%q is not an allowed role", name)
+	}
+
+	ttl := set.TTL
+	if ttlRaw, ok := data.GetOk("ttl"); ok {
+		ttl = time.Duration(ttlRaw.(int)) * time.Second
+	}
+	if set.MaxTTL > 0 && ttl > set.MaxTTL {
+		ttl = set.MaxTTL
+	}
+
+	for _, username := range set.ServiceAccountNames {
+		account, err := b.libraryAccount(ctx, req.Storage, name, username)
+		if err != nil {
+			return nil, err
+		}
+		if account == nil || !account.Available {
+			continue
+		}
+
+		checkoutID, err := uuid.GenerateUUID()
+		if err != nil {
+			return nil, err
+		}
+
+		account.Available = false
+		account.CheckoutID = checkoutID
+		account.BorrowerEntityID = req.EntityID
+		account.BorrowerClientTokenAccessor = req.ClientTokenAccessor
+		account.CheckedOutAt = time.Now()
+		if err := storeLibraryAccount(ctx, req.Storage, name, username, account); err != nil {
+			return nil, err
+		}
+		modified = true
+
+		respData := map[string]interface{}{
+			"username": username,
+			"password": account.Password,
+		}
+		internal := map[string]interface{}{
+			"set_name":    name,
+			"username":    username,
+			"checkout_id": checkoutID,
+		}
+		resp = b.Secret(SecretLibraryCredsType).Response(respData, internal)
+		resp.Secret.TTL = ttl
+		resp.Secret.MaxTTL = set.MaxTTL
+		return resp, nil
+	}
+
+	return logical.ErrorResponse("no service accounts available for check-out"), nil
+}
+
+// pathLibraryCheckIn returns accounts to a library set. Unless force is set,
+// callers may only check in accounts they borrowed themselves.
+func (b *databaseBackend) pathLibraryCheckIn(force bool) framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (resp *logical.Response, err error) {
+		name := data.Get("name").(string)
+		modified := false
+		defer func() {
+			if err == nil && (resp == nil || !resp.IsError()) {
+				b.dbEvent(ctx, "library-check-in", req.Path, name, modified)
+			} else {
+				b.dbEvent(ctx, "library-check-in-fail", req.Path, name, modified)
+			}
+		}()
+
+		lock := locksutil.LockForKey(b.roleLocks, libraryLockKey(name))
+		lock.Lock()
+		defer lock.Unlock()
+
+		set, err := b.librarySet(ctx, req.Storage, name)
+		if err != nil {
+			return nil, err
+		}
+		if set == nil {
+			return logical.ErrorResponse("unknown library set: %s", name), nil
+		}
+
+		enforce := !force && !set.DisableCheckInEnforcement
+
+		requested := data.Get("service_account_names").([]string)
+		for _, username := range requested {
+			if !strutil.StrListContains(set.ServiceAccountNames, username) {
+				return logical.ErrorResponse("service account %q is not part of library set %q", username, name), nil
+			}
+		}
+
+		accounts := make(map[string]*libraryAccount)
+		for _, username := range set.ServiceAccountNames {
+			if len(requested) > 0 && !strutil.StrListContains(requested, username) {
+				continue
+			}
+
+			account, err := b.libraryAccount(ctx, req.Storage, name, username)
+			if err != nil {
+				return nil, err
+			}
+			if account == nil || account.Available {
+				continue
+			}
+
+			if enforce && !account.borrowedBy(req) {
+				if len(requested) > 0 {
+					return logical.ErrorResponse("service account %q was not checked out by the caller", username), nil
+				}
+				continue
+			}
+			accounts[username] = account
+		}
+
+		if len(requested) == 0 && len(accounts) == 0 {
+			return logical.ErrorResponse("no service accounts to check in"), nil
+		}
+
+		checkedIn := make([]string, 0, len(accounts))
+		for _, username := range set.ServiceAccountNames {
+			account, ok := accounts[username]
+			if !ok {
+				continue
+			}
+			if err := b.checkInLibraryAccount(ctx, req.Storage, name, set, username, account); err != nil {
+				return nil, err
+			}
+			modified = true
+			checkedIn = append(checkedIn, username)
+		}
+
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"check_ins": checkedIn,
+			},
+		}, nil
+	}
+}
+
+// borrowedBy returns true if the account was checked out by the entity, or
+// for requests without an entity the token, making the request.
+func (a *libraryAccount) borrowedBy(req *logical.Request) bool {
+	if a.BorrowerEntityID != "" {
+		return a.BorrowerEntityID == req.EntityID
+	}
+	return a.BorrowerClientTokenAccessor != "" && a.BorrowerClientTokenAccessor == req.ClientTokenAccessor
+}
+
+// checkInLibraryAccount rotates the password of a checked out account and
+// makes it available again. If the rotation fails the account stays checked
+// out, as the previous borrower still knows its password.
+func (b *databaseBackend) checkInLibraryAccount(ctx context.Context, s logical.Storage, setName string, set *librarySet, username string, account *libraryAccount) error {
+	if account.Available {
+		return errLibraryAccountNotCheckedOut
+	}
+
+	if err := b.rotateLibraryAccount(ctx, s, setName, set, username, account); err != nil {
+		return fmt.Errorf("unable to rotate password for service account %q: %w", username, err)
+	}
+
+	account.Available = true
+	account.CheckoutID = ""
+	account.BorrowerEntityID = ""
+	account.BorrowerClientTokenAccessor = ""
+	account.CheckedOutAt = time.Time{}
+	return storeLibraryAccount(ctx, s, setName, username, account)
+}
+
+const pathLibraryCheckOutHelpSyn = `
+Check out an account from a library set.
+`
+
+const pathLibraryCheckOutHelpDesc = `
+This path checks out the first available account of the library set and
+returns its credentials as a lease. The account is checked in and its password
+rotated when the lease is revoked or expires.
+`
+
+const pathLibraryCheckInHelpSyn = `
+Check in accounts borrowed from a library set.
+`
+
+const pathLibraryCheckInHelpDesc = `
+This path checks in accounts that the caller checked out, rotating their
+passwords so they can be lent out again. Unless the set disables check-in
+enforcement, only the entity or token that checked an account out may check
+it in.
+`
+
+const pathLibraryManageCheckInHelpSyn = `
+Force the check-in of accounts borrowed from a library set.
+`
+
+const pathLibraryManageCheckInHelpDesc = `
+This path checks in accounts regardless of who checked them out. It is
+intended for operators that need to reclaim an account.
+`
@@ -0,0 +1,336 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	v5 "github.com/hashicorp/vault/sdk/database/dbplugin/v5"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/mock"
+)
+
+func createLibrarySet(t *testing.T, b *databaseBackend, storage logical.Storage, mockDB *mockNewDatabase, name string, data map[string]interface{}) {
+	t.Helper()
+	mockDB.On("UpdateUser", mock.Anything, mock.Anything).
+		Return(v5.UpdateUserResponse{}, nil)
+
+	data["db_name"] = mockv5
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "library/" + name,
+		Storage:   storage,
+		Data:      data,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatal(resp, err)
+	}
+}
+
+func checkOutLibraryAccount(t *testing.T, b *databaseBackend, storage logical.Storage, name, entityID string) *logical.Response {
+	t.Helper()
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "library/" + name + "/check-out",
+		Storage:   storage,
+		EntityID:  entityID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestBackend_Library_CheckOutCheckIn(t *testing.T) {
+	ctx := context.Background()
+	b, storage, mockDB := getBackend(t)
+	defer b.Cleanup(ctx)
+	configureDBMount(t, storage)
+
+	createLibrarySet(t, b, storage, mockDB, "legacy", map[string]interface{}{
+		"service_account_names": "svc1,svc2",
+		"ttl":                   "1h",
+		"max_ttl":               "2h",
+	})
+
+	first := checkOutLibraryAccount(t, b, storage, "legacy", "entity-1")
+	if first == nil || first.IsError() || first.Secret == nil {
+		t.Fatalf("expected check-out to succeed: %#v", first)
+	}
+	if first.Data["username"] != "svc1" || first.Data["password"] == "" {
+		t.Fatalf("unexpected credentials: %#v", first.Data)
+	}
+	if first.Secret.TTL != time.Hour || first.Secret.MaxTTL != 2*time.Hour {
+		t.Fatalf("unexpected lease durations: %v %v", first.Secret.TTL, first.Secret.MaxTTL)
+	}
+
+	second := checkOutLibraryAccount(t, b, storage, "legacy", "entity-2")
+	if second == nil || second.IsError() || second.Data["username"] != "svc2" {
+		t.Fatalf("expected second account to be checked out: %#v", second)
+	}
+
+	// Both accounts are now lent out exclusively
+	third := checkOutLibraryAccount(t, b, storage, "legacy", "entity-3")
+	if third == nil || !third.IsError() {
+		t.Fatalf("expected check-out to fail when no accounts are available: %#v", third)
+	}
+
+	// Only the borrower may check in an account
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "library/legacy/check-in",
+		Storage:   storage,
+		EntityID:  "entity-2",
+		Data: map[string]interface{}{
+			"service_account_names": "svc1",
+		},
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected check-in by another entity to fail: %#v %v", resp, err)
+	}
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "library/legacy/check-in",
+		Storage:   storage,
+		EntityID:  "entity-1",
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatal(resp, err)
+	}
+	if checkIns := resp.Data["check_ins"].([]string); len(checkIns) != 1 || checkIns[0] != "svc1" {
+		t.Fatalf("unexpected check-ins: %v", checkIns)
+	}
+
+	// The password is rotated on check-in
+	account, err := b.libraryAccount(ctx, storage, "legacy", "svc1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !account.Available || account.Password == first.Data["password"] {
+		t.Fatalf("expected svc1 to be available with a new password: %#v", account)
+	}
+
+	// Revoking the stale lease of the first check-out must not touch the
+	// account once it has been lent out again
+	again := checkOutLibraryAccount(t, b, storage, "legacy", "entity-3")
+	if again == nil || again.IsError() || again.Data["username"] != "svc1" {
+		t.Fatalf("expected svc1 to be checked out again: %#v", again)
+	}
+	_, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.RevokeOperation,
+		Storage:   storage,
+		Secret:    first.Secret,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	account, err = b.libraryAccount(ctx, storage, "legacy", "svc1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if account.Available || account.BorrowerEntityID != "entity-3" {
+		t.Fatalf("stale lease revocation checked in svc1: %#v", account)
+	}
+
+	// Revoking the current lease, as happens at max_ttl, returns the account
+	_, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.RevokeOperation,
+		Storage:   storage,
+		Secret:    again.Secret,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	account, err = b.libraryAccount(ctx, storage, "legacy", "svc1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !account.Available {
+		t.Fatalf("expected lease revocation to check in svc1: %#v", account)
+	}
+
+	// Operators can reclaim any account
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "library/manage/legacy/check-in",
+		Storage:   storage,
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatal(resp, err)
+	}
+	if checkIns := resp.Data["check_ins"].([]string); len(checkIns) != 1 || checkIns[0] != "svc2" {
+		t.Fatalf("unexpected check-ins: %v", checkIns)
+	}
+}
+
+func TestBackend_Library_SetValidation(t *testing.T) {
+	ctx := context.Background()
+	b, storage, mockDB := getBackend(t)
+	defer b.Cleanup(ctx)
+	configureDBMount(t, storage)
+
+	createLibrarySet(t, b, storage, mockDB, "first", map[string]interface{}{
+		"service_account_names": "svc1",
+	})
+
+	// An account cannot be managed by two sets
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "library/second",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"db_name":               mockv5,
+			"service_account_names": "svc1",
+		},
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected duplicate account to be rejected: %#v %v", resp, err)
+	}
+
+	if resp := checkOutLibraryAccount(t, b, storage, "first", "entity-1"); resp == nil || resp.IsError() {
+		t.Fatalf("expected check-out to succeed: %#v", resp)
+	}
+
+	// A set cannot be deleted while accounts are checked out
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.DeleteOperation,
+		Path:      "library/first",
+		Storage:   storage,
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected delete to be rejected: %#v %v", resp, err)
+	}
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "library/first/status",
+		Storage:   storage,
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatal(resp, err)
+	}
+	status := resp.Data["svc1"].(map[string]interface{})
+	if status["available"] != false || status["borrower_entity_id"] != "entity-1" {
+		t.Fatalf("unexpected status: %#v", status)
+	}
+}
+
+func TestBackend_Library_StaticRoleAccounts(t *testing.T) {
+	ctx := context.Background()
+	b, storage, mockDB := getBackend(t)
+	defer b.Cleanup(ctx)
+	configureDBMount(t, storage)
+
+	createRole(t, b, storage, mockDB, "static-user")
+	createLibrarySet(t, b, storage, mockDB, "first", map[string]interface{}{
+		"service_account_names": "svc1",
+	})
+
+	// A static role's account cannot be lent out
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "library/second",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"db_name":               mockv5,
+			"service_account_names": "svc2,static-user",
+		},
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected the static role's account to be rejected: %#v %v", resp, err)
+	}
+
+	// A lent out account cannot be managed by a static role
+	for _, data := range []map[string]interface{}{
+		{"username": "svc1"},
+		{"username": "other-user", "secondary_username": "svc1"},
+	} {
+		data["db_name"] = mockv5
+		data["rotation_period"] = "86400s"
+		resp, err = b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "static-roles/library-user",
+			Storage:   storage,
+			Data:      data,
+		})
+		if err != nil || resp == nil || !resp.IsError() {
+			t.Fatalf("expected the library set's account to be rejected for %v: %#v %v", data, resp, err)
+		}
+	}
+}
+
+func TestBackend_Library_CheckInRotationFailure(t *testing.T) {
+	ctx := context.Background()
+	b, storage, mockDB := getBackend(t)
+	defer b.Cleanup(ctx)
+	configureDBMount(t, storage)
+
+	mockDB.On("UpdateUser", mock.Anything, mock.Anything).
+		Return(v5.UpdateUserResponse{}, nil).
+		Once()
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "library/legacy",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"db_name":               mockv5,
+			"service_account_names": "svc1",
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatal(resp, err)
+	}
+
+	checkout := checkOutLibraryAccount(t, b, storage, "legacy", "entity-1")
+	if checkout == nil || checkout.IsError() {
+		t.Fatalf("expected check-out to succeed: %#v", checkout)
+	}
+
+	mockDB.On("UpdateUser", mock.Anything, mock.Anything).
+		Return(v5.UpdateUserResponse{}, errors.New("forced error")).
+		Once()
+	_, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.RevokeOperation,
+		Storage:   storage,
+		Secret:    checkout.Secret,
+	})
+	if err == nil {
+		t.Fatal("expected revocation to fail")
+	}
+
+	// The borrower still knows the password, so the account must not be
+	// lent out again
+	account, err := b.libraryAccount(ctx, storage, "legacy", "svc1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if account.Available {
+		t.Fatal("expected account to stay checked out after failed rotation")
+	}
+
+	// The WAL of the failed rotation rolls the password forward
+	walIDs := requireWALs(t, storage, 1)
+	wal, err := framework.GetWAL(ctx, storage, walIDs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	mockDB.On("UpdateUser", mock.Anything, mock.Anything).
+		Return(v5.UpdateUserResponse{}, nil).
+		Once()
+	if err := b.walRollback(ctx, &logical.Request{Storage: storage}, wal.Kind, wal.Data); err != nil {
+		t.Fatal(err)
+	}
+	account, err = b.libraryAccount(ctx, storage, "legacy", "svc1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if account.Password != wal.Data.(map[string]interface{})["new_password"] {
+		t.Fatal("expected password to be rolled forward from the WAL")
+	}
+}
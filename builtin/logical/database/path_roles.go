@@ -192,6 +192,12 @@ func staticFields() map[string]*framework.FieldSchema {
 			Type: framework.TypeString,
 			Description: `Name of the static user account for Vault to manage.
 	Requires "rotation_period" to be specified`,
+		},
+		"secondary_username": {
+			Type: framework.TypeString,
+			Description: `Name of a second static user account for Vault to
+	manage. Rotations alternate between "username" and this account, so the
+	credential returned before a rotation stays valid until the next one.`,
 		},
 		"rotation_period": {
 			Type: framework.TypeDurationSecond,
@@ -306,6 +312,10 @@ func (b *databaseBackend) pathStaticRoleRead(ctx context.Context, req *logical.R
 	// guard against nil StaticAccount; shouldn't happen but we'll be safe
 	if role.StaticAccount != nil {
 		data["username"] = role.StaticAccount.Username
+		if role.StaticAccount.DualAccount() {
+			data["secondary_username"] = role.StaticAccount.SecondaryUsername
+			data["active_username"] = role.StaticAccount.ActiveUsername()
+		}
 		data["rotation_statements"] = role.Statements.Rotation
 		if !role.StaticAccount.LastVaultRotation.IsZero() {
 			data["last_vault_rotation"] = role.StaticAccount.LastVaultRotation
@@ -562,6 +572,34 @@ func (b *databaseBackend) pathStaticRoleCreateUpdate(ctx context.Context, req *l
 	}
 	role.StaticAccount.Username = username
 
+	if secondaryRaw, ok := data.GetOk("secondary_username"); ok {
+		secondary := secondaryRaw.(string)
+		if !createRole && secondary != role.StaticAccount.SecondaryUsername {
+			return logical.ErrorResponse("cannot update static account secondary_username"), nil
+		}
+		if secondary == username {
+			return logical.ErrorResponse("secondary_username must differ from username"), nil
+		}
+		role.StaticAccount.SecondaryUsername = secondary
+	}
+
+	// Accounts lent out by a library set are rotated on check-in, a static
+	// role rotating them as well would change the password of a borrower
+	if createRole {
+		for _, account := range []string{role.StaticAccount.Username, role.StaticAccount.SecondaryUsername} {
+			if account == "" {
+				continue
+			}
+			setName, err := b.librarySetForAccount(ctx, req.Storage, role.DBName, account, "")
+			if err != nil {
+				return nil, err
+			}
+			if setName != "" {
+				return logical.ErrorResponse("account %q is already managed by library set %q", account, setName), nil
+			}
+		}
+	}
+
 	rotationPeriodSecondsRaw, rotationPeriodOk := data.GetOk("rotation_period")
 	rotationScheduleRaw, rotationScheduleOk := data.GetOk("rotation_schedule")
 	rotationWindowSecondsRaw, rotationWindowOk := data.GetOk("rotation_window")
@@ -646,11 +684,23 @@ func (b *databaseBackend) pathStaticRoleCreateUpdate(ctx context.Context, req *l
 	var item *queue.Item
 	switch req.Operation {
 	case logical.CreateOperation:
-		// setStaticAccount calls Storage.Put and saves the role to storage
-		resp, err := b.setStaticAccount(ctx, req.Storage, &setStaticAccountInput{
-			RoleName: name,
-			Role:     role,
-		})
+		// setStaticAccount calls Storage.Put and saves the role to storage.
+		// Dual-account roles rotate both accounts so Vault knows both
+		// credentials, the secondary first so the primary ends up active.
+		rotations := 1
+		if role.StaticAccount.DualAccount() {
+			rotations = 2
+		}
+		var resp *setStaticAccountOutput
+		for i := 0; i < rotations; i++ {
+			resp, err = b.setStaticAccount(ctx, req.Storage, &setStaticAccountInput{
+				RoleName: name,
+				Role:     role,
+			})
+			if err != nil {
+				break
+			}
+		}
 		if err != nil {
 			if resp != nil && resp.WALID != "" {
 				b.Logger().Debug("deleting WAL for failed role creation", "WAL ID", resp.WALID, "role", name)
@@ -797,6 +847,24 @@ type staticAccount struct {
 	// CredentialTypeRSAPrivateKey.
 	PrivateKey []byte `json:"private_key"`
 
+	// SecondaryUsername is the optional second account of a dual-account
+	// static role. Rotations alternate between the two accounts so that the
+	// credential handed out before a rotation stays valid until the following
+	// one.
+	SecondaryUsername string `json:"secondary_username"`
+
+	// SecondaryPassword is the current password credential of the secondary
+	// account.
+	SecondaryPassword string `json:"secondary_password"`
+
+	// SecondaryPrivateKey is the current private key credential of the
+	// secondary account.
+	SecondaryPrivateKey []byte `json:"secondary_private_key"`
+
+	// SecondaryActive is true if the secondary account holds the credential
+	// that was rotated last and is returned on credential requests.
+	SecondaryActive bool `json:"secondary_active"`
+
 	// LastVaultRotation represents the last time Vault rotated the password
 	LastVaultRotation time.Time `json:"last_vault_rotation"`
 
@@ -828,6 +896,70 @@ type staticAccount struct {
 	RevokeUserOnDelete bool `json:"revoke_user_on_delete"`
 }
 
+// DualAccount returns true if the static account alternates rotations
+// between two database accounts.
+func (s *staticAccount) DualAccount() bool {
+	return s.SecondaryUsername != ""
+}
+
+// ActiveUsername returns the username of the account whose credential is
+// returned on credential requests.
+func (s *staticAccount) ActiveUsername() string {
+	if s.DualAccount() && s.SecondaryActive {
+		return s.SecondaryUsername
+	}
+	return s.Username
+}
+
+// ActivePassword returns the password of the active account.
+func (s *staticAccount) ActivePassword() string {
+	if s.DualAccount() && s.SecondaryActive {
+		return s.SecondaryPassword
+	}
+	return s.Password
+}
+
+// ActivePrivateKey returns the private key of the active account.
+func (s *staticAccount) ActivePrivateKey() []byte {
+	if s.DualAccount() && s.SecondaryActive {
+		return s.SecondaryPrivateKey
+	}
+	return s.PrivateKey
+}
+
+// RotationUsername returns the username of the account to rotate next. For
+// dual-account roles this is the account that is not active, leaving the
+// credential currently handed out untouched.
+func (s *staticAccount) RotationUsername() string {
+	if s.DualAccount() && !s.SecondaryActive {
+		return s.SecondaryUsername
+	}
+	return s.Username
+}
+
+// setRotatedCredential stores a newly set credential for the given account
+// and, for dual-account roles, makes that account the active one.
+func (s *staticAccount) setRotatedCredential(username, password string, privateKey []byte) {
+	if s.DualAccount() && username == s.SecondaryUsername {
+		if password != "" {
+			s.SecondaryPassword = password
+		}
+		if privateKey != nil {
+			s.SecondaryPrivateKey = privateKey
+		}
+		s.SecondaryActive = true
+		return
+	}
+
+	if password != "" {
+		s.Password = password
+	}
+	if privateKey != nil {
+		s.PrivateKey = privateKey
+	}
+	s.SecondaryActive = false
+}
+
 // NextRotationTime calculates the next rotation for period and schedule-based
 // rotations.
 //
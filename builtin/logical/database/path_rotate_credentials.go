@@ -11,6 +11,7 @@ import (
 	"github.com/hashicorp/vault/helper/versions"
 	v5 "github.com/hashicorp/vault/sdk/database/dbplugin/v5"
 	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
 	"github.com/hashicorp/vault/sdk/logical"
 	"github.com/hashicorp/vault/sdk/queue"
 )
@@ -44,6 +45,31 @@ func pathRotateRootCredentials(b *databaseBackend) []*framework.Path {
 			HelpSynopsis:    pathRotateCredentialsUpdateHelpSyn,
 			HelpDescription: pathRotateCredentialsUpdateHelpDesc,
 		},
+		{
+			Pattern: "rotate-root/" + framework.GenericNameRegex("name") + "/history",
+
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: operationPrefixDatabase,
+				OperationVerb:   "read",
+				OperationSuffix: "root-credentials-rotation-history",
+			},
+
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeString,
+					Description: "Name of this database connection",
+				},
+			},
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.pathRotateRootHistoryRead(),
+				},
+			},
+
+			HelpSynopsis:    pathRotateRootHistoryHelpSyn,
+			HelpDescription: pathRotateRootHistoryHelpDesc,
+		},
 		{
 			Pattern: "rotate-role/" + framework.GenericNameRegex("name"),
 
@@ -75,117 +101,200 @@ func pathRotateRootCredentials(b *databaseBackend) []*framework.Path {
 }
 
 func (b *databaseBackend) pathRotateRootCredentialsUpdate() framework.OperationFunc {
-	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (resp *logical.Response, err error) {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 		name := data.Get("name").(string)
-		modified := false
-		defer func() {
-			if err == nil {
-				b.dbEvent(ctx, "rotate-root", req.Path, name, modified)
-			} else {
-				b.dbEvent(ctx, "rotate-root-fail", req.Path, name, modified)
-			}
-		}()
-
 		if name == "" {
 			return logical.ErrorResponse(respErrEmptyName), nil
 		}
 
-		config, err := b.DatabaseConfig(ctx, req.Storage, name)
-		if err != nil {
+		if err := b.rotateRootCredentials(ctx, req.Storage, name, req.Path, rootRotationTriggerManual); err != nil {
 			return nil, err
 		}
+		return nil, nil
+	}
+}
 
-		rootUsername, ok := config.ConnectionDetails["username"].(string)
-		if !ok || rootUsername == "" {
-			return nil, fmt.Errorf("unable to rotate root credentials: no username in configuration")
+func (b *databaseBackend) pathRotateRootHistoryRead() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		name := data.Get("name").(string)
+		if name == "" {
+			return logical.ErrorResponse(respErrEmptyName), nil
 		}
 
-		rootPassword, ok := config.ConnectionDetails["password"].(string)
-		if !ok || rootPassword == "" {
-			return nil, fmt.Errorf("unable to rotate root credentials: no password in configuration")
+		entry, err := req.Storage.Get(ctx, fmt.Sprintf("config/%s", name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read connection configuration: %w", err)
+		}
+		if entry == nil {
+			return nil, nil
 		}
 
-		dbi, err := b.GetConnection(ctx, req.Storage, name)
+		history, err := readRootRotationHistory(ctx, req.Storage, name)
 		if err != nil {
 			return nil, err
 		}
 
-		// Take the write lock on the instance
-		dbi.Lock()
-		defer func() {
-			dbi.Unlock()
-			// Even on error, still remove the connection
-			b.ClearConnectionId(name, dbi.id)
-		}()
-		defer func() {
-			// Close the plugin
-			dbi.closed = true
-			if err := dbi.database.Close(); err != nil {
-				b.Logger().Error("error closing the database plugin connection", "err", err)
+		records := make([]map[string]interface{}, 0, len(history.Records))
+		for _, record := range history.Records {
+			r := map[string]interface{}{
+				"time":    record.Time,
+				"trigger": record.Trigger,
+				"success": record.Success,
 			}
-		}()
-
-		generator, err := newPasswordGenerator(nil)
-		if err != nil {
-			return nil, fmt.Errorf("failed to construct credential generator: %s", err)
-		}
-		generator.PasswordPolicy = config.PasswordPolicy
-
-		// Generate new credentials
-		oldPassword := config.ConnectionDetails["password"].(string)
-		newPassword, err := generator.generate(ctx, b, dbi.database)
-		if err != nil {
-			b.CloseIfShutdown(dbi, err)
-			return nil, fmt.Errorf("failed to generate password: %s", err)
-		}
-		config.ConnectionDetails["password"] = newPassword
-
-		// Write a WAL entry
-		walID, err := framework.PutWAL(ctx, req.Storage, rotateRootWALKey, &rotateRootCredentialsWAL{
-			ConnectionName: name,
-			UserName:       rootUsername,
-			OldPassword:    oldPassword,
-			NewPassword:    newPassword,
-		})
-		if err != nil {
-			return nil, err
+			if record.Error != "" {
+				r["error"] = record.Error
+			}
+			records = append(records, r)
 		}
 
-		updateReq := v5.UpdateUserRequest{
-			Username:       rootUsername,
-			CredentialType: v5.CredentialTypePassword,
-			Password: &v5.ChangePassword{
-				NewPassword: newPassword,
-				Statements: v5.Statements{
-					Commands: config.RootCredentialsRotateStatements,
-				},
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"history": records,
 			},
+		}, nil
+	}
+}
+
+// rotateRootCredentials rotates the root credential of the named connection,
+// records the attempt in the connection's rotation history, and sends a
+// rotate-root or rotate-root-fail event unless events are disabled for the
+// connection.
+func (b *databaseBackend) rotateRootCredentials(ctx context.Context, s logical.Storage, name, path, trigger string) (err error) {
+	lock := locksutil.LockForKey(b.connectionLocks, name)
+	lock.Lock()
+	defer lock.Unlock()
+
+	config, err := b.DatabaseConfig(ctx, s, name)
+	if err != nil {
+		return err
+	}
+
+	modified := false
+	sendEvents := !config.DisableRootRotationEvents
+	defer func() {
+		record := rootRotationRecord{
+			Time:    time.Now(),
+			Trigger: trigger,
+			Success: err == nil,
 		}
-		newConfigDetails, err := dbi.database.UpdateUser(ctx, updateReq, true)
 		if err != nil {
-			return nil, fmt.Errorf("failed to update user: %w", err)
+			record.Error = err.Error()
 		}
-		if newConfigDetails != nil {
-			config.ConnectionDetails = newConfigDetails
+		if histErr := appendRootRotationHistory(ctx, s, name, record); histErr != nil {
+			b.Logger().Warn("unable to record root rotation history", "name", name, "error", histErr)
 		}
-		modified = true
 
-		// 1.12.0 and 1.12.1 stored builtin plugins in storage, but 1.12.2 reverted
-		// that, so clean up any pre-existing stored builtin versions on write.
-		if versions.IsBuiltinVersion(config.PluginVersion) {
-			config.PluginVersion = ""
+		if !sendEvents {
+			return
 		}
-		err = storeConfig(ctx, req.Storage, name, config)
-		if err != nil {
-			return nil, err
+		if err == nil {
+			b.dbEvent(ctx, "rotate-root", path, name, modified, "trigger", trigger)
+		} else {
+			b.dbEvent(ctx, "rotate-root-fail", path, name, modified, "trigger", trigger)
 		}
+	}()
 
-		err = framework.DeleteWAL(ctx, req.Storage, walID)
-		if err != nil {
-			b.Logger().Warn("unable to delete WAL", "error", err, "WAL ID", walID)
+	dbi, err := b.GetConnection(ctx, s, name)
+	if err != nil {
+		return err
+	}
+
+	// Take the write lock on the instance
+	dbi.Lock()
+	defer func() {
+		dbi.Unlock()
+		// Even on error, still remove the connection
+		b.ClearConnectionId(name, dbi.id)
+	}()
+	defer func() {
+		// Close the plugin
+		dbi.closed = true
+		if err := dbi.database.Close(); err != nil {
+			b.Logger().Error("error closing the database plugin connection", "err", err)
 		}
-		return nil, nil
+	}()
+
+	// Read the configuration again while holding the lock so that concurrent
+	// manual and scheduled rotations operate on the latest stored credential.
+	config, err = b.DatabaseConfig(ctx, s, name)
+	if err != nil {
+		return err
 	}
+
+	rootUsername, ok := config.ConnectionDetails["username"].(string)
+	if !ok || rootUsername == "" {
+		return fmt.Errorf("unable to rotate root credentials: no username in configuration")
+	}
+
+	oldPassword, ok := config.ConnectionDetails["password"].(string)
+	if !ok || oldPassword == "" {
+		return fmt.Errorf("unable to rotate root credentials: no password in configuration")
+	}
+
+	generator, err := newPasswordGenerator(nil)
+	if err != nil {
+		return fmt.Errorf("failed to construct credential generator: %s", err)
+	}
+	generator.PasswordPolicy = config.PasswordPolicy
+
+	// Generate new credentials
+	newPassword, err := generator.generate(ctx, b, dbi.database)
+	if err != nil {
+		b.CloseIfShutdown(dbi, err)
+		return fmt.Errorf("failed to generate password: %s", err)
+	}
+	config.ConnectionDetails["password"] = newPassword
+
+	// Write a WAL entry
+	walID, err := framework.PutWAL(ctx, s, rotateRootWALKey, &rotateRootCredentialsWAL{
+		ConnectionName: name,
+		UserName:       rootUsername,
+		OldPassword:    oldPassword,
+		NewPassword:    newPassword,
+	})
+	if err != nil {
+		return err
+	}
+
+	updateReq := v5.UpdateUserRequest{
+		Username:       rootUsername,
+		CredentialType: v5.CredentialTypePassword,
+		Password: &v5.ChangePassword{
+			NewPassword: newPassword,
+			Statements: v5.Statements{
+				Commands: config.RootCredentialsRotateStatements,
+			},
+		},
+	}
+	newConfigDetails, err := dbi.database.UpdateUser(ctx, updateReq, true)
+	if err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	if newConfigDetails != nil {
+		config.ConnectionDetails = newConfigDetails
+	}
+	modified = true
+
+	now := time.Now()
+	config.LastRootRotation = now
+	if config.NextRootRotation, err = b.nextRootRotationTime(config, now); err != nil {
+		b.Logger().Warn("unable to compute next root rotation", "name", name, "error", err)
+	}
+
+	// 1.12.0 and 1.12.1 stored builtin plugins in storage, but 1.12.2 reverted
+	// that, so clean up any pre-existing stored builtin versions on write.
+	if versions.IsBuiltinVersion(config.PluginVersion) {
+		config.PluginVersion = ""
+	}
+	err = storeConfig(ctx, s, name, config)
+	if err != nil {
+		return err
+	}
+
+	if err := framework.DeleteWAL(ctx, s, walID); err != nil {
+		b.Logger().Warn("unable to delete WAL", "error", err, "WAL ID", walID)
+	}
+	return nil
 }
 
 func (b *databaseBackend) pathRotateRoleCredentialsUpdate() framework.OperationFunc {
@@ -271,6 +380,16 @@ const pathRotateCredentialsUpdateHelpDesc = `
 This path attempts to rotate the root credentials for the given database. 
 `
 
+const pathRotateRootHistoryHelpSyn = `
+Read the root credential rotation history of a database connection.
+`
+
+const pathRotateRootHistoryHelpDesc = `
+This path returns the most recent root credential rotation attempts for the
+given database connection, including whether they were triggered manually or
+by the configured rotation schedule, and the error of failed attempts.
+`
+
 const pathRotateRoleCredentialsUpdateHelpSyn = `
 Request to rotate the credentials for a static user account.
 `
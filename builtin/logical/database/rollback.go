@@ -27,11 +27,20 @@ type rotateRootCredentialsWAL struct {
 }
 
 // walRollback handles WAL entries that result from partial failures
-// to rotate the root credentials of a database. It is responsible
-// for rolling back root database credentials when doing so would
-// reconcile the credentials with Vault storage.
+// to rotate the root credentials of a database or the password of a
+// library account. It is responsible for rolling back root database
+// credentials when doing so would reconcile the credentials with Vault
+// storage.
 func (b *databaseBackend) walRollback(ctx context.Context, req *logical.Request, kind string, data interface{}) error {
-	if kind != rotateRootWALKey {
+	switch kind {
+	case rotateRootWALKey:
+	case libraryWALKey:
+		var entry libraryRotationWAL
+		if err := mapstructure.Decode(data, &entry); err != nil {
+			return err
+		}
+		return b.rollbackLibraryRotation(ctx, req.Storage, entry)
+	default:
 		return errors.New("unknown type to rollback")
 	}
 
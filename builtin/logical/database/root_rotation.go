@@ -0,0 +1,173 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	// rootRotationHistoryPath is the storage prefix of the root credential
+	// rotation history of each connection.
+	rootRotationHistoryPath = "root-rotation-history/"
+
+	// maxRootRotationHistory is the number of rotation attempts kept in the
+	// history of a connection.
+	maxRootRotationHistory = 20
+
+	// minRootRotationPeriod is the shortest allowed root_rotation_period.
+	minRootRotationPeriod = time.Minute
+
+	// rootRotationCheckInterval is how often the configured connections are
+	// checked for a due root credential rotation.
+	rootRotationCheckInterval = time.Minute
+
+	// rootRotationRetryInterval is the delay before a failed scheduled root
+	// credential rotation is attempted again.
+	rootRotationRetryInterval = 10 * time.Minute
+
+	rootRotationTriggerManual    = "manual"
+	rootRotationTriggerScheduled = "scheduled"
+)
+
+// rootRotationRecord is a single root credential rotation attempt.
+type rootRotationRecord struct {
+	Time    time.Time `json:"time"`
+	Trigger string    `json:"trigger"`
+	Success bool      `json:"success"`
+	Error   string    `json:"error,omitempty"`
+}
+
+// rootRotationHistory holds the most recent root credential rotation attempts
+// of a connection, oldest first.
+type rootRotationHistory struct {
+	Records []rootRotationRecord `json:"records"`
+}
+
+func readRootRotationHistory(ctx context.Context, s logical.Storage, name string) (*rootRotationHistory, error) {
+	history := &rootRotationHistory{}
+	entry, err := s.Get(ctx, rootRotationHistoryPath+name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read root rotation history: %w", err)
+	}
+	if entry == nil {
+		return history, nil
+	}
+	if err := entry.DecodeJSON(history); err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+func appendRootRotationHistory(ctx context.Context, s logical.Storage, name string, record rootRotationRecord) error {
+	history, err := readRootRotationHistory(ctx, s, name)
+	if err != nil {
+		return err
+	}
+
+	history.Records = append(history.Records, record)
+	if len(history.Records) > maxRootRotationHistory {
+		history.Records = history.Records[len(history.Records)-maxRootRotationHistory:]
+	}
+
+	entry, err := logical.StorageEntryJSON(rootRotationHistoryPath+name, history)
+	if err != nil {
+		return err
+	}
+	return s.Put(ctx, entry)
+}
+
+// nextRootRotationTime returns the time after from at which the root credential
+// of the connection is next rotated automatically, or the zero time if it is
+// not rotated automatically.
+func (b *databaseBackend) nextRootRotationTime(config *DatabaseConfig, from time.Time) (time.Time, error) {
+	switch {
+	case config.RootRotationPeriod > 0:
+		return from.Add(config.RootRotationPeriod), nil
+	case config.RootRotationSchedule != "":
+		schedule, err := b.schedule.Parse(config.RootRotationSchedule)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("could not parse root_rotation_schedule: %w", err)
+		}
+		return schedule.Next(from), nil
+	default:
+		return time.Time{}, nil
+	}
+}
+
+// rotateRootCredentialsOnSchedule rotates the root credential of every
+// connection whose next automatic rotation is due. It is invoked by the
+// periodic ticker.
+func (b *databaseBackend) rotateRootCredentialsOnSchedule(ctx context.Context, s logical.Storage) {
+	names, err := s.List(ctx, "config/")
+	if err != nil {
+		b.Logger().Warn("unable to list connections for root rotation", "error", err)
+		return
+	}
+
+	for _, name := range names {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		config, err := b.DatabaseConfig(ctx, s, name)
+		if err != nil {
+			b.Logger().Warn("unable to read connection for root rotation", "name", name, "error", err)
+			continue
+		}
+		if !config.rootRotationEnabled() || config.NextRootRotation.IsZero() || time.Now().Before(config.NextRootRotation) {
+			continue
+		}
+
+		b.Logger().Debug("rotating root credentials on schedule", "name", name)
+		err = b.rotateRootCredentials(ctx, s, name, "rotate-root/"+name, rootRotationTriggerScheduled)
+		if err == nil {
+			continue
+		}
+		b.Logger().Error("unable to rotate root credentials on schedule", "name", name, "error", err)
+
+		if err := b.scheduleRootRotationRetry(ctx, s, name); err != nil {
+			b.Logger().Warn("unable to store next root rotation", "name", name, "error", err)
+		}
+	}
+}
+
+// scheduleRootRotationRetry sets the next root credential rotation of the
+// connection after a failed scheduled one to retry later, but no later than
+// the next regular rotation.
+func (b *databaseBackend) scheduleRootRotationRetry(ctx context.Context, s logical.Storage, name string) error {
+	lock := locksutil.LockForKey(b.connectionLocks, name)
+	lock.Lock()
+	defer lock.Unlock()
+
+	entry, err := s.Get(ctx, "config/"+name)
+	if err != nil {
+		return err
+	}
+	if entry == nil {
+		// The connection was deleted in the meantime
+		return nil
+	}
+	config := &DatabaseConfig{}
+	if err := entry.DecodeJSON(config); err != nil {
+		return err
+	}
+	if !config.rootRotationEnabled() {
+		return nil
+	}
+
+	retry := time.Now().Add(rootRotationRetryInterval)
+	if next, err := b.nextRootRotationTime(config, time.Now()); err == nil && !next.IsZero() && next.Before(retry) {
+		retry = next
+	}
+	config.NextRootRotation = retry
+	return storeConfig(ctx, s, name, config)
+}
@@ -0,0 +1,228 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/helper/namespace"
+	v5 "github.com/hashicorp/vault/sdk/database/dbplugin/v5"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/hashicorp/vault/sdk/queue"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// getRootRotationBackend returns a backend with an event sender and a stored
+// mock connection that has root credentials.
+func getRootRotationBackend(t *testing.T, dbConfig *DatabaseConfig) (*databaseBackend, logical.Storage, *logical.MockEventSender) {
+	t.Helper()
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+	eventSender := logical.NewMockEventSender()
+	config.EventsSender = eventSender
+
+	b := Backend(config)
+	require.NoError(t, b.Setup(context.Background(), config))
+	b.schedule = &TestSchedule{}
+	b.credRotationQueue = queue.New()
+
+	dbConfig.ConnectionDetails = map[string]interface{}{
+		"username": "root",
+		"password": "initial",
+	}
+	require.NoError(t, storeConfig(context.Background(), config.StorageView, mockv5, dbConfig))
+	return b, config.StorageView, eventSender
+}
+
+func readStoredConfig(t *testing.T, b *databaseBackend, s logical.Storage) *DatabaseConfig {
+	t.Helper()
+	config, err := b.DatabaseConfig(context.Background(), s, mockv5)
+	require.NoError(t, err)
+	return config
+}
+
+func readRootRotationHistoryAPI(t *testing.T, b *databaseBackend, s logical.Storage) []map[string]interface{} {
+	t.Helper()
+	resp, err := b.HandleRequest(namespace.RootContext(nil), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "rotate-root/" + mockv5 + "/history",
+		Storage:   s,
+	})
+	require.NoError(t, err)
+	require.NotNil(t, resp)
+	return resp.Data["history"].([]map[string]interface{})
+}
+
+func TestBackend_RootRotation_History(t *testing.T) {
+	b, s, events := getRootRotationBackend(t, &DatabaseConfig{})
+	defer b.Cleanup(context.Background())
+
+	rotateRoot := func() error {
+		t.Helper()
+		_, err := b.HandleRequest(namespace.RootContext(nil), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "rotate-root/" + mockv5,
+			Storage:   s,
+		})
+		return err
+	}
+
+	mockDB := setupMockDB(b)
+	mockDB.On("UpdateUser", mock.Anything, mock.Anything).
+		Return(v5.UpdateUserResponse{}, nil).Once()
+	require.NoError(t, rotateRoot())
+
+	config := readStoredConfig(t, b, s)
+	require.NotEqual(t, "initial", config.ConnectionDetails["password"])
+	require.False(t, config.LastRootRotation.IsZero())
+	require.True(t, config.NextRootRotation.IsZero())
+
+	mockDB = setupMockDB(b)
+	mockDB.On("UpdateUser", mock.Anything, mock.Anything).
+		Return(v5.UpdateUserResponse{}, errors.New("connection refused")).Once()
+	require.Error(t, rotateRoot())
+
+	history := readRootRotationHistoryAPI(t, b, s)
+	require.Len(t, history, 2)
+	require.Equal(t, true, history[0]["success"])
+	require.Equal(t, rootRotationTriggerManual, history[0]["trigger"])
+	require.Equal(t, false, history[1]["success"])
+	require.Contains(t, history[1]["error"], "connection refused")
+
+	require.Len(t, events.Events, 2)
+	require.Equal(t, "database/rotate-root", string(events.Events[0].Type))
+	require.Equal(t, rootRotationTriggerManual, events.Events[0].Event.Metadata.AsMap()["trigger"])
+	require.Equal(t, "database/rotate-root-fail", string(events.Events[1].Type))
+
+	// The history is capped.
+	for i := 0; i < maxRootRotationHistory; i++ {
+		require.NoError(t, appendRootRotationHistory(context.Background(), s, mockv5, rootRotationRecord{
+			Time:    time.Now(),
+			Trigger: rootRotationTriggerScheduled,
+			Success: true,
+		}))
+	}
+	history = readRootRotationHistoryAPI(t, b, s)
+	require.Len(t, history, maxRootRotationHistory)
+	require.Equal(t, rootRotationTriggerScheduled, history[0]["trigger"])
+
+	// Deleting the connection deletes its history.
+	_, err := b.HandleRequest(namespace.RootContext(nil), &logical.Request{
+		Operation: logical.DeleteOperation,
+		Path:      "config/" + mockv5,
+		Storage:   s,
+	})
+	require.NoError(t, err)
+	entry, err := s.Get(context.Background(), rootRotationHistoryPath+mockv5)
+	require.NoError(t, err)
+	require.Nil(t, entry)
+}
+
+func TestBackend_RootRotation_Scheduled(t *testing.T) {
+	b, s, events := getRootRotationBackend(t, &DatabaseConfig{
+		RootRotationPeriod:        time.Hour,
+		NextRootRotation:          time.Now().Add(time.Hour),
+		DisableRootRotationEvents: true,
+	})
+	defer b.Cleanup(context.Background())
+	ctx := context.Background()
+
+	// Not due yet, so no rotation is attempted.
+	setupMockDB(b)
+	b.rotateRootCredentialsOnSchedule(ctx, s)
+	require.Equal(t, "initial", readStoredConfig(t, b, s).ConnectionDetails["password"])
+
+	config := readStoredConfig(t, b, s)
+	config.NextRootRotation = time.Now().Add(-time.Second)
+	require.NoError(t, storeConfig(ctx, s, mockv5, config))
+
+	// A failed rotation is retried later.
+	mockDB := setupMockDB(b)
+	mockDB.On("UpdateUser", mock.Anything, mock.Anything).
+		Return(v5.UpdateUserResponse{}, errors.New("connection refused")).Once()
+	b.rotateRootCredentialsOnSchedule(ctx, s)
+	config = readStoredConfig(t, b, s)
+	require.Equal(t, "initial", config.ConnectionDetails["password"])
+	require.WithinDuration(t, time.Now().Add(rootRotationRetryInterval), config.NextRootRotation, time.Minute)
+
+	config.NextRootRotation = time.Now().Add(-time.Second)
+	require.NoError(t, storeConfig(ctx, s, mockv5, config))
+
+	mockDB = setupMockDB(b)
+	mockDB.On("UpdateUser", mock.Anything, mock.Anything).
+		Return(v5.UpdateUserResponse{}, nil).Once()
+	b.rotateRootCredentialsOnSchedule(ctx, s)
+	config = readStoredConfig(t, b, s)
+	require.NotEqual(t, "initial", config.ConnectionDetails["password"])
+	require.WithinDuration(t, time.Now().Add(time.Hour), config.NextRootRotation, time.Minute)
+
+	history := readRootRotationHistoryAPI(t, b, s)
+	require.Len(t, history, 2)
+	require.Equal(t, rootRotationTriggerScheduled, history[1]["trigger"])
+	require.Equal(t, true, history[1]["success"])
+
+	// Events are disabled for this connection.
+	require.Empty(t, events.Events)
+}
+
+// TestBackend_RootRotation_RetryKeepsConfigChanges tests that scheduling the
+// retry of a failed rotation waits for concurrent changes to the connection
+// and does not undo them
+func TestBackend_RootRotation_RetryKeepsConfigChanges(t *testing.T) {
+	b, s, _ := getRootRotationBackend(t, &DatabaseConfig{
+		RootRotationPeriod: time.Hour,
+	})
+	defer b.Cleanup(context.Background())
+	ctx := context.Background()
+
+	// Hold the connection as a config write or manual rotation would
+	lock := locksutil.LockForKey(b.connectionLocks, mockv5)
+	lock.Lock()
+
+	done := make(chan error)
+	go func() {
+		done <- b.scheduleRootRotationRetry(ctx, s, mockv5)
+	}()
+
+	config := readStoredConfig(t, b, s)
+	config.ConnectionDetails["password"] = "rotated"
+	require.NoError(t, storeConfig(ctx, s, mockv5, config))
+	lock.Unlock()
+	require.NoError(t, <-done)
+
+	config = readStoredConfig(t, b, s)
+	require.Equal(t, "rotated", config.ConnectionDetails["password"])
+	require.WithinDuration(t, time.Now().Add(rootRotationRetryInterval), config.NextRootRotation, time.Minute)
+
+	// A deleted connection is not stored again
+	require.NoError(t, s.Delete(ctx, "config/"+mockv5))
+	require.NoError(t, b.scheduleRootRotationRetry(ctx, s, mockv5))
+	entry, err := s.Get(ctx, "config/"+mockv5)
+	require.NoError(t, err)
+	require.Nil(t, entry)
+}
+
+func TestBackend_RootRotation_NextTime(t *testing.T) {
+	b, _, _ := getRootRotationBackend(t, &DatabaseConfig{})
+	defer b.Cleanup(context.Background())
+
+	from := time.Date(2024, 1, 1, 10, 30, 0, 0, time.UTC)
+
+	next, err := b.nextRootRotationTime(&DatabaseConfig{}, from)
+	require.NoError(t, err)
+	require.True(t, next.IsZero())
+
+	next, err = b.nextRootRotationTime(&DatabaseConfig{RootRotationPeriod: 2 * time.Hour}, from)
+	require.NoError(t, err)
+	require.Equal(t, from.Add(2*time.Hour), next)
+
+	next, err = b.nextRootRotationTime(&DatabaseConfig{RootRotationSchedule: "0 0 12 * * *"}, from)
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC), next)
+}
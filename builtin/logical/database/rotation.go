@@ -107,14 +107,21 @@ func (b *databaseBackend) populateQueue(ctx context.Context, s logical.Storage)
 
 // runTicker kicks off a periodic ticker that invoke the automatic credential
 // rotation method at a determined interval. The default interval is 5 seconds.
+// Connections with automatic root credential rotation are checked at most once
+// per rootRotationCheckInterval.
 func (b *databaseBackend) runTicker(ctx context.Context, queueTickInterval time.Duration, s logical.Storage) {
 	b.logger.Info("starting periodic ticker")
 	tick := time.NewTicker(queueTickInterval)
 	defer tick.Stop()
+	var lastRootRotationCheck time.Time
 	for {
 		select {
 		case <-tick.C:
 			b.rotateCredentials(ctx, s)
+			if time.Since(lastRootRotationCheck) >= rootRotationCheckInterval {
+				b.rotateRootCredentialsOnSchedule(ctx, s)
+				lastRootRotationCheck = time.Now()
+			}
 
 		case <-ctx.Done():
 			b.logger.Info("stopping periodic ticker")
@@ -334,6 +341,15 @@ func (b *databaseBackend) findStaticWAL(ctx context.Context, s logical.Storage,
 	return &walEntry, nil
 }
 
+// walUsername returns the account a WAL entry was written for, falling back
+// to the given username if the WAL does not name an account of the role.
+func walUsername(account *staticAccount, wal *setCredentialsWAL, fallback string) string {
+	if wal.Username == account.Username || (account.DualAccount() && wal.Username == account.SecondaryUsername) {
+		return wal.Username
+	}
+	return fallback
+}
+
 type setStaticAccountInput struct {
 	RoleName string
 	Role     *roleEntry
@@ -406,9 +422,12 @@ func (b *databaseBackend) setStaticAccount(ctx context.Context, s logical.Storag
 	dbi.RLock()
 	defer dbi.RUnlock()
 
-	updateReq := v5.UpdateUserRequest{
-		Username: input.Role.StaticAccount.Username,
-	}
+	// Dual-account roles rotate the account that is not currently active
+	username := input.Role.StaticAccount.RotationUsername()
+	var newPassword string
+	var newPrivateKey []byte
+
+	updateReq := v5.UpdateUserRequest{}
 	statements := v5.Statements{
 		Commands: input.Role.Statements.Rotation,
 	}
@@ -437,20 +456,22 @@ func (b *databaseBackend) setStaticAccount(ctx context.Context, s logical.Storag
 			output.WALID = ""
 		case wal.CredentialType == v5.CredentialTypePassword:
 			// Roll forward by using the credential in the existing WAL entry
+			username = walUsername(input.Role.StaticAccount, wal, username)
 			updateReq.CredentialType = v5.CredentialTypePassword
 			updateReq.Password = &v5.ChangePassword{
 				NewPassword: wal.NewPassword,
 				Statements:  statements,
 			}
-			input.Role.StaticAccount.Password = wal.NewPassword
+			newPassword = wal.NewPassword
 		case wal.CredentialType == v5.CredentialTypeRSAPrivateKey:
 			// Roll forward by using the credential in the existing WAL entry
+			username = walUsername(input.Role.StaticAccount, wal, username)
 			updateReq.CredentialType = v5.CredentialTypeRSAPrivateKey
 			updateReq.PublicKey = &v5.ChangePublicKey{
 				NewPublicKey: wal.NewPublicKey,
 				Statements:   statements,
 			}
-			input.Role.StaticAccount.PrivateKey = wal.NewPrivateKey
+			newPrivateKey = wal.NewPrivateKey
 		}
 	}
 
@@ -458,7 +479,7 @@ func (b *databaseBackend) setStaticAccount(ctx context.Context, s logical.Storag
 	if output.WALID == "" {
 		walEntry := &setCredentialsWAL{
 			RoleName:          input.RoleName,
-			Username:          input.Role.StaticAccount.Username,
+			Username:          username,
 			LastVaultRotation: input.Role.StaticAccount.LastVaultRotation,
 		}
 
@@ -475,7 +496,7 @@ func (b *databaseBackend) setStaticAccount(ctx context.Context, s logical.Storag
 			}
 
 			// Generate the password
-			newPassword, err := generator.generate(ctx, b, dbi.database)
+			newPassword, err = generator.generate(ctx, b, dbi.database)
 			if err != nil {
 				b.CloseIfShutdown(dbi, err)
 				return output, fmt.Errorf("failed to generate password: %s", err)
@@ -488,9 +509,6 @@ func (b *databaseBackend) setStaticAccount(ctx context.Context, s logical.Storag
 				NewPassword: newPassword,
 				Statements:  statements,
 			}
-
-			// Set new credential in static account
-			input.Role.StaticAccount.Password = newPassword
 		case v5.CredentialTypeRSAPrivateKey:
 			generator, err := newRSAKeyGenerator(input.Role.CredentialConfig)
 			if err != nil {
@@ -510,9 +528,7 @@ func (b *databaseBackend) setStaticAccount(ctx context.Context, s logical.Storag
 				NewPublicKey: public,
 				Statements:   statements,
 			}
-
-			// Set new credential in static account
-			input.Role.StaticAccount.PrivateKey = private
+			newPrivateKey = private
 		}
 
 		output.WALID, err = framework.PutWAL(ctx, s, staticWALKey, walEntry)
@@ -522,6 +538,7 @@ func (b *databaseBackend) setStaticAccount(ctx context.Context, s logical.Storag
 		b.Logger().Debug("writing WAL", "role", input.RoleName, "WAL ID", output.WALID)
 	}
 
+	updateReq.Username = username
 	_, err = dbi.database.UpdateUser(ctx, updateReq, false)
 	if err != nil {
 		b.CloseIfShutdown(dbi, err)
@@ -529,6 +546,9 @@ func (b *databaseBackend) setStaticAccount(ctx context.Context, s logical.Storag
 	}
 	modified = true
 
+	// Set new credential in static account
+	input.Role.StaticAccount.setRotatedCredential(username, newPassword, newPrivateKey)
+
 	// Store updated role information
 	// lvr is the known LastVaultRotation
 	lvr := time.Now()
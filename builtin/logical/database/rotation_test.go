@@ -1442,6 +1442,128 @@ func TestRollsPasswordForwardsUsingWAL(t *testing.T) {
 	requireWALs(t, storage, 0)
 }
 
+func TestBackend_StaticRole_Rotation_DualAccount(t *testing.T) {
+	ctx := context.Background()
+	b, storage, mockDB := getBackend(t)
+	defer b.Cleanup(ctx)
+	configureDBMount(t, storage)
+
+	updateUser := func(username string) *mock.Call {
+		return mockDB.On("UpdateUser", mock.Anything, mock.MatchedBy(func(req v5.UpdateUserRequest) bool {
+			return req.Username == username
+		}))
+	}
+
+	// Creating the role sets the credentials of both accounts
+	updateUser("green").Return(v5.UpdateUserResponse{}, nil).Once()
+	updateUser("blue").Return(v5.UpdateUserResponse{}, nil).Once()
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "static-roles/hashicorp",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"username":           "blue",
+			"secondary_username": "green",
+			"db_name":            mockv5,
+			"rotation_period":    "86400s",
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatal(resp, err)
+	}
+
+	readCreds := func() map[string]interface{} {
+		t.Helper()
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "static-creds/hashicorp",
+			Storage:   storage,
+		})
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatal(resp, err)
+		}
+		return resp.Data
+	}
+
+	rotate := func() {
+		t.Helper()
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "rotate-role/hashicorp",
+			Storage:   storage,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatal(resp, err)
+		}
+	}
+
+	blue := readCreds()
+	if blue["username"] != "blue" || blue["password"] == "" {
+		t.Fatalf("expected blue to be active: %#v", blue)
+	}
+
+	// A rotation only touches the standby account, leaving the credential
+	// handed out before it valid
+	updateUser("green").Return(v5.UpdateUserResponse{}, nil).Once()
+	rotate()
+
+	green := readCreds()
+	if green["username"] != "green" || green["password"] == "" {
+		t.Fatalf("expected green to be active: %#v", green)
+	}
+	role, err := b.StaticRole(ctx, storage, "hashicorp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if role.StaticAccount.Password != blue["password"] {
+		t.Fatal("expected the password of blue to be unchanged")
+	}
+
+	// A failed rotation of blue is rolled forward onto blue
+	updateUser("blue").Return(v5.UpdateUserResponse{}, errors.New("forced error")).Once()
+	_, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "rotate-role/hashicorp",
+		Storage:   storage,
+	})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	walIDs := requireWALs(t, storage, 1)
+	wal, err := b.findStaticWAL(ctx, storage, walIDs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wal.Username != "blue" {
+		t.Fatalf("expected WAL for blue, got %q", wal.Username)
+	}
+	if creds := readCreds(); creds["username"] != "green" || creds["password"] != green["password"] {
+		t.Fatalf("failed rotation changed the active credential: %#v", creds)
+	}
+
+	updateUser("blue").Return(v5.UpdateUserResponse{}, nil).Once()
+	rotate()
+	requireWALs(t, storage, 0)
+
+	if creds := readCreds(); creds["username"] != "blue" || creds["password"] != wal.NewPassword {
+		t.Fatalf("expected blue to be active with the WAL password: %#v", creds)
+	}
+
+	// The secondary account cannot be changed once set
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "static-roles/hashicorp",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"username":           "blue",
+			"secondary_username": "red",
+		},
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected secondary_username update to fail: %#v %v", resp, err)
+	}
+}
+
 func TestStoredWALsCorrectlyProcessed(t *testing.T) {
 	const walNewPassword = "new-password-from-wal"
 
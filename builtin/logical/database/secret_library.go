@@ -0,0 +1,116 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const SecretLibraryCredsType = "library_creds"
+
+func secretLibraryCreds(b *databaseBackend) *framework.Secret {
+	return &framework.Secret{
+		Type:   SecretLibraryCredsType,
+		Fields: map[string]*framework.FieldSchema{},
+
+		Renew:  b.secretLibraryCredsRenew(),
+		Revoke: b.secretLibraryCredsRevoke(),
+	}
+}
+
+// libraryCheckoutFromSecret returns the set name, username and check-out ID
+// stored in the internal data of a library lease.
+func libraryCheckoutFromSecret(secret *logical.Secret) (string, string, string, error) {
+	setName, ok := secret.InternalData["set_name"].(string)
+	if !ok {
+		return "", "", "", fmt.Errorf("secret is missing set_name internal data")
+	}
+	username, ok := secret.InternalData["username"].(string)
+	if !ok {
+		return "", "", "", fmt.Errorf("secret is missing username internal data")
+	}
+	checkoutID, ok := secret.InternalData["checkout_id"].(string)
+	if !ok {
+		return "", "", "", fmt.Errorf("secret is missing checkout_id internal data")
+	}
+	return setName, username, checkoutID, nil
+}
+
+func (b *databaseBackend) secretLibraryCredsRenew() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		setName, username, checkoutID, err := libraryCheckoutFromSecret(req.Secret)
+		if err != nil {
+			return nil, err
+		}
+
+		lock := locksutil.LockForKey(b.roleLocks, libraryLockKey(setName))
+		lock.RLock()
+		defer lock.RUnlock()
+
+		set, err := b.librarySet(ctx, req.Storage, setName)
+		if err != nil {
+			return nil, err
+		}
+		if set == nil {
+			return nil, fmt.Errorf("error during renew: could not find library set with name %q", setName)
+		}
+
+		account, err := b.libraryAccount(ctx, req.Storage, setName, username)
+		if err != nil {
+			return nil, err
+		}
+		if account == nil || account.Available || account.CheckoutID != checkoutID {
+			return nil, fmt.Errorf("error during renew: service account %q has already been checked in", username)
+		}
+
+		resp := &logical.Response{Secret: req.Secret}
+		resp.Secret.TTL = set.TTL
+		resp.Secret.MaxTTL = set.MaxTTL
+		return resp, nil
+	}
+}
+
+func (b *databaseBackend) secretLibraryCredsRevoke() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		setName, username, checkoutID, err := libraryCheckoutFromSecret(req.Secret)
+		if err != nil {
+			return nil, err
+		}
+
+		lock := locksutil.LockForKey(b.roleLocks, libraryLockKey(setName))
+		lock.Lock()
+		defer lock.Unlock()
+
+		set, err := b.librarySet(ctx, req.Storage, setName)
+		if err != nil {
+			return nil, err
+		}
+		if set == nil {
+			// The set, and with it the account, is gone
+			return nil, nil
+		}
+
+		account, err := b.libraryAccount(ctx, req.Storage, setName, username)
+		if err != nil {
+			return nil, err
+		}
+
+		// The account may have been checked in explicitly, possibly followed
+		// by another check-out, before this lease ended.
+		if account == nil || account.Available || account.CheckoutID != checkoutID {
+			return nil, nil
+		}
+
+		if err := b.checkInLibraryAccount(ctx, req.Storage, setName, set, username, account); err != nil {
+			return nil, err
+		}
+		b.dbEvent(ctx, "library-check-in", "", setName, true, "service_account_name", username)
+		return nil, nil
+	}
+}
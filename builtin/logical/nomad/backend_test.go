@@ -5,7 +5,10 @@ package nomad
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"reflect"
 	"runtime"
@@ -712,3 +715,277 @@ BEsMUc/DHYslZebbF1zAWnkKdTt+URhtHAFB2tYRDgkZfwW+wr/w12dJTIkX965o
 HO7tI4FgpU9b0i8FTuwYkBfjwp2j0Xd2/VBR8Qpd17qKl3I6NXDsf3ykjGZAvldH
 Tll+qwEZpXSRa5OWWTpGV8I=
 -----END PRIVATE KEY-----`
+
+func TestBackend_CredsCreate_IdentityTemplates(t *testing.T) {
+	var created []*nomadapi.ACLToken
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut || r.URL.Path != "/v1/acl/token" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var token nomadapi.ACLToken
+		if err := json.NewDecoder(r.Body).Decode(&token); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		created = append(created, &token)
+		token.AccessorID = fmt.Sprintf("accessor-%d", len(created))
+		token.SecretID = fmt.Sprintf("secret-%d", len(created))
+		json.NewEncoder(w).Encode(token)
+	}))
+	defer srv.Close()
+
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+	config.System = &logical.StaticSystemView{
+		EntityVal: &logical.Entity{
+			ID:   "entity-id",
+			Name: "entity-name",
+			Aliases: []*logical.Alias{
+				{
+					MountAccessor: "auth_jwt_1234",
+					Name:          "workload",
+					Metadata: map[string]string{
+						"nomad_namespace": "prod",
+						"nomad_job_id":    "web",
+					},
+				},
+			},
+		},
+	}
+	b, err := Factory(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	write := func(path string, data map[string]interface{}) *logical.Response {
+		t.Helper()
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Storage:   config.StorageView,
+			Data:      data,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+	readCreds := func(role, entityID string) *logical.Response {
+		t.Helper()
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "creds/" + role,
+			Storage:   config.StorageView,
+			EntityID:  entityID,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	if resp := write("config/access", map[string]interface{}{
+		"address": srv.URL,
+		"token":   "management",
+	}); resp != nil && resp.IsError() {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	if resp := write("role/invalid", map[string]interface{}{
+		"policies": "{{identity.entity.name",
+	}); resp == nil || !resp.IsError() {
+		t.Fatal("expected error for invalid template")
+	}
+	if resp := write("role/management", map[string]interface{}{
+		"type":  "management",
+		"roles": "ops",
+	}); resp == nil || !resp.IsError() {
+		t.Fatal("expected error for management token with roles")
+	}
+
+	if resp := write("role/workload", map[string]interface{}{
+		"policies": "base,{{identity.entity.aliases.auth_jwt_1234.metadata.nomad_namespace}}-{{identity.entity.aliases.auth_jwt_1234.metadata.nomad_job_id}}",
+		"roles":    "{{identity.entity.aliases.auth_jwt_1234.metadata.nomad_namespace}}-deployers",
+	}); resp != nil && resp.IsError() {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "role/workload",
+		Storage:   config.StorageView,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(resp.Data["roles"], []string{"{{identity.entity.aliases.auth_jwt_1234.metadata.nomad_namespace}}-deployers"}) {
+		t.Fatalf("unexpected roles: %v", resp.Data["roles"])
+	}
+
+	resp = readCreds("workload", "entity-id")
+	if resp == nil || resp.IsError() {
+		t.Fatalf("bad: %#v", resp)
+	}
+	if len(created) != 1 {
+		t.Fatalf("expected 1 token to be created, got %d", len(created))
+	}
+	if !reflect.DeepEqual(created[0].Policies, []string{"base", "prod-web"}) {
+		t.Fatalf("unexpected policies: %v", created[0].Policies)
+	}
+	if len(created[0].Roles) != 1 || created[0].Roles[0].Name != "prod-deployers" {
+		t.Fatalf("unexpected roles: %#v", created[0].Roles)
+	}
+
+	if resp := readCreds("workload", ""); resp == nil || !resp.IsError() {
+		t.Fatal("expected error for templated role without an entity")
+	}
+	if len(created) != 1 {
+		t.Fatalf("expected no token to be created, got %d", len(created))
+	}
+}
+
+func TestBackend_CredsCreate_Namespace(t *testing.T) {
+	policies := map[string]*nomadapi.ACLPolicy{}
+	tokens := map[string]*nomadapi.ACLToken{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPut && r.URL.Path == "/v1/acl/token":
+			var token nomadapi.ACLToken
+			if err := json.NewDecoder(r.Body).Decode(&token); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			token.AccessorID = fmt.Sprintf("accessor-%d", len(tokens)+1)
+			token.SecretID = fmt.Sprintf("secret-%d", len(tokens)+1)
+			tokens[token.AccessorID] = &token
+			json.NewEncoder(w).Encode(token)
+		case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/v1/acl/token/"):
+			delete(tokens, strings.TrimPrefix(r.URL.Path, "/v1/acl/token/"))
+		case r.Method == http.MethodPut && strings.HasPrefix(r.URL.Path, "/v1/acl/policy/"):
+			var policy nomadapi.ACLPolicy
+			if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			policies[policy.Name] = &policy
+		case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/v1/acl/policy/"):
+			delete(policies, strings.TrimPrefix(r.URL.Path, "/v1/acl/policy/"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+	config.System = &logical.StaticSystemView{
+		EntityVal: &logical.Entity{
+			ID:   "entity-id",
+			Name: "entity-name",
+			Metadata: map[string]string{
+				"team":  "payments",
+				"quote": `x" { capabilities = ["submit-job"] } namespace "*`,
+			},
+		},
+	}
+	b, err := Factory(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	write := func(path string, data map[string]interface{}) *logical.Response {
+		t.Helper()
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Storage:   config.StorageView,
+			Data:      data,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+	readCreds := func(role string) *logical.Response {
+		t.Helper()
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "creds/" + role,
+			Storage:   config.StorageView,
+			EntityID:  "entity-id",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	if resp := write("config/access", map[string]interface{}{
+		"address": srv.URL,
+		"token":   "management",
+	}); resp != nil && resp.IsError() {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	for name, data := range map[string]map[string]interface{}{
+		"invalid-namespace":  {"namespace": "prod/web"},
+		"invalid-capability": {"namespace": "prod", "namespace_capabilities": `read-job"]`},
+		"no-namespace":       {"policies": "base", "namespace_capabilities": "read-job"},
+		"management":         {"type": "management", "namespace": "prod"},
+	} {
+		if resp := write("role/"+name, data); resp == nil || !resp.IsError() {
+			t.Fatalf("expected error for role %q", name)
+		}
+	}
+
+	if resp := write("role/team", map[string]interface{}{
+		"namespace":              "{{identity.entity.metadata.team}}",
+		"namespace_capabilities": "read-job,submit-job",
+	}); resp != nil && resp.IsError() {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	resp := readCreds("team")
+	if resp == nil || resp.IsError() {
+		t.Fatalf("bad: %#v", resp)
+	}
+	if len(tokens) != 1 || len(policies) != 1 {
+		t.Fatalf("expected 1 token and 1 policy, got %d and %d", len(tokens), len(policies))
+	}
+	token := tokens[resp.Data["accessor_id"].(string)]
+	policy := policies[token.Policies[0]]
+	if policy == nil || !strings.HasPrefix(policy.Name, "vault-") {
+		t.Fatalf("unexpected token policies: %v", token.Policies)
+	}
+	expectedRules := "namespace \"payments\" {\n  capabilities = [\"read-job\", \"submit-job\"]\n}\n"
+	if policy.Rules != expectedRules {
+		t.Fatalf("unexpected rules: %q", policy.Rules)
+	}
+
+	// Revoking the token deletes its policy
+	_, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.RevokeOperation,
+		Storage:   config.StorageView,
+		Secret:    resp.Secret,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tokens) != 0 || len(policies) != 0 {
+		t.Fatalf("expected no tokens or policies, got %d and %d", len(tokens), len(policies))
+	}
+
+	// Populated namespaces must be valid Nomad names
+	if resp := write("role/quote", map[string]interface{}{
+		"namespace": "{{identity.entity.metadata.quote}}",
+	}); resp != nil && resp.IsError() {
+		t.Fatalf("bad: %#v", resp)
+	}
+	if resp := readCreds("quote"); resp == nil || !resp.IsError() {
+		t.Fatal("expected error for invalid populated namespace")
+	}
+	if len(policies) != 0 {
+		t.Fatalf("expected no policies, got %d", len(policies))
+	}
+}
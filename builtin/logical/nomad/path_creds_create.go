@@ -6,8 +6,11 @@ package nomad
 import (
 	"context"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
+	uuid "github.com/hashicorp/go-uuid"
 	"github.com/hashicorp/nomad/api"
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/logical"
@@ -82,26 +85,94 @@ func (b *backend) pathTokenRead(ctx context.Context, req *logical.Request, d *fr
 		tokenName = tokenName[:tokenNameLength]
 	}
 
+	// Populate any identity templates from the requesting entity, so a
+	// single role can mint tokens scoped to the caller's own workload.
+	populate := framework.IdentityTemplatePopulator(req.EntityID, b.System())
+	var policies []string
+	for _, policy := range role.Policies {
+		populated, err := populate(policy)
+		if err != nil {
+			return logical.ErrorResponse("unable to populate policies: %s", err), nil
+		}
+		policies = append(policies, populated)
+	}
+	var roleLinks []*api.ACLTokenRoleLink
+	for _, roleName := range role.Roles {
+		populated, err := populate(roleName)
+		if err != nil {
+			return logical.ErrorResponse("unable to populate roles: %s", err), nil
+		}
+		roleLinks = append(roleLinks, &api.ACLTokenRoleLink{Name: populated})
+	}
+
+	// Scope the token to the role's namespace with a policy of its own
+	var namespacePolicy string
+	if role.Namespace != "" {
+		namespace, err := populate(role.Namespace)
+		if err != nil {
+			return logical.ErrorResponse("unable to populate namespace: %s", err), nil
+		}
+		if !nomadNameRegex.MatchString(namespace) {
+			return logical.ErrorResponse("invalid namespace %q", namespace), nil
+		}
+
+		id, err := uuid.GenerateUUID()
+		if err != nil {
+			return nil, err
+		}
+		namespacePolicy = "vault-" + id
+		_, err = c.ACLPolicies().Upsert(&api.ACLPolicy{
+			Name:        namespacePolicy,
+			Description: fmt.Sprintf("Vault token %q in namespace %q", tokenName, namespace),
+			Rules:       namespacePolicyRules(namespace, role.NamespaceCapabilities),
+		}, nil)
+		if err != nil {
+			return nil, fmt.Errorf("error creating namespace policy: %w", err)
+		}
+		policies = append(policies, namespacePolicy)
+	}
+
 	// Create it
 	token, _, err := c.ACLTokens().Create(&api.ACLToken{
 		Name:     tokenName,
 		Type:     role.TokenType,
-		Policies: role.Policies,
+		Policies: policies,
+		Roles:    roleLinks,
 		Global:   role.Global,
 	}, nil)
 	if err != nil {
+		if namespacePolicy != "" {
+			if _, delErr := c.ACLPolicies().Delete(namespacePolicy, nil); delErr != nil {
+				b.Logger().Warn("failed to delete namespace policy", "policy", namespacePolicy, "error", delErr)
+			}
+		}
 		return nil, err
 	}
 
+	internalData := map[string]interface{}{
+		"accessor_id": token.AccessorID,
+	}
+	if namespacePolicy != "" {
+		internalData["policy"] = namespacePolicy
+	}
+
 	// Use the helper to create the secret
 	resp := b.Secret(SecretTokenType).Response(map[string]interface{}{
 		"secret_id":   token.SecretID,
 		"accessor_id": token.AccessorID,
-	}, map[string]interface{}{
-		"accessor_id": token.AccessorID,
-	})
+	}, internalData)
 	resp.Secret.TTL = leaseConfig.TTL
 	resp.Secret.MaxTTL = leaseConfig.MaxTTL
 
 	return resp, nil
 }
+
+// namespacePolicyRules returns the rules of a Nomad ACL policy that grants
+// capabilities in a single namespace.
+func namespacePolicyRules(namespace string, capabilities []string) string {
+	quoted := make([]string, 0, len(capabilities))
+	for _, capability := range capabilities {
+		quoted = append(quoted, strconv.Quote(capability))
+	}
+	return fmt.Sprintf("namespace %q {\n  capabilities = [%s]\n}\n", namespace, strings.Join(quoted, ", "))
+}
@@ -7,11 +7,23 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"regexp"
 
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/logical"
 )
 
+var (
+	// nomadNameRegex matches the names Nomad allows for namespaces and ACL
+	// policies.
+	nomadNameRegex = regexp.MustCompile(`^[a-zA-Z0-9-]{1,128}$`)
+
+	// capabilityRegex matches the names of Nomad namespace capabilities.
+	capabilityRegex = regexp.MustCompile(`^[a-z]+(-[a-z]+)*$`)
+
+	defaultNamespaceCapabilities = []string{"read-job"}
+)
+
 func pathListRoles(b *backend) *framework.Path {
 	return &framework.Path{
 		Pattern: "role/?$",
@@ -43,8 +55,32 @@ func pathRoles(b *backend) *framework.Path {
 			},
 
 			"policies": {
-				Type:        framework.TypeCommaStringSlice,
-				Description: "Comma-separated string or list of policies as previously created in Nomad. Required for 'client' token.",
+				Type: framework.TypeCommaStringSlice,
+				Description: `Comma-separated string or list of policies as previously created in Nomad.
+Either "policies" or "roles" are required for 'client' tokens. Policy names may
+contain identity templates, such as {{identity.entity.metadata.nomad_job_id}},
+populated from the requesting entity.`,
+			},
+
+			"roles": {
+				Type: framework.TypeCommaStringSlice,
+				Description: `Comma-separated string or list of ACL roles as previously created in Nomad.
+The token inherits the policies of each role. Requires Nomad 1.4 or above. Role
+names may contain identity templates populated from the requesting entity.`,
+			},
+
+			"namespace": {
+				Type: framework.TypeString,
+				Description: `Nomad namespace to scope the token to. For each token, Vault
+creates a Nomad ACL policy that grants "namespace_capabilities" in this
+namespace only, and deletes it when the token is revoked. May contain identity
+templates populated from the requesting entity.`,
+			},
+
+			"namespace_capabilities": {
+				Type: framework.TypeCommaStringSlice,
+				Description: `Comma-separated string or list of the capabilities granted
+in "namespace". Defaults to "read-job".`,
 			},
 
 			"global": {
@@ -130,6 +166,10 @@ func (b *backend) pathRolesRead(ctx context.Context, req *logical.Request, d *fr
 			"type":     role.TokenType,
 			"global":   role.Global,
 			"policies": role.Policies,
+			"roles":    role.Roles,
+
+			"namespace":              role.Namespace,
+			"namespace_capabilities": role.NamespaceCapabilities,
 		},
 	}
 	return resp, nil
@@ -151,23 +191,72 @@ func (b *backend) pathRolesWrite(ctx context.Context, req *logical.Request, d *f
 		role.Policies = policies.([]string)
 	}
 
+	roles, ok := d.GetOk("roles")
+	if ok {
+		role.Roles = roles.([]string)
+	}
+
+	namespace, ok := d.GetOk("namespace")
+	if ok {
+		role.Namespace = namespace.(string)
+	}
+
+	namespaceCapabilities, ok := d.GetOk("namespace_capabilities")
+	if ok {
+		role.NamespaceCapabilities = namespaceCapabilities.([]string)
+	}
+	if role.Namespace != "" && len(role.NamespaceCapabilities) == 0 {
+		role.NamespaceCapabilities = defaultNamespaceCapabilities
+	}
+
 	role.TokenType = d.Get("type").(string)
 	switch role.TokenType {
 	case "client":
-		if len(role.Policies) == 0 {
+		if len(role.Policies) == 0 && len(role.Roles) == 0 && role.Namespace == "" {
 			return logical.ErrorResponse(
-				"policies cannot be empty when using client tokens"), nil
+				"one of policies, roles or namespace is required when using client tokens"), nil
 		}
 	case "management":
 		if len(role.Policies) != 0 {
 			return logical.ErrorResponse(
 				"policies should be empty when using management tokens"), nil
 		}
+		if len(role.Roles) != 0 {
+			return logical.ErrorResponse(
+				"roles should be empty when using management tokens"), nil
+		}
+		if role.Namespace != "" {
+			return logical.ErrorResponse(
+				"namespace should be empty when using management tokens"), nil
+		}
 	default:
 		return logical.ErrorResponse(
 			`type must be "client" or "management"`), nil
 	}
 
+	for _, value := range append(append([]string{}, role.Policies...), role.Roles...) {
+		if _, err := framework.ValidateIdentityTemplate(value); err != nil {
+			return logical.ErrorResponse("invalid identity template %q: %s", value, err), nil
+		}
+	}
+
+	if role.Namespace != "" {
+		hasTemplating, err := framework.ValidateIdentityTemplate(role.Namespace)
+		if err != nil {
+			return logical.ErrorResponse("invalid identity template %q: %s", role.Namespace, err), nil
+		}
+		if !hasTemplating && !nomadNameRegex.MatchString(role.Namespace) {
+			return logical.ErrorResponse("invalid namespace %q", role.Namespace), nil
+		}
+	} else if len(role.NamespaceCapabilities) != 0 {
+		return logical.ErrorResponse("namespace_capabilities requires a namespace"), nil
+	}
+	for _, capability := range role.NamespaceCapabilities {
+		if !capabilityRegex.MatchString(capability) {
+			return logical.ErrorResponse("invalid namespace capability %q", capability), nil
+		}
+	}
+
 	global, ok := d.GetOk("global")
 	if ok {
 		role.Global = global.(bool)
@@ -195,6 +284,10 @@ func (b *backend) pathRolesDelete(ctx context.Context, req *logical.Request, d *
 
 type roleConfig struct {
 	Policies  []string `json:"policies"`
+	Roles     []string `json:"roles"`
 	TokenType string   `json:"type"`
 	Global    bool     `json:"global"`
+
+	Namespace             string   `json:"namespace"`
+	NamespaceCapabilities []string `json:"namespace_capabilities"`
 }
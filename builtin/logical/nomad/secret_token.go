@@ -68,5 +68,16 @@ func (b *backend) secretTokenRevoke(ctx context.Context, req *logical.Request, d
 		return nil, err
 	}
 
+	// Tokens scoped to a namespace have a policy of their own
+	if policyRaw, ok := req.Secret.InternalData["policy"]; ok {
+		policy, ok := policyRaw.(string)
+		if !ok {
+			return nil, errors.New("unable to convert policy")
+		}
+		if _, err := c.ACLPolicies().Delete(policy, nil); err != nil {
+			return nil, err
+		}
+	}
+
 	return nil, nil
 }
@@ -181,7 +181,7 @@ func (b *backend) pathCredsRead(ctx context.Context, req *logical.Request, d *fr
 				}()
 				if !isIn200s(resp.StatusCode) {
 					body, _ := ioutil.ReadAll(resp.Body)
-					return fmt.Errorf("error updating vhost permissions for %s - %d: %s", vhost, resp.StatusCode, body)
+					return fmt.Errorf("error updating topic permissions for %s on exchange %s - %d: %s", vhost, exchange, resp.StatusCode, body)
 				}
 				return nil
 			}()
@@ -190,6 +190,30 @@ func (b *backend) pathCredsRead(ctx context.Context, req *logical.Request, d *fr
 			}
 		}
 	}
+
+	// If the role had user limits specified, apply them to the created
+	// username. Limits are set one at a time so every response is checked.
+	for limit, value := range role.userLimits() {
+		err := func() error {
+			resp, err := client.PutUserLimits(username, rabbithole.UserLimitsValues{limit: value})
+			if err != nil {
+				return err
+			}
+			defer func() {
+				if err := resp.Body.Close(); err != nil {
+					b.Logger().Error(fmt.Sprintf("unable to close response body: %s", err))
+				}
+			}()
+			if !isIn200s(resp.StatusCode) {
+				body, _ := ioutil.ReadAll(resp.Body)
+				return fmt.Errorf("error updating user limit %s - %d: %s", limit, resp.StatusCode, body)
+			}
+			return nil
+		}()
+		if err != nil {
+			return nil, err
+		}
+	}
 	success = true
 
 	// Return the secret
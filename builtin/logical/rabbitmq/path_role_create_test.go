@@ -5,6 +5,10 @@ package rabbitmq
 
 import (
 	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
 	"testing"
 
 	"github.com/hashicorp/vault/sdk/logical"
@@ -162,3 +166,94 @@ func TestBackend_RoleCreate_CustomUsernameTemplate(t *testing.T) {
 
 	require.Regexp(t, `^foo-token$`, username)
 }
+
+func TestBackend_RoleCreate_TopicPermissionsAndUserLimits(t *testing.T) {
+	var mu sync.Mutex
+	requests := map[string]string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		requests[r.Method+" "+r.URL.EscapedPath()] = string(body)
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+	b := Backend()
+	if err := b.Setup(context.Background(), config); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config/connection",
+		Storage:   config.StorageView,
+		Data: map[string]interface{}{
+			"connection_uri":    srv.URL,
+			"username":          "guest",
+			"password":          "guest",
+			"username_template": "{{ .RoleName }}-user",
+			"verify_connection": false,
+		},
+	})
+	require.NoError(t, err)
+	require.False(t, resp != nil && resp.IsError(), "bad: %#v", resp)
+
+	for _, data := range []map[string]interface{}{
+		{"tags": "bar", "max_connections": -1},
+		{"tags": "bar", "max_channels": -1},
+		{"tags": "bar", "vhost_topics": `{"/": {"": {"write": ".*", "read": ".*"}}}`},
+	} {
+		resp, err = b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "roles/mqtt",
+			Storage:   config.StorageView,
+			Data:      data,
+		})
+		require.NoError(t, err)
+		require.True(t, resp != nil && resp.IsError(), "expected error for %v", data)
+	}
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "roles/mqtt",
+		Storage:   config.StorageView,
+		Data: map[string]interface{}{
+			"vhosts":          `{"iot": {"configure": "", "write": "", "read": ""}}`,
+			"vhost_topics":    `{"iot": {"amq.topic": {"write": "^devices\\.42\\.", "read": "^commands\\.42\\."}}}`,
+			"max_connections": 2,
+			"max_channels":    10,
+		},
+	})
+	require.NoError(t, err)
+	require.Nil(t, resp)
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "roles/mqtt",
+		Storage:   config.StorageView,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, resp.Data["max_connections"])
+	require.Equal(t, 10, resp.Data["max_channels"])
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "creds/mqtt",
+		Storage:   config.StorageView,
+	})
+	require.NoError(t, err)
+	require.False(t, resp == nil || resp.IsError(), "bad: %#v", resp)
+	require.Equal(t, "mqtt-user", resp.Data["username"])
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Contains(t, requests, "PUT /api/users/mqtt-user")
+	require.JSONEq(t, `{"configure": "", "write": "", "read": ""}`, requests["PUT /api/permissions/iot/mqtt-user"])
+	require.JSONEq(t, `{"exchange": "amq.topic", "write": "^devices\\.42\\.", "read": "^commands\\.42\\."}`, requests["PUT /api/topic-permissions/iot/mqtt-user"])
+	require.JSONEq(t, `{"value": 2}`, requests["PUT /api/user-limits/mqtt-user/max-connections"])
+	require.JSONEq(t, `{"value": 10}`, requests["PUT /api/user-limits/mqtt-user/max-channels"])
+	require.NotContains(t, requests, "DELETE /api/users/mqtt-user")
+}
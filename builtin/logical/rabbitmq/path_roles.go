@@ -11,6 +11,7 @@ import (
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/helper/jsonutil"
 	"github.com/hashicorp/vault/sdk/logical"
+	rabbithole "github.com/michaelklishin/rabbit-hole/v2"
 )
 
 func pathListRoles(b *backend) *framework.Path {
@@ -52,6 +53,14 @@ func pathRoles(b *backend) *framework.Path {
 				Type:        framework.TypeString,
 				Description: "A nested map of virtual hosts and exchanges to topic permissions.",
 			},
+			"max_connections": {
+				Type:        framework.TypeInt,
+				Description: "Maximum number of connections the user may open. Defaults to 0, which does not set a limit.",
+			},
+			"max_channels": {
+				Type:        framework.TypeInt,
+				Description: "Maximum number of channels the user may open across all connections. Defaults to 0, which does not set a limit.",
+			},
 		},
 		Callbacks: map[logical.Operation]framework.OperationFunc{
 			logical.ReadOperation:   b.pathRoleRead,
@@ -151,11 +160,31 @@ func (b *backend) pathRoleUpdate(ctx context.Context, req *logical.Request, d *f
 		}
 	}
 
+	for vhost, permissions := range vhostTopics {
+		for exchange := range permissions {
+			if exchange == "" {
+				return logical.ErrorResponse("vhost_topics for vhost %q contains an empty exchange name", vhost), nil
+			}
+		}
+	}
+
+	maxConnections := d.Get("max_connections").(int)
+	if maxConnections < 0 {
+		return logical.ErrorResponse("max_connections must not be negative"), nil
+	}
+
+	maxChannels := d.Get("max_channels").(int)
+	if maxChannels < 0 {
+		return logical.ErrorResponse("max_channels must not be negative"), nil
+	}
+
 	// Store it
 	entry, err := logical.StorageEntryJSON("role/"+name, &roleEntry{
-		Tags:        tags,
-		VHosts:      vhosts,
-		VHostTopics: vhostTopics,
+		Tags:           tags,
+		VHosts:         vhosts,
+		VHostTopics:    vhostTopics,
+		MaxConnections: maxConnections,
+		MaxChannels:    maxChannels,
 	})
 	if err != nil {
 		return nil, err
@@ -171,11 +200,27 @@ func (b *backend) pathRoleUpdate(ctx context.Context, req *logical.Request, d *f
 // Maps are used because the names of vhosts and exchanges will vary widely.
 // VHosts is a map with a vhost name as key and the permissions as value.
 // VHostTopics is a nested map with vhost name and exchange name as keys and
-// the topic permissions as value.
+// the topic permissions as value. MaxConnections and MaxChannels are user
+// limits, where zero means no limit is set.
 type roleEntry struct {
-	Tags        string                                     `json:"tags" structs:"tags" mapstructure:"tags"`
-	VHosts      map[string]vhostPermission                 `json:"vhosts" structs:"vhosts" mapstructure:"vhosts"`
-	VHostTopics map[string]map[string]vhostTopicPermission `json:"vhost_topics" structs:"vhost_topics" mapstructure:"vhost_topics"`
+	Tags           string                                     `json:"tags" structs:"tags" mapstructure:"tags"`
+	VHosts         map[string]vhostPermission                 `json:"vhosts" structs:"vhosts" mapstructure:"vhosts"`
+	VHostTopics    map[string]map[string]vhostTopicPermission `json:"vhost_topics" structs:"vhost_topics" mapstructure:"vhost_topics"`
+	MaxConnections int                                        `json:"max_connections" structs:"max_connections" mapstructure:"max_connections"`
+	MaxChannels    int                                        `json:"max_channels" structs:"max_channels" mapstructure:"max_channels"`
+}
+
+// userLimits returns the user limits of the role in the form expected by the
+// RabbitMQ management API.
+func (r *roleEntry) userLimits() rabbithole.UserLimitsValues {
+	limits := rabbithole.UserLimitsValues{}
+	if r.MaxConnections > 0 {
+		limits["max-connections"] = r.MaxConnections
+	}
+	if r.MaxChannels > 0 {
+		limits["max-channels"] = r.MaxChannels
+	}
+	return limits
 }
 
 // Structure representing the permissions of a vhost
@@ -234,4 +279,6 @@ will be granted. This is a JSON object passed as a string in the form:
 		}
 	}
 }
+The "max_connections" and "max_channels" parameters set user limits on the
+created user. They require RabbitMQ 3.8.10 or later.
 `
@@ -6,11 +6,10 @@ package totp
 import (
 	"context"
 	"strings"
-	"time"
 
 	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
 	"github.com/hashicorp/vault/sdk/logical"
-	cache "github.com/patrickmn/go-cache"
 )
 
 const operationPrefixTOTP = "totp"
@@ -38,13 +37,14 @@ func Backend() *backend {
 			pathListKeys(&b),
 			pathKeys(&b),
 			pathCode(&b),
+			pathLockout(&b),
 		},
 
 		Secrets:     []*framework.Secret{},
 		BackendType: logical.TypeLogical,
 	}
 
-	b.usedCodes = cache.New(0, 30*time.Second)
+	b.keyLocks = locksutil.CreateLocks()
 
 	return &b
 }
@@ -52,7 +52,9 @@ func Backend() *backend {
 type backend struct {
 	*framework.Backend
 
-	usedCodes *cache.Cache
+	// keyLocks serializes updates to the per-key validation state so that
+	// concurrent validations of the same code cannot both succeed.
+	keyLocks []*locksutil.LockEntry
 }
 
 const backendHelp = `
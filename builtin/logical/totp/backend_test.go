@@ -347,6 +347,160 @@ func TestBackend_keyCrudDefaultValues(t *testing.T) {
 	})
 }
 
+func TestBackend_usedCodePersisted(t *testing.T) {
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+	b, err := Factory(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, _ := createKey()
+
+	resp, err := b.HandleRequest(namespace.RootContext(nil), &logical.Request{
+		Path:      "keys/test",
+		Operation: logical.UpdateOperation,
+		Storage:   config.StorageView,
+		Data: map[string]interface{}{
+			"key":      key,
+			"generate": false,
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v\nerr: %v", resp, err)
+	}
+
+	code, _ := generateCode(key, 30, otplib.DigitsSix, otplib.AlgorithmSHA1)
+
+	validate := func(b logical.Backend) (*logical.Response, error) {
+		return b.HandleRequest(namespace.RootContext(nil), &logical.Request{
+			Path:      "code/test",
+			Operation: logical.UpdateOperation,
+			Storage:   config.StorageView,
+			Data: map[string]interface{}{
+				"code": code,
+			},
+		})
+	}
+
+	resp, err = validate(b)
+	if err != nil || resp.IsError() || !resp.Data["valid"].(bool) {
+		t.Fatalf("expected code to be valid: resp: %#v\nerr: %v", resp, err)
+	}
+
+	// A fresh backend sharing the same storage, as after a restart or
+	// leadership change, must still reject the code
+	b2, err := Factory(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err = validate(b2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.IsError() {
+		t.Fatalf("expected replayed code to be rejected: %#v", resp)
+	}
+}
+
+func TestBackend_failedValidationLockout(t *testing.T) {
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+	b, err := Factory(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, _ := createKey()
+
+	resp, err := b.HandleRequest(namespace.RootContext(nil), &logical.Request{
+		Path:      "keys/test",
+		Operation: logical.UpdateOperation,
+		Storage:   config.StorageView,
+		Data: map[string]interface{}{
+			"key":                    key,
+			"generate":               false,
+			"max_failed_validations": 3,
+			"lockout_duration":       "1h",
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v\nerr: %v", resp, err)
+	}
+
+	resp, err = b.HandleRequest(namespace.RootContext(nil), &logical.Request{
+		Path:      "keys/test",
+		Operation: logical.ReadOperation,
+		Storage:   config.StorageView,
+	})
+	if err != nil || resp == nil {
+		t.Fatalf("bad: resp: %#v\nerr: %v", resp, err)
+	}
+	if resp.Data["max_failed_validations"] != 3 || resp.Data["lockout_duration"] != int64(3600) {
+		t.Fatalf("unexpected lockout settings: %#v", resp.Data)
+	}
+
+	validate := func(code string) *logical.Response {
+		resp, err := b.HandleRequest(namespace.RootContext(nil), &logical.Request{
+			Path:      "code/test",
+			Operation: logical.UpdateOperation,
+			Storage:   config.StorageView,
+			Data: map[string]interface{}{
+				"code": code,
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	code, _ := generateCode(key, 30, otplib.DigitsSix, otplib.AlgorithmSHA1)
+	invalidCode := "000000"
+	if code == invalidCode {
+		invalidCode = "111111"
+	}
+
+	for i := 0; i < 3; i++ {
+		resp = validate(invalidCode)
+		if resp.IsError() || resp.Data["valid"].(bool) {
+			t.Fatalf("expected invalid code response: %#v", resp)
+		}
+	}
+
+	// The key is now locked, so even a correct code is rejected
+	resp = validate(code)
+	if !resp.IsError() {
+		t.Fatalf("expected locked key to reject validation: %#v", resp)
+	}
+
+	resp, err = b.HandleRequest(namespace.RootContext(nil), &logical.Request{
+		Path:      "lockout/test",
+		Operation: logical.ReadOperation,
+		Storage:   config.StorageView,
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: resp: %#v\nerr: %v", resp, err)
+	}
+	if !resp.Data["locked"].(bool) {
+		t.Fatalf("expected key to be locked: %#v", resp.Data)
+	}
+
+	resp, err = b.HandleRequest(namespace.RootContext(nil), &logical.Request{
+		Path:      "lockout/test",
+		Operation: logical.DeleteOperation,
+		Storage:   config.StorageView,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v\nerr: %v", resp, err)
+	}
+
+	resp = validate(code)
+	if resp.IsError() || !resp.Data["valid"].(bool) {
+		t.Fatalf("expected code to be valid after reset: %#v", resp)
+	}
+}
+
 func TestBackend_createKeyMissingKeyValue(t *testing.T) {
 	config := logical.TestBackendConfig()
 	config.StorageView = &logical.InmemStorage{}
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
 	"github.com/hashicorp/vault/sdk/logical"
 	otplib "github.com/pquerna/otp"
 	totplib "github.com/pquerna/otp/totp"
@@ -93,6 +94,10 @@ func (b *backend) pathValidateCode(ctx context.Context, req *logical.Request, da
 		return logical.ErrorResponse("the code value is required"), nil
 	}
 
+	lock := locksutil.LockForKey(b.keyLocks, name)
+	lock.Lock()
+	defer lock.Unlock()
+
 	// Get the key's stored values
 	key, err := b.Key(ctx, req.Storage, name)
 	if err != nil {
@@ -102,14 +107,27 @@ func (b *backend) pathValidateCode(ctx context.Context, req *logical.Request, da
 		return logical.ErrorResponse(fmt.Sprintf("unknown key: %s", name)), nil
 	}
 
-	usedName := fmt.Sprintf("%s_%s", name, code)
+	state, err := b.codeState(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	state.prune(now)
 
-	_, ok := b.usedCodes.Get(usedName)
-	if ok {
+	if state.locked(now) {
+		return logical.ErrorResponse("key is locked due to too many failed validations; try again later"), nil
+	}
+
+	if _, ok := state.UsedCodes[code]; ok {
+		state.recordFailure(key, now)
+		if err := b.putCodeState(ctx, req.Storage, name, state); err != nil {
+			return nil, err
+		}
 		return logical.ErrorResponse("code already used; wait until the next time period"), nil
 	}
 
-	valid, err := totplib.ValidateCustom(code, key.Key, time.Now(), totplib.ValidateOpts{
+	valid, err := totplib.ValidateCustom(code, key.Key, now, totplib.ValidateOpts{
 		Period:    key.Period,
 		Skew:      key.Skew,
 		Digits:    key.Digits,
@@ -119,14 +137,20 @@ func (b *backend) pathValidateCode(ctx context.Context, req *logical.Request, da
 		return logical.ErrorResponse("an error occurred while validating the code"), err
 	}
 
-	// Take the key skew, add two for behind and in front, and multiple that by
-	// the period to cover the full possibility of the validity of the key
-	err = b.usedCodes.Add(usedName, nil, time.Duration(
-		int64(time.Second)*
-			int64(key.Period)*
-			int64((2+key.Skew))))
-	if err != nil {
-		return nil, fmt.Errorf("error adding code to used cache: %w", err)
+	if valid {
+		// Take the key skew, add two for behind and in front, and multiple that by
+		// the period to cover the full possibility of the validity of the key
+		state.UsedCodes[code] = now.Add(time.Duration(
+			int64(time.Second) *
+				int64(key.Period) *
+				int64((2 + key.Skew))))
+		state.FailedValidations = 0
+	} else {
+		state.recordFailure(key, now)
+	}
+
+	if err := b.putCodeState(ctx, req.Storage, name, state); err != nil {
+		return nil, err
 	}
 
 	return &logical.Response{
@@ -136,11 +160,85 @@ func (b *backend) pathValidateCode(ctx context.Context, req *logical.Request, da
 	}, nil
 }
 
+// codeStateEntry tracks the validation history of a single key. It is
+// persisted so that replay protection and lockouts survive restarts and
+// leadership changes.
+type codeStateEntry struct {
+	// UsedCodes maps each successfully validated code to the time after
+	// which it can no longer be valid and may be forgotten.
+	UsedCodes         map[string]time.Time `json:"used_codes"`
+	FailedValidations int                  `json:"failed_validations"`
+	LockedUntil       time.Time            `json:"locked_until"`
+}
+
+func (s *codeStateEntry) prune(now time.Time) {
+	for code, expiry := range s.UsedCodes {
+		if now.After(expiry) {
+			delete(s.UsedCodes, code)
+		}
+	}
+	if !s.LockedUntil.IsZero() && !now.Before(s.LockedUntil) {
+		s.LockedUntil = time.Time{}
+	}
+}
+
+func (s *codeStateEntry) locked(now time.Time) bool {
+	return now.Before(s.LockedUntil)
+}
+
+// recordFailure counts a failed validation and locks the key once the
+// configured threshold has been reached.
+func (s *codeStateEntry) recordFailure(key *keyEntry, now time.Time) {
+	if key.MaxFailedValidations <= 0 {
+		return
+	}
+
+	s.FailedValidations++
+	if s.FailedValidations >= key.MaxFailedValidations {
+		s.LockedUntil = now.Add(key.LockoutDuration)
+		s.FailedValidations = 0
+	}
+}
+
+func (b *backend) codeState(ctx context.Context, s logical.Storage, name string) (*codeStateEntry, error) {
+	result := &codeStateEntry{
+		UsedCodes: make(map[string]time.Time),
+	}
+
+	entry, err := s.Get(ctx, "state/"+name)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return result, nil
+	}
+
+	if err := entry.DecodeJSON(result); err != nil {
+		return nil, err
+	}
+	if result.UsedCodes == nil {
+		result.UsedCodes = make(map[string]time.Time)
+	}
+
+	return result, nil
+}
+
+func (b *backend) putCodeState(ctx context.Context, s logical.Storage, name string, state *codeStateEntry) error {
+	entry, err := logical.StorageEntryJSON("state/"+name, state)
+	if err != nil {
+		return err
+	}
+
+	return s.Put(ctx, entry)
+}
+
 const pathCodeHelpSyn = `
 Request time-based one-time use password or validate a password for a certain key .
 `
 
 const pathCodeHelpDesc = `
-This path generates and validates time-based one-time use passwords for a certain key. 
+This path generates and validates time-based one-time use passwords for a certain key.
+A code validates successfully at most once. If the key has max_failed_validations
+set, the key is locked for lockout_duration after that many failed validations.
 
 `
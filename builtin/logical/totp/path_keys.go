@@ -13,8 +13,10 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
 	"github.com/hashicorp/vault/sdk/logical"
 	otplib "github.com/pquerna/otp"
 	totplib "github.com/pquerna/otp/totp"
@@ -120,6 +122,18 @@ func pathKeys(b *backend) *framework.Path {
 				Type:        framework.TypeString,
 				Description: `A TOTP url string containing all of the parameters for key setup. Only used if generate is false.`,
 			},
+
+			"max_failed_validations": {
+				Type:        framework.TypeInt,
+				Default:     0,
+				Description: `The number of consecutive failed code validations after which the key is locked. If this value is 0, keys are never locked.`,
+			},
+
+			"lockout_duration": {
+				Type:        framework.TypeDurationSecond,
+				Default:     300,
+				Description: `The length of time a key stays locked after reaching max_failed_validations.`,
+			},
 		},
 
 		Operations: map[logical.Operation]framework.OperationHandler{
@@ -166,11 +180,21 @@ func (b *backend) Key(ctx context.Context, s logical.Storage, n string) (*keyEnt
 }
 
 func (b *backend) pathKeyDelete(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	err := req.Storage.Delete(ctx, "key/"+data.Get("name").(string))
+	name := data.Get("name").(string)
+
+	lock := locksutil.LockForKey(b.keyLocks, name)
+	lock.Lock()
+	defer lock.Unlock()
+
+	err := req.Storage.Delete(ctx, "key/"+name)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := req.Storage.Delete(ctx, "state/"+name); err != nil {
+		return nil, err
+	}
+
 	return nil, nil
 }
 
@@ -194,6 +218,9 @@ func (b *backend) pathKeyRead(ctx context.Context, req *logical.Request, data *f
 			"period":       key.Period,
 			"algorithm":    algorithm,
 			"digits":       key.Digits,
+
+			"max_failed_validations": key.MaxFailedValidations,
+			"lockout_duration":       int64(key.LockoutDuration.Seconds()),
 		},
 	}, nil
 }
@@ -221,6 +248,8 @@ func (b *backend) pathKeyCreate(ctx context.Context, req *logical.Request, data
 	qrSize := data.Get("qr_size").(int)
 	keySize := data.Get("key_size").(int)
 	inputURL := data.Get("url").(string)
+	maxFailedValidations := data.Get("max_failed_validations").(int)
+	lockoutDuration := time.Duration(data.Get("lockout_duration").(int)) * time.Second
 
 	if generate {
 		if keyString != "" {
@@ -335,6 +364,14 @@ func (b *backend) pathKeyCreate(ctx context.Context, req *logical.Request, data
 		return logical.ErrorResponse("the key_size value must be greater than zero"), nil
 	}
 
+	if maxFailedValidations < 0 {
+		return logical.ErrorResponse("the max_failed_validations value must be greater than or equal to zero"), nil
+	}
+
+	if maxFailedValidations > 0 && lockoutDuration <= 0 {
+		return logical.ErrorResponse("the lockout_duration value must be greater than zero when max_failed_validations is set"), nil
+	}
+
 	// Period, Skew and Key Size need to be unsigned ints
 	uintPeriod := uint(period)
 	uintSkew := uint(skew)
@@ -415,15 +452,21 @@ func (b *backend) pathKeyCreate(ctx context.Context, req *logical.Request, data
 		}
 	}
 
+	lock := locksutil.LockForKey(b.keyLocks, name)
+	lock.Lock()
+	defer lock.Unlock()
+
 	// Store it
 	entry, err := logical.StorageEntryJSON("key/"+name, &keyEntry{
-		Key:         keyString,
-		Issuer:      issuer,
-		AccountName: accountName,
-		Period:      uintPeriod,
-		Algorithm:   keyAlgorithm,
-		Digits:      keyDigits,
-		Skew:        uintSkew,
+		Key:                  keyString,
+		Issuer:               issuer,
+		AccountName:          accountName,
+		Period:               uintPeriod,
+		Algorithm:            keyAlgorithm,
+		Digits:               keyDigits,
+		Skew:                 uintSkew,
+		MaxFailedValidations: maxFailedValidations,
+		LockoutDuration:      lockoutDuration,
 	})
 	if err != nil {
 		return nil, err
@@ -432,6 +475,11 @@ func (b *backend) pathKeyCreate(ctx context.Context, req *logical.Request, data
 		return nil, err
 	}
 
+	// Validation history belongs to the previous key material, if any
+	if err := req.Storage.Delete(ctx, "state/"+name); err != nil {
+		return nil, err
+	}
+
 	return response, nil
 }
 
@@ -443,6 +491,9 @@ type keyEntry struct {
 	Algorithm   otplib.Algorithm `json:"algorithm" mapstructure:"algorithm" structs:"algorithm"`
 	Digits      otplib.Digits    `json:"digits" mapstructure:"digits" structs:"digits"`
 	Skew        uint             `json:"skew" mapstructure:"skew" structs:"skew"`
+
+	MaxFailedValidations int           `json:"max_failed_validations" mapstructure:"max_failed_validations" structs:"max_failed_validations"`
+	LockoutDuration      time.Duration `json:"lockout_duration" mapstructure:"lockout_duration" structs:"lockout_duration"`
 }
 
 const pathKeyHelpSyn = `
@@ -0,0 +1,128 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package totp
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func pathLockout(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "lockout/" + framework.GenericNameWithAtRegex("name"),
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixTOTP,
+			OperationSuffix: "lockout",
+		},
+
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: "Name of the key.",
+			},
+		},
+
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.pathLockoutRead,
+				DisplayAttrs: &framework.DisplayAttributes{
+					OperationVerb: "read",
+				},
+			},
+			logical.DeleteOperation: &framework.PathOperation{
+				Callback: b.pathLockoutReset,
+				DisplayAttrs: &framework.DisplayAttributes{
+					OperationVerb: "reset",
+				},
+			},
+		},
+
+		HelpSynopsis:    pathLockoutHelpSyn,
+		HelpDescription: pathLockoutHelpDesc,
+	}
+}
+
+func (b *backend) pathLockoutRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+
+	lock := locksutil.LockForKey(b.keyLocks, name)
+	lock.RLock()
+	defer lock.RUnlock()
+
+	key, err := b.Key(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return logical.ErrorResponse(fmt.Sprintf("unknown key: %s", name)), nil
+	}
+
+	state, err := b.codeState(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	state.prune(now)
+
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"locked":             state.locked(now),
+			"failed_validations": state.FailedValidations,
+		},
+	}
+	if state.locked(now) {
+		resp.Data["locked_until"] = state.LockedUntil.Format(time.RFC3339)
+	}
+
+	return resp, nil
+}
+
+func (b *backend) pathLockoutReset(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+
+	lock := locksutil.LockForKey(b.keyLocks, name)
+	lock.Lock()
+	defer lock.Unlock()
+
+	key, err := b.Key(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return logical.ErrorResponse(fmt.Sprintf("unknown key: %s", name)), nil
+	}
+
+	state, err := b.codeState(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep the used codes so that resetting a lockout cannot be used to
+	// replay a code
+	state.FailedValidations = 0
+	state.LockedUntil = time.Time{}
+
+	if err := b.putCodeState(ctx, req.Storage, name, state); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+const pathLockoutHelpSyn = `
+Read or reset the failed validation lockout state of a key.
+`
+
+const pathLockoutHelpDesc = `
+This path reports whether a key is currently locked because of too many failed
+code validations. Deleting it clears the failed validation count and unlocks
+the key.
+`
@@ -0,0 +1,68 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package webhook
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const operationPrefixWebhook = "webhook"
+
+// Factory creates and configures the backend
+func Factory(ctx context.Context, conf *logical.BackendConfig) (logical.Backend, error) {
+	b := Backend()
+	if err := b.Setup(ctx, conf); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Backend creates a new backend with all the paths and secrets belonging to it
+func Backend() *backend {
+	var b backend
+	b.Backend = &framework.Backend{
+		Help: strings.TrimSpace(backendHelp),
+
+		PathsSpecial: &logical.Paths{
+			SealWrapStorage: []string{
+				"role/",
+			},
+		},
+
+		Paths: []*framework.Path{
+			pathListRoles(&b),
+			pathRoles(&b),
+			pathCreds(&b),
+		},
+
+		Secrets: []*framework.Secret{
+			secretCreds(&b),
+		},
+
+		WALRollback:       b.walRollback,
+		WALRollbackMinAge: 5 * time.Minute,
+		BackendType:       logical.TypeLogical,
+	}
+
+	return &b
+}
+
+type backend struct {
+	*framework.Backend
+}
+
+const backendHelp = `
+The webhook secrets engine generates dynamic credentials by calling
+operator-configured HTTP endpoints.
+
+Each role configures a create hook that is called to issue a credential, a
+revoke hook that is called when the lease expires or is revoked, and an
+optional renew hook that is called when the lease is renewed. The JSON
+response of the create hook is returned as the credential.
+`
@@ -0,0 +1,426 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package webhook
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+)
+
+func getBackend(t *testing.T) (*backend, logical.Storage) {
+	t.Helper()
+
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+	config.System = &logical.StaticSystemView{
+		DefaultLeaseTTLVal: time.Hour,
+		MaxLeaseTTLVal:     24 * time.Hour,
+	}
+
+	b := Backend()
+	require.NoError(t, b.Setup(context.Background(), config))
+	return b, config.StorageView
+}
+
+type hookRequest struct {
+	Method string
+	Path   string
+	Header http.Header
+	Body   string
+}
+
+// testHookServer records the requests it receives and answers them with the
+// given handler.
+type testHookServer struct {
+	*httptest.Server
+
+	lock     sync.Mutex
+	requests []hookRequest
+}
+
+func newTestHookServer(t *testing.T, handler func(w http.ResponseWriter, r *http.Request)) *testHookServer {
+	t.Helper()
+
+	srv := &testHookServer{}
+	srv.Server = httptest.NewServer(srv.recorder(handler))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func (s *testHookServer) recorder(handler func(w http.ResponseWriter, r *http.Request)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		s.lock.Lock()
+		s.requests = append(s.requests, hookRequest{
+			Method: r.Method,
+			Path:   r.URL.Path,
+			Header: r.Header.Clone(),
+			Body:   string(body),
+		})
+		s.lock.Unlock()
+		handler(w, r)
+	}
+}
+
+func (s *testHookServer) Requests() []hookRequest {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]hookRequest(nil), s.requests...)
+}
+
+func saasHandler(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/keys":
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":      "key-1",
+			"api_key": "s3cr3t",
+		})
+	case r.Method == http.MethodPost && r.URL.Path == "/keys/key-1/extend":
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodDelete && r.URL.Path == "/keys/key-1":
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func writeRole(t *testing.T, b *backend, s logical.Storage, name string, data map[string]interface{}) *logical.Response {
+	t.Helper()
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "role/" + name,
+		Storage:   s,
+		Data:      data,
+	})
+	require.NoError(t, err)
+	return resp
+}
+
+func readCreds(t *testing.T, b *backend, s logical.Storage, name string) (*logical.Response, error) {
+	t.Helper()
+
+	return b.HandleRequest(context.Background(), &logical.Request{
+		Operation:   logical.ReadOperation,
+		Path:        "creds/" + name,
+		Storage:     s,
+		DisplayName: "token-app",
+	})
+}
+
+func TestBackend_CredsLifecycle(t *testing.T) {
+	b, s := getBackend(t)
+	srv := newTestHookServer(t, saasHandler)
+
+	resp := writeRole(t, b, s, "saas", map[string]interface{}{
+		"create_url":  srv.URL + "/keys",
+		"create_body": `{"name": {{ printf "vault-%s-%s" .DisplayName .RequestID | json }}, "ttl": {{ .TTL }}}`,
+		"renew_url":   srv.URL + "/keys/{{ .Response.id | urlquery }}/extend",
+		"renew_body":  `{"ttl": {{ .TTL }}}`,
+		"revoke_url":  srv.URL + "/keys/{{ .Response.id | urlquery }}",
+		"headers":     map[string]interface{}{"Authorization": "Bearer admin"},
+		"ttl":         "10m",
+		"max_ttl":     "1h",
+	})
+	require.Nil(t, resp)
+
+	resp, err := readCreds(t, b, s, "saas")
+	require.NoError(t, err)
+	require.False(t, resp.IsError(), "bad: %#v", resp)
+	require.Equal(t, "s3cr3t", resp.Data["api_key"])
+	require.Equal(t, "key-1", resp.Data["id"])
+	require.Equal(t, 10*time.Minute, resp.Secret.TTL)
+	require.Equal(t, time.Hour, resp.Secret.MaxTTL)
+
+	wals, err := framework.ListWAL(context.Background(), s)
+	require.NoError(t, err)
+	require.Empty(t, wals)
+
+	requests := srv.Requests()
+	require.Len(t, requests, 1)
+	require.Equal(t, "Bearer admin", requests[0].Header.Get("Authorization"))
+	require.Equal(t, "application/json", requests[0].Header.Get("Content-Type"))
+	var createBody struct {
+		Name string `json:"name"`
+		TTL  int    `json:"ttl"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(requests[0].Body), &createBody))
+	require.Equal(t, "vault-token-app-"+resp.Secret.InternalData["request_id"].(string), createBody.Name)
+	require.Equal(t, 600, createBody.TTL)
+
+	// Round trip the internal data through JSON like the expiration manager
+	// does when the lease is stored.
+	secret := resp.Secret
+	internal, err := json.Marshal(secret.InternalData)
+	require.NoError(t, err)
+	secret.InternalData = nil
+	require.NoError(t, json.Unmarshal(internal, &secret.InternalData))
+	secret.InternalData["secret_type"] = SecretCredsType
+	secret.IssueTime = time.Now()
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.RenewOperation,
+		Storage:   s,
+		Secret:    secret,
+	})
+	require.NoError(t, err)
+	require.False(t, resp.IsError(), "bad: %#v", resp)
+
+	requests = srv.Requests()
+	require.Len(t, requests, 2)
+	require.Equal(t, "/keys/key-1/extend", requests[1].Path)
+	require.JSONEq(t, `{"ttl": 600}`, requests[1].Body)
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.RevokeOperation,
+		Storage:   s,
+		Secret:    secret,
+	})
+	require.NoError(t, err)
+	require.Nil(t, resp)
+
+	requests = srv.Requests()
+	require.Len(t, requests, 3)
+	require.Equal(t, http.MethodDelete, requests[2].Method)
+	require.Equal(t, "/keys/key-1", requests[2].Path)
+	require.Empty(t, requests[2].Body)
+
+	// Revoking a credential the endpoint no longer knows succeeds.
+	secret.InternalData["response"] = map[string]interface{}{"id": "key-2"}
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.RevokeOperation,
+		Storage:   s,
+		Secret:    secret,
+	})
+	require.NoError(t, err)
+	require.Nil(t, resp)
+}
+
+func TestBackend_CredsCreateHookRejected(t *testing.T) {
+	b, s := getBackend(t)
+	srv := newTestHookServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		io.WriteString(w, `{"error": "quota exceeded"}`)
+	})
+
+	writeRole(t, b, s, "saas", map[string]interface{}{
+		"create_url": srv.URL + "/keys",
+		"revoke_url": srv.URL + "/keys/{{ .Response.id }}",
+	})
+
+	resp, err := readCreds(t, b, s, "saas")
+	require.NoError(t, err)
+	require.True(t, resp.IsError())
+	require.Contains(t, resp.Error().Error(), "quota exceeded")
+
+	// The endpoint responded, so there is nothing to roll back.
+	wals, err := framework.ListWAL(context.Background(), s)
+	require.NoError(t, err)
+	require.Empty(t, wals)
+}
+
+func TestBackend_CredsRollback(t *testing.T) {
+	var created []string
+	var lock sync.Mutex
+	srv := newTestHookServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			// Abort the connection, so the outcome of the create hook is
+			// unknown to the backend.
+			panic(http.ErrAbortHandler)
+		}
+		lock.Lock()
+		created = append(created, r.URL.Path)
+		lock.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	rollback := func(t *testing.T, b *backend, s logical.Storage) {
+		t.Helper()
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.RollbackOperation,
+			Storage:   s,
+			Data:      map[string]interface{}{"immediate": true},
+		})
+		require.NoError(t, err)
+		require.Nil(t, resp)
+
+		wals, err := framework.ListWAL(context.Background(), s)
+		require.NoError(t, err)
+		require.Empty(t, wals)
+	}
+
+	t.Run("revoke by request ID", func(t *testing.T) {
+		b, s := getBackend(t)
+		writeRole(t, b, s, "saas", map[string]interface{}{
+			"create_url": srv.URL + "/keys",
+			"revoke_url": srv.URL + "/keys/by-name/{{ .RequestID }}",
+		})
+
+		_, err := readCreds(t, b, s, "saas")
+		require.Error(t, err)
+
+		wals, err := framework.ListWAL(context.Background(), s)
+		require.NoError(t, err)
+		require.Len(t, wals, 1)
+		wal, err := framework.GetWAL(context.Background(), s, wals[0])
+		require.NoError(t, err)
+		requestID := wal.Data.(map[string]interface{})["request_id"].(string)
+
+		rollback(t, b, s)
+
+		lock.Lock()
+		defer lock.Unlock()
+		require.Equal(t, []string{"/keys/by-name/" + requestID}, created)
+	})
+
+	t.Run("revoke requires create response", func(t *testing.T) {
+		b, s := getBackend(t)
+		writeRole(t, b, s, "saas", map[string]interface{}{
+			"create_url": srv.URL + "/keys",
+			"revoke_url": srv.URL + "/keys/{{ .Response.id }}",
+		})
+
+		lock.Lock()
+		created = nil
+		lock.Unlock()
+
+		_, err := readCreds(t, b, s, "saas")
+		require.Error(t, err)
+
+		rollback(t, b, s)
+
+		lock.Lock()
+		defer lock.Unlock()
+		require.Empty(t, created)
+	})
+}
+
+func TestBackend_CredsMutualTLS(t *testing.T) {
+	clientCert, clientKey := generateTestCertificate(t)
+	pool := x509.NewCertPool()
+	require.True(t, pool.AppendCertsFromPEM([]byte(clientCert)))
+
+	srv := &testHookServer{}
+	srv.Server = httptest.NewUnstartedServer(srv.recorder(saasHandler))
+	srv.TLS = &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  pool,
+	}
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+
+	caCert := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}))
+
+	b, s := getBackend(t)
+
+	// Without a client certificate the handshake fails.
+	writeRole(t, b, s, "saas", map[string]interface{}{
+		"create_url": srv.URL + "/keys",
+		"revoke_url": srv.URL + "/keys/{{ .Response.id }}",
+		"ca_cert":    caCert,
+	})
+	_, err := readCreds(t, b, s, "saas")
+	require.Error(t, err)
+
+	resp := writeRole(t, b, s, "saas", map[string]interface{}{
+		"client_cert": clientCert,
+		"client_key":  clientKey,
+	})
+	require.Nil(t, resp)
+
+	resp, err = readCreds(t, b, s, "saas")
+	require.NoError(t, err)
+	require.False(t, resp.IsError(), "bad: %#v", resp)
+	require.Equal(t, "s3cr3t", resp.Data["api_key"])
+}
+
+func generateTestCertificate(t *testing.T) (string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "vault"},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+}
+
+func TestBuildHookRequest_Escaping(t *testing.T) {
+	role := &roleEntry{}
+	data := hookTemplateData{
+		DisplayName: `token-app", "admin": true, "x": "`,
+		TTL:         3600,
+		Response: map[string]interface{}{
+			"id": "../admin?force=true",
+		},
+	}
+
+	// Values are JSON encoded in bodies, unless the template already does
+	for _, body := range []string{
+		`{"name": {{ .DisplayName }}, "ttl": {{ .TTL }}}`,
+		`{"name": {{ .DisplayName | json }}, "ttl": {{ .TTL }}}`,
+		`{"name": {{ if .DisplayName }}{{ .DisplayName }}{{ end }}, "ttl": {{ .TTL }}}`,
+	} {
+		req, err := role.buildHookRequest(context.Background(), hookConfig{
+			URL:    "https://saas.example.com/keys",
+			Method: http.MethodPost,
+			Body:   body,
+		}, data)
+		require.NoError(t, err, body)
+		var decoded map[string]interface{}
+		require.NoError(t, json.NewDecoder(req.Body).Decode(&decoded))
+		require.Equal(t, map[string]interface{}{"name": data.DisplayName, "ttl": float64(3600)}, decoded, body)
+	}
+
+	// Quoting a value produces invalid JSON rather than letting it escape
+	// the string
+	_, err := role.buildHookRequest(context.Background(), hookConfig{
+		URL:    "https://saas.example.com/keys",
+		Method: http.MethodPost,
+		Body:   `{"name": "{{ .DisplayName }}"}`,
+	}, data)
+	require.EqualError(t, err, "rendered body is not valid JSON")
+
+	// Values are percent-encoded in URLs
+	req, err := role.buildHookRequest(context.Background(), hookConfig{
+		URL:    "https://saas.example.com/keys/{{ .Response.id }}",
+		Method: http.MethodDelete,
+	}, data)
+	require.NoError(t, err)
+	require.Equal(t, "/keys/..%2Fadmin%3Fforce%3Dtrue", req.URL.EscapedPath())
+	require.Empty(t, req.URL.RawQuery)
+}
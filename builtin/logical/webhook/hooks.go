@@ -0,0 +1,258 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	cleanhttp "github.com/hashicorp/go-cleanhttp"
+	"github.com/hashicorp/go-secure-stdlib/strutil"
+)
+
+// maxResponseSize is the maximum size of a hook response body that is read.
+const maxResponseSize = 1024 * 1024
+
+// hookTemplateData holds the values available to hook URL and body
+// templates.
+type hookTemplateData struct {
+	RoleName    string
+	DisplayName string
+	RequestID   string
+	TTL         int64
+	Response    map[string]interface{}
+}
+
+// hookError is returned when a hook endpoint responds with a non-2xx status.
+// A hookError means the endpoint processed the request, unlike transport
+// errors where the outcome of the request is unknown.
+type hookError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *hookError) Error() string {
+	return fmt.Sprintf("hook responded with status %d: %s", e.StatusCode, e.Body)
+}
+
+// hookTemplateContext is where a hook template is rendered. It decides
+// how values interpolated into the template are escaped by default.
+type hookTemplateContext int
+
+const (
+	hookTemplateURL hookTemplateContext = iota
+	hookTemplateBody
+)
+
+// escapers returns the functions that escape a value for the context. The
+// first one is added to pipelines that don't end with any of them.
+func (c hookTemplateContext) escapers() []string {
+	if c == hookTemplateBody {
+		return []string{"json"}
+	}
+	return []string{"pathescape", "urlquery"}
+}
+
+// parseHookTemplate parses a hook URL or body template. Referencing a
+// missing value is an error, so a hook is never called with a partially
+// rendered request.
+//
+// Values are escaped for the context by default: they are JSON encoded in
+// bodies and percent-encoded in URLs, so neither the requesting token nor
+// the create hook's response can change the structure of a request.
+func parseHookTemplate(raw string, tc hookTemplateContext) (*template.Template, error) {
+	tmpl, err := template.New("hook").
+		Option("missingkey=error").
+		Funcs(template.FuncMap{
+			"json":       jsonEncode,
+			"pathescape": pathEscape,
+		}).
+		Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil {
+			escapeHookTemplateNode(t.Tree.Root, tc.escapers())
+		}
+	}
+	return tmpl, nil
+}
+
+// escapeHookTemplateNode appends the default escaper to the pipelines of the
+// actions under the node that output a value without escaping it.
+func escapeHookTemplateNode(node parse.Node, escapers []string) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			escapeHookTemplateNode(child, escapers)
+		}
+	case *parse.ActionNode:
+		// Declarations and assignments don't output anything
+		if len(n.Pipe.Decl) > 0 || len(n.Pipe.Cmds) == 0 {
+			return
+		}
+		last := n.Pipe.Cmds[len(n.Pipe.Cmds)-1]
+		if ident, ok := last.Args[0].(*parse.IdentifierNode); ok && strutil.StrListContains(escapers, ident.Ident) {
+			return
+		}
+		n.Pipe.Cmds = append(n.Pipe.Cmds, &parse.CommandNode{
+			NodeType: parse.NodeCommand,
+			Pos:      n.Pos,
+			Args:     []parse.Node{parse.NewIdentifier(escapers[0]).SetPos(n.Pos)},
+		})
+	case *parse.IfNode:
+		escapeHookTemplateNode(n.List, escapers)
+		escapeHookTemplateNode(n.ElseList, escapers)
+	case *parse.RangeNode:
+		escapeHookTemplateNode(n.List, escapers)
+		escapeHookTemplateNode(n.ElseList, escapers)
+	case *parse.WithNode:
+		escapeHookTemplateNode(n.List, escapers)
+		escapeHookTemplateNode(n.ElseList, escapers)
+	}
+}
+
+func jsonEncode(v interface{}) (string, error) {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
+
+// pathEscape percent-encodes every character of the value but the
+// unreserved ones, so it is a single path segment or query value.
+func pathEscape(v interface{}) string {
+	raw := fmt.Sprint(v)
+	var buf strings.Builder
+	for i := 0; i < len(raw); i++ {
+		c := raw[i]
+		if 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '-' || c == '_' || c == '.' || c == '~' {
+			buf.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&buf, "%%%02X", c)
+	}
+	return buf.String()
+}
+
+func renderHookTemplate(raw string, tc hookTemplateContext, data hookTemplateData) (string, error) {
+	tmpl, err := parseHookTemplate(raw, tc)
+	if err != nil {
+		return "", err
+	}
+	var buf strings.Builder
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// buildHookRequest renders the hook into an HTTP request. Rendering is kept
+// separate from sending, so callers can tell a hook that cannot be rendered
+// from one that failed.
+func (r *roleEntry) buildHookRequest(ctx context.Context, hook hookConfig, data hookTemplateData) (*http.Request, error) {
+	rawURL, err := renderHookTemplate(hook.URL, hookTemplateURL, data)
+	if err != nil {
+		return nil, fmt.Errorf("unable to render url: %w", err)
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid url scheme %q", u.Scheme)
+	}
+
+	body, err := renderHookTemplate(hook.Body, hookTemplateBody, data)
+	if err != nil {
+		return nil, fmt.Errorf("unable to render body: %w", err)
+	}
+	var bodyReader io.Reader
+	if body != "" {
+		if !json.Valid([]byte(body)) {
+			return nil, fmt.Errorf("rendered body is not valid JSON")
+		}
+		bodyReader = strings.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, hook.Method, u.String(), bodyReader)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range r.Headers {
+		req.Header.Set(name, value)
+	}
+	if bodyReader != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+
+	return req, nil
+}
+
+// sendHookRequest sends the request and returns the response body. Non-2xx
+// responses are returned as a *hookError.
+func (r *roleEntry) sendHookRequest(req *http.Request) ([]byte, error) {
+	tlsConfig, err := r.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	transport := cleanhttp.DefaultTransport()
+	if tlsConfig != nil {
+		transport.TLSClientConfig = tlsConfig
+	}
+	client := &http.Client{
+		Transport: transport,
+		Timeout:   r.RequestTimeout,
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, &hookError{StatusCode: resp.StatusCode, Body: string(bytes.TrimSpace(body))}
+	}
+
+	return body, nil
+}
+
+// callHook renders and sends a hook request.
+func (r *roleEntry) callHook(ctx context.Context, hook hookConfig, data hookTemplateData) ([]byte, error) {
+	req, err := r.buildHookRequest(ctx, hook, data)
+	if err != nil {
+		return nil, err
+	}
+	return r.sendHookRequest(req)
+}
+
+// revoke calls the revoke hook of the role. Endpoints reporting that the
+// credential does not exist are treated as a successful revocation.
+func (r *roleEntry) revoke(ctx context.Context, data hookTemplateData) error {
+	_, err := r.callHook(ctx, r.RevokeHook, data)
+	if herr, ok := err.(*hookError); ok && (herr.StatusCode == http.StatusNotFound || herr.StatusCode == http.StatusGone) {
+		return nil
+	}
+	return err
+}
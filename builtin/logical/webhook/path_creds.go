@@ -0,0 +1,138 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func pathCreds(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "creds/" + framework.GenericNameRegex("name"),
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixWebhook,
+			OperationVerb:   "generate",
+			OperationSuffix: "credentials",
+		},
+
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: "Name of the role.",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.pathCredsRead,
+		},
+
+		HelpSynopsis:    pathCredsHelpSyn,
+		HelpDescription: pathCredsHelpDesc,
+	}
+}
+
+func (b *backend) pathCredsRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+
+	role, err := b.Role(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return logical.ErrorResponse("unknown role: %s", name), nil
+	}
+
+	requestID, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := role.TTL
+	if ttl == 0 {
+		ttl = b.System().DefaultLeaseTTL()
+	}
+	if role.MaxTTL > 0 && ttl > role.MaxTTL {
+		ttl = role.MaxTTL
+	}
+
+	data := hookTemplateData{
+		RoleName:    name,
+		DisplayName: req.DisplayName,
+		RequestID:   requestID,
+		TTL:         int64(ttl.Seconds()),
+	}
+
+	hookReq, err := role.buildHookRequest(ctx, role.CreateHook, data)
+	if err != nil {
+		return nil, fmt.Errorf("unable to build create hook request: %w", err)
+	}
+
+	// Write to the WAL that this credential will be created. We do this
+	// before calling the hook, because if the outcome of the call is unknown
+	// the credential may exist and has to be rolled back.
+	walID, err := framework.PutWAL(ctx, req.Storage, walTypeCredential, &walCredential{
+		RoleName:    name,
+		RequestID:   requestID,
+		DisplayName: req.DisplayName,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error writing WAL entry: %w", err)
+	}
+
+	body, err := role.sendHookRequest(hookReq)
+	if err != nil {
+		// The endpoint responded, so nothing was created and there is
+		// nothing to roll back.
+		var herr *hookError
+		if errors.As(err, &herr) {
+			if walErr := framework.DeleteWAL(ctx, req.Storage, walID); walErr != nil {
+				return nil, errwrap.Wrap(fmt.Errorf("failed to delete WAL entry: %w", walErr), err)
+			}
+			return logical.ErrorResponse("error calling create hook: %s", err), nil
+		}
+		return nil, fmt.Errorf("error calling create hook: %w", err)
+	}
+
+	var response map[string]interface{}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("create hook did not respond with a JSON object: %w", err)
+	}
+
+	// Remove the WAL entry, we succeeded! If we fail, we don't return the
+	// secret because it'll get rolled back anyways, so we have to return an
+	// error here.
+	if err := framework.DeleteWAL(ctx, req.Storage, walID); err != nil {
+		return nil, fmt.Errorf("failed to commit WAL entry: %w", err)
+	}
+
+	resp := b.Secret(SecretCredsType).Response(response, map[string]interface{}{
+		"role":         name,
+		"request_id":   requestID,
+		"display_name": req.DisplayName,
+		"response":     response,
+	})
+	resp.Secret.TTL = ttl
+	resp.Secret.MaxTTL = role.MaxTTL
+
+	return resp, nil
+}
+
+const pathCredsHelpSyn = `
+Request credentials for a certain role.
+`
+
+const pathCredsHelpDesc = `
+This path calls the create hook of a role and returns its JSON response as
+the credential. The revoke hook is called when the lease expires or is
+revoked.
+`
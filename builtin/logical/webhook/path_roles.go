@@ -0,0 +1,427 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package webhook
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const defaultRequestTimeout = 30 * time.Second
+
+func pathListRoles(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "role/?$",
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixWebhook,
+			OperationSuffix: "roles",
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ListOperation: b.pathRoleList,
+		},
+
+		HelpSynopsis:    pathRoleHelpSyn,
+		HelpDescription: pathRoleHelpDesc,
+	}
+}
+
+func pathRoles(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "role/" + framework.GenericNameRegex("name"),
+
+		DisplayAttrs: &framework.DisplayAttributes{
+			OperationPrefix: operationPrefixWebhook,
+			OperationSuffix: "role",
+		},
+
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: "Name of the role.",
+			},
+			"create_url": {
+				Type:        framework.TypeString,
+				Description: "URL template of the hook called to create a credential.",
+			},
+			"create_method": {
+				Type:        framework.TypeString,
+				Default:     http.MethodPost,
+				Description: "HTTP method of the create hook. Defaults to POST.",
+			},
+			"create_body": {
+				Type:        framework.TypeString,
+				Description: "JSON body template of the create hook. If empty, no body is sent.",
+			},
+			"renew_url": {
+				Type:        framework.TypeString,
+				Description: "URL template of the hook called when a lease is renewed. If empty, renewals only extend the lease.",
+			},
+			"renew_method": {
+				Type:        framework.TypeString,
+				Default:     http.MethodPost,
+				Description: "HTTP method of the renew hook. Defaults to POST.",
+			},
+			"renew_body": {
+				Type:        framework.TypeString,
+				Description: "JSON body template of the renew hook. If empty, no body is sent.",
+			},
+			"revoke_url": {
+				Type:        framework.TypeString,
+				Description: "URL template of the hook called to revoke a credential.",
+			},
+			"revoke_method": {
+				Type:        framework.TypeString,
+				Default:     http.MethodDelete,
+				Description: "HTTP method of the revoke hook. Defaults to DELETE.",
+			},
+			"revoke_body": {
+				Type:        framework.TypeString,
+				Description: "JSON body template of the revoke hook. If empty, no body is sent.",
+			},
+			"headers": {
+				Type:        framework.TypeKVPairs,
+				Description: "Headers sent with every hook request. Header values are not returned when reading the role.",
+			},
+			"ca_cert": {
+				Type:        framework.TypeString,
+				Description: "PEM-encoded CA certificates used to verify the hook endpoints. Defaults to the system CAs.",
+			},
+			"client_cert": {
+				Type:        framework.TypeString,
+				Description: "PEM-encoded client certificate used for mutual TLS with the hook endpoints.",
+			},
+			"client_key": {
+				Type:        framework.TypeString,
+				Description: "PEM-encoded private key of the client certificate.",
+				DisplayAttrs: &framework.DisplayAttributes{
+					Sensitive: true,
+				},
+			},
+			"tls_server_name": {
+				Type:        framework.TypeString,
+				Description: "Server name used to verify the certificates of the hook endpoints.",
+			},
+			"request_timeout": {
+				Type:        framework.TypeDurationSecond,
+				Default:     int(defaultRequestTimeout.Seconds()),
+				Description: "Timeout of each hook request. Defaults to 30 seconds.",
+			},
+			"ttl": {
+				Type:        framework.TypeDurationSecond,
+				Description: "Default lease TTL of generated credentials. Defaults to the mount TTL.",
+			},
+			"max_ttl": {
+				Type:        framework.TypeDurationSecond,
+				Description: "Maximum lease TTL of generated credentials. Defaults to the mount maximum TTL.",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathRoleRead,
+			logical.CreateOperation: b.pathRoleWrite,
+			logical.UpdateOperation: b.pathRoleWrite,
+			logical.DeleteOperation: b.pathRoleDelete,
+		},
+
+		ExistenceCheck: b.pathRoleExistenceCheck,
+
+		HelpSynopsis:    pathRoleHelpSyn,
+		HelpDescription: pathRoleHelpDesc,
+	}
+}
+
+func (b *backend) pathRoleExistenceCheck(ctx context.Context, req *logical.Request, d *framework.FieldData) (bool, error) {
+	role, err := b.Role(ctx, req.Storage, d.Get("name").(string))
+	if err != nil {
+		return false, err
+	}
+	return role != nil, nil
+}
+
+// Role reads the role from storage
+func (b *backend) Role(ctx context.Context, s logical.Storage, name string) (*roleEntry, error) {
+	entry, err := s.Get(ctx, "role/"+name)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving role: %w", err)
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var result roleEntry
+	if err := entry.DecodeJSON(&result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (b *backend) pathRoleList(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	entries, err := req.Storage.List(ctx, "role/")
+	if err != nil {
+		return nil, err
+	}
+
+	return logical.ListResponse(entries), nil
+}
+
+func (b *backend) pathRoleRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	role, err := b.Role(ctx, req.Storage, d.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: role.toResponseData(),
+	}, nil
+}
+
+func (b *backend) pathRoleDelete(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	if err := req.Storage.Delete(ctx, "role/"+d.Get("name").(string)); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+func (b *backend) pathRoleWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+	if name == "" {
+		return logical.ErrorResponse("missing name"), nil
+	}
+
+	role, err := b.Role(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		role = &roleEntry{}
+	}
+
+	for field, value := range map[string]*string{
+		"create_url":      &role.CreateHook.URL,
+		"create_body":     &role.CreateHook.Body,
+		"renew_url":       &role.RenewHook.URL,
+		"renew_body":      &role.RenewHook.Body,
+		"revoke_url":      &role.RevokeHook.URL,
+		"revoke_body":     &role.RevokeHook.Body,
+		"ca_cert":         &role.CACert,
+		"client_cert":     &role.ClientCert,
+		"client_key":      &role.ClientKey,
+		"tls_server_name": &role.TLSServerName,
+	} {
+		if raw, ok := d.GetOk(field); ok {
+			*value = raw.(string)
+		}
+	}
+
+	for field, value := range map[string]*string{
+		"create_method": &role.CreateHook.Method,
+		"renew_method":  &role.RenewHook.Method,
+		"revoke_method": &role.RevokeHook.Method,
+	} {
+		if raw, ok := d.GetOk(field); ok {
+			*value = strings.ToUpper(raw.(string))
+		} else if *value == "" {
+			*value = d.Get(field).(string)
+		}
+	}
+
+	if raw, ok := d.GetOk("headers"); ok {
+		role.Headers = raw.(map[string]string)
+	}
+	if raw, ok := d.GetOk("request_timeout"); ok {
+		role.RequestTimeout = time.Duration(raw.(int)) * time.Second
+	} else if role.RequestTimeout == 0 {
+		role.RequestTimeout = time.Duration(d.Get("request_timeout").(int)) * time.Second
+	}
+	if raw, ok := d.GetOk("ttl"); ok {
+		role.TTL = time.Duration(raw.(int)) * time.Second
+	}
+	if raw, ok := d.GetOk("max_ttl"); ok {
+		role.MaxTTL = time.Duration(raw.(int)) * time.Second
+	}
+
+	if err := role.validate(); err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	entry, err := logical.StorageEntryJSON("role/"+name, role)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// roleEntry configures the hooks called to manage the lifecycle of the
+// credentials generated against a role.
+type roleEntry struct {
+	CreateHook     hookConfig        `json:"create_hook"`
+	RenewHook      hookConfig        `json:"renew_hook"`
+	RevokeHook     hookConfig        `json:"revoke_hook"`
+	Headers        map[string]string `json:"headers"`
+	CACert         string            `json:"ca_cert"`
+	ClientCert     string            `json:"client_cert"`
+	ClientKey      string            `json:"client_key"`
+	TLSServerName  string            `json:"tls_server_name"`
+	RequestTimeout time.Duration     `json:"request_timeout"`
+	TTL            time.Duration     `json:"ttl"`
+	MaxTTL         time.Duration     `json:"max_ttl"`
+}
+
+// hookConfig is a single HTTP endpoint called by the backend. The URL and
+// body are templates rendered with hookTemplateData.
+type hookConfig struct {
+	URL    string `json:"url"`
+	Method string `json:"method"`
+	Body   string `json:"body"`
+}
+
+func (r *roleEntry) validate() error {
+	if r.CreateHook.URL == "" {
+		return fmt.Errorf("create_url is required")
+	}
+	if r.RevokeHook.URL == "" {
+		return fmt.Errorf("revoke_url is required")
+	}
+
+	for name, hook := range map[string]hookConfig{
+		"create": r.CreateHook,
+		"renew":  r.RenewHook,
+		"revoke": r.RevokeHook,
+	} {
+		if err := hook.validate(); err != nil {
+			return fmt.Errorf("invalid %s hook: %w", name, err)
+		}
+	}
+
+	if r.RequestTimeout <= 0 {
+		return fmt.Errorf("request_timeout must be greater than zero")
+	}
+	if r.MaxTTL > 0 && r.TTL > r.MaxTTL {
+		return fmt.Errorf("ttl cannot be greater than max_ttl")
+	}
+
+	if _, err := r.tlsConfig(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (h hookConfig) validate() error {
+	switch h.Method {
+	case http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+	default:
+		return fmt.Errorf("unsupported method %q", h.Method)
+	}
+	if _, err := parseHookTemplate(h.URL, hookTemplateURL); err != nil {
+		return fmt.Errorf("invalid url template: %w", err)
+	}
+	if _, err := parseHookTemplate(h.Body, hookTemplateBody); err != nil {
+		return fmt.Errorf("invalid body template: %w", err)
+	}
+	return nil
+}
+
+// tlsConfig returns the TLS configuration used to call the hooks of the
+// role, or nil if the defaults should be used.
+func (r *roleEntry) tlsConfig() (*tls.Config, error) {
+	if r.CACert == "" && r.ClientCert == "" && r.ClientKey == "" && r.TLSServerName == "" {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: r.TLSServerName,
+	}
+
+	if r.CACert != "" {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM([]byte(r.CACert)) {
+			return nil, fmt.Errorf("could not parse ca_cert")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	switch {
+	case r.ClientCert != "" && r.ClientKey != "":
+		cert, err := tls.X509KeyPair([]byte(r.ClientCert), []byte(r.ClientKey))
+		if err != nil {
+			return nil, fmt.Errorf("could not parse client_cert and client_key: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	case r.ClientCert != "" || r.ClientKey != "":
+		return nil, fmt.Errorf("client_cert and client_key must be set together")
+	}
+
+	return tlsConfig, nil
+}
+
+func (r *roleEntry) toResponseData() map[string]interface{} {
+	headerNames := make([]string, 0, len(r.Headers))
+	for name := range r.Headers {
+		headerNames = append(headerNames, name)
+	}
+	sort.Strings(headerNames)
+
+	return map[string]interface{}{
+		"create_url":      r.CreateHook.URL,
+		"create_method":   r.CreateHook.Method,
+		"create_body":     r.CreateHook.Body,
+		"renew_url":       r.RenewHook.URL,
+		"renew_method":    r.RenewHook.Method,
+		"renew_body":      r.RenewHook.Body,
+		"revoke_url":      r.RevokeHook.URL,
+		"revoke_method":   r.RevokeHook.Method,
+		"revoke_body":     r.RevokeHook.Body,
+		"header_names":    headerNames,
+		"ca_cert":         r.CACert,
+		"client_cert":     r.ClientCert,
+		"tls_server_name": r.TLSServerName,
+		"request_timeout": int64(r.RequestTimeout.Seconds()),
+		"ttl":             int64(r.TTL.Seconds()),
+		"max_ttl":         int64(r.MaxTTL.Seconds()),
+	}
+}
+
+const pathRoleHelpSyn = `
+Manage the roles that can be created with this backend.
+`
+
+const pathRoleHelpDesc = `
+This path lets you manage the roles that can be created with this backend.
+
+Each role configures the hooks called to create, renew, and revoke
+credentials. The "create_url" and "revoke_url" parameters are required. If
+"renew_url" is not set, renewing a lease only extends its TTL.
+
+URLs and bodies are Go templates rendered with the following values:
+
+  .RoleName     The name of the role.
+  .DisplayName  The display name of the requesting token.
+  .RequestID    A unique ID for the credential, usable as an idempotency key.
+  .TTL          The lease TTL in seconds.
+  .Response     The JSON response of the create hook. Not available to the
+                create hook itself.
+
+Values are escaped by default: they are inserted into bodies as JSON values,
+for example {"name": {{ .DisplayName }}}, and percent-encoded in URLs.
+`
@@ -0,0 +1,94 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRole_Validation(t *testing.T) {
+	b, s := getBackend(t)
+	clientCert, clientKey := generateTestCertificate(t)
+
+	valid := map[string]interface{}{
+		"create_url": "https://saas.example.com/keys",
+		"revoke_url": "https://saas.example.com/keys/{{ .Response.id }}",
+	}
+
+	tests := map[string]map[string]interface{}{
+		"missing create_url": {
+			"revoke_url": "https://saas.example.com/keys/{{ .Response.id }}",
+		},
+		"missing revoke_url": {
+			"create_url": "https://saas.example.com/keys",
+		},
+		"invalid url template": {
+			"create_url": "https://saas.example.com/keys/{{ .RequestID",
+			"revoke_url": "https://saas.example.com/keys/{{ .Response.id }}",
+		},
+		"invalid body template": {
+			"create_url":  "https://saas.example.com/keys",
+			"create_body": `{"name": {{ .DisplayName | unknown }}}`,
+			"revoke_url":  "https://saas.example.com/keys/{{ .Response.id }}",
+		},
+		"unsupported method": {
+			"create_url":    "https://saas.example.com/keys",
+			"revoke_url":    "https://saas.example.com/keys/{{ .Response.id }}",
+			"revoke_method": "TRACE",
+		},
+		"client_cert without client_key": {
+			"create_url":  "https://saas.example.com/keys",
+			"revoke_url":  "https://saas.example.com/keys/{{ .Response.id }}",
+			"client_cert": clientCert,
+		},
+		"invalid ca_cert": {
+			"create_url": "https://saas.example.com/keys",
+			"revoke_url": "https://saas.example.com/keys/{{ .Response.id }}",
+			"ca_cert":    "not a certificate",
+		},
+		"ttl greater than max_ttl": {
+			"create_url": "https://saas.example.com/keys",
+			"revoke_url": "https://saas.example.com/keys/{{ .Response.id }}",
+			"ttl":        "2h",
+			"max_ttl":    "1h",
+		},
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			resp := writeRole(t, b, s, "invalid", data)
+			require.True(t, resp != nil && resp.IsError(), "expected error, got %#v", resp)
+		})
+	}
+
+	t.Run("read", func(t *testing.T) {
+		data := map[string]interface{}{
+			"client_cert":   clientCert,
+			"client_key":    clientKey,
+			"headers":       map[string]interface{}{"Authorization": "Bearer admin", "X-Team": "platform"},
+			"revoke_method": "post",
+		}
+		for k, v := range valid {
+			data[k] = v
+		}
+		require.Nil(t, writeRole(t, b, s, "valid", data))
+
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "role/valid",
+			Storage:   s,
+		})
+		require.NoError(t, err)
+		require.Equal(t, "POST", resp.Data["create_method"])
+		require.Equal(t, "POST", resp.Data["revoke_method"])
+		require.Equal(t, []string{"Authorization", "X-Team"}, resp.Data["header_names"])
+		require.Equal(t, int64(30), resp.Data["request_timeout"])
+		require.Equal(t, clientCert, resp.Data["client_cert"])
+		require.NotContains(t, resp.Data, "client_key")
+		require.NotContains(t, resp.Data, "headers")
+	})
+}
@@ -0,0 +1,73 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package webhook
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/consts"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/mitchellh/mapstructure"
+)
+
+const walTypeCredential = "credential"
+
+// walCredential is written before the create hook is called, and deleted
+// once the credential has been returned.
+type walCredential struct {
+	RoleName    string `json:"role_name" mapstructure:"role_name"`
+	RequestID   string `json:"request_id" mapstructure:"request_id"`
+	DisplayName string `json:"display_name" mapstructure:"display_name"`
+}
+
+func (b *backend) walRollback(ctx context.Context, req *logical.Request, kind string, data interface{}) error {
+	walRollbackMap := map[string]framework.WALRollbackFunc{
+		walTypeCredential: b.credentialRollback,
+	}
+
+	if !b.System().LocalMount() && b.System().ReplicationState().HasState(consts.ReplicationPerformanceSecondary|consts.ReplicationPerformanceStandby) {
+		return nil
+	}
+
+	f, ok := walRollbackMap[kind]
+	if !ok {
+		return fmt.Errorf("unknown type to rollback")
+	}
+
+	return f(ctx, req, kind, data)
+}
+
+// credentialRollback calls the revoke hook for a credential whose create
+// hook had an unknown outcome. Only the values known before the create hook
+// was called are available, so revoke hooks that reference the create
+// response cannot be rolled back.
+func (b *backend) credentialRollback(ctx context.Context, req *logical.Request, _kind string, data interface{}) error {
+	var entry walCredential
+	if err := mapstructure.Decode(data, &entry); err != nil {
+		return err
+	}
+
+	role, err := b.Role(ctx, req.Storage, entry.RoleName)
+	if err != nil {
+		return err
+	}
+	if role == nil {
+		b.Logger().Warn("role of credential to roll back no longer exists", "role", entry.RoleName, "request_id", entry.RequestID)
+		return nil
+	}
+
+	hookData := hookTemplateData{
+		RoleName:    entry.RoleName,
+		DisplayName: entry.DisplayName,
+		RequestID:   entry.RequestID,
+	}
+	if _, err := role.buildHookRequest(ctx, role.RevokeHook, hookData); err != nil {
+		b.Logger().Warn("unable to roll back credential, revoke hook requires values from the create response", "role", entry.RoleName, "request_id", entry.RequestID, "error", err)
+		return nil
+	}
+
+	return role.revoke(ctx, hookData)
+}
@@ -0,0 +1,91 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package webhook
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// SecretCredsType is the key for this backend's secrets.
+const SecretCredsType = "creds"
+
+func secretCreds(b *backend) *framework.Secret {
+	return &framework.Secret{
+		Type:   SecretCredsType,
+		Fields: map[string]*framework.FieldSchema{},
+		Renew:  b.secretCredsRenew,
+		Revoke: b.secretCredsRevoke,
+	}
+}
+
+// secretRole returns the role and template data of a previously issued
+// secret.
+func (b *backend) secretRole(ctx context.Context, req *logical.Request) (*roleEntry, hookTemplateData, error) {
+	roleName, ok := req.Secret.InternalData["role"].(string)
+	if !ok {
+		return nil, hookTemplateData{}, fmt.Errorf("secret is missing role internal data")
+	}
+	requestID, ok := req.Secret.InternalData["request_id"].(string)
+	if !ok {
+		return nil, hookTemplateData{}, fmt.Errorf("secret is missing request_id internal data")
+	}
+	displayName, _ := req.Secret.InternalData["display_name"].(string)
+	response, _ := req.Secret.InternalData["response"].(map[string]interface{})
+
+	role, err := b.Role(ctx, req.Storage, roleName)
+	if err != nil {
+		return nil, hookTemplateData{}, err
+	}
+	if role == nil {
+		return nil, hookTemplateData{}, fmt.Errorf("role %q not found", roleName)
+	}
+
+	return role, hookTemplateData{
+		RoleName:    roleName,
+		DisplayName: displayName,
+		RequestID:   requestID,
+		TTL:         int64(req.Secret.TTL.Seconds()),
+		Response:    response,
+	}, nil
+}
+
+// Renew the previously issued secret
+func (b *backend) secretCredsRenew(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	role, data, err := b.secretRole(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := framework.LeaseExtend(role.TTL, role.MaxTTL, b.System())(ctx, req, d)
+	if err != nil {
+		return nil, err
+	}
+
+	if role.RenewHook.URL != "" {
+		data.TTL = int64(resp.Secret.TTL.Seconds())
+		if _, err := role.callHook(ctx, role.RenewHook, data); err != nil {
+			return nil, fmt.Errorf("error calling renew hook: %w", err)
+		}
+	}
+
+	return resp, nil
+}
+
+// Revoke the previously issued secret
+func (b *backend) secretCredsRevoke(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	role, data, err := b.secretRole(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := role.revoke(ctx, data); err != nil {
+		return nil, fmt.Errorf("error calling revoke hook: %w", err)
+	}
+
+	return nil, nil
+}
@@ -354,6 +354,7 @@ func TestPredict_Plugins(t *testing.T) {
 				"centrify",
 				"cert",
 				"cf",
+				"clickhouse-database-plugin",
 				"consul",
 				"couchbase-database-plugin",
 				"elasticsearch-database-plugin",
@@ -398,6 +399,8 @@ func TestPredict_Plugins(t *testing.T) {
 				"transform",
 				"transit",
 				"userpass",
+				"webauthn",
+				"webhook",
 			},
 		},
 	}
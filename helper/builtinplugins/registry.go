@@ -39,6 +39,7 @@ import (
 	credOkta "github.com/hashicorp/vault/builtin/credential/okta"
 	credRadius "github.com/hashicorp/vault/builtin/credential/radius"
 	credUserpass "github.com/hashicorp/vault/builtin/credential/userpass"
+	credWebauthn "github.com/hashicorp/vault/builtin/credential/webauthn"
 	logicalAws "github.com/hashicorp/vault/builtin/logical/aws"
 	logicalConsul "github.com/hashicorp/vault/builtin/logical/consul"
 	logicalNomad "github.com/hashicorp/vault/builtin/logical/nomad"
@@ -47,7 +48,9 @@ import (
 	logicalSsh "github.com/hashicorp/vault/builtin/logical/ssh"
 	logicalTotp "github.com/hashicorp/vault/builtin/logical/totp"
 	logicalTransit "github.com/hashicorp/vault/builtin/logical/transit"
+	logicalWebhook "github.com/hashicorp/vault/builtin/logical/webhook"
 	dbCass "github.com/hashicorp/vault/plugins/database/cassandra"
+	dbClickHouse "github.com/hashicorp/vault/plugins/database/clickhouse"
 	dbHana "github.com/hashicorp/vault/plugins/database/hana"
 	dbInflux "github.com/hashicorp/vault/plugins/database/influxdb"
 	dbMongo "github.com/hashicorp/vault/plugins/database/mongodb"
@@ -126,6 +129,7 @@ func newRegistry() *registry {
 			},
 			"radius":   {Factory: credRadius.Factory},
 			"userpass": {Factory: credUserpass.Factory},
+			"webauthn": {Factory: credWebauthn.Factory},
 		},
 		databasePlugins: map[string]databasePlugin{
 			// These four plugins all use the same mysql implementation but with
@@ -136,6 +140,7 @@ func newRegistry() *registry {
 			"mysql-legacy-database-plugin": {Factory: dbMysql.New(dbMysql.DefaultLegacyUserNameTemplate)},
 
 			"cassandra-database-plugin":         {Factory: dbCass.New},
+			"clickhouse-database-plugin":        {Factory: dbClickHouse.New},
 			"couchbase-database-plugin":         {Factory: dbCouchbase.New},
 			"elasticsearch-database-plugin":     {Factory: dbElastic.New},
 			"hana-database-plugin":              {Factory: dbHana.New},
@@ -194,6 +199,7 @@ func newRegistry() *registry {
 			"terraform": {Factory: logicalTerraform.Factory},
 			"totp":      {Factory: logicalTotp.Factory},
 			"transit":   {Factory: logicalTransit.Factory},
+			"webhook":   {Factory: logicalWebhook.Factory},
 		},
 	}
 
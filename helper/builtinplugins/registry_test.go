@@ -98,18 +98,18 @@ func Test_RegistryKeyCounts(t *testing.T) {
 		{
 			name:       "number of auth plugins",
 			pluginType: consts.PluginTypeCredential,
-			want:       19,
+			want:       20,
 			entWant:    1,
 		},
 		{
 			name:       "number of database plugins",
 			pluginType: consts.PluginTypeDatabase,
-			want:       17,
+			want:       18,
 		},
 		{
 			name:       "number of secrets plugins",
 			pluginType: consts.PluginTypeSecrets,
-			want:       19,
+			want:       20,
 			entWant:    3,
 		},
 	}
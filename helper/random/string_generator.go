@@ -10,6 +10,7 @@ import (
 	"io"
 	"math"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 	"unicode"
@@ -225,6 +226,38 @@ func randomRunes(rng io.Reader, charset []rune, length int) (candidate []rune, e
 	return runes, nil
 }
 
+// Validate checks that a string, such as a user chosen password, is at least as long as the strings this generator
+// produces and passes all of its rules. Characters outside of the charset are allowed.
+func (g *StringGenerator) Validate(value string) error {
+	merr := &multierror.Error{}
+	merr.ErrorFormat = validationErrorFormat
+
+	candidate := []rune(value)
+	if len(candidate) < g.Length {
+		merr = multierror.Append(merr, fmt.Errorf("must be at least %d characters long", g.Length))
+	}
+	for _, rule := range g.Rules {
+		if rule.Pass(candidate) {
+			continue
+		}
+		if cr, ok := rule.(CharsetRule); ok {
+			merr = multierror.Append(merr, fmt.Errorf("must contain at least %d of the characters %q", cr.MinChars, string(cr.Charset)))
+			continue
+		}
+		merr = multierror.Append(merr, fmt.Errorf("does not pass the %s rule", rule.Type()))
+	}
+	return merr.ErrorOrNil()
+}
+
+// validationErrorFormat lists the failed requirements of Validate on a single line.
+func validationErrorFormat(errs []error) string {
+	msgs := make([]string, 0, len(errs))
+	for _, err := range errs {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, ", ")
+}
+
 // validateConfig of the generator to ensure that we can successfully generate a string.
 func (g *StringGenerator) validateConfig() (err error) {
 	merr := &multierror.Error{}
@@ -635,6 +635,63 @@ func TestValidate(t *testing.T) {
 	}
 }
 
+func TestStringGenerator_Validate(t *testing.T) {
+	type testCase struct {
+		value       string
+		expectedErr string
+	}
+
+	generator := &StringGenerator{
+		Length: 8,
+		Rules: []Rule{
+			CharsetRule{
+				Charset:  LowercaseRuneset,
+				MinChars: 1,
+			},
+			CharsetRule{
+				Charset:  NumericRuneset,
+				MinChars: 2,
+			},
+		},
+	}
+
+	tests := map[string]testCase{
+		"passes": {
+			value: "abcdef12",
+		},
+		"characters outside of the charset": {
+			value: "abc!DEF12",
+		},
+		"too short": {
+			value:       "abc12",
+			expectedErr: "must be at least 8 characters long",
+		},
+		"fails a rule": {
+			value:       "abcdefgh1",
+			expectedErr: `must contain at least 2 of the characters "0123456789"`,
+		},
+		"fails everything": {
+			value:       "ABC",
+			expectedErr: `must be at least 8 characters long, must contain at least 1 of the characters "abcdefghijklmnopqrstuvwxyz", must contain at least 2 of the characters "0123456789"`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := generator.Validate(test.value)
+			if test.expectedErr == "" {
+				if err != nil {
+					t.Fatalf("no error expected, got: %s", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != test.expectedErr {
+				t.Fatalf("expected error %q, got: %v", test.expectedErr, err)
+			}
+		})
+	}
+}
+
 type testNonCharsetRule struct {
 	String string `mapstructure:"string" json:"string"`
 }
@@ -151,6 +151,7 @@ func (m *mockBuiltinRegistry) Keys(pluginType consts.PluginType) []string {
 			"mysql-legacy-database-plugin",
 
 			"cassandra-database-plugin",
+			"clickhouse-database-plugin",
 			"couchbase-database-plugin",
 			"elasticsearch-database-plugin",
 			"hana-database-plugin",
@@ -0,0 +1,289 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package clickhouse
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	_ "github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/hashicorp/go-secure-stdlib/strutil"
+	dbplugin "github.com/hashicorp/vault/sdk/database/dbplugin/v5"
+	"github.com/hashicorp/vault/sdk/database/helper/connutil"
+	"github.com/hashicorp/vault/sdk/database/helper/dbutil"
+	"github.com/hashicorp/vault/sdk/helper/dbtxn"
+	"github.com/hashicorp/vault/sdk/helper/template"
+)
+
+const (
+	// This is how this plugin will be reflected in middleware
+	// such as metrics.
+	middlewareTypeName = "clickhouse"
+
+	// The database/sql driver registered by clickhouse-go.
+	sqlTypeName = "clickhouse"
+
+	defaultChangePasswordStatement = `
+ALTER USER "{{name}}" IDENTIFIED WITH sha256_password BY '{{password}}';
+`
+	defaultExpirationStatement = `
+ALTER USER "{{name}}" VALID UNTIL '{{expiration}}';
+`
+	defaultDeleteUserStatement = `
+DROP USER IF EXISTS "{{name}}";
+`
+	defaultUserNameTemplate = `{{ printf "v-%s-%s-%s-%s" (.DisplayName | truncate 8) (.RoleName | truncate 8) (random 20) (unix_time) | truncate 63 | lowercase }}`
+
+	// ClickHouse parses VALID UNTIL with best effort, so an explicit UTC
+	// timestamp is independent of the server's time zone.
+	expirationFormat = "2006-01-02T15:04:05Z"
+)
+
+var _ dbplugin.Database = (*ClickHouse)(nil)
+
+// New implements builtinplugins.BuiltinFactory
+func New() (interface{}, error) {
+	db := newClickHouse()
+	// Wrap the plugin with middleware to sanitize errors
+	dbType := dbplugin.NewDatabaseErrorSanitizerMiddleware(db, db.secretValues)
+	return dbType, nil
+}
+
+func newClickHouse() *ClickHouse {
+	connProducer := &connutil.SQLConnectionProducer{}
+	connProducer.Type = sqlTypeName
+
+	db := &ClickHouse{
+		SQLConnectionProducer: connProducer,
+	}
+
+	return db
+}
+
+// ClickHouse manages SQL-driven users through the ClickHouse access control
+// statements. ClickHouse has no transactions for DDL, so statements are
+// executed one after another and stop at the first failure.
+type ClickHouse struct {
+	*connutil.SQLConnectionProducer
+
+	usernameProducer template.StringTemplate
+}
+
+func (c *ClickHouse) secretValues() map[string]string {
+	return map[string]string{
+		c.Password: "[password]",
+	}
+}
+
+func (c *ClickHouse) Type() (string, error) {
+	return middlewareTypeName, nil
+}
+
+// Initialize must be called on each new ClickHouse struct before use.
+// It uses the connutil.SQLConnectionProducer's Init function to do all the lifting.
+func (c *ClickHouse) Initialize(ctx context.Context, req dbplugin.InitializeRequest) (dbplugin.InitializeResponse, error) {
+	conf, err := c.Init(ctx, req.Config, req.VerifyConnection)
+	if err != nil {
+		return dbplugin.InitializeResponse{}, fmt.Errorf("error initializing db: %w", err)
+	}
+
+	usernameTemplate, err := strutil.GetString(req.Config, "username_template")
+	if err != nil {
+		return dbplugin.InitializeResponse{}, fmt.Errorf("failed to retrieve username_template: %w", err)
+	}
+	if usernameTemplate == "" {
+		usernameTemplate = defaultUserNameTemplate
+	}
+
+	up, err := template.NewTemplate(template.Template(usernameTemplate))
+	if err != nil {
+		return dbplugin.InitializeResponse{}, fmt.Errorf("unable to initialize username template: %w", err)
+	}
+	c.usernameProducer = up
+
+	_, err = c.usernameProducer.Generate(dbplugin.UsernameMetadata{})
+	if err != nil {
+		return dbplugin.InitializeResponse{}, fmt.Errorf("invalid username template: %w", err)
+	}
+
+	return dbplugin.InitializeResponse{
+		Config: conf,
+	}, nil
+}
+
+// getConnection accepts a context and returns a new pointer to a sql.DB object.
+// It's up to the caller to close the connection or handle reuse logic.
+func (c *ClickHouse) getConnection(ctx context.Context) (*sql.DB, error) {
+	db, err := c.Connection(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return db.(*sql.DB), nil
+}
+
+// NewUser creates a new user in the database. There is no default statement for
+// creating users, so one must be specified in the role.
+func (c *ClickHouse) NewUser(ctx context.Context, req dbplugin.NewUserRequest) (dbplugin.NewUserResponse, error) {
+	if len(req.Statements.Commands) == 0 {
+		return dbplugin.NewUserResponse{}, dbutil.ErrEmptyCreationStatement
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	username, err := c.usernameProducer.Generate(req.UsernameConfig)
+	if err != nil {
+		return dbplugin.NewUserResponse{}, err
+	}
+
+	db, err := c.getConnection(ctx)
+	if err != nil {
+		return dbplugin.NewUserResponse{}, fmt.Errorf("unable to get connection: %w", err)
+	}
+
+	m := map[string]string{
+		"name":       username,
+		"username":   username,
+		"password":   req.Password,
+		"expiration": req.Expiration.UTC().Format(expirationFormat),
+	}
+	if err := executeStatements(ctx, db, req.Statements.Commands, m); err != nil {
+		// Creation statements may have partially succeeded. Attempt to
+		// remove whatever was created, ignoring any errors as the original
+		// error is more relevant.
+		rollback := req.RollbackStatements.Commands
+		if len(rollback) == 0 {
+			rollback = []string{defaultDeleteUserStatement}
+		}
+		_ = executeStatements(ctx, db, rollback, m)
+		return dbplugin.NewUserResponse{}, err
+	}
+
+	return dbplugin.NewUserResponse{
+		Username: username,
+	}, nil
+}
+
+// UpdateUser can update the expiration or the password of a user, or both.
+// Both updates support both default and custom statements.
+func (c *ClickHouse) UpdateUser(ctx context.Context, req dbplugin.UpdateUserRequest) (dbplugin.UpdateUserResponse, error) {
+	if req.Username == "" {
+		return dbplugin.UpdateUserResponse{}, fmt.Errorf("missing username")
+	}
+	if req.Password == nil && req.Expiration == nil {
+		return dbplugin.UpdateUserResponse{}, errors.New("no changes requested")
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	db, err := c.getConnection(ctx)
+	if err != nil {
+		return dbplugin.UpdateUserResponse{}, fmt.Errorf("unable to get connection: %w", err)
+	}
+
+	if req.Password != nil {
+		if err := changeUserPassword(ctx, db, req.Username, req.Password); err != nil {
+			return dbplugin.UpdateUserResponse{}, fmt.Errorf("failed to change password: %w", err)
+		}
+	}
+
+	if req.Expiration != nil {
+		if err := changeUserExpiration(ctx, db, req.Username, req.Expiration); err != nil {
+			return dbplugin.UpdateUserResponse{}, fmt.Errorf("failed to change expiration: %w", err)
+		}
+	}
+
+	return dbplugin.UpdateUserResponse{}, nil
+}
+
+func changeUserPassword(ctx context.Context, db *sql.DB, username string, changePass *dbplugin.ChangePassword) error {
+	password := changePass.NewPassword
+	if password == "" {
+		return fmt.Errorf("missing password")
+	}
+
+	// Vault requires the database user already exist, and that the credentials
+	// used to execute the rotation statements has sufficient privileges.
+	var count uint64
+	if err := db.QueryRowContext(ctx, "SELECT count() FROM system.users WHERE name = ?", username).Scan(&count); err != nil {
+		return fmt.Errorf("unable to look up user: %w", err)
+	}
+	if count == 0 {
+		return fmt.Errorf("cannot update password for username %q because it does not exist", username)
+	}
+
+	stmts := changePass.Statements.Commands
+	if len(stmts) == 0 {
+		stmts = []string{defaultChangePasswordStatement}
+	}
+
+	m := map[string]string{
+		"name":     username,
+		"username": username,
+		"password": password,
+	}
+	return executeStatements(ctx, db, stmts, m)
+}
+
+func changeUserExpiration(ctx context.Context, db *sql.DB, username string, changeExp *dbplugin.ChangeExpiration) error {
+	stmts := changeExp.Statements.Commands
+	if len(stmts) == 0 {
+		stmts = []string{defaultExpirationStatement}
+	}
+
+	m := map[string]string{
+		"name":       username,
+		"username":   username,
+		"expiration": changeExp.NewExpiration.UTC().Format(expirationFormat),
+	}
+	return executeStatements(ctx, db, stmts, m)
+}
+
+// DeleteUser supports both default and custom statements to delete a user.
+func (c *ClickHouse) DeleteUser(ctx context.Context, req dbplugin.DeleteUserRequest) (dbplugin.DeleteUserResponse, error) {
+	if req.Username == "" {
+		return dbplugin.DeleteUserResponse{}, fmt.Errorf("missing username")
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	db, err := c.getConnection(ctx)
+	if err != nil {
+		return dbplugin.DeleteUserResponse{}, fmt.Errorf("unable to get connection: %w", err)
+	}
+
+	stmts := req.Statements.Commands
+	if len(stmts) == 0 {
+		stmts = []string{defaultDeleteUserStatement}
+	}
+
+	m := map[string]string{
+		"name":     req.Username,
+		"username": req.Username,
+	}
+	return dbplugin.DeleteUserResponse{}, executeStatements(ctx, db, stmts, m)
+}
+
+// executeStatements runs each semicolon separated query of the given
+// statements with the template values substituted.
+func executeStatements(ctx context.Context, db *sql.DB, stmts []string, m map[string]string) error {
+	for _, stmt := range stmts {
+		for _, query := range strutil.ParseArbitraryStringSlice(stmt, ";") {
+			query = strings.TrimSpace(query)
+			if len(query) == 0 {
+				continue
+			}
+
+			if err := dbtxn.ExecuteDBQueryDirect(ctx, db, m, query); err != nil {
+				return fmt.Errorf("failed to execute query: %w", err)
+			}
+		}
+	}
+	return nil
+}
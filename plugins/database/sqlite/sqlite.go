@@ -0,0 +1,489 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/go-secure-stdlib/strutil"
+	dbplugin "github.com/hashicorp/vault/sdk/database/dbplugin/v5"
+	"github.com/hashicorp/vault/sdk/database/helper/connutil"
+	"github.com/hashicorp/vault/sdk/database/helper/dbutil"
+	"github.com/hashicorp/vault/sdk/helper/dbtxn"
+	"github.com/hashicorp/vault/sdk/helper/template"
+	"github.com/mitchellh/mapstructure"
+	"golang.org/x/crypto/bcrypt"
+	_ "modernc.org/sqlite"
+)
+
+const (
+	// This is how this plugin will be reflected in middleware
+	// such as metrics.
+	middlewareTypeName = "sqlite"
+
+	// The database/sql driver registered by modernc.org/sqlite.
+	sqlTypeName = "sqlite"
+
+	// dataDirEnv names the environment variable holding the directory the
+	// database files must be in. The plugin runs with the permissions of
+	// the Vault server, so it must not open files anywhere else.
+	dataDirEnv = "VAULT_SQLITE_DATA_DIR"
+
+	// SQLite has no database users of its own. Unless statements are given,
+	// users are kept in this table, which applications read to authenticate
+	// their own clients.
+	defaultUsersTable = "users"
+
+	defaultUserNameTemplate = `{{ printf "v-%s-%s-%s-%s" (.DisplayName | truncate 8) (.RoleName | truncate 8) (random 20) (unix_time) | truncate 63 | lowercase }}`
+
+	expirationFormat = "2006-01-02 15:04:05"
+)
+
+var (
+	_ dbplugin.Database = (*SQLite)(nil)
+
+	// schemaNameRegex restricts the names attached databases are available
+	// under to plain identifiers.
+	schemaNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// New implements builtinplugins.BuiltinFactory
+func New() (interface{}, error) {
+	db := newSQLite()
+	db.dataDir = os.Getenv(dataDirEnv)
+	// Wrap the plugin with middleware to sanitize errors
+	dbType := dbplugin.NewDatabaseErrorSanitizerMiddleware(db, db.secretValues)
+	return dbType, nil
+}
+
+func newSQLite() *SQLite {
+	connProducer := &connutil.SQLConnectionProducer{}
+	connProducer.Type = sqlTypeName
+
+	db := &SQLite{
+		SQLConnectionProducer: connProducer,
+	}
+
+	return db
+}
+
+// SQLite manages users of embedded applications that keep their credentials
+// in a SQLite database file. The connection_url is the path of the main
+// database file; additional files, such as a separate credentials database,
+// can be attached to every connection with attach_databases. All files must
+// be within the data directory.
+type SQLite struct {
+	*connutil.SQLConnectionProducer
+
+	// dataDir is the directory the database files must be in.
+	dataDir string
+
+	// attachDatabases maps schema names to the database files attached
+	// under them.
+	attachDatabases map[string]string
+
+	// usersTable is the quoted, optionally schema qualified, table used by
+	// the default statements.
+	usersTable string
+
+	usernameProducer template.StringTemplate
+}
+
+func (s *SQLite) secretValues() map[string]string {
+	return map[string]string{
+		s.Password: "[password]",
+	}
+}
+
+func (s *SQLite) Type() (string, error) {
+	return middlewareTypeName, nil
+}
+
+// Initialize must be called on each new SQLite struct before use.
+// It uses the connutil.SQLConnectionProducer's Init function to do all the lifting.
+func (s *SQLite) Initialize(ctx context.Context, req dbplugin.InitializeRequest) (dbplugin.InitializeResponse, error) {
+	attach := map[string]string{}
+	switch raw := req.Config["attach_databases"].(type) {
+	case nil:
+	case string:
+		// Accept a JSON object as sent by the CLI
+		if raw != "" {
+			if err := json.Unmarshal([]byte(raw), &attach); err != nil {
+				return dbplugin.InitializeResponse{}, fmt.Errorf("invalid attach_databases: %w", err)
+			}
+		}
+	default:
+		if err := mapstructure.WeakDecode(raw, &attach); err != nil {
+			return dbplugin.InitializeResponse{}, fmt.Errorf("invalid attach_databases: %w", err)
+		}
+	}
+	for schema, path := range attach {
+		if !schemaNameRegex.MatchString(schema) || schema == "main" || schema == "temp" {
+			return dbplugin.InitializeResponse{}, fmt.Errorf("invalid schema name %q in attach_databases", schema)
+		}
+		if path == "" {
+			return dbplugin.InitializeResponse{}, fmt.Errorf("missing database path for schema %q in attach_databases", schema)
+		}
+		resolved, err := s.resolvePath(path)
+		if err != nil {
+			return dbplugin.InitializeResponse{}, fmt.Errorf("invalid database path for schema %q in attach_databases: %w", schema, err)
+		}
+		attach[schema] = resolved
+	}
+
+	usersTable, err := strutil.GetString(req.Config, "users_table")
+	if err != nil {
+		return dbplugin.InitializeResponse{}, fmt.Errorf("failed to retrieve users_table: %w", err)
+	}
+	if usersTable == "" {
+		usersTable = defaultUsersTable
+	}
+	parts := strings.Split(usersTable, ".")
+	if len(parts) > 2 {
+		return dbplugin.InitializeResponse{}, fmt.Errorf("invalid users_table %q", usersTable)
+	}
+	for i, part := range parts {
+		if part == "" {
+			return dbplugin.InitializeResponse{}, fmt.Errorf("invalid users_table %q", usersTable)
+		}
+		parts[i] = dbutil.QuoteIdentifier(part)
+	}
+
+	// SQLite databases are plain files, but the SQL connection producer
+	// still handles pooling and connection verification.
+	conf, err := s.Init(ctx, req.Config, false)
+	if err != nil {
+		return dbplugin.InitializeResponse{}, fmt.Errorf("error initializing db: %w", err)
+	}
+	// The connection is only opened once verified or used, so the resolved
+	// path is what gets opened.
+	mainPath, err := s.resolvePath(s.ConnectionURL)
+	if err != nil {
+		return dbplugin.InitializeResponse{}, fmt.Errorf("invalid connection_url: %w", err)
+	}
+	s.ConnectionURL = mainPath
+	s.attachDatabases = attach
+	s.usersTable = strings.Join(parts, ".")
+
+	usernameTemplate, err := strutil.GetString(req.Config, "username_template")
+	if err != nil {
+		return dbplugin.InitializeResponse{}, fmt.Errorf("failed to retrieve username_template: %w", err)
+	}
+	if usernameTemplate == "" {
+		usernameTemplate = defaultUserNameTemplate
+	}
+
+	up, err := template.NewTemplate(template.Template(usernameTemplate))
+	if err != nil {
+		return dbplugin.InitializeResponse{}, fmt.Errorf("unable to initialize username template: %w", err)
+	}
+	s.usernameProducer = up
+
+	_, err = s.usernameProducer.Generate(dbplugin.UsernameMetadata{})
+	if err != nil {
+		return dbplugin.InitializeResponse{}, fmt.Errorf("invalid username template: %w", err)
+	}
+
+	if req.VerifyConnection {
+		// Attaching verifies that every configured database file can be
+		// opened, not only the main one.
+		s.Lock()
+		err := s.withTx(ctx, func(*sql.Tx) error { return nil })
+		s.Unlock()
+		if err != nil {
+			return dbplugin.InitializeResponse{}, fmt.Errorf("error verifying connection: %w", err)
+		}
+	}
+
+	return dbplugin.InitializeResponse{
+		Config: conf,
+	}, nil
+}
+
+// resolvePath returns the absolute path of a database file, which must be
+// within the data directory. Relative paths are relative to the data
+// directory. Symbolic links are resolved, so they can't lead out of it, and
+// URIs are refused as their parameters can change how files are opened.
+func (s *SQLite) resolvePath(path string) (string, error) {
+	if s.dataDir == "" {
+		return "", fmt.Errorf("%s must be set to the directory of the database files", dataDirEnv)
+	}
+	if strings.HasPrefix(path, "file:") || strings.ContainsAny(path, "?\x00") || path == ":memory:" {
+		return "", fmt.Errorf("%q is not a database file path", path)
+	}
+
+	dataDir, err := filepath.Abs(s.dataDir)
+	if err != nil {
+		return "", err
+	}
+	dataDir, err = filepath.EvalSymlinks(dataDir)
+	if err != nil {
+		return "", fmt.Errorf("unable to resolve %s: %w", dataDirEnv, err)
+	}
+
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dataDir, path)
+	}
+	resolved, err := filepath.EvalSymlinks(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		// New database files are created by SQLite, but only in an
+		// existing directory and not through a dangling link.
+		if _, err := os.Lstat(path); err == nil {
+			return "", fmt.Errorf("%q is a dangling link", path)
+		}
+		dir, err := filepath.EvalSymlinks(filepath.Dir(path))
+		if err != nil {
+			return "", err
+		}
+		resolved = filepath.Join(dir, filepath.Base(path))
+	} else if err != nil {
+		return "", err
+	}
+
+	rel, err := filepath.Rel(dataDir, resolved)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%q is not within %s", path, dataDirEnv)
+	}
+	return resolved, nil
+}
+
+// withTx runs fn in a transaction on a connection that has all configured
+// databases attached. SQLite does not allow ATTACH inside a transaction, so
+// a dedicated connection is attached first and detached again afterwards.
+func (s *SQLite) withTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	db, err := s.Connection(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to get connection: %w", err)
+	}
+
+	conn, err := db.(*sql.DB).Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	schemas := make([]string, 0, len(s.attachDatabases))
+	for schema := range s.attachDatabases {
+		schemas = append(schemas, schema)
+	}
+	sort.Strings(schemas)
+
+	for _, schema := range schemas {
+		if _, err := conn.ExecContext(ctx, fmt.Sprintf("ATTACH DATABASE ? AS %s;", dbutil.QuoteIdentifier(schema)), s.attachDatabases[schema]); err != nil {
+			return fmt.Errorf("unable to attach database %q: %w", schema, err)
+		}
+		defer conn.ExecContext(context.Background(), fmt.Sprintf("DETACH DATABASE %s;", dbutil.QuoteIdentifier(schema)))
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("unable to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// The default statements store a bcrypt hash of the password, never the
+// password itself.
+func (s *SQLite) defaultCreationStatement() string {
+	return fmt.Sprintf(`INSERT INTO %s (username, password_hash, expiration) VALUES ('{{name}}', '{{password_hash}}', '{{expiration}}');`, s.usersTable)
+}
+
+func (s *SQLite) defaultChangePasswordStatement() string {
+	return fmt.Sprintf(`UPDATE %s SET password_hash = '{{password_hash}}' WHERE username = '{{name}}';`, s.usersTable)
+}
+
+func (s *SQLite) defaultExpirationStatement() string {
+	return fmt.Sprintf(`UPDATE %s SET expiration = '{{expiration}}' WHERE username = '{{name}}';`, s.usersTable)
+}
+
+func (s *SQLite) defaultDeleteUserStatement() string {
+	return fmt.Sprintf(`DELETE FROM %s WHERE username = '{{name}}';`, s.usersTable)
+}
+
+// NewUser creates a new user. Without creation statements the user is
+// inserted into the users table.
+func (s *SQLite) NewUser(ctx context.Context, req dbplugin.NewUserRequest) (dbplugin.NewUserResponse, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	username, err := s.usernameProducer.Generate(req.UsernameConfig)
+	if err != nil {
+		return dbplugin.NewUserResponse{}, err
+	}
+
+	stmts := req.Statements.Commands
+	if len(stmts) == 0 {
+		stmts = []string{s.defaultCreationStatement()}
+	}
+
+	passwordHash, err := hashPassword(req.Password)
+	if err != nil {
+		return dbplugin.NewUserResponse{}, err
+	}
+
+	m := map[string]string{
+		"name":          username,
+		"username":      username,
+		"password":      req.Password,
+		"password_hash": passwordHash,
+		"expiration":    req.Expiration.UTC().Format(expirationFormat),
+	}
+	err = s.withTx(ctx, func(tx *sql.Tx) error {
+		return executeStatements(ctx, tx, stmts, m)
+	})
+	if err != nil {
+		return dbplugin.NewUserResponse{}, err
+	}
+
+	return dbplugin.NewUserResponse{
+		Username: username,
+	}, nil
+}
+
+// UpdateUser can update the expiration or the password of a user, or both.
+// The updates all happen in a single transaction, so they will either all
+// succeed or all fail.
+func (s *SQLite) UpdateUser(ctx context.Context, req dbplugin.UpdateUserRequest) (dbplugin.UpdateUserResponse, error) {
+	if req.Username == "" {
+		return dbplugin.UpdateUserResponse{}, fmt.Errorf("missing username")
+	}
+	if req.Password == nil && req.Expiration == nil {
+		return dbplugin.UpdateUserResponse{}, errors.New("no changes requested")
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		if req.Password != nil {
+			if err := s.changeUserPassword(ctx, tx, req.Username, req.Password); err != nil {
+				return fmt.Errorf("failed to change password: %w", err)
+			}
+		}
+		if req.Expiration != nil {
+			if err := s.changeUserExpiration(ctx, tx, req.Username, req.Expiration); err != nil {
+				return fmt.Errorf("failed to change expiration: %w", err)
+			}
+		}
+		return nil
+	})
+	return dbplugin.UpdateUserResponse{}, err
+}
+
+func (s *SQLite) changeUserPassword(ctx context.Context, tx *sql.Tx, username string, changePass *dbplugin.ChangePassword) error {
+	password := changePass.NewPassword
+	if password == "" {
+		return fmt.Errorf("missing password")
+	}
+
+	stmts := changePass.Statements.Commands
+	if len(stmts) == 0 {
+		// Static accounts must already exist. Custom statements may manage
+		// users elsewhere, so this can only be checked for the users table.
+		var count int
+		query := fmt.Sprintf("SELECT count(*) FROM %s WHERE username = ?;", s.usersTable)
+		if err := tx.QueryRowContext(ctx, query, username).Scan(&count); err != nil {
+			return fmt.Errorf("unable to look up user: %w", err)
+		}
+		if count == 0 {
+			return fmt.Errorf("cannot update password for username %q because it does not exist", username)
+		}
+		stmts = []string{s.defaultChangePasswordStatement()}
+	}
+
+	passwordHash, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	m := map[string]string{
+		"name":          username,
+		"username":      username,
+		"password":      password,
+		"password_hash": passwordHash,
+	}
+	return executeStatements(ctx, tx, stmts, m)
+}
+
+func (s *SQLite) changeUserExpiration(ctx context.Context, tx *sql.Tx, username string, changeExp *dbplugin.ChangeExpiration) error {
+	stmts := changeExp.Statements.Commands
+	if len(stmts) == 0 {
+		stmts = []string{s.defaultExpirationStatement()}
+	}
+
+	m := map[string]string{
+		"name":       username,
+		"username":   username,
+		"expiration": changeExp.NewExpiration.UTC().Format(expirationFormat),
+	}
+	return executeStatements(ctx, tx, stmts, m)
+}
+
+// DeleteUser supports both default and custom statements to delete a user.
+func (s *SQLite) DeleteUser(ctx context.Context, req dbplugin.DeleteUserRequest) (dbplugin.DeleteUserResponse, error) {
+	if req.Username == "" {
+		return dbplugin.DeleteUserResponse{}, fmt.Errorf("missing username")
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	stmts := req.Statements.Commands
+	if len(stmts) == 0 {
+		stmts = []string{s.defaultDeleteUserStatement()}
+	}
+
+	m := map[string]string{
+		"name":     req.Username,
+		"username": req.Username,
+	}
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		return executeStatements(ctx, tx, stmts, m)
+	})
+	return dbplugin.DeleteUserResponse{}, err
+}
+
+// hashPassword returns the bcrypt hash of the password that the default
+// statements store.
+func hashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("unable to hash password: %w", err)
+	}
+	return string(hash), nil
+}
+
+// executeStatements runs each semicolon separated query of the given
+// statements with the template values substituted.
+func executeStatements(ctx context.Context, tx *sql.Tx, stmts []string, m map[string]string) error {
+	for _, stmt := range stmts {
+		for _, query := range strutil.ParseArbitraryStringSlice(stmt, ";") {
+			query = strings.TrimSpace(query)
+			if len(query) == 0 {
+				continue
+			}
+
+			if err := dbtxn.ExecuteTxQueryDirect(ctx, tx, m, query); err != nil {
+				return fmt.Errorf("failed to execute query: %w", err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,366 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	dbplugin "github.com/hashicorp/vault/sdk/database/dbplugin/v5"
+	dbtesting "github.com/hashicorp/vault/sdk/database/dbplugin/v5/testing"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const testUsersTable = `
+CREATE TABLE users (
+	username TEXT PRIMARY KEY,
+	password_hash TEXT NOT NULL,
+	expiration TEXT
+);
+`
+
+// prepareTestDatabase creates a database file in the data directory with
+// the given statements applied and returns its path.
+func prepareTestDatabase(t *testing.T, dataDir, name string, stmts ...string) string {
+	t.Helper()
+
+	path := filepath.Join(dataDir, name)
+	db, err := sql.Open(sqlTypeName, path)
+	require.NoError(t, err)
+	defer db.Close()
+
+	for _, stmt := range stmts {
+		_, err := db.Exec(stmt)
+		require.NoError(t, err)
+	}
+	return path
+}
+
+func getSQLite(t *testing.T, dataDir string, config map[string]interface{}) *SQLite {
+	t.Helper()
+
+	db := newSQLite()
+	db.dataDir = dataDir
+	dbtesting.AssertInitialize(t, db, dbplugin.InitializeRequest{
+		Config:           config,
+		VerifyConnection: true,
+	})
+	t.Cleanup(func() {
+		dbtesting.AssertClose(t, db)
+	})
+	return db
+}
+
+// readUser returns the stored password hash and expiration of a user, and
+// false if the user does not exist.
+func readUser(t *testing.T, path, table, username string) (string, string, bool) {
+	t.Helper()
+
+	db, err := sql.Open(sqlTypeName, path)
+	require.NoError(t, err)
+	defer db.Close()
+
+	var passwordHash string
+	var expiration sql.NullString
+	err = db.QueryRow("SELECT password_hash, expiration FROM "+table+" WHERE username = ?", username).Scan(&passwordHash, &expiration)
+	if err == sql.ErrNoRows {
+		return "", "", false
+	}
+	require.NoError(t, err)
+	return passwordHash, expiration.String, true
+}
+
+func requirePasswordHash(t *testing.T, password, hash string) {
+	t.Helper()
+	require.NoError(t, bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)))
+}
+
+func TestSQLite_Initialize(t *testing.T) {
+	dataDir := t.TempDir()
+	path := prepareTestDatabase(t, dataDir, "app.db", testUsersTable)
+
+	t.Run("missing connection_url", func(t *testing.T) {
+		db := newSQLite()
+		db.dataDir = dataDir
+		_, err := db.Initialize(context.Background(), dbplugin.InitializeRequest{
+			Config: map[string]interface{}{},
+		})
+		require.Error(t, err)
+	})
+
+	t.Run("invalid schema name", func(t *testing.T) {
+		db := newSQLite()
+		db.dataDir = dataDir
+		_, err := db.Initialize(context.Background(), dbplugin.InitializeRequest{
+			Config: map[string]interface{}{
+				"connection_url": path,
+				"attach_databases": map[string]interface{}{
+					"main": path,
+				},
+			},
+		})
+		require.Error(t, err)
+	})
+
+	t.Run("invalid users_table", func(t *testing.T) {
+		db := newSQLite()
+		db.dataDir = dataDir
+		_, err := db.Initialize(context.Background(), dbplugin.InitializeRequest{
+			Config: map[string]interface{}{
+				"connection_url": path,
+				"users_table":    "a.b.c",
+			},
+		})
+		require.Error(t, err)
+	})
+
+	t.Run("attach_databases as JSON", func(t *testing.T) {
+		db := getSQLite(t, dataDir, map[string]interface{}{
+			"connection_url":   path,
+			"attach_databases": `{"auth": "app.db"}`,
+		})
+		require.Equal(t, map[string]string{"auth": path}, db.attachDatabases)
+	})
+
+	t.Run("valid", func(t *testing.T) {
+		config := map[string]interface{}{
+			"connection_url": path,
+		}
+		db := getSQLite(t, dataDir, config)
+		require.True(t, db.Initialized)
+		require.Equal(t, `"users"`, db.usersTable)
+	})
+}
+
+func TestSQLite_NewUser(t *testing.T) {
+	dataDir := t.TempDir()
+	path := prepareTestDatabase(t, dataDir, "app.db", testUsersTable)
+	db := getSQLite(t, dataDir, map[string]interface{}{
+		"connection_url": path,
+	})
+
+	expiration := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	resp := dbtesting.AssertNewUser(t, db, dbplugin.NewUserRequest{
+		UsernameConfig: dbplugin.UsernameMetadata{
+			DisplayName: "token",
+			RoleName:    "myrole",
+		},
+		Password:   "SuperSecret-1",
+		Expiration: expiration,
+	})
+	require.Regexp(t, `^v-token-myrole-[a-z0-9]{20}-[0-9]{10}$`, resp.Username)
+
+	passwordHash, exp, ok := readUser(t, path, "users", resp.Username)
+	require.True(t, ok)
+	requirePasswordHash(t, "SuperSecret-1", passwordHash)
+	require.Equal(t, expiration.Format(expirationFormat), exp)
+
+	// Custom statements are executed instead of the default
+	resp = dbtesting.AssertNewUser(t, db, dbplugin.NewUserRequest{
+		UsernameConfig: dbplugin.UsernameMetadata{
+			DisplayName: "token",
+			RoleName:    "myrole",
+		},
+		Statements: dbplugin.Statements{
+			Commands: []string{`INSERT INTO users (username, password_hash) VALUES ('{{name}}', 'custom-{{password}}');`},
+		},
+		Password:   "SuperSecret-2",
+		Expiration: expiration,
+	})
+	passwordHash, _, ok = readUser(t, path, "users", resp.Username)
+	require.True(t, ok)
+	require.Equal(t, "custom-SuperSecret-2", passwordHash)
+}
+
+func TestSQLite_UpdateUser(t *testing.T) {
+	dataDir := t.TempDir()
+	path := prepareTestDatabase(t, dataDir, "app.db", testUsersTable,
+		`INSERT INTO users (username, password_hash, expiration) VALUES ('static', 'old', '');`)
+	db := getSQLite(t, dataDir, map[string]interface{}{
+		"connection_url": path,
+	})
+
+	dbtesting.AssertUpdateUser(t, db, dbplugin.UpdateUserRequest{
+		Username: "static",
+		Password: &dbplugin.ChangePassword{
+			NewPassword: "rotated",
+		},
+	})
+	passwordHash, _, _ := readUser(t, path, "users", "static")
+	requirePasswordHash(t, "rotated", passwordHash)
+
+	expiration := time.Now().Add(2 * time.Hour).UTC()
+	dbtesting.AssertUpdateUser(t, db, dbplugin.UpdateUserRequest{
+		Username: "static",
+		Expiration: &dbplugin.ChangeExpiration{
+			NewExpiration: expiration,
+		},
+	})
+	_, exp, _ := readUser(t, path, "users", "static")
+	require.Equal(t, expiration.Format(expirationFormat), exp)
+
+	// Static accounts must exist before Vault can manage them
+	_, err := db.UpdateUser(context.Background(), dbplugin.UpdateUserRequest{
+		Username: "missing",
+		Password: &dbplugin.ChangePassword{
+			NewPassword: "rotated",
+		},
+	})
+	require.Error(t, err)
+
+	// A failing statement leaves the previous password in place
+	_, err = db.UpdateUser(context.Background(), dbplugin.UpdateUserRequest{
+		Username: "static",
+		Password: &dbplugin.ChangePassword{
+			NewPassword: "partial",
+			Statements: dbplugin.Statements{
+				Commands: []string{
+					`UPDATE users SET password_hash = '{{password_hash}}' WHERE username = '{{name}}';`,
+					`UPDATE missing_table SET password_hash = '{{password_hash}}';`,
+				},
+			},
+		},
+	})
+	require.Error(t, err)
+	passwordHash, _, _ = readUser(t, path, "users", "static")
+	requirePasswordHash(t, "rotated", passwordHash)
+}
+
+func TestSQLite_DeleteUser(t *testing.T) {
+	dataDir := t.TempDir()
+	path := prepareTestDatabase(t, dataDir, "app.db", testUsersTable,
+		`INSERT INTO users (username, password_hash, expiration) VALUES ('dynamic', 'secret', '');`)
+	db := getSQLite(t, dataDir, map[string]interface{}{
+		"connection_url": path,
+	})
+
+	dbtesting.AssertDeleteUser(t, db, dbplugin.DeleteUserRequest{
+		Username: "dynamic",
+	})
+	_, _, ok := readUser(t, path, "users", "dynamic")
+	require.False(t, ok)
+}
+
+func TestSQLite_AttachedUsersTable(t *testing.T) {
+	dataDir := t.TempDir()
+	appPath := prepareTestDatabase(t, dataDir, "app.db")
+	authPath := prepareTestDatabase(t, dataDir, "auth.db", testUsersTable,
+		`INSERT INTO users (username, password_hash, expiration) VALUES ('static', 'old', '');`)
+
+	db := getSQLite(t, dataDir, map[string]interface{}{
+		"connection_url": appPath,
+		"attach_databases": map[string]interface{}{
+			"auth": "auth.db",
+		},
+		"users_table": "auth.users",
+	})
+
+	resp := dbtesting.AssertNewUser(t, db, dbplugin.NewUserRequest{
+		UsernameConfig: dbplugin.UsernameMetadata{
+			DisplayName: "token",
+			RoleName:    "myrole",
+		},
+		Password:   "SuperSecret-1",
+		Expiration: time.Now().Add(time.Hour),
+	})
+	passwordHash, _, ok := readUser(t, authPath, "users", resp.Username)
+	require.True(t, ok)
+	requirePasswordHash(t, "SuperSecret-1", passwordHash)
+
+	dbtesting.AssertUpdateUser(t, db, dbplugin.UpdateUserRequest{
+		Username: "static",
+		Password: &dbplugin.ChangePassword{
+			NewPassword: "rotated",
+		},
+	})
+	passwordHash, _, _ = readUser(t, authPath, "users", "static")
+	requirePasswordHash(t, "rotated", passwordHash)
+
+	// A missing attached database fails verification
+	bad := newSQLite()
+	bad.dataDir = dataDir
+	_, err := bad.Initialize(context.Background(), dbplugin.InitializeRequest{
+		Config: map[string]interface{}{
+			"connection_url": appPath,
+			"attach_databases": map[string]interface{}{
+				"auth": filepath.Join(dataDir, "missing", "auth.db"),
+			},
+		},
+		VerifyConnection: true,
+	})
+	require.Error(t, err)
+}
+
+// TestSQLite_DataDir tests that only files within the data directory can be
+// opened or attached
+func TestSQLite_DataDir(t *testing.T) {
+	dataDir := t.TempDir()
+	prepareTestDatabase(t, dataDir, "app.db", testUsersTable)
+
+	outside := t.TempDir()
+	outsidePath := prepareTestDatabase(t, outside, "other.db", testUsersTable)
+	require.NoError(t, os.Symlink(outsidePath, filepath.Join(dataDir, "link.db")))
+	require.NoError(t, os.Symlink(filepath.Join(outside, "new.db"), filepath.Join(dataDir, "dangling.db")))
+
+	initialize := func(dataDir string, config map[string]interface{}) error {
+		db := newSQLite()
+		db.dataDir = dataDir
+		_, err := db.Initialize(context.Background(), dbplugin.InitializeRequest{
+			Config:           config,
+			VerifyConnection: true,
+		})
+		if err == nil {
+			db.Close()
+		}
+		return err
+	}
+
+	for name, path := range map[string]string{
+		"absolute path outside": outsidePath,
+		"relative path outside": filepath.Join("..", filepath.Base(outside), "other.db"),
+		"link to outside":       "link.db",
+		"dangling link":         "dangling.db",
+		"data directory":        dataDir,
+		"URI":                   "file:" + outsidePath,
+		"parameters":            "app.db?_pragma=journal_mode(WAL)",
+		"in memory":             ":memory:",
+		"missing directory":     filepath.Join("missing", "app.db"),
+	} {
+		t.Run(name, func(t *testing.T) {
+			require.Error(t, initialize(dataDir, map[string]interface{}{
+				"connection_url": path,
+			}))
+			require.Error(t, initialize(dataDir, map[string]interface{}{
+				"connection_url": "app.db",
+				"attach_databases": map[string]interface{}{
+					"auth": path,
+				},
+			}))
+		})
+	}
+
+	t.Run("relative path", func(t *testing.T) {
+		db := getSQLite(t, dataDir, map[string]interface{}{
+			"connection_url": "app.db",
+			"attach_databases": map[string]interface{}{
+				"auth": "auth.db",
+			},
+		})
+		resolvedDir, err := filepath.EvalSymlinks(dataDir)
+		require.NoError(t, err)
+		require.Equal(t, filepath.Join(resolvedDir, "app.db"), db.ConnectionURL)
+		require.Equal(t, map[string]string{"auth": filepath.Join(resolvedDir, "auth.db")}, db.attachDatabases)
+	})
+
+	t.Run("no data directory", func(t *testing.T) {
+		require.ErrorContains(t, initialize("", map[string]interface{}{
+			"connection_url": filepath.Join(dataDir, "app.db"),
+		}), dataDirEnv)
+	})
+}
@@ -67,6 +67,7 @@ vault auth enable "oci"
 vault auth enable "okta"
 vault auth enable "radius"
 vault auth enable "userpass"
+vault auth enable "webauthn"
 
 # Enable secrets plugins
 vault secrets enable "alicloud"
@@ -88,6 +89,7 @@ vault secrets enable "ssh"
 vault secrets enable "terraform"
 vault secrets enable "totp"
 vault secrets enable "transit"
+vault secrets enable "webhook"
 
 # Enable enterprise features
 if [[ -n "${VAULT_LICENSE:-}" ]]; then
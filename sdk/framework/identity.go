@@ -5,6 +5,7 @@ package framework
 
 import (
 	"errors"
+	"fmt"
 
 	"github.com/hashicorp/errwrap"
 	"github.com/hashicorp/vault/sdk/helper/identitytpl"
@@ -44,6 +45,30 @@ func PopulateIdentityTemplate(tpl string, entityID string, sysView logical.Syste
 	return out, nil
 }
 
+// IdentityTemplatePopulator returns a function that populates the identity
+// templates of a string, such as {{identity.entity.metadata.service}}, from
+// the given entity. Strings without templating are returned unchanged, while
+// templated strings require an entity.
+func IdentityTemplatePopulator(entityID string, sysView logical.SystemView) func(string) (string, error) {
+	return func(tpl string) (string, error) {
+		hasTemplating, err := ValidateIdentityTemplate(tpl)
+		if err != nil {
+			return "", err
+		}
+		if !hasTemplating {
+			return tpl, nil
+		}
+		if entityID == "" {
+			return "", fmt.Errorf("templated value %q requires a request from an identity entity", tpl)
+		}
+		populated, err := PopulateIdentityTemplate(tpl, entityID, sysView)
+		if err != nil {
+			return "", fmt.Errorf("failed to populate %q: %w", tpl, err)
+		}
+		return populated, nil
+	}
+}
+
 // ValidateIdentityTemplate takes a template string and returns if the string is
 // a valid identity template.
 func ValidateIdentityTemplate(tpl string) (bool, error) {
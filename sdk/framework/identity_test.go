@@ -101,3 +101,34 @@ func TestIdentityTemplating(t *testing.T) {
 		}
 	}
 }
+
+func TestIdentityTemplatePopulator(t *testing.T) {
+	sysView := &logical.StaticSystemView{
+		EntityVal: &logical.Entity{
+			ID:   "test-id",
+			Name: "test",
+		},
+	}
+
+	populate := IdentityTemplatePopulator("test-id", sysView)
+	out, err := populate("static")
+	if err != nil || out != "static" {
+		t.Fatalf("got %q, %v, expected the value unchanged", out, err)
+	}
+	out, err = populate("prefix-{{identity.entity.name}}")
+	if err != nil || out != "prefix-test" {
+		t.Fatalf("got %q, %v, expected %q", out, err, "prefix-test")
+	}
+	if _, err := populate("{{identity.entity.name"); err == nil {
+		t.Fatal("expected error for an invalid template")
+	}
+
+	// Templated values require an entity, while static ones don't
+	populate = IdentityTemplatePopulator("", sysView)
+	if out, err := populate("static"); err != nil || out != "static" {
+		t.Fatalf("got %q, %v, expected the value unchanged", out, err)
+	}
+	if _, err := populate("{{identity.entity.name}}"); err == nil {
+		t.Fatal("expected error without an entity")
+	}
+}
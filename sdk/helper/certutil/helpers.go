@@ -13,10 +13,12 @@ import (
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/sha1"
+	"crypto/sha256"
 	"crypto/tls"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/asn1"
+	"encoding/base64"
 	"encoding/binary"
 	"encoding/hex"
 	"encoding/pem"
@@ -135,6 +137,15 @@ func ParseHexFormatted(in, sep string) []byte {
 	return ret.Bytes()
 }
 
+// GetCertificateThumbprint returns the base64url encoded SHA-256 hash of the
+// DER encoding of the certificate, as used by the x5t#S256 confirmation
+// method of certificate-bound tokens in
+// https://www.rfc-editor.org/rfc/rfc8705#section-3.1
+func GetCertificateThumbprint(cert *x509.Certificate) string {
+	hash := sha256.Sum256(cert.Raw)
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
+
 // GetSubjKeyID returns the subject key ID. The computed ID is the SHA-1 hash of
 // the marshaled public key according to
 // https://tools.ietf.org/html/rfc5280#section-4.2.1.2 (1)
@@ -573,6 +573,10 @@ func (c *Client) performLdapTokenGroupsSearch(cfg *ConfigEntry, conn Connection,
  *
  * NOTE - If cfg.GroupFilter is empty, no query is performed and an empty result slice is returned.
  *
+ * If cfg.NestedGroups is set, the groups the found groups are transitively members of are
+ * searched for in cfg.GroupDN as well, and resolved the same way. This is not needed with
+ * cfg.UseTokenGroups, as tokenGroups already includes nested groups.
+ *
  */
 func (c *Client) GetLdapGroups(cfg *ConfigEntry, conn Connection, userDN string, username string) ([]string, error) {
 	var entries []*ldap.Entry
@@ -585,6 +589,11 @@ func (c *Client) GetLdapGroups(cfg *ConfigEntry, conn Connection, userDN string,
 		} else {
 			entries, err = c.performLdapFilterGroupsSearch(cfg, conn, userDN, username)
 		}
+		if err == nil && cfg.ResolvesNestedGroups() {
+			var parents []*ldap.Entry
+			parents, err = c.performNestedGroupsSearch(cfg, conn, entries)
+			entries = append(entries, parents...)
+		}
 	}
 	if err != nil {
 		return nil, err
@@ -232,6 +232,31 @@ Default: ({{.UserAttr}}={{.Username}})`,
 			Description: "In Vault 1.1.1 a fix for handling group CN values of different cases unfortunately introduced a regression that could cause previously defined groups to not be found due to a change in the resulting name. If set true, the pre-1.1.1 behavior for matching group CNs will be used. This is only needed in some upgrade scenarios for backwards compatibility. It is enabled by default if the config is upgraded but disabled by default on new configurations.",
 		},
 
+		"nested_groups": {
+			Type:          framework.TypeString,
+			Description:   "How to resolve the parent groups of the groups the user is a member of. Accepted values are 'disabled', 'in_chain' to use the Active Directory LDAP_MATCHING_RULE_IN_CHAIN matching rule, and 'recursive' to search the parents of each group level by level. Defaults to 'disabled'.",
+			Default:       NestedGroupsDisabled,
+			AllowedValues: []interface{}{NestedGroupsDisabled, NestedGroupsInChain, NestedGroupsRecursive},
+		},
+
+		"nested_groups_max_depth": {
+			Type:        framework.TypeInt,
+			Description: "The maximum number of levels of parent groups to search when nested_groups is 'recursive'. Defaults to 10.",
+			Default:     defaultNestedGroupsMaxDepth,
+		},
+
+		"group_member_attr": {
+			Type:        framework.TypeString,
+			Description: "The attribute of group objects that lists the DNs of their member groups, used to resolve nested groups. Defaults to 'member'.",
+			Default:     defaultGroupMemberAttr,
+		},
+
+		"group_cache_ttl": {
+			Type:        framework.TypeDurationSecond,
+			Description: "How long to cache the groups of a user after a successful login. Changes to group memberships take up to this long to apply. Defaults to 0, which disables caching.",
+			Default:     0,
+		},
+
 		"request_timeout": {
 			Type:        framework.TypeDurationSecond,
 			Description: "Timeout, in seconds, for the connection when making requests against the server before returning back an error.",
@@ -418,6 +443,32 @@ func NewConfigEntry(existing *ConfigEntry, d *framework.FieldData) (*ConfigEntry
 		cfg.UseTokenGroups = d.Get("use_token_groups").(bool)
 	}
 
+	if _, ok := d.Raw["nested_groups"]; ok || !hadExisting {
+		cfg.NestedGroups = d.Get("nested_groups").(string)
+	}
+
+	if _, ok := d.Raw["nested_groups_max_depth"]; ok || !hadExisting {
+		cfg.NestedGroupsMaxDepth = d.Get("nested_groups_max_depth").(int)
+		if cfg.NestedGroupsMaxDepth < 1 {
+			return nil, errors.New("'nested_groups_max_depth' must be at least 1")
+		}
+	}
+
+	if _, ok := d.Raw["group_member_attr"]; ok || !hadExisting {
+		cfg.GroupMemberAttr = d.Get("group_member_attr").(string)
+	}
+
+	if _, ok := d.Raw["group_cache_ttl"]; ok || !hadExisting {
+		cfg.GroupCacheTTL = d.Get("group_cache_ttl").(int)
+		if cfg.GroupCacheTTL < 0 {
+			return nil, errors.New("'group_cache_ttl' must not be negative")
+		}
+	}
+
+	if cfg.UseTokenGroups && cfg.nestedGroups() != NestedGroupsDisabled {
+		return nil, errors.New("'nested_groups' cannot be used with 'use_token_groups', which already includes nested groups")
+	}
+
 	if _, ok := d.Raw["request_timeout"]; ok || !hadExisting {
 		cfg.RequestTimeout = d.Get("request_timeout").(int)
 	}
@@ -463,6 +514,10 @@ type ConfigEntry struct {
 	ConnectionTimeout        int    `json:"connection_timeout"` // deprecated: use RequestTimeout
 	DerefAliases             string `json:"dereference_aliases"`
 	MaximumPageSize          int    `json:"max_page_size"`
+	NestedGroups             string `json:"nested_groups"`
+	NestedGroupsMaxDepth     int    `json:"nested_groups_max_depth"`
+	GroupMemberAttr          string `json:"group_member_attr"`
+	GroupCacheTTL            int    `json:"group_cache_ttl"`
 
 	// These json tags deviate from snake case because there was a past issue
 	// where the tag was being ignored, causing it to be jsonified as "CaseSensitiveNames", etc.
@@ -481,29 +536,33 @@ func (c *ConfigEntry) Map() map[string]interface{} {
 
 func (c *ConfigEntry) PasswordlessMap() map[string]interface{} {
 	m := map[string]interface{}{
-		"url":                    c.Url,
-		"userdn":                 c.UserDN,
-		"groupdn":                c.GroupDN,
-		"groupfilter":            c.GroupFilter,
-		"groupattr":              c.GroupAttr,
-		"userfilter":             c.UserFilter,
-		"upndomain":              c.UPNDomain,
-		"userattr":               c.UserAttr,
-		"certificate":            c.Certificate,
-		"insecure_tls":           c.InsecureTLS,
-		"starttls":               c.StartTLS,
-		"binddn":                 c.BindDN,
-		"deny_null_bind":         c.DenyNullBind,
-		"discoverdn":             c.DiscoverDN,
-		"tls_min_version":        c.TLSMinVersion,
-		"tls_max_version":        c.TLSMaxVersion,
-		"use_token_groups":       c.UseTokenGroups,
-		"anonymous_group_search": c.AnonymousGroupSearch,
-		"request_timeout":        c.RequestTimeout,
-		"connection_timeout":     c.ConnectionTimeout,
-		"username_as_alias":      c.UsernameAsAlias,
-		"dereference_aliases":    c.DerefAliases,
-		"max_page_size":          c.MaximumPageSize,
+		"url":                     c.Url,
+		"userdn":                  c.UserDN,
+		"groupdn":                 c.GroupDN,
+		"groupfilter":             c.GroupFilter,
+		"groupattr":               c.GroupAttr,
+		"userfilter":              c.UserFilter,
+		"upndomain":               c.UPNDomain,
+		"userattr":                c.UserAttr,
+		"certificate":             c.Certificate,
+		"insecure_tls":            c.InsecureTLS,
+		"starttls":                c.StartTLS,
+		"binddn":                  c.BindDN,
+		"deny_null_bind":          c.DenyNullBind,
+		"discoverdn":              c.DiscoverDN,
+		"tls_min_version":         c.TLSMinVersion,
+		"tls_max_version":         c.TLSMaxVersion,
+		"use_token_groups":        c.UseTokenGroups,
+		"anonymous_group_search":  c.AnonymousGroupSearch,
+		"request_timeout":         c.RequestTimeout,
+		"connection_timeout":      c.ConnectionTimeout,
+		"username_as_alias":       c.UsernameAsAlias,
+		"dereference_aliases":     c.DerefAliases,
+		"max_page_size":           c.MaximumPageSize,
+		"nested_groups":           c.nestedGroups(),
+		"nested_groups_max_depth": c.nestedGroupsMaxDepth(),
+		"group_member_attr":       c.groupMemberAttr(),
+		"group_cache_ttl":         c.GroupCacheTTL,
 	}
 	if c.CaseSensitiveNames != nil {
 		m["case_sensitive_names"] = *c.CaseSensitiveNames
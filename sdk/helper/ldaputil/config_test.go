@@ -49,6 +49,41 @@ func TestNewConfigEntry(t *testing.T) {
 	})
 }
 
+func TestNewConfigEntry_NestedGroups(t *testing.T) {
+	s := &framework.FieldData{
+		Schema: ConfigFields(),
+		Raw: map[string]interface{}{
+			"nested_groups":    NestedGroupsRecursive,
+			"use_token_groups": true,
+		},
+	}
+	if _, err := NewConfigEntry(nil, s); err == nil {
+		t.Fatal("expected nested_groups to be rejected with use_token_groups")
+	}
+
+	s.Raw = map[string]interface{}{
+		"nested_groups_max_depth": 0,
+	}
+	if _, err := NewConfigEntry(nil, s); err == nil {
+		t.Fatal("expected nested_groups_max_depth of 0 to be rejected")
+	}
+
+	s.Raw = map[string]interface{}{
+		"nested_groups":   NestedGroupsInChain,
+		"group_cache_ttl": "5m",
+	}
+	config, err := NewConfigEntry(nil, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !config.ResolvesNestedGroups() {
+		t.Error("expected nested groups to be resolved")
+	}
+	if config.GroupCacheTTL != 300 {
+		t.Errorf("expected group_cache_ttl of 300 seconds but got %d", config.GroupCacheTTL)
+	}
+}
+
 func TestConfig(t *testing.T) {
 	config := testConfig(t)
 	configFromJSON := testJSONConfig(t, jsonConfig)
@@ -176,6 +211,10 @@ var jsonConfigDefault = []byte(`
   "connection_timeout": 30,
   "dereference_aliases": "never",
   "max_page_size": 0,
+  "nested_groups": "disabled",
+  "nested_groups_max_depth": 10,
+  "group_member_attr": "member",
+  "group_cache_ttl": 0,
   "CaseSensitiveNames": false,
   "ClientTLSCert": "",
   "ClientTLSKey": ""
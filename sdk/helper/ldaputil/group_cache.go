@@ -0,0 +1,81 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package ldaputil
+
+import (
+	"sync"
+	"time"
+)
+
+// GroupCache caches the groups of users for a limited time, so that logins
+// don't search the directory for groups every time. It is safe for
+// concurrent use.
+type GroupCache struct {
+	mu      sync.Mutex
+	entries map[string]groupCacheEntry
+
+	// now is overridden in tests
+	now func() time.Time
+}
+
+type groupCacheEntry struct {
+	groups    []string
+	expiresAt time.Time
+}
+
+func NewGroupCache() *GroupCache {
+	return &GroupCache{
+		entries: make(map[string]groupCacheEntry),
+		now:     time.Now,
+	}
+}
+
+// Get returns the cached groups of the user, if they haven't expired.
+func (c *GroupCache) Get(userDN, username string) ([]string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := groupCacheKey(userDN, username)
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if !c.now().Before(entry.expiresAt) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return append([]string(nil), entry.groups...), true
+}
+
+// Set caches the groups of the user for the given time. Expired entries are
+// removed as new ones are added.
+func (c *GroupCache) Set(userDN, username string, groups []string, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	for key, entry := range c.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(c.entries, key)
+		}
+	}
+
+	c.entries[groupCacheKey(userDN, username)] = groupCacheEntry{
+		groups:    append([]string(nil), groups...),
+		expiresAt: now.Add(ttl),
+	}
+}
+
+// Purge removes all cached groups, such as when the configuration changes.
+func (c *GroupCache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = make(map[string]groupCacheEntry)
+}
+
+// groupCacheKey includes the username as group filters can use it.
+func groupCacheKey(userDN, username string) string {
+	return userDN + "\x00" + username
+}
@@ -0,0 +1,201 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package ldaputil
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+const (
+	// NestedGroupsDisabled only returns the groups the user is a direct
+	// member of, as found by the group filter.
+	NestedGroupsDisabled = "disabled"
+
+	// NestedGroupsInChain resolves all parent groups with a single search
+	// using the Active Directory LDAP_MATCHING_RULE_IN_CHAIN matching rule.
+	NestedGroupsInChain = "in_chain"
+
+	// NestedGroupsRecursive resolves parent groups by searching for the
+	// groups that list the groups found so far as members, one level at a
+	// time. It works with any directory.
+	NestedGroupsRecursive = "recursive"
+
+	// matchingRuleInChain is the OID of LDAP_MATCHING_RULE_IN_CHAIN, which
+	// walks the chain of ancestry of the object the filter matches.
+	matchingRuleInChain = "1.2.840.113556.1.4.1941"
+
+	defaultNestedGroupsMaxDepth = 10
+	defaultGroupMemberAttr      = "member"
+
+	// maxGroupsPerFilter bounds the number of groups searched for at once
+	// in recursive mode, to keep filters within server limits.
+	maxGroupsPerFilter = 50
+)
+
+func (c *ConfigEntry) nestedGroups() string {
+	if c.NestedGroups == "" {
+		return NestedGroupsDisabled
+	}
+	return c.NestedGroups
+}
+
+func (c *ConfigEntry) nestedGroupsMaxDepth() int {
+	if c.NestedGroupsMaxDepth <= 0 {
+		return defaultNestedGroupsMaxDepth
+	}
+	return c.NestedGroupsMaxDepth
+}
+
+func (c *ConfigEntry) groupMemberAttr() string {
+	if c.GroupMemberAttr == "" {
+		return defaultGroupMemberAttr
+	}
+	return c.GroupMemberAttr
+}
+
+// ResolvesNestedGroups reports whether the configuration resolves the parent
+// groups of the groups of the user.
+func (c *ConfigEntry) ResolvesNestedGroups() bool {
+	return c.nestedGroups() != NestedGroupsDisabled
+}
+
+// performNestedGroupsSearch returns the groups the given groups are
+// transitively members of, excluding the given groups themselves.
+func (c *Client) performNestedGroupsSearch(cfg *ConfigEntry, conn Connection, groups []*ldap.Entry) ([]*ldap.Entry, error) {
+	if len(groups) == 0 || cfg.GroupDN == "" {
+		return nil, nil
+	}
+
+	seen := make(map[string]bool, len(groups))
+	var dns []string
+	for _, e := range groups {
+		key := strings.ToLower(e.DN)
+		if !seen[key] {
+			seen[key] = true
+			dns = append(dns, e.DN)
+		}
+	}
+
+	switch cfg.nestedGroups() {
+	case NestedGroupsInChain:
+		return c.performInChainGroupsSearch(cfg, conn, dns, seen)
+	case NestedGroupsRecursive:
+		return c.performRecursiveGroupsSearch(cfg, conn, dns, seen)
+	default:
+		return nil, nil
+	}
+}
+
+// performInChainGroupsSearch finds all ancestors of the groups in a single
+// search, leaving the traversal to the directory.
+func (c *Client) performInChainGroupsSearch(cfg *ConfigEntry, conn Connection, dns []string, seen map[string]bool) ([]*ldap.Entry, error) {
+	filter := memberFilter(fmt.Sprintf("%s:%s:", cfg.groupMemberAttr(), matchingRuleInChain), dns)
+
+	entries, err := c.searchGroups(cfg, conn, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var parents []*ldap.Entry
+	for _, e := range entries {
+		key := strings.ToLower(e.DN)
+		if !seen[key] {
+			seen[key] = true
+			parents = append(parents, e)
+		}
+	}
+	return parents, nil
+}
+
+// performRecursiveGroupsSearch walks up the group hierarchy one level at a
+// time, until no new groups are found or the maximum depth is reached.
+// Groups already found are not searched again, so cycles terminate.
+func (c *Client) performRecursiveGroupsSearch(cfg *ConfigEntry, conn Connection, dns []string, seen map[string]bool) ([]*ldap.Entry, error) {
+	var parents []*ldap.Entry
+	level := dns
+
+	for depth := 0; depth < cfg.nestedGroupsMaxDepth() && len(level) > 0; depth++ {
+		var next []string
+		for start := 0; start < len(level); start += maxGroupsPerFilter {
+			end := start + maxGroupsPerFilter
+			if end > len(level) {
+				end = len(level)
+			}
+
+			entries, err := c.searchGroups(cfg, conn, memberFilter(cfg.groupMemberAttr(), level[start:end]))
+			if err != nil {
+				return nil, err
+			}
+
+			for _, e := range entries {
+				key := strings.ToLower(e.DN)
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+				parents = append(parents, e)
+				next = append(next, e.DN)
+			}
+		}
+		level = next
+	}
+
+	if len(level) > 0 {
+		c.Logger.Warn("stopped resolving nested groups at the maximum depth", "nested_groups_max_depth", cfg.nestedGroupsMaxDepth())
+	}
+
+	return parents, nil
+}
+
+// searchGroups searches groupdn for the groups matching the filter, using
+// paging if the connection and configuration support it.
+func (c *Client) searchGroups(cfg *ConfigEntry, conn Connection, filter string) ([]*ldap.Entry, error) {
+	if c.Logger.IsDebug() {
+		c.Logger.Debug("searching for nested groups", "groupdn", cfg.GroupDN, "filter", filter)
+	}
+
+	req := &ldap.SearchRequest{
+		BaseDN:       cfg.GroupDN,
+		Scope:        ldap.ScopeWholeSubtree,
+		DerefAliases: ldapDerefAliasMap[cfg.DerefAliases],
+		Filter:       filter,
+		Attributes: []string{
+			cfg.GroupAttr,
+		},
+		SizeLimit: math.MaxInt32,
+	}
+
+	var result *ldap.SearchResult
+	var err error
+	if paging, ok := conn.(PagingConnection); ok && cfg.MaximumPageSize > 0 {
+		result, err = paging.SearchWithPaging(req, uint32(cfg.MaximumPageSize))
+	} else {
+		result, err = conn.Search(req)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("LDAP search for nested groups failed: %w", err)
+	}
+
+	return result.Entries, nil
+}
+
+// memberFilter returns a filter matching the objects that have any of the
+// DNs as value of the given attribute description.
+func memberFilter(attr string, dns []string) string {
+	var b strings.Builder
+	if len(dns) > 1 {
+		b.WriteString("(|")
+	}
+	for _, dn := range dns {
+		fmt.Fprintf(&b, "(%s=%s)", attr, ldap.EscapeFilter(dn))
+	}
+	if len(dns) > 1 {
+		b.WriteString(")")
+	}
+	return b.String()
+}
@@ -0,0 +1,258 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package ldaputil
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDirectory is an in-process stand-in for an LDAP server. It evaluates
+// the filters used for group searches, including extensible matches with
+// LDAP_MATCHING_RULE_IN_CHAIN, against a fixed set of entries.
+type fakeDirectory struct {
+	Connection
+
+	entries  map[string]map[string][]string
+	searches []string
+}
+
+func (d *fakeDirectory) Search(req *ldap.SearchRequest) (*ldap.SearchResult, error) {
+	d.searches = append(d.searches, req.Filter)
+
+	filter, err := ldap.CompileFilter(req.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var dns []string
+	for dn := range d.entries {
+		dns = append(dns, dn)
+	}
+	sort.Strings(dns)
+
+	result := &ldap.SearchResult{}
+	for _, dn := range dns {
+		if !strings.HasSuffix(strings.ToLower(dn), strings.ToLower(req.BaseDN)) {
+			continue
+		}
+		matches, err := d.matches(dn, filter)
+		if err != nil {
+			return nil, err
+		}
+		if !matches {
+			continue
+		}
+
+		attrs := make(map[string][]string)
+		for _, name := range req.Attributes {
+			if values, ok := d.entries[dn][name]; ok {
+				attrs[name] = values
+			}
+		}
+		result.Entries = append(result.Entries, ldap.NewEntry(dn, attrs))
+	}
+	return result, nil
+}
+
+func (d *fakeDirectory) matches(dn string, filter *ber.Packet) (bool, error) {
+	switch filter.Tag {
+	case ldap.FilterAnd, ldap.FilterOr:
+		for _, child := range filter.Children {
+			matches, err := d.matches(dn, child)
+			if err != nil {
+				return false, err
+			}
+			if matches == (filter.Tag == ldap.FilterOr) {
+				return matches, nil
+			}
+		}
+		return filter.Tag == ldap.FilterAnd, nil
+	case ldap.FilterNot:
+		matches, err := d.matches(dn, filter.Children[0])
+		return !matches, err
+	case ldap.FilterPresent:
+		return len(d.values(dn, filter.Data.String())) > 0, nil
+	case ldap.FilterEqualityMatch:
+		return d.hasValue(dn, filter.Children[0].Data.String(), filter.Children[1].Data.String()), nil
+	case ldap.FilterExtensibleMatch:
+		var rule, attr, value string
+		for _, child := range filter.Children {
+			switch child.Tag {
+			case ldap.MatchingRuleAssertionMatchingRule:
+				rule = child.Data.String()
+			case ldap.MatchingRuleAssertionType:
+				attr = child.Data.String()
+			case ldap.MatchingRuleAssertionMatchValue:
+				value = child.Data.String()
+			}
+		}
+		if rule != matchingRuleInChain {
+			return false, fmt.Errorf("unsupported matching rule %q", rule)
+		}
+		return d.inChain(dn, attr, value, map[string]bool{}), nil
+	default:
+		return false, fmt.Errorf("unsupported filter %q", ldap.FilterMap[uint64(filter.Tag)])
+	}
+}
+
+// inChain reports whether the value is reachable from the entry by following
+// the attribute, as LDAP_MATCHING_RULE_IN_CHAIN does.
+func (d *fakeDirectory) inChain(dn, attr, value string, visited map[string]bool) bool {
+	if visited[strings.ToLower(dn)] {
+		return false
+	}
+	visited[strings.ToLower(dn)] = true
+
+	for _, v := range d.values(dn, attr) {
+		if strings.EqualFold(v, value) || d.inChain(v, attr, value, visited) {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *fakeDirectory) hasValue(dn, attr, value string) bool {
+	for _, v := range d.values(dn, attr) {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *fakeDirectory) values(dn, attr string) []string {
+	for entryDN, attrs := range d.entries {
+		if strings.EqualFold(entryDN, dn) {
+			for name, values := range attrs {
+				if strings.EqualFold(name, attr) {
+					return values
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// testNestedGroupsDirectory returns a directory in which alice is a direct
+// member of dev, which is nested three levels deep, and eng and ops are
+// members of each other.
+func testNestedGroupsDirectory() *fakeDirectory {
+	group := func(cn string, members ...string) map[string][]string {
+		return map[string][]string{"objectClass": {"group"}, "cn": {cn}, "member": members}
+	}
+	return &fakeDirectory{
+		entries: map[string]map[string][]string{
+			"uid=alice,ou=people,dc=example,dc=org": {"objectClass": {"person"}},
+			"cn=dev,ou=groups,dc=example,dc=org":    group("dev", "uid=alice,ou=people,dc=example,dc=org"),
+			"cn=eng,ou=groups,dc=example,dc=org":    group("eng", "cn=dev,ou=groups,dc=example,dc=org", "cn=ops,ou=groups,dc=example,dc=org"),
+			"cn=ops,ou=groups,dc=example,dc=org":    group("ops", "cn=eng,ou=groups,dc=example,dc=org"),
+			"cn=staff,ou=groups,dc=example,dc=org":  group("staff", "cn=eng,ou=groups,dc=example,dc=org"),
+			"cn=all,ou=groups,dc=example,dc=org":    group("all", "cn=staff,ou=groups,dc=example,dc=org"),
+			"cn=sales,ou=groups,dc=example,dc=org":  group("sales"),
+		},
+	}
+}
+
+func testNestedGroupsConfig(nestedGroups string) *ConfigEntry {
+	return &ConfigEntry{
+		GroupDN:      "ou=groups,dc=example,dc=org",
+		GroupFilter:  "(|(memberUid={{.Username}})(member={{.UserDN}})(uniqueMember={{.UserDN}}))",
+		GroupAttr:    "cn",
+		NestedGroups: nestedGroups,
+	}
+}
+
+// TestGetLdapGroups_NestedGroups tests that the groups the groups of the
+// user are members of are resolved, in all modes
+func TestGetLdapGroups_NestedGroups(t *testing.T) {
+	ldapClient := Client{Logger: hclog.NewNullLogger()}
+	userDN := "uid=alice,ou=people,dc=example,dc=org"
+
+	for name, tc := range map[string]struct {
+		cfg      *ConfigEntry
+		groups   []string
+		searches int
+	}{
+		"disabled": {
+			cfg:      testNestedGroupsConfig(""),
+			groups:   []string{"dev"},
+			searches: 1,
+		},
+		"in_chain": {
+			cfg:      testNestedGroupsConfig(NestedGroupsInChain),
+			groups:   []string{"all", "dev", "eng", "ops", "staff"},
+			searches: 2,
+		},
+		"recursive": {
+			// dev, then eng, then ops and staff, then all, then nothing new
+			cfg:      testNestedGroupsConfig(NestedGroupsRecursive),
+			groups:   []string{"all", "dev", "eng", "ops", "staff"},
+			searches: 5,
+		},
+		"recursive max depth": {
+			cfg: func() *ConfigEntry {
+				cfg := testNestedGroupsConfig(NestedGroupsRecursive)
+				cfg.NestedGroupsMaxDepth = 2
+				return cfg
+			}(),
+			groups:   []string{"dev", "eng", "ops", "staff"},
+			searches: 3,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			directory := testNestedGroupsDirectory()
+
+			groups, err := ldapClient.GetLdapGroups(tc.cfg, directory, userDN, "alice")
+			require.NoError(t, err)
+			sort.Strings(groups)
+			assert.Equal(t, tc.groups, groups)
+			assert.Len(t, directory.searches, tc.searches)
+		})
+	}
+}
+
+func TestMemberFilter(t *testing.T) {
+	assert.Equal(t, `(member=cn=a\2a,dc=org)`, memberFilter("member", []string{"cn=a*,dc=org"}))
+	assert.Equal(t,
+		"(|(member:1.2.840.113556.1.4.1941:=cn=a,dc=org)(member:1.2.840.113556.1.4.1941:=cn=b,dc=org))",
+		memberFilter("member:"+matchingRuleInChain+":", []string{"cn=a,dc=org", "cn=b,dc=org"}),
+	)
+}
+
+func TestGroupCache(t *testing.T) {
+	now := time.Now()
+	cache := NewGroupCache()
+	cache.now = func() time.Time { return now }
+
+	_, ok := cache.Get("uid=alice", "alice")
+	assert.False(t, ok)
+
+	cache.Set("uid=alice", "alice", []string{"dev"}, time.Minute)
+	groups, ok := cache.Get("uid=alice", "alice")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"dev"}, groups)
+
+	// Entries are per user DN and username
+	_, ok = cache.Get("uid=alice", "Alice")
+	assert.False(t, ok)
+
+	now = now.Add(time.Minute)
+	_, ok = cache.Get("uid=alice", "alice")
+	assert.False(t, ok)
+
+	cache.Set("uid=alice", "alice", []string{"dev"}, time.Minute)
+	cache.Purge()
+	_, ok = cache.Get("uid=alice", "alice")
+	assert.False(t, ok)
+}
@@ -93,6 +93,11 @@ type Auth struct {
 	// The set of CIDRs that this token can be used with
 	BoundCIDRs []*sockaddr.SockAddrMarshaler `json:"bound_cidrs"`
 
+	// BoundCertificateThumbprint, if set, is the x5t#S256 thumbprint of the
+	// client certificate that requests made with this token must be sent
+	// over mTLS with
+	BoundCertificateThumbprint string `json:"bound_certificate_thumbprint"`
+
 	// CreationPath is a path that the backend can return to use in the lease.
 	// This is currently only supported for the token store where roles may
 	// change the perceived path of the lease, even though they don't change
@@ -107,6 +107,16 @@ type PasswordPolicy interface {
 	Generate(context.Context, io.Reader) (string, error)
 }
 
+// PasswordPolicyValidator is implemented by system views that can check
+// user chosen passwords against the password policies of the system. It is
+// not available to plugins running externally.
+type PasswordPolicyValidator interface {
+	// ValidatePasswordWithPolicy returns an error describing the requirements
+	// of the referenced policy the password doesn't meet. If the policy does
+	// not exist, this will return an error.
+	ValidatePasswordWithPolicy(ctx context.Context, policyName, password string) error
+}
+
 type WellKnownSystemView interface {
 	// RequestWellKnownRedirect registers a redirect from .well-known/src
 	// to dest, where dest is a sub-path of the mount. An error
@@ -150,6 +150,10 @@ type TokenEntry struct {
 	// The set of CIDRs that this token can be used with
 	BoundCIDRs []*sockaddr.SockAddrMarshaler `json:"bound_cidrs" sentinel:""`
 
+	// The x5t#S256 thumbprint of the client certificate that this token can be
+	// used with
+	BoundCertificateThumbprint string `json:"bound_certificate_thumbprint" mapstructure:"bound_certificate_thumbprint" structs:"bound_certificate_thumbprint" sentinel:""`
+
 	// NamespaceID is the identifier of the namespace to which this token is
 	// confined to. Do not return this value over the API when the token is
 	// being looked up.
@@ -604,28 +604,29 @@ func LogicalTokenEntryToProtoTokenEntry(t *logical.TokenEntry) *TokenEntry {
 	}
 
 	return &TokenEntry{
-		ID:                 t.ID,
-		Accessor:           t.Accessor,
-		Parent:             t.Parent,
-		Policies:           t.Policies,
-		InlinePolicy:       t.InlinePolicy,
-		Path:               t.Path,
-		Meta:               t.Meta,
-		InternalMeta:       t.InternalMeta,
-		DisplayName:        t.DisplayName,
-		NumUses:            int64(t.NumUses),
-		CreationTime:       t.CreationTime,
-		TTL:                int64(t.TTL),
-		ExplicitMaxTTL:     int64(t.ExplicitMaxTTL),
-		Role:               t.Role,
-		Period:             int64(t.Period),
-		EntityID:           t.EntityID,
-		NoIdentityPolicies: t.NoIdentityPolicies,
-		BoundCIDRs:         boundCIDRs,
-		NamespaceID:        t.NamespaceID,
-		CubbyholeID:        t.CubbyholeID,
-		Type:               uint32(t.Type),
-		ExternalID:         t.ExternalID,
+		ID:                         t.ID,
+		Accessor:                   t.Accessor,
+		Parent:                     t.Parent,
+		Policies:                   t.Policies,
+		InlinePolicy:               t.InlinePolicy,
+		Path:                       t.Path,
+		Meta:                       t.Meta,
+		InternalMeta:               t.InternalMeta,
+		DisplayName:                t.DisplayName,
+		NumUses:                    int64(t.NumUses),
+		CreationTime:               t.CreationTime,
+		TTL:                        int64(t.TTL),
+		ExplicitMaxTTL:             int64(t.ExplicitMaxTTL),
+		Role:                       t.Role,
+		Period:                     int64(t.Period),
+		EntityID:                   t.EntityID,
+		NoIdentityPolicies:         t.NoIdentityPolicies,
+		BoundCIDRs:                 boundCIDRs,
+		NamespaceID:                t.NamespaceID,
+		CubbyholeID:                t.CubbyholeID,
+		Type:                       uint32(t.Type),
+		ExternalID:                 t.ExternalID,
+		BoundCertificateThumbprint: t.BoundCertificateThumbprint,
 	}
 }
 
@@ -645,28 +646,29 @@ func ProtoTokenEntryToLogicalTokenEntry(t *TokenEntry) (*logical.TokenEntry, err
 	}
 
 	return &logical.TokenEntry{
-		ID:                 t.ID,
-		Accessor:           t.Accessor,
-		Parent:             t.Parent,
-		Policies:           t.Policies,
-		InlinePolicy:       t.InlinePolicy,
-		Path:               t.Path,
-		Meta:               t.Meta,
-		InternalMeta:       t.InternalMeta,
-		DisplayName:        t.DisplayName,
-		NumUses:            int(t.NumUses),
-		CreationTime:       t.CreationTime,
-		TTL:                time.Duration(t.TTL),
-		ExplicitMaxTTL:     time.Duration(t.ExplicitMaxTTL),
-		Role:               t.Role,
-		Period:             time.Duration(t.Period),
-		EntityID:           t.EntityID,
-		NoIdentityPolicies: t.NoIdentityPolicies,
-		BoundCIDRs:         boundCIDRs,
-		NamespaceID:        t.NamespaceID,
-		CubbyholeID:        t.CubbyholeID,
-		Type:               logical.TokenType(t.Type),
-		ExternalID:         t.ExternalID,
+		ID:                         t.ID,
+		Accessor:                   t.Accessor,
+		Parent:                     t.Parent,
+		Policies:                   t.Policies,
+		InlinePolicy:               t.InlinePolicy,
+		Path:                       t.Path,
+		Meta:                       t.Meta,
+		InternalMeta:               t.InternalMeta,
+		DisplayName:                t.DisplayName,
+		NumUses:                    int(t.NumUses),
+		CreationTime:               t.CreationTime,
+		TTL:                        time.Duration(t.TTL),
+		ExplicitMaxTTL:             time.Duration(t.ExplicitMaxTTL),
+		Role:                       t.Role,
+		Period:                     time.Duration(t.Period),
+		EntityID:                   t.EntityID,
+		NoIdentityPolicies:         t.NoIdentityPolicies,
+		BoundCIDRs:                 boundCIDRs,
+		NamespaceID:                t.NamespaceID,
+		CubbyholeID:                t.CubbyholeID,
+		Type:                       logical.TokenType(t.Type),
+		ExternalID:                 t.ExternalID,
+		BoundCertificateThumbprint: t.BoundCertificateThumbprint,
 	}, nil
 }
 
@@ -419,10 +419,6 @@ func (d dynamicSystemView) VaultVersion(_ context.Context) (string, error) {
 }
 
 func (d dynamicSystemView) GeneratePasswordFromPolicy(ctx context.Context, policyName string) (password string, err error) {
-	if policyName == "" {
-		return "", fmt.Errorf("missing password policy name")
-	}
-
 	// Ensure there's a timeout on the context of some sort
 	if _, hasTimeout := ctx.Deadline(); !hasTimeout {
 		var cancel func()
@@ -430,23 +426,49 @@ func (d dynamicSystemView) GeneratePasswordFromPolicy(ctx context.Context, polic
 		defer cancel()
 	}
 
+	passPolicy, err := d.passwordPolicy(ctx, policyName)
+	if err != nil {
+		return "", err
+	}
+
+	return passPolicy.Generate(ctx, nil)
+}
+
+var _ logical.PasswordPolicyValidator = dynamicSystemView{}
+
+func (d dynamicSystemView) ValidatePasswordWithPolicy(ctx context.Context, policyName, password string) error {
+	passPolicy, err := d.passwordPolicy(ctx, policyName)
+	if err != nil {
+		return err
+	}
+
+	return passPolicy.Validate(password)
+}
+
+// passwordPolicy retrieves and parses a password policy in the namespace of
+// the mount.
+func (d dynamicSystemView) passwordPolicy(ctx context.Context, policyName string) (*random.StringGenerator, error) {
+	if policyName == "" {
+		return nil, fmt.Errorf("missing password policy name")
+	}
+
 	ctx = namespace.ContextWithNamespace(ctx, d.mountEntry.Namespace())
 
 	policyCfg, err := d.retrievePasswordPolicy(ctx, policyName)
 	if err != nil {
-		return "", fmt.Errorf("failed to retrieve password policy: %w", err)
+		return nil, fmt.Errorf("failed to retrieve password policy: %w", err)
 	}
 
 	if policyCfg == nil {
-		return "", fmt.Errorf("no password policy found")
+		return nil, fmt.Errorf("no password policy found")
 	}
 
 	passPolicy, err := random.ParsePolicy(policyCfg.HCLPolicy)
 	if err != nil {
-		return "", fmt.Errorf("stored password policy is invalid: %w", err)
+		return nil, fmt.Errorf("stored password policy is invalid: %w", err)
 	}
 
-	return passPolicy.Generate(ctx, nil)
+	return &passPolicy, nil
 }
 
 func (d dynamicSystemView) ClusterID(ctx context.Context) (string, error) {
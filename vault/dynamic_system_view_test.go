@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"reflect"
 	"sort"
+	"strings"
 	"testing"
 	"time"
 
@@ -286,6 +287,40 @@ func TestDynamicSystemView_GeneratePasswordFromPolicy_failed(t *testing.T) {
 	}
 }
 
+func TestDynamicSystemView_ValidatePasswordWithPolicy(t *testing.T) {
+	entry, err := logical.StorageEntryJSON(getPasswordPolicyKey(testPolicyName), &passwordPolicyConfig{
+		HCLPolicy: `
+length = 20
+rule "charset" {
+	charset = "abcdefghijklmnopqrstuvwxyz"
+}
+rule "charset" {
+	charset = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	min-chars = 1
+}`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	core := &Core{
+		systemBarrierView: NewBarrierView(fakeBarrier{getEntry: entry}, "sys/"),
+	}
+	dsv := TestDynamicSystemView(core, nil).(logical.PasswordPolicyValidator)
+
+	if err := dsv.ValidatePasswordWithPolicy(context.Background(), testPolicyName, "abcdefghijKLMNOPQRST0"); err != nil {
+		t.Fatalf("no error expected, got: %s", err)
+	}
+	err = dsv.ValidatePasswordWithPolicy(context.Background(), testPolicyName, "abcdefghijklmnopqrst")
+	if err == nil || !strings.Contains(err.Error(), `must contain at least 1 of the characters "ABCDEFGHIJKLMNOPQRSTUVWXYZ"`) {
+		t.Fatalf("expected the missing uppercase characters to be reported, got: %v", err)
+	}
+
+	core.systemBarrierView = NewBarrierView(fakeBarrier{}, "sys/")
+	if err := dsv.ValidatePasswordWithPolicy(context.Background(), testPolicyName, "abcdefghijKLMNOPQRST0"); err == nil {
+		t.Fatal("expected an error for a missing policy")
+	}
+}
+
 type runes []rune
 
 func (r runes) Len() int           { return len(r) }
@@ -8,7 +8,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"sync"
+	"time"
 
+	uuid "github.com/hashicorp/go-uuid"
 	"github.com/hashicorp/vault/helper/versions"
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/helper/consts"
@@ -43,6 +46,73 @@ type CubbyholeBackend struct {
 
 	saltUUID    string
 	storageView logical.Storage
+
+	// grantLock serializes updates to grants, so that read limits are
+	// enforced.
+	grantLock sync.Mutex
+}
+
+const (
+	// cubbyholeGrantsPrefix is the storage prefix of grants. It is outside
+	// of the per-token storage prefixes, which are salted token or cubbyhole
+	// IDs, so revoking a token does not remove grants to other tokens.
+	cubbyholeGrantsPrefix = "grants/"
+
+	// cubbyholeGrantIDPrefix stores grants by their ID.
+	cubbyholeGrantIDPrefix = cubbyholeGrantsPrefix + "id/"
+
+	// cubbyholeGrantOwnerPrefix indexes grant IDs by the storage key of the
+	// cubbyhole that owns them.
+	cubbyholeGrantOwnerPrefix = cubbyholeGrantsPrefix + "owner/"
+
+	// cubbyholeGrantDefaultTTL is the default lifetime of a grant.
+	cubbyholeGrantDefaultTTL = time.Hour
+)
+
+// cubbyholeGrant gives an entity read access to a path of another token's
+// cubbyhole until it expires or its read limit is reached.
+type cubbyholeGrant struct {
+	ID             string               `json:"id"`
+	OwnerKey       string               `json:"owner_key"`
+	OwnerEntityID  string               `json:"owner_entity_id"`
+	Path           string               `json:"path"`
+	EntityID       string               `json:"entity_id"`
+	CreationTime   time.Time            `json:"creation_time"`
+	ExpirationTime time.Time            `json:"expiration_time"`
+	MaxReads       int                  `json:"max_reads"`
+	Reads          []cubbyholeGrantRead `json:"reads"`
+}
+
+// cubbyholeGrantRead records a read of a grant, so the owner can audit the
+// use of the grant.
+type cubbyholeGrantRead struct {
+	Time      time.Time `json:"time"`
+	RequestID string    `json:"request_id"`
+}
+
+func (g *cubbyholeGrant) exhausted() bool {
+	return g.MaxReads > 0 && len(g.Reads) >= g.MaxReads
+}
+
+func (g *cubbyholeGrant) toResponseData() map[string]interface{} {
+	reads := make([]map[string]interface{}, 0, len(g.Reads))
+	for _, read := range g.Reads {
+		reads = append(reads, map[string]interface{}{
+			"time":       read.Time.Format(time.RFC3339Nano),
+			"request_id": read.RequestID,
+		})
+	}
+
+	return map[string]interface{}{
+		"grant_id":        g.ID,
+		"path":            g.Path,
+		"entity_id":       g.EntityID,
+		"owner_entity_id": g.OwnerEntityID,
+		"creation_time":   g.CreationTime.Format(time.RFC3339Nano),
+		"expiration_time": g.ExpirationTime.Format(time.RFC3339Nano),
+		"max_reads":       g.MaxReads,
+		"reads":           reads,
+	}
 }
 
 func (b *CubbyholeBackend) paths() []*framework.Path {
@@ -118,9 +188,266 @@ func (b *CubbyholeBackend) revoke(ctx context.Context, view *BarrierView, salted
 		return err
 	}
 
+	// Grants of the revoked cubbyhole no longer give access to anything
+	b.grantLock.Lock()
+	defer b.grantLock.Unlock()
+
+	grantIDs, err := view.List(ctx, cubbyholeGrantOwnerPrefix+saltedToken+"/")
+	if err != nil {
+		return err
+	}
+	for _, grantID := range grantIDs {
+		if err := b.deleteGrant(ctx, view, saltedToken, grantID); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
+// tidyGrants removes expired grants and grants whose cubbyhole no longer
+// belongs to a valid token.
+func (b *CubbyholeBackend) tidyGrants(ctx context.Context, view logical.Storage, validOwner func(string) bool) (int, error) {
+	b.grantLock.Lock()
+	defer b.grantLock.Unlock()
+
+	grantIDs, err := view.List(ctx, cubbyholeGrantIDPrefix)
+	if err != nil {
+		return 0, err
+	}
+
+	var deleted int
+	for _, grantID := range grantIDs {
+		grant, err := b.grant(ctx, view, grantID)
+		if err != nil {
+			return deleted, err
+		}
+		if grant == nil || (validOwner(grant.OwnerKey) && time.Now().Before(grant.ExpirationTime)) {
+			continue
+		}
+		if err := b.deleteGrant(ctx, view, grant.OwnerKey, grant.ID); err != nil {
+			return deleted, err
+		}
+		deleted++
+	}
+
+	return deleted, nil
+}
+
+func (b *CubbyholeBackend) grant(ctx context.Context, s logical.Storage, grantID string) (*cubbyholeGrant, error) {
+	entry, err := s.Get(ctx, cubbyholeGrantIDPrefix+grantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read grant: %w", err)
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var grant cubbyholeGrant
+	if err := entry.DecodeJSON(&grant); err != nil {
+		return nil, fmt.Errorf("failed to decode grant: %w", err)
+	}
+	return &grant, nil
+}
+
+func (b *CubbyholeBackend) putGrant(ctx context.Context, s logical.Storage, grant *cubbyholeGrant) error {
+	entry, err := logical.StorageEntryJSON(cubbyholeGrantIDPrefix+grant.ID, grant)
+	if err != nil {
+		return err
+	}
+	if err := s.Put(ctx, entry); err != nil {
+		return fmt.Errorf("failed to write grant: %w", err)
+	}
+	return nil
+}
+
+func (b *CubbyholeBackend) deleteGrant(ctx context.Context, s logical.Storage, ownerKey, grantID string) error {
+	if err := s.Delete(ctx, cubbyholeGrantIDPrefix+grantID); err != nil {
+		return fmt.Errorf("failed to delete grant: %w", err)
+	}
+	if err := s.Delete(ctx, cubbyholeGrantOwnerPrefix+ownerKey+"/"+grantID); err != nil {
+		return fmt.Errorf("failed to delete grant index: %w", err)
+	}
+	return nil
+}
+
+// ownedGrant returns the grant if it belongs to the cubbyhole with the given
+// storage key.
+func (b *CubbyholeBackend) ownedGrant(ctx context.Context, s logical.Storage, ownerKey, grantID string) (*cubbyholeGrant, error) {
+	grant, err := b.grant(ctx, s, grantID)
+	if err != nil {
+		return nil, err
+	}
+	if grant == nil || grant.OwnerKey != ownerKey {
+		return nil, nil
+	}
+	return grant, nil
+}
+
+// createGrant creates a grant to a path of the cubbyhole with the given
+// storage key. It is served by the system backend, so grants do not take up
+// paths that tokens can store secrets at.
+func (b *CubbyholeBackend) createGrant(ctx context.Context, s logical.Storage, ownerKey, ownerEntityID string, data *framework.FieldData) (*logical.Response, error) {
+	path := strings.Trim(data.Get("path").(string), "/")
+	if path == "" {
+		return logical.ErrorResponse("missing path"), nil
+	}
+
+	entityID := data.Get("entity_id").(string)
+	if entityID == "" {
+		return logical.ErrorResponse("missing entity_id"), nil
+	}
+	entity, err := b.System().EntityInfo(entityID)
+	if err != nil {
+		return nil, err
+	}
+	if entity == nil {
+		return logical.ErrorResponse("entity %q not found", entityID), nil
+	}
+
+	ttl := time.Duration(data.Get("ttl").(int)) * time.Second
+	if ttl <= 0 {
+		return logical.ErrorResponse("ttl must be greater than zero"), nil
+	}
+	if maxTTL := b.System().MaxLeaseTTL(); maxTTL > 0 && ttl > maxTTL {
+		return logical.ErrorResponse("ttl cannot be greater than the maximum lease TTL of %s", maxTTL), nil
+	}
+
+	maxReads := data.Get("max_reads").(int)
+	if maxReads < 0 {
+		return logical.ErrorResponse("max_reads cannot be negative"), nil
+	}
+
+	grantID, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	grant := &cubbyholeGrant{
+		ID:             grantID,
+		OwnerKey:       ownerKey,
+		OwnerEntityID:  ownerEntityID,
+		Path:           path,
+		EntityID:       entityID,
+		CreationTime:   now,
+		ExpirationTime: now.Add(ttl),
+		MaxReads:       maxReads,
+	}
+
+	b.grantLock.Lock()
+	defer b.grantLock.Unlock()
+
+	if err := s.Put(ctx, &logical.StorageEntry{
+		Key: cubbyholeGrantOwnerPrefix + ownerKey + "/" + grantID,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to write grant index: %w", err)
+	}
+	if err := b.putGrant(ctx, s, grant); err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: grant.toResponseData(),
+	}, nil
+}
+
+// listGrants lists the IDs of the grants of the cubbyhole with the given
+// storage key.
+func (b *CubbyholeBackend) listGrants(ctx context.Context, s logical.Storage, ownerKey string) (*logical.Response, error) {
+	grantIDs, err := s.List(ctx, cubbyholeGrantOwnerPrefix+ownerKey+"/")
+	if err != nil {
+		return nil, err
+	}
+
+	return logical.ListResponse(grantIDs), nil
+}
+
+// readGrant returns a grant of the cubbyhole with the given storage key,
+// including its reads.
+func (b *CubbyholeBackend) readGrant(ctx context.Context, s logical.Storage, ownerKey, grantID string) (*logical.Response, error) {
+	grant, err := b.ownedGrant(ctx, s, ownerKey, grantID)
+	if err != nil {
+		return nil, err
+	}
+	if grant == nil {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: grant.toResponseData(),
+	}, nil
+}
+
+// revokeGrant deletes a grant of the cubbyhole with the given storage key.
+func (b *CubbyholeBackend) revokeGrant(ctx context.Context, s logical.Storage, ownerKey, grantID string) error {
+	b.grantLock.Lock()
+	defer b.grantLock.Unlock()
+
+	grant, err := b.ownedGrant(ctx, s, ownerKey, grantID)
+	if err != nil {
+		return err
+	}
+	if grant == nil {
+		return nil
+	}
+
+	return b.deleteGrant(ctx, s, grant.OwnerKey, grant.ID)
+}
+
+// readShared reads the path shared by a grant on behalf of the entity of the
+// grant, and records the read on the grant.
+func (b *CubbyholeBackend) readShared(ctx context.Context, s logical.Storage, entityID, requestID, grantID string) (*logical.Response, error) {
+	b.grantLock.Lock()
+	defer b.grantLock.Unlock()
+
+	grant, err := b.grant(ctx, s, grantID)
+	if err != nil {
+		return nil, err
+	}
+	if grant == nil {
+		return nil, nil
+	}
+	if grant.EntityID != entityID {
+		return nil, logical.ErrPermissionDenied
+	}
+	if !time.Now().Before(grant.ExpirationTime) || grant.exhausted() {
+		return nil, b.deleteGrant(ctx, s, grant.OwnerKey, grant.ID)
+	}
+
+	out, err := s.Get(ctx, grant.OwnerKey+"/"+grant.Path)
+	if err != nil {
+		return nil, fmt.Errorf("read failed: %w", err)
+	}
+	if out == nil {
+		return nil, nil
+	}
+
+	var rawData map[string]interface{}
+	if err := jsonutil.DecodeJSON(out.Value, &rawData); err != nil {
+		return nil, fmt.Errorf("json decoding failed: %w", err)
+	}
+
+	// Record the read on the grant, so the owner can audit it, and remove
+	// the grant once its read limit is reached.
+	grant.Reads = append(grant.Reads, cubbyholeGrantRead{
+		Time:      time.Now(),
+		RequestID: requestID,
+	})
+	if grant.exhausted() {
+		err = b.deleteGrant(ctx, s, grant.OwnerKey, grant.ID)
+	} else {
+		err = b.putGrant(ctx, s, grant)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: rawData,
+	}, nil
+}
+
 func (b *CubbyholeBackend) handleExistenceCheck(ctx context.Context, req *logical.Request, data *framework.FieldData) (bool, error) {
 	out, err := req.Storage.Get(ctx, req.ClientToken+"/"+req.Path)
 	if err != nil {
@@ -258,6 +585,9 @@ sharing possible (per-token cubbyholes). This can be useful for implementing
 certain authentication workflows, as well as "scratch" areas for individual
 clients. When the token is revoked, the entire set of stored values for that
 token is also removed.
+
+A token can grant another entity read access to a path of its cubbyhole for
+a limited time or number of reads through the "sys/cubbyhole/grant" path.
 `
 
 const cubbyholeHelpSynopsis = `
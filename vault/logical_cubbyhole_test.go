@@ -5,12 +5,15 @@ package vault
 
 import (
 	"context"
+	"errors"
 	"reflect"
 	"sort"
 	"testing"
 	"time"
 
 	uuid "github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/logical"
 )
 
@@ -268,3 +271,282 @@ func testCubbyholeBackend() logical.Backend {
 	})
 	return b
 }
+
+func TestCubbyholeBackend_Grants(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+	ctx := namespace.RootContext(nil)
+
+	request := func(op logical.Operation, path, clientToken string, data map[string]interface{}) (*logical.Response, error) {
+		t.Helper()
+		req := logical.TestRequest(t, op, path)
+		req.ClientToken = clientToken
+		req.Data = data
+		return c.HandleRequest(ctx, req)
+	}
+	createEntity := func(name string) string {
+		t.Helper()
+		resp, err := request(logical.UpdateOperation, "identity/entity", root, map[string]interface{}{"name": name})
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+		}
+		return resp.Data["id"].(string)
+	}
+
+	grantee := createEntity("grantee")
+	testMakeTokenDirectly(t, c.tokenStore, &logical.TokenEntry{
+		ID:       "grantee-token",
+		TTL:      time.Hour,
+		Policies: []string{"default"},
+		EntityID: grantee,
+	})
+	testMakeTokenDirectly(t, c.tokenStore, &logical.TokenEntry{
+		ID:       "other-token",
+		TTL:      time.Hour,
+		Policies: []string{"default"},
+		EntityID: createEntity("other"),
+	})
+	testMakeServiceTokenViaCore(t, c, root, "owner", "1h", []string{"default"})
+	testMakeServiceTokenViaCore(t, c, root, "no-entity", "1h", []string{"default"})
+
+	resp, err := request(logical.UpdateOperation, "cubbyhole/drop/box", "owner", map[string]interface{}{"secret": "s3cr3t"})
+	if err != nil || resp != nil {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+
+	// Grants do not take up paths of the cubbyhole
+	resp, err = request(logical.UpdateOperation, "cubbyhole/sys/grant", "owner", map[string]interface{}{"secret": "mine"})
+	if err != nil || resp != nil {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	resp, err = request(logical.ReadOperation, "cubbyhole/sys/grant", "owner", nil)
+	if err != nil || resp == nil || resp.Data["secret"] != "mine" {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+
+	for _, data := range []map[string]interface{}{
+		{"entity_id": grantee},
+		{"path": "drop/box"},
+		{"path": "drop/box", "entity_id": "missing"},
+		{"path": "drop/box", "entity_id": grantee, "ttl": "100000h"},
+		{"path": "drop/box", "entity_id": grantee, "max_reads": -1},
+	} {
+		resp, err = request(logical.UpdateOperation, "sys/cubbyhole/grant", "owner", data)
+		if resp == nil || !resp.IsError() {
+			t.Fatalf("expected error for %v: resp: %#v, err: %v", data, resp, err)
+		}
+	}
+
+	resp, err = request(logical.UpdateOperation, "sys/cubbyhole/grant", "owner", map[string]interface{}{
+		"path":      "drop/box",
+		"entity_id": grantee,
+		"ttl":       "10m",
+		"max_reads": 2,
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	grantID := resp.Data["grant_id"].(string)
+	if resp.Data["entity_id"] != grantee {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// The grant is not visible in the owner's cubbyhole listing, and grants
+	// of other tokens are not visible to the owner.
+	resp, err = request(logical.ListOperation, "cubbyhole/", "owner", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(resp.Data["keys"], []string{"drop/", "sys/"}) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	resp, err = request(logical.ListOperation, "sys/cubbyhole/grants/", "owner", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(resp.Data["keys"], []string{grantID}) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	resp, err = request(logical.ReadOperation, "sys/cubbyhole/grants/"+grantID, "no-entity", nil)
+	if err != nil || resp != nil {
+		t.Fatalf("expected no grant for other token: resp: %#v, err: %v", resp, err)
+	}
+
+	// Only the grantee entity can read the shared path
+	if _, err = request(logical.ReadOperation, "sys/cubbyhole/shared/"+grantID, "no-entity", nil); !errors.Is(err, logical.ErrPermissionDenied) {
+		t.Fatalf("expected permission denied without entity, got %v", err)
+	}
+	if _, err = request(logical.ReadOperation, "sys/cubbyhole/shared/"+grantID, "other-token", nil); !errors.Is(err, logical.ErrPermissionDenied) {
+		t.Fatalf("expected permission denied for other entity, got %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		resp, err = request(logical.ReadOperation, "sys/cubbyhole/shared/"+grantID, "grantee-token", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp == nil || resp.Data["secret"] != "s3cr3t" {
+			t.Fatalf("bad: %#v", resp)
+		}
+
+		if i == 0 {
+			resp, err = request(logical.ReadOperation, "sys/cubbyhole/grants/"+grantID, "owner", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if reads := resp.Data["reads"].([]map[string]interface{}); len(reads) != 1 {
+				t.Fatalf("expected the read to be recorded, got %#v", reads)
+			}
+		}
+	}
+
+	// The read limit was reached, so the grant is gone
+	resp, err = request(logical.ReadOperation, "sys/cubbyhole/shared/"+grantID, "grantee-token", nil)
+	if err != nil || resp != nil {
+		t.Fatalf("expected no data: resp: %#v, err: %v", resp, err)
+	}
+	resp, err = request(logical.ListOperation, "sys/cubbyhole/grants/", "owner", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Data) != 0 {
+		t.Fatalf("expected no grants, got %#v", resp.Data)
+	}
+
+	cb, storage, err := c.systemBackend.cubbyholeGrantStorage(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Owners can revoke grants
+	resp, err = request(logical.UpdateOperation, "sys/cubbyhole/grant", "owner", map[string]interface{}{
+		"path":      "drop/box",
+		"entity_id": grantee,
+	})
+	if err != nil || resp.IsError() {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	grantID = resp.Data["grant_id"].(string)
+	if _, err = request(logical.DeleteOperation, "sys/cubbyhole/grants/"+grantID, "no-entity", nil); err != nil {
+		t.Fatal(err)
+	}
+	if grant, _ := cb.grant(ctx, storage, grantID); grant == nil {
+		t.Fatal("expected grant to survive deletion by another token")
+	}
+	if _, err = request(logical.DeleteOperation, "sys/cubbyhole/grants/"+grantID, "owner", nil); err != nil {
+		t.Fatal(err)
+	}
+	resp, err = request(logical.ReadOperation, "sys/cubbyhole/shared/"+grantID, "grantee-token", nil)
+	if err != nil || resp != nil {
+		t.Fatalf("expected no data: resp: %#v, err: %v", resp, err)
+	}
+
+	// Expired grants can not be read
+	resp, err = request(logical.UpdateOperation, "sys/cubbyhole/grant", "owner", map[string]interface{}{
+		"path":      "drop/box",
+		"entity_id": grantee,
+	})
+	if err != nil || resp.IsError() {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	grantID = resp.Data["grant_id"].(string)
+	grant, err := cb.grant(ctx, storage, grantID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	grant.ExpirationTime = time.Now().Add(-time.Second)
+	if err := cb.putGrant(ctx, storage, grant); err != nil {
+		t.Fatal(err)
+	}
+	resp, err = request(logical.ReadOperation, "sys/cubbyhole/shared/"+grantID, "grantee-token", nil)
+	if err != nil || resp != nil {
+		t.Fatalf("expected no data: resp: %#v, err: %v", resp, err)
+	}
+	if grant, _ := cb.grant(ctx, storage, grantID); grant != nil {
+		t.Fatal("expected expired grant to be removed")
+	}
+
+	// Revoking the owner removes its grants
+	resp, err = request(logical.UpdateOperation, "sys/cubbyhole/grant", "owner", map[string]interface{}{
+		"path":      "drop/box",
+		"entity_id": grantee,
+	})
+	if err != nil || resp.IsError() {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	grantID = resp.Data["grant_id"].(string)
+	if _, err = request(logical.UpdateOperation, "auth/token/revoke-self", "owner", nil); err != nil {
+		t.Fatal(err)
+	}
+	if grant, _ := cb.grant(ctx, storage, grantID); grant != nil {
+		t.Fatal("expected grant of revoked token to be removed")
+	}
+}
+
+func TestCubbyholeBackend_GrantsRevokeAndTidy(t *testing.T) {
+	ctx := context.Background()
+	view := NewBarrierView(&logical.InmemStorage{}, "")
+	b, err := CubbyholeBackendFactory(ctx, &logical.BackendConfig{
+		System: logical.StaticSystemView{
+			MaxLeaseTTLVal: time.Hour * 24,
+			EntityVal:      &logical.Entity{ID: "grantee"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cb := b.(*CubbyholeBackend)
+
+	createGrant := func(owner string) string {
+		t.Helper()
+		resp, err := cb.createGrant(ctx, view, owner, "", &framework.FieldData{
+			Raw: map[string]interface{}{
+				"path":      "foo",
+				"entity_id": "grantee",
+			},
+			Schema: (&SystemBackend{}).cubbyholeGrantPaths()[0].Fields,
+		})
+		if err != nil || resp.IsError() {
+			t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+		}
+		return resp.Data["grant_id"].(string)
+	}
+
+	revoked := createGrant("revoked")
+	orphaned := createGrant("orphaned")
+	expired := createGrant("valid")
+	valid := createGrant("valid")
+
+	if err := cb.revoke(ctx, view, "revoked"); err != nil {
+		t.Fatal(err)
+	}
+	if grant, _ := cb.grant(ctx, view, revoked); grant != nil {
+		t.Fatal("expected grant of revoked cubbyhole to be removed")
+	}
+
+	grant, err := cb.grant(ctx, view, expired)
+	if err != nil {
+		t.Fatal(err)
+	}
+	grant.ExpirationTime = time.Now().Add(-time.Second)
+	if err := cb.putGrant(ctx, view, grant); err != nil {
+		t.Fatal(err)
+	}
+
+	deleted, err := cb.tidyGrants(ctx, view, func(key string) bool {
+		return key == "valid"
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != 2 {
+		t.Fatalf("expected 2 grants to be tidied, got %d", deleted)
+	}
+	for _, grantID := range []string{orphaned, expired} {
+		if grant, _ := cb.grant(ctx, view, grantID); grant != nil {
+			t.Fatalf("expected grant %s to be tidied", grantID)
+		}
+	}
+	if grant, _ := cb.grant(ctx, view, valid); grant == nil {
+		t.Fatal("expected valid grant to remain")
+	}
+}
@@ -5,13 +5,18 @@ package vault
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"sort"
 	"strings"
 
 	"github.com/hashicorp/go-secure-stdlib/parseutil"
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/helper/jsonutil"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
 	"github.com/hashicorp/vault/sdk/helper/wrapping"
 	"github.com/hashicorp/vault/sdk/logical"
 )
@@ -35,7 +40,9 @@ type revokeFunc func(ctx context.Context, req *logical.Request, data *framework.
 // LeaseSwitchedPassthroughBackend returns a PassthroughBackend
 // with leases switched on or off
 func LeaseSwitchedPassthroughBackend(ctx context.Context, conf *logical.BackendConfig, revoke revokeFunc) (logical.Backend, error) {
-	var b PassthroughBackend
+	b := PassthroughBackend{
+		locks: locksutil.CreateLocks(),
+	}
 	if revoke == nil {
 		// We probably don't need this, since we should never have to handle revoke requests, but just in case...
 		b.revoke = func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
@@ -87,6 +94,21 @@ func LeaseSwitchedPassthroughBackend(ctx context.Context, conf *logical.BackendC
 	if conf == nil {
 		return nil, fmt.Errorf("configuration passed into backend is nil")
 	}
+
+	if raw := conf.Config[passthroughConditionalWritesOption]; raw != "" {
+		conditionalWrites, err := parseutil.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %q option: %w", passthroughConditionalWritesOption, err)
+		}
+		b.conditionalWrites = conditionalWrites
+	}
+
+	schemas, err := parsePassthroughSchemas(conf.Config)
+	if err != nil {
+		return nil, err
+	}
+	b.schemas = schemas
+
 	b.Backend.Setup(ctx, conf)
 
 	return &b, nil
@@ -100,6 +122,167 @@ type PassthroughBackend struct {
 	*framework.Backend
 	generateLeases bool
 	revoke         func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error)
+
+	// conditionalWrites enables versions and conditional writes and deletes
+	// through the If-Match and If-None-Match request headers.
+	conditionalWrites bool
+
+	// locks serialize conditional writes and deletes to a key so a version
+	// check and the storage operation that follows it happen atomically.
+	locks []*locksutil.LockEntry
+
+	// schemas are the JSON Schemas configured through the mount options,
+	// ordered from the most specific path prefix to the least specific.
+	schemas []*passthroughSchema
+}
+
+const (
+	// passthroughConditionalWritesOption is the mount option that enables
+	// conditional writes and deletes.
+	passthroughConditionalWritesOption = "conditional_writes"
+
+	passthroughETagHeader        = "ETag"
+	passthroughIfMatchHeader     = "If-Match"
+	passthroughIfNoneMatchHeader = "If-None-Match"
+)
+
+// isPassthroughOnlyOption reports whether the mount option configures a
+// feature that only the builtin passthrough backend implements.
+func isPassthroughOnlyOption(key string) bool {
+	return key == passthroughConditionalWritesOption || key == passthroughResolveReferencesOption || isPassthroughSchemaOption(key)
+}
+
+// checkPassthroughOptions rejects mount options that only the builtin
+// passthrough backend implements when the mount is served by another
+// backend, such as the KV plugin, which would silently ignore them. Empty
+// values are allowed, since tuning an option to an empty value removes it.
+func checkPassthroughOptions(options map[string]string, backend logical.Backend) error {
+	if _, ok := backend.(*PassthroughBackend); ok {
+		return nil
+	}
+
+	keys := make([]string, 0, len(options))
+	for key := range options {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if options[key] != "" && isPassthroughOnlyOption(key) {
+			return fmt.Errorf("option %q is only supported by the builtin passthrough kv backend", key)
+		}
+	}
+	return nil
+}
+
+// passthroughOptionHeaders returns the request and response headers the
+// passthrough backend needs for the features enabled by the mount options.
+// They are passed through on the mount regardless of its
+// passthrough_request_headers and allowed_response_headers, since without
+// them a conditional write would silently become an unconditional one.
+func passthroughOptionHeaders(options map[string]string) ([]string, []string) {
+	if conditionalWrites, _ := parseutil.ParseBool(options[passthroughConditionalWritesOption]); !conditionalWrites {
+		return nil, nil
+	}
+	return []string{passthroughIfMatchHeader, passthroughIfNoneMatchHeader}, []string{passthroughETagHeader}
+}
+
+// passthroughVersion returns the version of a stored entry, which is the
+// hex-encoded SHA-256 digest of its stored value. Entries written before
+// versions were enabled get one without having to be rewritten. A missing
+// entry has an empty version.
+func passthroughVersion(entry *logical.StorageEntry) string {
+	if entry == nil {
+		return ""
+	}
+	sum := sha256.Sum256(entry.Value)
+	return hex.EncodeToString(sum[:])
+}
+
+// versionHeaders returns the response headers advertising the version of
+// an entry.
+func versionHeaders(version string) map[string][]string {
+	return map[string][]string{
+		passthroughETagHeader: {`"` + version + `"`},
+	}
+}
+
+// entityTags returns the entity tags given in a request header, with quotes
+// and weak validator prefixes removed.
+func entityTags(headers map[string][]string, name string) []string {
+	var values []string
+	for key, raw := range headers {
+		if !strings.EqualFold(key, name) {
+			continue
+		}
+		for _, header := range raw {
+			for _, tag := range strings.Split(header, ",") {
+				tag = strings.TrimSpace(tag)
+				tag = strings.TrimPrefix(tag, "W/")
+				tag = strings.Trim(tag, `"`)
+				if tag != "" {
+					values = append(values, tag)
+				}
+			}
+		}
+	}
+	return values
+}
+
+// checkVersion verifies the If-Match and If-None-Match headers of a request
+// against the current entry at the request path. An If-Match of "*" matches
+// any existing key, and an If-None-Match of "*" only matches a key that does
+// not exist yet. Requests without either always match.
+func checkVersion(req *logical.Request, current *logical.StorageEntry) bool {
+	version := passthroughVersion(current)
+
+	for _, tag := range entityTags(req.Headers, passthroughIfNoneMatchHeader) {
+		if current != nil && (tag == "*" || tag == version) {
+			return false
+		}
+	}
+
+	if tags := entityTags(req.Headers, passthroughIfMatchHeader); len(tags) > 0 {
+		if current == nil {
+			return false
+		}
+		for _, tag := range tags {
+			if tag == "*" || tag == version {
+				return true
+			}
+		}
+		return false
+	}
+
+	return true
+}
+
+// versionMismatchResponse is returned when the precondition of a write or
+// delete does not hold. The current version is not included, since the
+// caller may not be allowed to read the key.
+func versionMismatchResponse(req *logical.Request) (*logical.Response, error) {
+	resp := logical.ErrorResponse("precondition did not match the current version")
+	return logical.RespondWithStatusCode(resp, req, http.StatusPreconditionFailed)
+}
+
+// checkPrecondition checks the version a conditional write or delete
+// expects the key to be at. It reports whether the request was
+// conditional, and returns a response if the request must not proceed. The
+// caller must hold the lock for the key.
+func (b *PassthroughBackend) checkPrecondition(ctx context.Context, req *logical.Request) (bool, *logical.Response, error) {
+	if len(entityTags(req.Headers, passthroughIfMatchHeader)) == 0 && len(entityTags(req.Headers, passthroughIfNoneMatchHeader)) == 0 {
+		return false, nil, nil
+	}
+
+	current, err := req.Storage.Get(ctx, req.Path)
+	if err != nil {
+		return true, nil, fmt.Errorf("read failed: %w", err)
+	}
+	if !checkVersion(req, current) {
+		resp, err := versionMismatchResponse(req)
+		return true, resp, err
+	}
+
+	return true, nil, nil
 }
 
 func (b *PassthroughBackend) handleRevoke(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
@@ -174,6 +357,9 @@ func (b *PassthroughBackend) handleRead(ctx context.Context, req *logical.Reques
 	}
 
 	resp.Secret.TTL = ttlDuration
+	if b.conditionalWrites {
+		resp.Headers = versionHeaders(passthroughVersion(out))
+	}
 
 	return resp, nil
 }
@@ -198,6 +384,30 @@ func (b *PassthroughBackend) handleWrite(ctx context.Context, req *logical.Reque
 		return nil, fmt.Errorf("json encoding failed: %w", err)
 	}
 
+	// Reject secrets that don't match the schema configured for the path
+	if schema := schemaForPath(b.schemas, req.Path); schema != nil {
+		errs, err := schema.validate(buf)
+		if err != nil {
+			return nil, err
+		}
+		if len(errs) > 0 {
+			return logical.ErrorResponse("secret does not match the schema for path prefix %q: %s", schema.prefix, strings.Join(errs, "; ")), nil
+		}
+	}
+
+	var conditional bool
+	if b.conditionalWrites {
+		lock := locksutil.LockForKey(b.locks, req.Path)
+		lock.Lock()
+		defer lock.Unlock()
+
+		var resp *logical.Response
+		conditional, resp, err = b.checkPrecondition(ctx, req)
+		if resp != nil || err != nil {
+			return resp, err
+		}
+	}
+
 	// Write out a new key
 	entry := &logical.StorageEntry{
 		Key:   req.Path,
@@ -207,10 +417,32 @@ func (b *PassthroughBackend) handleWrite(ctx context.Context, req *logical.Reque
 		return nil, fmt.Errorf("failed to write: %w", err)
 	}
 
-	return nil, nil
+	// Unconditional writes keep their empty response for compatibility
+	if !conditional {
+		return nil, nil
+	}
+
+	version := passthroughVersion(entry)
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"version": version,
+		},
+		Headers: versionHeaders(version),
+	}, nil
 }
 
 func (b *PassthroughBackend) handleDelete(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	if b.conditionalWrites {
+		lock := locksutil.LockForKey(b.locks, req.Path)
+		lock.Lock()
+		defer lock.Unlock()
+
+		_, resp, err := b.checkPrecondition(ctx, req)
+		if resp != nil || err != nil {
+			return resp, err
+		}
+	}
+
 	// Delete the key at the request path
 	if err := req.Storage.Delete(ctx, req.Path); err != nil {
 		return nil, err
@@ -264,4 +496,18 @@ can be used as a hint from the writer of a secret to the consumer of a secret
 that the consumer should re-read the value before the TTL has expired.
 However, any revocation must be handled by the user of this backend; the lease
 duration does not affect the provided data in any way.
+
+If the "conditional_writes" mount option is true, every secret has a version
+derived from its contents, returned in the ETag response header. Writes and
+deletes can be made conditional on the version with the If-Match request
+header, and an If-None-Match header of "*" only allows writing a secret that
+does not exist yet.
+
+A JSON Schema can be configured through the "schema" mount option, or the
+"schema:<prefix>" option for secrets below a path prefix. Writes are rejected
+unless the secret matches the schema with the longest matching prefix.
+
+If the "resolve_references" mount option is true, references such as
+{{vault "database/creds/app" "password"}} in a secret are replaced on read by
+the field of the secret at the referenced path, read with the caller's token.
 `
@@ -0,0 +1,220 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/textproto"
+	"regexp"
+	"sort"
+
+	"github.com/hashicorp/go-secure-stdlib/parseutil"
+	"github.com/hashicorp/vault/sdk/helper/consts"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// passthroughResolveReferencesOption is the mount option that enables the
+// resolution of secret references in reads from a passthrough mount.
+const passthroughResolveReferencesOption = "resolve_references"
+
+// passthroughReferenceRegex matches a reference to a field of the secret at
+// another path, e.g. {{vault "database/creds/app" "password"}}.
+var passthroughReferenceRegex = regexp.MustCompile(`\{\{\s*vault\s+"([^"]+)"\s+"([^"]+)"\s*\}\}`)
+
+// passthroughReferenceCtxKey marks the context of the reads issued to
+// resolve references, so references in the secrets they return are not
+// resolved in turn.
+type passthroughReferenceCtxKey struct{}
+
+// shouldResolvePassthroughReferences reports whether the response to a read
+// from a passthrough mount needs its references resolved.
+func (c *Core) shouldResolvePassthroughReferences(ctx context.Context, req *logical.Request, resp *logical.Response) bool {
+	if req.Operation != logical.ReadOperation || resp == nil || resp.IsError() || len(resp.Data) == 0 {
+		return false
+	}
+	if resolving, _ := ctx.Value(passthroughReferenceCtxKey{}).(bool); resolving {
+		return false
+	}
+
+	if _, ok := c.router.MatchingBackend(ctx, req.Path).(*PassthroughBackend); !ok {
+		return false
+	}
+	entry := c.router.MatchingMountEntry(ctx, req.Path)
+	if entry == nil {
+		return false
+	}
+	enabled, err := parseutil.ParseBool(entry.Options[passthroughResolveReferencesOption])
+	return err == nil && enabled
+}
+
+// resolvePassthroughReferences replaces the references in the data of a
+// passthrough read with the fields they point to. Each referenced path is
+// read once with the token of the original request, so ACLs apply as if the
+// caller had read it, and fields of the same dynamic secret stay consistent.
+func (c *Core) resolvePassthroughReferences(ctx context.Context, req *logical.Request, resp *logical.Response) error {
+	r := &passthroughReferenceResolver{
+		core:      c,
+		ctx:       context.WithValue(ctx, passthroughReferenceCtxKey{}, true),
+		req:       req,
+		responses: make(map[string]*logical.Response),
+	}
+
+	for k, v := range resp.Data {
+		resolved, err := r.resolve(v)
+		if err != nil {
+			return err
+		}
+		resp.Data[k] = resolved
+	}
+
+	// Advise the caller to re-read the secret before any of the referenced
+	// leases expire
+	paths := make([]string, 0, len(r.responses))
+	for path := range r.responses {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		refResp := r.responses[path]
+		if refResp.Secret == nil || refResp.Secret.LeaseID == "" {
+			continue
+		}
+		resp.AddWarning(fmt.Sprintf("reference to %q created lease %q", path, refResp.Secret.LeaseID))
+		if resp.Secret != nil && refResp.Secret.TTL > 0 && (resp.Secret.TTL == 0 || refResp.Secret.TTL < resp.Secret.TTL) {
+			resp.Secret.TTL = refResp.Secret.TTL
+		}
+	}
+
+	return nil
+}
+
+type passthroughReferenceResolver struct {
+	core      *Core
+	ctx       context.Context
+	req       *logical.Request
+	responses map[string]*logical.Response
+}
+
+// resolve walks a decoded secret value and resolves the references in the
+// strings it contains.
+func (r *passthroughReferenceResolver) resolve(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		return r.resolveString(v)
+	case map[string]interface{}:
+		for k, elem := range v {
+			resolved, err := r.resolve(elem)
+			if err != nil {
+				return nil, err
+			}
+			v[k] = resolved
+		}
+		return v, nil
+	case []interface{}:
+		for i, elem := range v {
+			resolved, err := r.resolve(elem)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = resolved
+		}
+		return v, nil
+	default:
+		return value, nil
+	}
+}
+
+// resolveString resolves the references in a string. A string consisting
+// of a single reference is replaced by the referenced value as is, so
+// non-string fields keep their type. References embedded in a larger
+// string are replaced by their string or JSON representation.
+func (r *passthroughReferenceResolver) resolveString(s string) (interface{}, error) {
+	matches := passthroughReferenceRegex.FindAllStringSubmatchIndex(s, -1)
+	if len(matches) == 0 {
+		return s, nil
+	}
+
+	if len(matches) == 1 && matches[0][0] == 0 && matches[0][1] == len(s) {
+		return r.field(s[matches[0][2]:matches[0][3]], s[matches[0][4]:matches[0][5]])
+	}
+
+	var out []byte
+	last := 0
+	for _, m := range matches {
+		value, err := r.field(s[m[2]:m[3]], s[m[4]:m[5]])
+		if err != nil {
+			return nil, err
+		}
+
+		out = append(out, s[last:m[0]]...)
+		switch v := value.(type) {
+		case string:
+			out = append(out, v...)
+		default:
+			encoded, err := json.Marshal(v)
+			if err != nil {
+				return nil, fmt.Errorf("unable to encode field %q of %q: %w", s[m[4]:m[5]], s[m[2]:m[3]], err)
+			}
+			out = append(out, encoded...)
+		}
+		last = m[1]
+	}
+	out = append(out, s[last:]...)
+
+	return string(out), nil
+}
+
+// field returns a field of the secret at the given path.
+func (r *passthroughReferenceResolver) field(path, field string) (interface{}, error) {
+	resp, err := r.read(path)
+	if err != nil {
+		return nil, err
+	}
+	value, ok := resp.Data[field]
+	if !ok {
+		return nil, fmt.Errorf("unable to resolve reference to %q: field %q not found", path, field)
+	}
+	return value, nil
+}
+
+// read reads the secret at the given path on behalf of the caller. Reads
+// are cached, so every reference to a path sees the same secret.
+func (r *passthroughReferenceResolver) read(path string) (*logical.Response, error) {
+	if resp, ok := r.responses[path]; ok {
+		return resp, nil
+	}
+
+	refReq, err := r.req.Clone()
+	if err != nil {
+		return nil, err
+	}
+	refReq.Path = path
+	refReq.Operation = logical.ReadOperation
+	refReq.Data = nil
+
+	// The referenced secret is embedded in the original response, so it
+	// must not be wrapped on its own
+	delete(refReq.Headers, textproto.CanonicalMIMEHeaderKey(consts.WrapTTLHeaderName))
+	refReq.WrapInfo = nil
+
+	resp, err := r.core.handleCancelableRequest(r.ctx, refReq)
+	if err != nil {
+		return nil, fmt.Errorf("unable to resolve reference to %q: %w", path, err)
+	}
+	switch {
+	case resp == nil:
+		return nil, fmt.Errorf("unable to resolve reference to %q: %w", path, logical.ErrNotFound)
+	case resp.IsError():
+		return nil, fmt.Errorf("unable to resolve reference to %q: %w", path, resp.Error())
+	case resp.WrapInfo != nil && resp.WrapInfo.Token != "":
+		return nil, fmt.Errorf("unable to resolve reference to %q: response was wrapped", path)
+	case resp.Data == nil:
+		return nil, fmt.Errorf("unable to resolve reference to %q: %w", path, logical.ErrNotFound)
+	}
+
+	r.responses[path] = resp
+	return resp, nil
+}
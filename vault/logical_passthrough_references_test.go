@@ -0,0 +1,130 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package vault
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPassthroughReferences(t *testing.T) {
+	c, _, root := TestCoreUnsealedWithConfig(t, &CoreConfig{
+		LogicalBackends: map[string]logical.Factory{
+			"kv": PassthroughBackendFactory,
+		},
+	})
+	ctx := namespace.RootContext(nil)
+
+	request := func(token string, op logical.Operation, path string, data map[string]interface{}) (*logical.Response, error) {
+		t.Helper()
+		req := &logical.Request{
+			Operation:   op,
+			Path:        path,
+			Data:        data,
+			ClientToken: token,
+		}
+		return c.HandleRequest(ctx, req)
+	}
+	mustRequest := func(token string, op logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		t.Helper()
+		resp, err := request(token, op, path, data)
+		require.NoError(t, err)
+		require.False(t, resp.IsError(), "unexpected error: %v", resp.Error())
+		return resp
+	}
+
+	mustRequest(root, logical.UpdateOperation, "sys/mounts/refs", map[string]interface{}{
+		"type":    "kv",
+		"options": map[string]string{"resolve_references": "true"},
+	})
+	mustRequest(root, logical.UpdateOperation, "sys/mounts/plain", map[string]interface{}{
+		"type": "kv",
+	})
+
+	mustRequest(root, logical.UpdateOperation, "secret/db", map[string]interface{}{
+		"username": "app",
+		"password": "correct-horse",
+		"port":     5432,
+	})
+	document := map[string]interface{}{
+		"dsn":    `postgres://{{vault "secret/db" "username"}}:{{ vault "secret/db" "password" }}@db:{{vault "secret/db" "port"}}`,
+		"port":   `{{vault "secret/db" "port"}}`,
+		"nested": map[string]interface{}{"passwords": []interface{}{`{{vault "secret/db" "password"}}`}},
+		"other":  "{{ .Values.unrelated }}",
+	}
+	mustRequest(root, logical.UpdateOperation, "refs/app", document)
+	mustRequest(root, logical.UpdateOperation, "plain/app", document)
+
+	t.Run("resolved", func(t *testing.T) {
+		resp := mustRequest(root, logical.ReadOperation, "refs/app", nil)
+		require.Equal(t, "postgres://app:correct-horse@db:5432", resp.Data["dsn"])
+		require.Equal(t, json.Number("5432"), resp.Data["port"])
+		require.Equal(t, map[string]interface{}{"passwords": []interface{}{"correct-horse"}}, resp.Data["nested"])
+		require.Equal(t, "{{ .Values.unrelated }}", resp.Data["other"])
+	})
+
+	t.Run("not enabled", func(t *testing.T) {
+		resp := mustRequest(root, logical.ReadOperation, "plain/app", nil)
+		require.Equal(t, document["dsn"], resp.Data["dsn"])
+	})
+
+	t.Run("missing field", func(t *testing.T) {
+		mustRequest(root, logical.UpdateOperation, "refs/missing", map[string]interface{}{
+			"value": `{{vault "secret/db" "hostname"}}`,
+		})
+		resp, err := request(root, logical.ReadOperation, "refs/missing", nil)
+		require.Error(t, err)
+		require.ErrorContains(t, resp.Error(), `unable to resolve reference to "secret/db": field "hostname" not found`)
+	})
+
+	t.Run("acl", func(t *testing.T) {
+		policy, err := ParseACLPolicy(namespace.RootNamespace, `
+path "refs/*" {
+	capabilities = ["read"]
+}
+`)
+		require.NoError(t, err)
+		policy.Name = "refs-only"
+		require.NoError(t, c.policyStore.SetPolicy(ctx, policy))
+		testMakeServiceTokenViaCore(t, c, root, "refs-only-token", "", []string{"refs-only"})
+
+		resp, err := request("refs-only-token", logical.ReadOperation, "refs/app", nil)
+		require.True(t, errors.Is(err, logical.ErrPermissionDenied), "expected permission denied, got: %v", err)
+		require.ErrorContains(t, resp.Error(), `unable to resolve reference to "secret/db"`)
+	})
+}
+
+// TestPassthroughReferences_KVPlugin ensures that mounts served by the KV
+// plugin, which does not resolve references, reject the option.
+func TestPassthroughReferences_KVPlugin(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+	ctx := namespace.RootContext(nil)
+
+	_, err := c.HandleRequest(ctx, &logical.Request{
+		Operation:   logical.UpdateOperation,
+		Path:        "sys/mounts/refs",
+		ClientToken: root,
+		Data: map[string]interface{}{
+			"type":    "kv",
+			"options": map[string]string{"resolve_references": "true"},
+		},
+	})
+	require.ErrorContains(t, err, `option "resolve_references" is only supported by the builtin passthrough kv backend`)
+
+	resp, err := c.HandleRequest(ctx, &logical.Request{
+		Operation:   logical.UpdateOperation,
+		Path:        "sys/mounts/secret/tune",
+		ClientToken: root,
+		Data: map[string]interface{}{
+			"options": map[string]string{"resolve_references": "true"},
+		},
+	})
+	require.Error(t, err)
+	require.ErrorContains(t, resp.Error(), `option "resolve_references" is only supported by the builtin passthrough kv backend`)
+}
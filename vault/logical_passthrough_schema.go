@@ -0,0 +1,92 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package vault
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+const (
+	// passthroughSchemaOption is the mount option holding a JSON Schema
+	// that every secret written to the mount must satisfy.
+	passthroughSchemaOption = "schema"
+
+	// passthroughSchemaOptionPrefix prefixes mount options holding a JSON
+	// Schema for the secrets below a path prefix, e.g. "schema:app/".
+	passthroughSchemaOptionPrefix = "schema:"
+)
+
+// passthroughSchema is a JSON Schema applied to the secrets written below a
+// path prefix of a passthrough mount.
+type passthroughSchema struct {
+	prefix string
+	schema *gojsonschema.Schema
+}
+
+// isPassthroughSchemaOption reports whether the mount option configures a
+// JSON Schema.
+func isPassthroughSchemaOption(key string) bool {
+	return key == passthroughSchemaOption || strings.HasPrefix(key, passthroughSchemaOptionPrefix)
+}
+
+// parsePassthroughSchemas compiles the JSON Schemas configured in the mount
+// options. The result is ordered from the longest prefix to the shortest,
+// so the first schema whose prefix matches a path is the most specific one.
+func parsePassthroughSchemas(options map[string]string) ([]*passthroughSchema, error) {
+	var schemas []*passthroughSchema
+	for key, raw := range options {
+		if !isPassthroughSchemaOption(key) || raw == "" {
+			continue
+		}
+
+		prefix := strings.TrimPrefix(strings.TrimPrefix(key, passthroughSchemaOption), ":")
+		schema, err := gojsonschema.NewSchema(gojsonschema.NewStringLoader(raw))
+		if err != nil {
+			return nil, fmt.Errorf("invalid JSON schema in option %q: %w", key, err)
+		}
+		schemas = append(schemas, &passthroughSchema{
+			prefix: prefix,
+			schema: schema,
+		})
+	}
+
+	sort.Slice(schemas, func(i, j int) bool {
+		return len(schemas[i].prefix) > len(schemas[j].prefix)
+	})
+
+	return schemas, nil
+}
+
+// schemaForPath returns the most specific schema for the path, or nil if no
+// schema applies.
+func schemaForPath(schemas []*passthroughSchema, path string) *passthroughSchema {
+	for _, schema := range schemas {
+		if strings.HasPrefix(path, schema.prefix) {
+			return schema
+		}
+	}
+	return nil
+}
+
+// validate checks an encoded secret against the schema. It returns one
+// error per violated constraint, naming the offending field.
+func (s *passthroughSchema) validate(encoded []byte) ([]string, error) {
+	result, err := s.schema.Validate(gojsonschema.NewBytesLoader(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("schema validation failed: %w", err)
+	}
+	if result.Valid() {
+		return nil, nil
+	}
+
+	errs := make([]string, 0, len(result.Errors()))
+	for _, resultErr := range result.Errors() {
+		errs = append(errs, fmt.Sprintf("%s: %s", resultErr.Field(), resultErr.Description()))
+	}
+	return errs, nil
+}
@@ -6,7 +6,9 @@ package vault
 import (
 	"context"
 	"encoding/json"
+	"net/http"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -166,6 +168,181 @@ func TestPassthroughBackend_Delete(t *testing.T) {
 	test(b)
 }
 
+func TestPassthroughBackend_ConditionalWrites(t *testing.T) {
+	options := map[string]string{passthroughConditionalWritesOption: "true"}
+	test := func(t *testing.T, b logical.Backend) {
+		storage := &logical.InmemStorage{}
+		request := func(op logical.Operation, data map[string]interface{}, headers map[string][]string) *logical.Response {
+			t.Helper()
+			req := logical.TestRequest(t, op, "foo")
+			req.Storage = storage
+			req.Data = data
+			req.Headers = headers
+			resp, err := b.HandleRequest(context.Background(), req)
+			if err != nil {
+				t.Fatalf("err: %v", err)
+			}
+			return resp
+		}
+		write := func(data map[string]interface{}, headers map[string][]string) *logical.Response {
+			t.Helper()
+			return request(logical.UpdateOperation, data, headers)
+		}
+		requireMismatch := func(resp *logical.Response) {
+			t.Helper()
+			if resp == nil || resp.Data[logical.HTTPStatusCode] != http.StatusPreconditionFailed {
+				t.Fatalf("expected precondition failure, got: %#v", resp)
+			}
+		}
+		version := func(resp *logical.Response) string {
+			t.Helper()
+			if resp == nil || resp.Data["version"] == "" {
+				t.Fatalf("expected version, got: %#v", resp)
+			}
+			return resp.Data["version"].(string)
+		}
+		ifMatch := func(tags ...string) map[string][]string {
+			return map[string][]string{"If-Match": tags}
+		}
+		createOnly := map[string][]string{"If-None-Match": {"*"}}
+
+		// Requiring a new key fails once it exists
+		v1 := version(write(map[string]interface{}{"raw": "one"}, createOnly))
+		requireMismatch(write(map[string]interface{}{"raw": "two"}, createOnly))
+
+		// A field named like a write option is stored like any other field
+		// and the read advertises the version
+		if resp := write(map[string]interface{}{"raw": "one", "cas": "0"}, ifMatch(`"`+v1+`"`)); resp == nil {
+			t.Fatal("expected a conditional write response")
+		}
+		resp := request(logical.ReadOperation, nil, nil)
+		if !reflect.DeepEqual(resp.Data, map[string]interface{}{"raw": "one", "cas": "0"}) {
+			t.Fatalf("bad data: %#v", resp.Data)
+		}
+		stored, err := storage.Get(context.Background(), "foo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		v2 := passthroughVersion(stored)
+		if etag := resp.Headers["ETag"]; !reflect.DeepEqual(etag, []string{`"` + v2 + `"`}) {
+			t.Fatalf("bad etag: %v", etag)
+		}
+
+		// A write with the current version succeeds and changes the version,
+		// so a second writer with the same version is rejected. If-Match
+		// accepts quoted, weak and wildcard tags.
+		requireMismatch(write(map[string]interface{}{"raw": "three"}, ifMatch(`"`+v1+`"`)))
+		v3 := version(write(map[string]interface{}{"raw": "three"}, ifMatch(`"other", W/"`+v2+`"`)))
+		if v3 == v2 {
+			t.Fatal("expected version to change")
+		}
+		version(write(map[string]interface{}{"raw": "four"}, ifMatch("*")))
+
+		// Unconditional writes still succeed with an empty response
+		if resp := write(map[string]interface{}{"raw": "five"}, nil); resp != nil {
+			t.Fatalf("bad: %#v", resp)
+		}
+
+		// Conditional deletes
+		requireMismatch(request(logical.DeleteOperation, nil, ifMatch(v3)))
+		stored, err = storage.Get(context.Background(), "foo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp := request(logical.DeleteOperation, nil, ifMatch(passthroughVersion(stored))); resp != nil {
+			t.Fatalf("bad: %#v", resp)
+		}
+
+		// If-Match never matches a missing key
+		requireMismatch(write(map[string]interface{}{"raw": "six"}, ifMatch("*")))
+	}
+	t.Run("passthrough", func(t *testing.T) {
+		test(t, testPassthroughBackendWithOptions(t, PassthroughBackendFactory, options))
+	})
+	t.Run("passthrough-leased", func(t *testing.T) {
+		test(t, testPassthroughBackendWithOptions(t, LeasedPassthroughBackendFactory, options))
+	})
+	t.Run("disabled", func(t *testing.T) {
+		b := testPassthroughBackend()
+		storage := &logical.InmemStorage{}
+		req := logical.TestRequest(t, logical.UpdateOperation, "foo")
+		req.Storage = storage
+		req.Data["raw"] = "test"
+		req.Headers = map[string][]string{"If-Match": {"*"}}
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil || resp != nil {
+			t.Fatalf("expected headers to be ignored: %#v, %v", resp, err)
+		}
+
+		req = logical.TestRequest(t, logical.ReadOperation, "foo")
+		req.Storage = storage
+		resp, err = b.HandleRequest(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Headers != nil {
+			t.Fatalf("expected no version headers, got: %v", resp.Headers)
+		}
+	})
+}
+
+func TestPassthroughBackend_Schema(t *testing.T) {
+	options := map[string]string{
+		"version":     "1",
+		"schema":      `{"type": "object", "properties": {"ttl": {"type": "string"}}}`,
+		"schema:app/": `{"type": "object", "required": ["password"], "properties": {"password": {"type": "string", "minLength": 8}}}`,
+	}
+	test := func(t *testing.T, b logical.Backend) {
+		write := func(path string, data map[string]interface{}) *logical.Response {
+			t.Helper()
+			req := logical.TestRequest(t, logical.UpdateOperation, path)
+			req.Data = data
+			resp, err := b.HandleRequest(context.Background(), req)
+			if err != nil {
+				t.Fatalf("err: %v", err)
+			}
+			return resp
+		}
+
+		// The most specific prefix applies
+		if resp := write("app/db", map[string]interface{}{"password": "correct-horse"}); resp != nil {
+			t.Fatalf("bad: %#v", resp)
+		}
+		resp := write("app/db", map[string]interface{}{"passwd": "correct-horse"})
+		if !resp.IsError() || !strings.Contains(resp.Error().Error(), `schema for path prefix "app/": (root): password is required`) {
+			t.Fatalf("bad: %#v", resp)
+		}
+		resp = write("app/db", map[string]interface{}{"password": "short"})
+		if !resp.IsError() || !strings.Contains(resp.Error().Error(), "password: String length must be greater than or equal to 8") {
+			t.Fatalf("bad: %#v", resp)
+		}
+
+		// The mount-wide schema applies everywhere else
+		if resp := write("other", map[string]interface{}{"passwd": "anything"}); resp != nil {
+			t.Fatalf("bad: %#v", resp)
+		}
+		resp = write("other", map[string]interface{}{"ttl": 60})
+		if !resp.IsError() || !strings.Contains(resp.Error().Error(), `schema for path prefix "": ttl: Invalid type`) {
+			t.Fatalf("bad: %#v", resp)
+		}
+	}
+	t.Run("passthrough", func(t *testing.T) {
+		test(t, testPassthroughBackendWithOptions(t, PassthroughBackendFactory, options))
+	})
+	t.Run("passthrough-leased", func(t *testing.T) {
+		test(t, testPassthroughBackendWithOptions(t, LeasedPassthroughBackendFactory, options))
+	})
+	t.Run("invalid schema", func(t *testing.T) {
+		_, err := PassthroughBackendFactory(context.Background(), &logical.BackendConfig{
+			System: logical.StaticSystemView{},
+			Config: map[string]string{"schema:app/": `{"type": 1}`},
+		})
+		if err == nil || !strings.Contains(err.Error(), `invalid JSON schema in option "schema:app/"`) {
+			t.Fatalf("expected invalid schema error, got: %v", err)
+		}
+	})
+}
+
 func TestPassthroughBackend_List(t *testing.T) {
 	test := func(b logical.Backend) {
 		req := logical.TestRequest(t, logical.UpdateOperation, "foo")
@@ -242,3 +419,19 @@ func testPassthroughLeasedBackend() logical.Backend {
 	})
 	return b
 }
+
+func testPassthroughBackendWithOptions(t *testing.T, factory logical.Factory, options map[string]string) logical.Backend {
+	t.Helper()
+	b, err := factory(context.Background(), &logical.BackendConfig{
+		Logger: nil,
+		System: logical.StaticSystemView{
+			DefaultLeaseTTLVal: time.Hour * 24,
+			MaxLeaseTTLVal:     time.Hour * 24 * 32,
+		},
+		Config: options,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
@@ -213,6 +213,7 @@ func NewSystemBackend(core *Core, logger log.Logger, config *logical.BackendConf
 	b.Backend.Paths = append(b.Backend.Paths, b.leasePaths()...)
 	b.Backend.Paths = append(b.Backend.Paths, b.policyPaths()...)
 	b.Backend.Paths = append(b.Backend.Paths, b.wrappingPaths()...)
+	b.Backend.Paths = append(b.Backend.Paths, b.cubbyholeGrantPaths()...)
 	b.Backend.Paths = append(b.Backend.Paths, b.toolsPaths()...)
 	b.Backend.Paths = append(b.Backend.Paths, b.capabilitiesPaths()...)
 	b.Backend.Paths = append(b.Backend.Paths, b.internalPaths()...)
@@ -2785,6 +2786,28 @@ func (b *SystemBackend) handleTuneWriteCommon(ctx context.Context, path string,
 			}
 		}
 
+		// Options that only the builtin passthrough backend implements are
+		// rejected on other backends, such as the KV plugin, which would
+		// silently ignore them
+		if err := checkPassthroughOptions(options, b.Core.router.MatchingBackend(ctx, path)); err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+		var passthroughChanged bool
+		for k := range options {
+			if isPassthroughOnlyOption(k) {
+				passthroughChanged = true
+				break
+			}
+		}
+
+		// JSON Schema options are validated up front, since an invalid schema
+		// would keep the mount from loading
+		if passthroughChanged {
+			if _, err := parsePassthroughSchemas(options); err != nil {
+				return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+			}
+		}
+
 		// Upsert options value to a copy of the existing mountEntry's options
 		for k, v := range mountEntry.Options {
 			newOptions[k] = v
@@ -2815,9 +2838,10 @@ func (b *SystemBackend) handleTuneWriteCommon(ctx context.Context, path string,
 			return handleError(err)
 		}
 
-		// Reload the backend to kick off the upgrade process. It should only apply to KV backend so we
-		// trigger based on the version logic above.
-		if kvUpgraded {
+		// Reload the backend to kick off the upgrade process or to pick up
+		// changes to the options of the passthrough backend. It should only
+		// apply to KV backend so we trigger based on the logic above.
+		if kvUpgraded || passthroughChanged {
 			err = b.Core.reloadBackendCommon(ctx, mountEntry, strings.HasPrefix(path, credentialRoutePrefix))
 			if err != nil {
 				b.Core.logger.Error("mount tuning of options: could not reload backend", "error", err, "path", path, "options", options)
@@ -0,0 +1,258 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package vault
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// cubbyholeGrantPaths returns the paths that share paths of a token's
+// cubbyhole with other entities. They are served by the system backend so
+// that they do not take up paths of the cubbyholes themselves.
+func (b *SystemBackend) cubbyholeGrantPaths() []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: "cubbyhole/grant$",
+
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: "cubbyhole",
+				OperationVerb:   "create",
+				OperationSuffix: "grant",
+			},
+
+			Fields: map[string]*framework.FieldSchema{
+				"path": {
+					Type:        framework.TypeString,
+					Description: "Path in the cubbyhole of the token to grant read access to.",
+					Required:    true,
+				},
+				"entity_id": {
+					Type:        framework.TypeString,
+					Description: "ID of the entity to grant read access to.",
+					Required:    true,
+				},
+				"ttl": {
+					Type:        framework.TypeDurationSecond,
+					Description: "Lifetime of the grant. Defaults to one hour, and cannot exceed the maximum lease TTL of the cubbyhole mount.",
+					Default:     int(cubbyholeGrantDefaultTTL.Seconds()),
+				},
+				"max_reads": {
+					Type:        framework.TypeInt,
+					Description: "Number of reads after which the grant is removed. Defaults to 0, which only limits the grant by its TTL.",
+				},
+			},
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.handleCubbyholeGrantCreate,
+					Summary:  "Grant an entity read access to a path of the cubbyhole.",
+				},
+			},
+
+			HelpSynopsis:    strings.TrimSpace(cubbyholeGrantHelp["grant"][0]),
+			HelpDescription: strings.TrimSpace(cubbyholeGrantHelp["grant"][1]),
+		},
+		{
+			Pattern: "cubbyhole/grants/?$",
+
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: "cubbyhole",
+				OperationVerb:   "list",
+				OperationSuffix: "grants",
+			},
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ListOperation: &framework.PathOperation{
+					Callback: b.handleCubbyholeGrantList,
+					Summary:  "List the grants of the cubbyhole.",
+				},
+			},
+
+			HelpSynopsis:    strings.TrimSpace(cubbyholeGrantHelp["grant"][0]),
+			HelpDescription: strings.TrimSpace(cubbyholeGrantHelp["grant"][1]),
+		},
+		{
+			Pattern: "cubbyhole/grants/" + framework.GenericNameRegex("grant_id"),
+
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: "cubbyhole",
+				OperationSuffix: "grant",
+			},
+
+			Fields: map[string]*framework.FieldSchema{
+				"grant_id": {
+					Type:        framework.TypeString,
+					Description: "ID of the grant.",
+				},
+			},
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.handleCubbyholeGrantRead,
+					DisplayAttrs: &framework.DisplayAttributes{
+						OperationVerb: "read",
+					},
+					Summary: "Read a grant of the cubbyhole, including its reads.",
+				},
+				logical.DeleteOperation: &framework.PathOperation{
+					Callback: b.handleCubbyholeGrantDelete,
+					DisplayAttrs: &framework.DisplayAttributes{
+						OperationVerb: "revoke",
+					},
+					Summary: "Revoke a grant of the cubbyhole.",
+				},
+			},
+
+			HelpSynopsis:    strings.TrimSpace(cubbyholeGrantHelp["grant"][0]),
+			HelpDescription: strings.TrimSpace(cubbyholeGrantHelp["grant"][1]),
+		},
+		{
+			Pattern: "cubbyhole/shared/" + framework.GenericNameRegex("grant_id"),
+
+			DisplayAttrs: &framework.DisplayAttributes{
+				OperationPrefix: "cubbyhole",
+				OperationVerb:   "read",
+				OperationSuffix: "shared",
+			},
+
+			Fields: map[string]*framework.FieldSchema{
+				"grant_id": {
+					Type:        framework.TypeString,
+					Description: "ID of the grant.",
+				},
+			},
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.handleCubbyholeSharedRead,
+					Summary:  "Read a path of another cubbyhole that was shared with the requesting entity.",
+				},
+			},
+
+			HelpSynopsis:    strings.TrimSpace(cubbyholeGrantHelp["shared"][0]),
+			HelpDescription: strings.TrimSpace(cubbyholeGrantHelp["shared"][1]),
+		},
+	}
+}
+
+// cubbyholeGrantStorage returns the storage of the cubbyhole mount of the
+// request's namespace, which holds the grants.
+func (b *SystemBackend) cubbyholeGrantStorage(ctx context.Context) (*CubbyholeBackend, logical.Storage, error) {
+	ts := b.Core.tokenStore
+	if ts == nil || ts.cubbyholeBackend == nil {
+		return nil, nil, errors.New("no cubbyhole backend")
+	}
+
+	storage := b.Core.router.MatchingStorageByAPIPath(ctx, mountPathCubbyhole)
+	if storage == nil {
+		return nil, nil, errors.New("no cubby mount entry")
+	}
+
+	return ts.cubbyholeBackend, storage, nil
+}
+
+// cubbyholeGrantOwner returns the cubbyhole backend and storage along with the
+// storage key of the requesting token's cubbyhole.
+func (b *SystemBackend) cubbyholeGrantOwner(ctx context.Context, req *logical.Request) (*CubbyholeBackend, logical.Storage, string, *logical.Response, error) {
+	te, err := b.Core.LookupToken(ctx, req.ClientToken)
+	if err != nil {
+		return nil, nil, "", nil, err
+	}
+	if te == nil {
+		return nil, nil, "", nil, errors.New("nil token entry")
+	}
+	if te.Type != logical.TokenTypeService {
+		return nil, nil, "", logical.ErrorResponse(`cubbyhole operations are only supported by "service" type tokens`), nil
+	}
+
+	cb, storage, err := b.cubbyholeGrantStorage(ctx)
+	if err != nil {
+		return nil, nil, "", nil, err
+	}
+
+	key, err := b.Core.tokenStore.cubbyholeKey(ctx, te)
+	if err != nil {
+		return nil, nil, "", nil, err
+	}
+
+	return cb, storage, key, nil, nil
+}
+
+func (b *SystemBackend) handleCubbyholeGrantCreate(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	cb, storage, key, resp, err := b.cubbyholeGrantOwner(ctx, req)
+	if resp != nil || err != nil {
+		return resp, err
+	}
+
+	return cb.createGrant(ctx, storage, key, req.EntityID, data)
+}
+
+func (b *SystemBackend) handleCubbyholeGrantList(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	cb, storage, key, resp, err := b.cubbyholeGrantOwner(ctx, req)
+	if resp != nil || err != nil {
+		return resp, err
+	}
+
+	return cb.listGrants(ctx, storage, key)
+}
+
+func (b *SystemBackend) handleCubbyholeGrantRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	cb, storage, key, resp, err := b.cubbyholeGrantOwner(ctx, req)
+	if resp != nil || err != nil {
+		return resp, err
+	}
+
+	return cb.readGrant(ctx, storage, key, data.Get("grant_id").(string))
+}
+
+func (b *SystemBackend) handleCubbyholeGrantDelete(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	cb, storage, key, resp, err := b.cubbyholeGrantOwner(ctx, req)
+	if resp != nil || err != nil {
+		return resp, err
+	}
+
+	return nil, cb.revokeGrant(ctx, storage, key, data.Get("grant_id").(string))
+}
+
+func (b *SystemBackend) handleCubbyholeSharedRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	if req.EntityID == "" {
+		return logical.ErrorResponse("reading a shared path requires a token with an entity"), logical.ErrPermissionDenied
+	}
+
+	cb, storage, err := b.cubbyholeGrantStorage(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return cb.readShared(ctx, storage, req.EntityID, req.ID, data.Get("grant_id").(string))
+}
+
+var cubbyholeGrantHelp = map[string][2]string{
+	"grant": {
+		"Share a path of the cubbyhole with another entity for a limited time.",
+		`
+A grant gives an entity read access to a path of the cubbyhole of the token
+that created it. Grants are created by writing to "sys/cubbyhole/grant", and
+expire after their TTL or once they have been read "max_reads" times. Grants
+are also removed when the token that created them is revoked.
+
+Every read of the shared path is recorded on the grant, which the owner can
+read at "sys/cubbyhole/grants/<grant_id>". The owner can list its grants at
+"sys/cubbyhole/grants" and revoke a grant by deleting it.
+`,
+	},
+	"shared": {
+		"Read a path of another token's cubbyhole that was shared with the requesting entity.",
+		`
+The grantee reads the shared path at "sys/cubbyhole/shared/<grant_id>". The
+requesting token must belong to the entity of the grant. Each read counts
+towards the "max_reads" of the grant.
+`,
+	},
+}
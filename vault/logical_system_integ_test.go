@@ -133,6 +133,14 @@ func TestSystemBackend_InternalUIResultantACL(t *testing.T) {
 					"update",
 				},
 			},
+			"sys/cubbyhole/": map[string]interface{}{
+				"capabilities": []interface{}{
+					"delete",
+					"list",
+					"read",
+					"update",
+				},
+			},
 			"sys/tools/hash/": map[string]interface{}{
 				"capabilities": []interface{}{
 					"update",
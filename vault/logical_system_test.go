@@ -674,6 +674,114 @@ func TestSystemBackend_tune_identity_token_key(t *testing.T) {
 	}
 }
 
+// TestSystemBackend_passthroughOnlyOptions ensures that options only the
+// builtin passthrough backend implements are rejected on mounts served by the
+// KV plugin, which would ignore them.
+func TestSystemBackend_passthroughOnlyOptions(t *testing.T) {
+	_, b, _ := testCoreSystemBackend(t)
+	ctx := namespace.RootContext(nil)
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "mounts/secret/tune")
+	req.Data["options"] = map[string]interface{}{
+		passthroughConditionalWritesOption: "true",
+	}
+	resp, err := b.HandleRequest(ctx, req)
+	require.Equal(t, logical.ErrInvalidRequest, err)
+	require.ErrorContains(t, resp.Error(), `option "conditional_writes" is only supported by the builtin passthrough kv backend`)
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "mounts/other")
+	req.Data["type"] = "kv"
+	req.Data["options"] = map[string]interface{}{
+		passthroughConditionalWritesOption: "true",
+	}
+	_, err = b.HandleRequest(ctx, req)
+	require.ErrorContains(t, err, `option "conditional_writes" is only supported by the builtin passthrough kv backend`)
+
+	c, _, root := TestCoreUnsealedWithConfig(t, &CoreConfig{
+		LogicalBackends: map[string]logical.Factory{
+			"kv": PassthroughBackendFactory,
+		},
+	})
+	req = logical.TestRequest(t, logical.UpdateOperation, "mounts/secret/tune")
+	req.Data["options"] = map[string]interface{}{
+		passthroughConditionalWritesOption: "true",
+	}
+	resp, err = c.systemBackend.HandleRequest(ctx, req)
+	require.NoError(t, err)
+	require.False(t, resp.IsError(), "unexpected error: %v", resp.Error())
+	backend, ok := c.router.MatchingBackend(ctx, "secret/").(*PassthroughBackend)
+	require.True(t, ok)
+	require.True(t, backend.conditionalWrites)
+
+	// The conditional write headers reach the backend and the client without
+	// tuning the mount's header lists
+	write := func() *logical.Response {
+		t.Helper()
+		req := logical.TestRequest(t, logical.UpdateOperation, "secret/foo")
+		req.ClientToken = root
+		req.Data["raw"] = "value"
+		req.Headers = map[string][]string{passthroughIfNoneMatchHeader: {"*"}}
+		resp, err := c.HandleRequest(ctx, req)
+		require.NoError(t, err)
+		return resp
+	}
+	resp = write()
+	require.NotNil(t, resp)
+	require.NotEmpty(t, resp.Headers[passthroughETagHeader])
+	resp = write()
+	require.NotNil(t, resp)
+	require.Equal(t, http.StatusPreconditionFailed, resp.Data[logical.HTTPStatusCode])
+}
+
+// TestSystemBackend_tune_kvSchemaOptions ensures that JSON schema options
+// are validated when tuning a passthrough KV mount, and rejected on mounts
+// served by the KV plugin, which does not enforce them.
+func TestSystemBackend_tune_kvSchemaOptions(t *testing.T) {
+	_, b, _ := testCoreSystemBackend(t)
+	ctx := namespace.RootContext(nil)
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "mounts/secret/tune")
+	req.Data["options"] = map[string]interface{}{
+		"schema:app/": `{"type": "object", "required": ["password"]}`,
+	}
+	resp, err := b.HandleRequest(ctx, req)
+	require.Equal(t, logical.ErrInvalidRequest, err)
+	require.ErrorContains(t, resp.Error(), `option "schema:app/" is only supported by the builtin passthrough kv backend`)
+
+	c, _, root := TestCoreUnsealedWithConfig(t, &CoreConfig{
+		LogicalBackends: map[string]logical.Factory{
+			"kv": PassthroughBackendFactory,
+		},
+	})
+
+	req.Data["options"] = map[string]interface{}{
+		"schema:app/": `{"type": "object", "required": [`,
+	}
+	resp, err = c.systemBackend.HandleRequest(ctx, req)
+	require.Equal(t, logical.ErrInvalidRequest, err)
+	require.ErrorContains(t, resp.Error(), `invalid JSON schema in option "schema:app/"`)
+
+	req.Data["options"] = map[string]interface{}{
+		"schema:app/": `{"type": "object", "required": ["password"]}`,
+	}
+	resp, err = c.systemBackend.HandleRequest(ctx, req)
+	require.NoError(t, err)
+	require.False(t, resp.IsError(), "unexpected error: %v", resp.Error())
+
+	mountEntry := c.router.MatchingMountEntry(ctx, "secret/")
+	require.NotNil(t, mountEntry)
+	require.Equal(t, `{"type": "object", "required": ["password"]}`, mountEntry.Options["schema:app/"])
+
+	// The reloaded backend enforces the schema
+	resp, err = c.HandleRequest(ctx, &logical.Request{
+		Operation:   logical.UpdateOperation,
+		Path:        "secret/app/db",
+		ClientToken: root,
+		Data:        map[string]interface{}{"user": "app"},
+	})
+	require.ErrorContains(t, resp.Error(), "password is required")
+}
+
 func TestSystemBackend_mount_invalid(t *testing.T) {
 	b := testSystemBackend(t)
 
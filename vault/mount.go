@@ -488,16 +488,22 @@ func (e *MountEntry) SyncCache() {
 		e.synthesizedConfigCache.Store("audit_non_hmac_response_keys", e.Config.AuditNonHMACResponseKeys)
 	}
 
-	if len(e.Config.PassthroughRequestHeaders) == 0 {
+	// Headers the mount options of the passthrough backend depend on are
+	// always passed through, whatever the tuned header lists
+	requestHeaders, responseHeaders := passthroughOptionHeaders(e.Options)
+	requestHeaders = append(requestHeaders, e.Config.PassthroughRequestHeaders...)
+	responseHeaders = append(responseHeaders, e.Config.AllowedResponseHeaders...)
+
+	if len(requestHeaders) == 0 {
 		e.synthesizedConfigCache.Delete("passthrough_request_headers")
 	} else {
-		e.synthesizedConfigCache.Store("passthrough_request_headers", e.Config.PassthroughRequestHeaders)
+		e.synthesizedConfigCache.Store("passthrough_request_headers", requestHeaders)
 	}
 
-	if len(e.Config.AllowedResponseHeaders) == 0 {
+	if len(responseHeaders) == 0 {
 		e.synthesizedConfigCache.Delete("allowed_response_headers")
 	} else {
-		e.synthesizedConfigCache.Store("allowed_response_headers", e.Config.AllowedResponseHeaders)
+		e.synthesizedConfigCache.Store("allowed_response_headers", responseHeaders)
 	}
 
 	if len(e.Config.AllowedManagedKeys) == 0 {
@@ -716,6 +722,11 @@ func (c *Core) mountInternal(ctx context.Context, entry *MountEntry, updateStora
 		}
 	}
 
+	if err := checkPassthroughOptions(entry.Options, backend); err != nil {
+		backend.Cleanup(ctx)
+		return logical.CodedError(400, err.Error())
+	}
+
 	addPathCheckers(c, entry, backend, viewPath)
 
 	c.setCoreBackend(entry, backend, view)
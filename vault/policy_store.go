@@ -122,6 +122,12 @@ path "cubbyhole/*" {
     capabilities = ["create", "read", "update", "delete", "list"]
 }
 
+# Allow a token to share paths of its own cubbyhole with other entities, and
+# to read paths shared with its entity
+path "sys/cubbyhole/*" {
+    capabilities = ["read", "update", "delete", "list"]
+}
+
 # Allow a token to wrap arbitrary values in a response-wrapping token
 path "sys/wrapping/wrap" {
     capabilities = ["update"]
@@ -6,6 +6,7 @@ package vault
 import (
 	"context"
 	"crypto/hmac"
+	"crypto/subtle"
 	"encoding/base64"
 	"errors"
 	"fmt"
@@ -33,6 +34,7 @@ import (
 	"github.com/hashicorp/vault/http/priority"
 	"github.com/hashicorp/vault/internalshared/configutil"
 	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/certutil"
 	"github.com/hashicorp/vault/sdk/helper/consts"
 	"github.com/hashicorp/vault/sdk/helper/errutil"
 	"github.com/hashicorp/vault/sdk/helper/jsonutil"
@@ -278,6 +280,18 @@ func (c *Core) fetchACLTokenEntryAndEntity(ctx context.Context, req *logical.Req
 		}
 	}
 
+	// Certificate-bound tokens can only be used over mTLS with the client
+	// certificate they were issued for
+	if te.BoundCertificateThumbprint != "" {
+		if req.Connection == nil || req.Connection.ConnState == nil || len(req.Connection.ConnState.PeerCertificates) == 0 {
+			return nil, nil, nil, nil, logical.ErrPermissionDenied
+		}
+		thumbprint := certutil.GetCertificateThumbprint(req.Connection.ConnState.PeerCertificates[0])
+		if subtle.ConstantTimeCompare([]byte(thumbprint), []byte(te.BoundCertificateThumbprint)) != 1 {
+			return nil, nil, nil, nil, logical.ErrPermissionDenied
+		}
+	}
+
 	policyNames := make(map[string][]string)
 	// Add tokens policies
 	policyNames[te.NamespaceID] = append(policyNames[te.NamespaceID], te.Policies...)
@@ -1181,6 +1195,15 @@ func (c *Core) handleRequest(ctx context.Context, req *logical.Request) (retResp
 
 	// Route the request
 	resp, routeErr := c.doRouting(ctx, req)
+	if routeErr == nil && c.shouldResolvePassthroughReferences(ctx, req, resp) {
+		if err := c.resolvePassthroughReferences(ctx, req, resp); err != nil {
+			retErr = multierror.Append(retErr, err)
+			if errors.Is(err, logical.ErrPermissionDenied) {
+				retErr = multierror.Append(retErr, logical.ErrPermissionDenied)
+			}
+			return logical.ErrorResponse(err.Error()), auth, retErr
+		}
+	}
 	if resp != nil {
 		// Add mount type information to the response
 		if entry != nil {
@@ -2400,6 +2423,12 @@ func (c *Core) RegisterAuth(ctx context.Context, tokenTTL time.Duration, path st
 		ExplicitMaxTTL: auth.ExplicitMaxTTL,
 		Period:         auth.Period,
 		Type:           auth.TokenType,
+
+		BoundCertificateThumbprint: auth.BoundCertificateThumbprint,
+	}
+
+	if te.BoundCertificateThumbprint != "" && te.Type == logical.TokenTypeBatch {
+		return fmt.Errorf("batch tokens cannot be bound to a client certificate")
 	}
 
 	if te.TTL == 0 && (len(te.Policies) != 1 || te.Policies[0] != "root") {
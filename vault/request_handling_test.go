@@ -4,6 +4,13 @@
 package vault
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
 	"strings"
 	"testing"
 	"time"
@@ -14,6 +21,7 @@ import (
 	"github.com/hashicorp/vault/builtin/credential/approle"
 	credUserpass "github.com/hashicorp/vault/builtin/credential/userpass"
 	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/helper/certutil"
 	"github.com/hashicorp/vault/sdk/logical"
 )
 
@@ -478,3 +486,157 @@ func TestRequestHandling_SecretLeaseMetric(t *testing.T) {
 		},
 	)
 }
+
+func TestRequestHandling_CertificateBoundToken(t *testing.T) {
+	core, _, _ := TestCoreUnsealed(t)
+
+	testClientCert := func() *x509.Certificate {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(1),
+			Subject:      pkix.Name{CommonName: "client"},
+			NotBefore:    time.Now().Add(-time.Minute),
+			NotAfter:     time.Now().Add(time.Hour),
+			ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cert
+	}
+	boundCert := testClientCert()
+	otherCert := testClientCert()
+	connWith := func(cert *x509.Certificate) *logical.Connection {
+		return &logical.Connection{ConnState: &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}}
+	}
+
+	te := &logical.TokenEntry{
+		Path:                       "auth/cert/login",
+		Policies:                   []string{"root"},
+		TTL:                        time.Hour,
+		BoundCertificateThumbprint: certutil.GetCertificateThumbprint(boundCert),
+	}
+	testMakeTokenDirectly(t, core.tokenStore, te)
+
+	lookupSelf := func(token string, conn *logical.Connection) (*logical.Response, error) {
+		return core.HandleRequest(namespace.RootContext(nil), &logical.Request{
+			Path:        "auth/token/lookup-self",
+			Operation:   logical.ReadOperation,
+			ClientToken: token,
+			Connection:  conn,
+		})
+	}
+
+	for name, conn := range map[string]*logical.Connection{
+		"no TLS":            {},
+		"other certificate": connWith(otherCert),
+	} {
+		if _, err := lookupSelf(te.ID, conn); err == nil || !strings.Contains(err.Error(), logical.ErrPermissionDenied.Error()) {
+			t.Fatalf("%s: expected permission denied, got: %v", name, err)
+		}
+	}
+
+	resp, err := lookupSelf(te.ID, connWith(boundCert))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Data["bound_certificate_thumbprint"] != te.BoundCertificateThumbprint {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Child tokens are bound to the same certificate
+	resp, err = core.HandleRequest(namespace.RootContext(nil), &logical.Request{
+		Path:        "auth/token/create",
+		Operation:   logical.UpdateOperation,
+		ClientToken: te.ID,
+		Data: map[string]interface{}{
+			"policies": []string{"default"},
+		},
+		Connection: connWith(boundCert),
+	})
+	if err != nil || resp == nil || resp.Auth == nil {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	child := resp.Auth.ClientToken
+	if _, err := lookupSelf(child, connWith(otherCert)); err == nil {
+		t.Fatal("expected child token to be bound to the certificate")
+	}
+	if _, err := lookupSelf(child, connWith(boundCert)); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// So are orphans and tokens created with a role or an entity alias
+	resp, err = core.HandleRequest(namespace.RootContext(nil), &logical.Request{
+		Path:        "auth/token/roles/orphans",
+		Operation:   logical.UpdateOperation,
+		ClientToken: te.ID,
+		Data: map[string]interface{}{
+			"orphan":                 true,
+			"allowed_entity_aliases": []string{"alias"},
+		},
+		Connection: connWith(boundCert),
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v, err: %v", resp, err)
+	}
+	for name, req := range map[string]*logical.Request{
+		"orphan": {
+			Path: "auth/token/create-orphan",
+		},
+		"no_parent": {
+			Path: "auth/token/create",
+			Data: map[string]interface{}{"no_parent": true},
+		},
+		"orphan role": {
+			Path: "auth/token/create/orphans",
+		},
+		"entity alias": {
+			Path: "auth/token/create/orphans",
+			Data: map[string]interface{}{"entity_alias": "alias"},
+		},
+	} {
+		if req.Data == nil {
+			req.Data = map[string]interface{}{}
+		}
+		req.Data["policies"] = []string{"default"}
+		req.Operation = logical.UpdateOperation
+		req.ClientToken = te.ID
+		req.Connection = connWith(boundCert)
+		resp, err := core.HandleRequest(namespace.RootContext(nil), req)
+		if err != nil || resp == nil || resp.Auth == nil {
+			t.Fatalf("%s: bad: resp: %#v, err: %v", name, resp, err)
+		}
+		if !resp.Auth.Orphan {
+			t.Fatalf("%s: expected an orphan token", name)
+		}
+		if _, err := lookupSelf(resp.Auth.ClientToken, connWith(otherCert)); err == nil {
+			t.Fatalf("%s: expected token to be bound to the certificate", name)
+		}
+		if _, err := lookupSelf(resp.Auth.ClientToken, connWith(boundCert)); err != nil {
+			t.Fatalf("%s: err: %v", name, err)
+		}
+	}
+
+	// Batch tokens cannot carry the binding
+	resp, err = core.HandleRequest(namespace.RootContext(nil), &logical.Request{
+		Path:        "auth/token/create",
+		Operation:   logical.UpdateOperation,
+		ClientToken: te.ID,
+		Data: map[string]interface{}{
+			"policies": []string{"default"},
+			"type":     "batch",
+		},
+		Connection: connWith(boundCert),
+	})
+	if err == nil || !strings.Contains(resp.Error().Error(), "batch tokens cannot be bound to a client certificate") {
+		t.Fatalf("expected batch token creation to fail, resp: %#v, err: %v", resp, err)
+	}
+}
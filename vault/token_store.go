@@ -119,23 +119,34 @@ var (
 		}
 		view := storage.(*BarrierView)
 
-		switch {
-		case te.NamespaceID == namespace.RootNamespaceID && !IsServiceToken(te.ID):
-			saltedID, err := ts.SaltID(ctx, te.ID)
-			if err != nil {
-				return err
-			}
-			return ts.cubbyholeBackend.revoke(ctx, view, salt.SaltID(ts.cubbyholeBackend.saltUUID, saltedID, salt.SHA1Hash))
-
-		default:
-			if te.CubbyholeID == "" {
-				return fmt.Errorf("missing cubbyhole ID while destroying")
-			}
-			return ts.cubbyholeBackend.revoke(ctx, view, te.CubbyholeID)
+		key, err := ts.cubbyholeKey(ctx, te)
+		if err != nil {
+			return fmt.Errorf("%w while destroying", err)
 		}
+		return ts.cubbyholeBackend.revoke(ctx, view, key)
 	}
 )
 
+// cubbyholeKey returns the storage key of the cubbyhole of the given token,
+// which is the salted ID the router passes to the cubbyhole backend as the
+// client token.
+func (ts *TokenStore) cubbyholeKey(ctx context.Context, te *logical.TokenEntry) (string, error) {
+	switch {
+	case te.NamespaceID == namespace.RootNamespaceID && !IsServiceToken(te.ID):
+		saltedID, err := ts.SaltID(ctx, te.ID)
+		if err != nil {
+			return "", err
+		}
+		return salt.SaltID(ts.cubbyholeBackend.saltUUID, saltedID, salt.SHA1Hash), nil
+
+	default:
+		if te.CubbyholeID == "" {
+			return "", errors.New("missing cubbyhole ID")
+		}
+		return te.CubbyholeID, nil
+	}
+}
+
 func (ts *TokenStore) paths() []*framework.Path {
 	commonFieldsForCreate := map[string]*framework.FieldSchema{
 		"display_name": {
@@ -1145,6 +1156,12 @@ func (ts *TokenStore) create(ctx context.Context, entry *logical.TokenEntry) err
 		return nil
 
 	case logical.TokenTypeBatch:
+		// The certificate binding cannot be encoded in batch tokens, refuse
+		// rather than silently dropping it
+		if entry.BoundCertificateThumbprint != "" {
+			return fmt.Errorf("batch tokens cannot be bound to a client certificate")
+		}
+
 		// Ensure fields we don't support/care about are nilled, proto marshal,
 		// encrypt, skip persistence
 		entry.ID = ""
@@ -2413,6 +2430,11 @@ func (ts *TokenStore) handleTidy(ctx context.Context, req *logical.Request, data
 					ts.logger.Info("checking if there are invalid cubbyholes", "progress", countCubbyholeKeys, "percent_complete", percentComplete)
 				}
 
+				// Grants are stored next to the cubbyholes and tidied below
+				if key == cubbyholeGrantsPrefix {
+					continue
+				}
+
 				key := strings.TrimSuffix(key, "/")
 				if !validCubbyholeKeys[key] {
 					ts.logger.Info("deleting invalid cubbyhole", "key", key)
@@ -2424,6 +2446,15 @@ func (ts *TokenStore) handleTidy(ctx context.Context, req *logical.Request, data
 				}
 			}
 
+			// Remove cubbyhole grants that expired or whose cubbyhole was
+			// deleted
+			deletedCountCubbyholeGrants, err := ts.cubbyholeBackend.tidyGrants(quitCtx, bview, func(key string) bool {
+				return validCubbyholeKeys[key]
+			})
+			if err != nil {
+				tidyErrors = multierror.Append(tidyErrors, fmt.Errorf("failed to tidy cubbyhole grants: %w", err))
+			}
+
 			ts.logger.Info("number of entries scanned in parent prefix", "count", countParentEntries)
 			ts.logger.Info("number of entries deleted in parent prefix", "count", deletedCountParentEntries)
 			ts.logger.Info("number of tokens scanned in parent index list", "count", countParentList)
@@ -2433,6 +2464,7 @@ func (ts *TokenStore) handleTidy(ctx context.Context, req *logical.Request, data
 			ts.logger.Info("number of revoked tokens which were invalid but present in accessors", "count", deletedCountInvalidTokenInAccessor)
 			ts.logger.Info("number of deleted accessors which had invalid tokens", "count", deletedCountAccessorInvalidToken)
 			ts.logger.Info("number of deleted cubbyhole keys that were invalid", "count", deletedCountInvalidCubbyholeKey)
+			ts.logger.Info("number of deleted cubbyhole grants that were expired or invalid", "count", deletedCountCubbyholeGrants)
 
 			return tidyErrors.ErrorOrNil()
 		}
@@ -3052,6 +3084,11 @@ func (ts *TokenStore) handleCreateCommon(ctx context.Context, req *logical.Reque
 		}
 	}
 
+	// Tokens created by certificate-bound tokens are bound to the same
+	// certificate, whether they are orphans, use a role, or set an entity
+	// alias, so the binding cannot be shed by creating a token.
+	te.BoundCertificateThumbprint = parent.BoundCertificateThumbprint
+
 	var explicitMaxTTLToUse time.Duration
 	if explicitMaxTTL != "" {
 		dur, err := parseutil.ParseDurationSecond(explicitMaxTTL)
@@ -3398,6 +3435,10 @@ func (ts *TokenStore) handleLookup(ctx context.Context, req *logical.Request, da
 		resp.Data["bound_cidrs"] = out.BoundCIDRs
 	}
 
+	if out.BoundCertificateThumbprint != "" {
+		resp.Data["bound_certificate_thumbprint"] = out.BoundCertificateThumbprint
+	}
+
 	tokenNS, err := NamespaceByID(ctx, out.NamespaceID, ts.core)
 	if err != nil {
 		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest